@@ -0,0 +1,103 @@
+// Package main registers the operations of apollo/queries as persisted queries,
+// so they keep running when the server only accepts allowlisted operations
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/operations"
+	"github.com/prototype01/internal/config"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/internal/repository/mongodb"
+	"github.com/prototype01/pkg/logger"
+)
+
+// registerTimeout bounds the whole registration
+const registerTimeout = time.Minute
+
+// manifest is an Apollo persisted query manifest, which clients use to send the
+// registered documents by hash
+type manifest struct {
+	Format     string              `json:"format"`
+	Version    int                 `json:"version"`
+	Operations []manifestOperation `json:"operations"`
+}
+
+// manifestOperation is an operation of a persisted query manifest
+type manifestOperation struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Body string `json:"body"`
+}
+
+func main() {
+	dir := flag.String("dir", "apollo/queries", "directory of the .graphql files to register")
+	manifestPath := flag.String("manifest", "", "write an Apollo persisted query manifest of the registered operations to this file")
+	dryRun := flag.Bool("dry-run", false, "validate and print the operations without registering them")
+	flag.Parse()
+
+	logger.Init()
+
+	// Validate the operations against the served schema before storing any of them
+	schema := generated.NewExecutableSchema(generated.Config{}).Schema()
+	ops, err := operations.LoadDir(*dir, schema)
+	if err != nil {
+		logger.Fatal("Failed to load operations", err)
+	}
+	if len(ops) == 0 {
+		logger.Fatal("No operations found in "+*dir, nil)
+	}
+
+	register := func(ctx context.Context, name, query string) (string, error) {
+		return services.QueryHash(query), nil
+	}
+	if !*dryRun {
+		cfg, err := config.Load()
+		if err != nil {
+			logger.Fatal("Failed to load configuration", err)
+		}
+
+		client, err := mongodb.Connect(context.Background(), cfg.MongoDB.URI)
+		if err != nil {
+			logger.Fatal("Failed to connect to MongoDB", err)
+		}
+		defer func() {
+			if err := client.Disconnect(context.Background()); err != nil {
+				logger.Error("Failed to disconnect from MongoDB", err)
+			}
+		}()
+
+		repository := mongodb.NewPersistedQueryRepository(client.Database(cfg.MongoDB.Database))
+		register = services.NewPersistedQueryService(repository, cfg.GraphQL.AllowlistOnly, cfg.GraphQL.PersistedQueryTTL, cfg.GraphQL.PersistedQueryMaxLength).Register
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), registerTimeout)
+	defer cancel()
+
+	out := manifest{Format: "apollo-persisted-query-manifest", Version: 1}
+	for _, op := range ops {
+		hash, err := register(ctx, op.Name, op.Query)
+		if err != nil {
+			logger.Fatal("Failed to register operation "+op.Name, err)
+		}
+		fmt.Printf("%s %s\n", hash, op.Name)
+		out.Operations = append(out.Operations, manifestOperation{ID: hash, Name: op.Name, Type: string(op.Type), Body: op.Query})
+	}
+
+	if *manifestPath != "" {
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			logger.Fatal("Failed to encode the manifest", err)
+		}
+		if err := os.WriteFile(*manifestPath, append(data, '\n'), 0o644); err != nil {
+			logger.Fatal("Failed to write the manifest", err)
+		}
+	}
+}
@@ -54,14 +54,18 @@ func RunServer() {
 		fmt.Fprintf(w, "E-Commerce Backend Setup Complete!")
 	})
 
+	// Background jobs started by the API stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
 	// GraphQL handler (to be implemented in Step 2)
-	graphqlHandler, err := api.NewHandler(client)
+	graphqlHandler, err := api.NewHandler(jobsCtx, cfg, client)
 	if err != nil {
 		logger.Fatal("Failed to create GraphQL handler", err)
 	}
 
-	// Set up GraphQL endpoint with CORS for Apollo Studio
-	mux.Handle("/graphql", middleware.CORSMiddleware(graphqlHandler))
+	// Set up GraphQL endpoint with CORS for Apollo Studio, exposing the request to the auth middleware
+	mux.Handle("/graphql", middleware.CORSMiddleware(middleware.RequestContextMiddleware(graphqlHandler)))
 
 	// Set up GraphQL playground in development mode
 	if cfg.Env == "development" {
@@ -109,6 +113,7 @@ func RunServer() {
 	<-quit
 
 	logger.Info("Shutting down server...")
+	stopJobs()
 
 	// Create context with timeout for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newComplexityRoot returns the cost hints of list fields: the cost of what is
+// selected on each item times the number of items requested with first, last or
+// limit. Other fields cost 1 plus the cost of their selection.
+func newComplexityRoot() generated.ComplexityRoot {
+	var c generated.ComplexityRoot
+
+	c.Query.Products = func(child int, _ *generated.ProductFilterInput, _ *generated.ProductSortInput, pagination *connection.Args) int {
+		return pageCost(child, pagination)
+	}
+	c.Query.SearchProducts = func(child int, _ string, _ *generated.ProductSearchFilterInput, pagination *connection.Args) int {
+		return pageCost(child, pagination)
+	}
+	c.Query.PopularProducts = func(child int, limit int, _ models.TimePeriod) int {
+		return listCost(child, limit)
+	}
+	c.Query.MyOrders = func(child int, _ *models.OrderStatus, pagination *connection.Args) int {
+		return pageCost(child, pagination)
+	}
+	c.Query.UserOrders = func(child int, _ primitive.ObjectID, _ *models.OrderStatus, pagination *connection.Args) int {
+		return pageCost(child, pagination)
+	}
+	c.Query.Reviews = func(child int, _ *primitive.ObjectID, _ *models.ReviewStatus, pagination *connection.Args) int {
+		return pageCost(child, pagination)
+	}
+	c.Query.MyReviews = func(child int, pagination *connection.Args) int {
+		return pageCost(child, pagination)
+	}
+	c.Product.Reviews = func(child int, pagination *connection.Args) int {
+		return pageCost(child, pagination)
+	}
+	c.Inventory.Movements = func(child int, pagination *connection.Args) int {
+		return pageCost(child, pagination)
+	}
+	return c
+}
+
+// pageCost is the cost of a connection page of the requested size
+func pageCost(child int, pagination *connection.Args) int {
+	size := connection.DefaultPageSize
+	if pagination != nil {
+		size = pagination.Limit()
+	}
+	return listCost(child, min(size, connection.MaxPageSize))
+}
+
+// listCost is the cost of a list of size items
+func listCost(child, size int) int {
+	return 1 + child*max(size, 0)
+}
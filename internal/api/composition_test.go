@@ -0,0 +1,90 @@
+//go:build composition
+
+package api_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// shippingSubgraph is the stub subgraph the served SDL is composed with
+const shippingSubgraph = "testdata/shipping.graphql"
+
+// supergraphConfig is the rover configuration composing the API with the stub subgraph
+const supergraphConfig = `federation_version: =2.3.2
+subgraphs:
+  api:
+    routing_url: http://api.internal/query
+    schema:
+      file: ./api.graphql
+  shipping:
+    routing_url: http://shipping.internal/query
+    schema:
+      file: ./shipping.graphql
+`
+
+// TestSupergraphComposes composes the SDL served by _service with a stub shipping
+// subgraph using rover, so schema changes that break composition fail here rather
+// than at the gateway. It needs rover on the PATH, or at $ROVER, and runs with
+// make compose.
+func TestSupergraphComposes(t *testing.T) {
+	rover := os.Getenv("ROVER")
+	if rover == "" {
+		rover = "rover"
+	}
+	if _, err := exec.LookPath(rover); err != nil {
+		t.Fatalf("rover is required to compose the supergraph: %v", err)
+	}
+
+	dir := t.TempDir()
+	shipping, err := os.ReadFile(shippingSubgraph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"api.graphql":      newSubgraphFixture(t, nil).serviceSDL(t),
+		"shipping.graphql": string(shipping),
+		"supergraph.yaml":  supergraphConfig,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(rover, "supergraph", "compose", "--config", "supergraph.yaml", "--elv2-license", "accept")
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("supergraph composition failed: %v\n%s", err, stderr.String())
+	}
+
+	supergraph, err := parser.ParseSchema(&ast.Source{Name: "supergraph.graphql", Input: stdout.String()})
+	if err != nil {
+		t.Fatalf("rover returned an invalid supergraph: %v", err)
+	}
+	// Both subgraphs define Order and Product, the shipping subgraph only references products
+	for _, name := range []string{"Order", "Product"} {
+		graphs := map[string]bool{}
+		for _, def := range append(supergraph.Definitions, supergraph.Extensions...) {
+			if def.Name != name {
+				continue
+			}
+			for _, directive := range def.Directives.ForNames("join__type") {
+				graphs[directive.Arguments.ForName("graph").Value.Raw] = true
+			}
+		}
+		for _, graph := range []string{"API", "SHIPPING"} {
+			if !graphs[graph] {
+				t.Errorf("%s is not joined from the %s subgraph", name, graph)
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Package directives implements the GraphQL schema directives
+package directives
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/auth"
+)
+
+// Auth implements @auth, which requires an authenticated user
+func Auth(ctx context.Context, obj any, next graphql.Resolver) (any, error) {
+	if _, ok := auth.GetUserIDFromContext(ctx); !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return next(ctx)
+}
+
+// HasRole returns the @hasRole implementation, which requires an authenticated user
+// holding the role or a role ranked above it in the hierarchy
+func HasRole(hierarchy *auth.RoleHierarchy) func(ctx context.Context, obj any, next graphql.Resolver, role string) (any, error) {
+	return func(ctx context.Context, obj any, next graphql.Resolver, role string) (any, error) {
+		if _, ok := auth.GetUserIDFromContext(ctx); !ok {
+			return nil, gqlerrors.Unauthenticated(ctx)
+		}
+
+		claims, _ := auth.GetClaimsFromContext(ctx)
+		if !hierarchy.Satisfies(claims.Roles, role) {
+			return nil, gqlerrors.Forbidden(ctx, "requires role "+role)
+		}
+		return next(ctx)
+	}
+}
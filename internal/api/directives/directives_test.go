@@ -0,0 +1,73 @@
+package directives_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prototype01/internal/api/directives"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/auth"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func resolved(ctx context.Context) (any, error) {
+	return "ok", nil
+}
+
+func withRoles(roles ...string) context.Context {
+	claims := &auth.Claims{Roles: roles}
+	claims.Subject = "user-1"
+	return auth.WithClaims(context.Background(), claims)
+}
+
+func errorCode(t *testing.T, err error) gqlerrors.Code {
+	t.Helper()
+	gqlErr, ok := err.(*gqlerror.Error)
+	if !ok {
+		t.Fatalf("expected *gqlerror.Error, got %T (%v)", err, err)
+	}
+	code, _ := gqlErr.Extensions["code"].(gqlerrors.Code)
+	return code
+}
+
+func TestAuth(t *testing.T) {
+	if _, err := directives.Auth(context.Background(), nil, resolved); errorCode(t, err) != gqlerrors.CodeUnauthenticated {
+		t.Errorf("expected UNAUTHENTICATED for anonymous request, got %v", err)
+	}
+	if res, err := directives.Auth(withRoles("customer"), nil, resolved); err != nil || res != "ok" {
+		t.Errorf("expected authenticated request to resolve, got %v, %v", res, err)
+	}
+}
+
+func TestHasRole(t *testing.T) {
+	hasRole := directives.HasRole(auth.NewRoleHierarchy([]string{"customer", "staff", "admin"}))
+
+	if _, err := hasRole(context.Background(), nil, resolved, "staff"); errorCode(t, err) != gqlerrors.CodeUnauthenticated {
+		t.Errorf("expected UNAUTHENTICATED for anonymous request, got %v", err)
+	}
+
+	tests := []struct {
+		roles    []string
+		required string
+		allowed  bool
+	}{
+		{[]string{"customer"}, "customer", true},
+		{[]string{"customer"}, "staff", false},
+		{[]string{"staff"}, "customer", true},
+		{[]string{"admin"}, "staff", true},
+		{[]string{"staff"}, "admin", false},
+		{[]string{"customer", "admin"}, "admin", true},
+		{[]string{"admin"}, "auditor", false},
+		{[]string{"auditor"}, "auditor", true},
+		{nil, "customer", false},
+	}
+	for _, tt := range tests {
+		_, err := hasRole(withRoles(tt.roles...), nil, resolved, tt.required)
+		if tt.allowed && err != nil {
+			t.Errorf("roles %v requiring %s: expected access, got %v", tt.roles, tt.required, err)
+		}
+		if !tt.allowed && errorCode(t, err) != gqlerrors.CodeForbidden {
+			t.Errorf("roles %v requiring %s: expected FORBIDDEN, got %v", tt.roles, tt.required, err)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/loaders"
+	"github.com/prototype01/internal/api/resolvers"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// subgraphSchema is the SDL published to the gateway, kept in sync by the contract test
+const subgraphSchema = "../../api/federation/subgraph.graphql"
+
+var update = flag.Bool("update", false, "rewrite "+subgraphSchema+" from the served SDL")
+
+// federationDefinitions declares the federation directives the subgraph imports,
+// as the composition does before validating a subgraph
+const federationDefinitions = `
+scalar FieldSet
+directive @link(url: String!, import: [String!]) repeatable on SCHEMA
+directive @key(fields: FieldSet!, resolvable: Boolean = true) repeatable on OBJECT | INTERFACE
+directive @shareable repeatable on FIELD_DEFINITION | OBJECT
+`
+
+// memoryRepository is an in-memory batch lookup counting its calls
+type memoryRepository[T any] struct {
+	mu       sync.Mutex
+	entities map[primitive.ObjectID]T
+	calls    int
+}
+
+func newMemoryRepository[T any](entities map[primitive.ObjectID]T) *memoryRepository[T] {
+	return &memoryRepository[T]{entities: entities}
+}
+
+func (m *memoryRepository[T]) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]T, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	found := []T{}
+	for _, id := range ids {
+		if entity, ok := m.entities[id]; ok {
+			found = append(found, entity)
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryRepository[T]) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+// noCarts and noInventory satisfy the loaders the entity resolvers don't use
+type noCarts struct{}
+
+func (noCarts) FindByUserIDs(ctx context.Context, userIDs []string) ([]models.Cart, error) {
+	return nil, nil
+}
+
+type noInventory struct{}
+
+func (noInventory) FindItemsBySKUs(ctx context.Context, skus []string) ([]models.InventoryItem, error) {
+	return nil, nil
+}
+
+// subgraphFixture is the subgraph served over HTTP with in-memory repositories
+type subgraphFixture struct {
+	server     *httptest.Server
+	products   *memoryRepository[models.Product]
+	categories *memoryRepository[models.Category]
+	users      *memoryRepository[models.User]
+	orders     *memoryRepository[models.Order]
+}
+
+// newSubgraphFixture serves the subgraph to a caller holding claims, nil for guests
+func newSubgraphFixture(t *testing.T, claims *auth.Claims) *subgraphFixture {
+	t.Helper()
+	f := &subgraphFixture{
+		products:   newMemoryRepository(map[primitive.ObjectID]models.Product{}),
+		categories: newMemoryRepository(map[primitive.ObjectID]models.Category{}),
+		users:      newMemoryRepository(map[primitive.ObjectID]models.User{}),
+		orders:     newMemoryRepository(map[primitive.ObjectID]models.Order{}),
+	}
+	resolver := &resolvers.Resolver{
+		Roles: auth.NewRoleHierarchy([]string{models.RoleCustomer, models.RoleStaff, models.RoleAdmin}),
+	}
+
+	h := handler.New(generated.NewExecutableSchema(generated.Config{Resolvers: resolver}))
+	h.AddTransport(transport.POST{})
+	h.Use(extension.Introspection{})
+	h.AroundOperations(func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		if claims != nil {
+			ctx = auth.WithClaims(ctx, claims)
+		}
+		return next(ctx)
+	})
+
+	withLoaders := loaders.Middleware(loaders.Repositories{
+		Users:      f.users,
+		Products:   f.products,
+		Categories: f.categories,
+		Orders:     f.orders,
+		Reviews:    newMemoryRepository(map[primitive.ObjectID]models.Review{}),
+		Wishlists:  newMemoryRepository(map[primitive.ObjectID]models.Wishlist{}),
+		Carts:      noCarts{},
+		Inventory:  noInventory{},
+	})
+	f.server = httptest.NewServer(withLoaders(h))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// graphqlResponse is the body of a GraphQL response
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// query runs a query and decodes its data into data, failing on errors
+func (f *subgraphFixture) query(t *testing.T, query string, variables map[string]interface{}, data interface{}) {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Post(f.server.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var response graphqlResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Errors) > 0 {
+		t.Fatalf("query failed: %s", response.Errors[0].Message)
+	}
+	if err := json.Unmarshal(response.Data, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serviceSDL returns the SDL the subgraph serves to the gateway
+func (f *subgraphFixture) serviceSDL(t *testing.T) string {
+	t.Helper()
+	var data struct {
+		Service struct {
+			SDL string `json:"sdl"`
+		} `json:"_service"`
+	}
+	f.query(t, `{ _service { sdl } }`, nil, &data)
+	return data.Service.SDL
+}
+
+// entityKeys returns the key field sets of every entity of the schema
+func entityKeys(schema *ast.Schema) map[string][]string {
+	keys := map[string][]string{}
+	for name, def := range schema.Types {
+		for _, directive := range def.Directives.ForNames("key") {
+			keys[name] = append(keys[name], directive.Arguments.ForName("fields").Value.Raw)
+		}
+	}
+	return keys
+}
+
+// checkFieldSet reports the fields of a key field set the type doesn't define
+func checkFieldSet(t *testing.T, schema *ast.Schema, def *ast.Definition, set ast.SelectionSet) {
+	t.Helper()
+	for _, selection := range set {
+		field, ok := selection.(*ast.Field)
+		if !ok {
+			t.Errorf("%s: key field sets can only select fields", def.Name)
+			continue
+		}
+		fieldDef := def.Fields.ForName(field.Name)
+		if fieldDef == nil {
+			t.Errorf("%s: key field %q is not defined", def.Name, field.Name)
+			continue
+		}
+		if len(fieldDef.Arguments) > 0 {
+			t.Errorf("%s: key field %q takes arguments", def.Name, field.Name)
+		}
+		if len(field.SelectionSet) > 0 {
+			checkFieldSet(t, schema, schema.Types[fieldDef.Type.Name()], field.SelectionSet)
+		}
+	}
+}
+
+// TestSubgraphSchemaIsValid validates the served SDL: the schema must be valid with
+// the federation directives, every key must select existing fields, value types
+// other subgraphs may return must be shareable and the SDL must match the schema
+// published to the gateway. It doesn't compose a supergraph.
+func TestSubgraphSchemaIsValid(t *testing.T) {
+	sdl := newSubgraphFixture(t, nil).serviceSDL(t)
+
+	schema, err := gqlparser.LoadSchema(
+		&ast.Source{Name: "federation.graphql", Input: federationDefinitions, BuiltIn: true},
+		&ast.Source{Name: "subgraph.graphql", Input: sdl},
+	)
+	if err != nil {
+		t.Fatalf("subgraph SDL is invalid: %v", err)
+	}
+
+	link := schema.SchemaDirectives.ForName("link")
+	if link == nil || !strings.HasPrefix(link.Arguments.ForName("url").Value.Raw, "https://specs.apollo.dev/federation/v2.") {
+		t.Fatal("subgraph SDL must link the federation v2 spec")
+	}
+
+	keys := entityKeys(schema)
+	var entities []string
+	for name, fieldSets := range keys {
+		entities = append(entities, name)
+		for _, fields := range fieldSets {
+			doc, err := parser.ParseQuery(&ast.Source{Input: "{" + fields + "}"})
+			if err != nil {
+				t.Errorf("%s: invalid key %q: %v", name, fields, err)
+				continue
+			}
+			checkFieldSet(t, schema, schema.Types[name], doc.Operations[0].SelectionSet)
+		}
+	}
+	sort.Strings(entities)
+	if got, want := strings.Join(entities, ","), "Category,Order,Product,User"; got != want {
+		t.Errorf("got entities %s, want %s", got, want)
+	}
+	for _, name := range []string{"Address", "MutationResult", "PageInfo", "ProductImage"} {
+		if schema.Types[name].Directives.ForName("shareable") == nil {
+			t.Errorf("%s must be @shareable", name)
+		}
+	}
+
+	if *update {
+		if err := os.WriteFile(subgraphSchema, []byte(sdl), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	published, err := os.ReadFile(subgraphSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(published) != sdl {
+		t.Errorf("%s is out of date, run go test ./internal/api -run TestSubgraphSchemaIsValid -update", subgraphSchema)
+	}
+}
+
+const entitiesQuery = `query ($representations: [_Any!]!) {
+  _entities(representations: $representations) {
+    __typename
+    ... on Product { id name }
+    ... on Category { id name }
+    ... on User { id email }
+    ... on Order { id userId }
+  }
+}`
+
+// entity is an item of an _entities response, null for entities that don't resolve
+type entity *struct {
+	Typename string `json:"__typename"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	UserID   string `json:"userId"`
+}
+
+// resolveEntities resolves the entity representations of typename and id pairs
+func (f *subgraphFixture) resolveEntities(t *testing.T, refs ...string) []entity {
+	t.Helper()
+	representations := make([]map[string]interface{}, 0, len(refs)/2)
+	for i := 0; i < len(refs); i += 2 {
+		representations = append(representations, map[string]interface{}{"__typename": refs[i], "id": refs[i+1]})
+	}
+	var data struct {
+		Entities []entity `json:"_entities"`
+	}
+	f.query(t, entitiesQuery, map[string]interface{}{"representations": representations}, &data)
+	if len(data.Entities) != len(representations) {
+		t.Fatalf("got %d entities, want %d", len(data.Entities), len(representations))
+	}
+	return data.Entities
+}
+
+func TestEntitiesBatchLookups(t *testing.T) {
+	f := newSubgraphFixture(t, nil)
+	mug, lamp, books := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	f.products.entities[mug] = models.Product{BaseModel: models.BaseModel{ID: mug}, Name: "Mug"}
+	f.products.entities[lamp] = models.Product{BaseModel: models.BaseModel{ID: lamp}, Name: "Lamp"}
+	f.categories.entities[books] = models.Category{BaseModel: models.BaseModel{ID: books}, Name: "Books"}
+
+	entities := f.resolveEntities(t,
+		"Product", mug.Hex(),
+		"Category", books.Hex(),
+		"Product", primitive.NewObjectID().Hex(),
+		"Product", lamp.Hex(),
+	)
+
+	if entities[0] == nil || entities[0].Typename != "Product" || entities[0].Name != "Mug" {
+		t.Errorf("got %+v, want the mug", entities[0])
+	}
+	if entities[1] == nil || entities[1].Typename != "Category" || entities[1].Name != "Books" {
+		t.Errorf("got %+v, want the books category", entities[1])
+	}
+	if entities[2] != nil {
+		t.Errorf("got %+v for a missing product, want null", entities[2])
+	}
+	if entities[3] == nil || entities[3].Name != "Lamp" {
+		t.Errorf("got %+v, want the lamp", entities[3])
+	}
+	if calls := f.products.Calls(); calls != 1 {
+		t.Errorf("got %d product lookups, want 1 batched lookup", calls)
+	}
+}
+
+func TestEntitiesCheckAccess(t *testing.T) {
+	owner, other := primitive.NewObjectID(), primitive.NewObjectID()
+	ownOrder, otherOrder := primitive.NewObjectID(), primitive.NewObjectID()
+
+	tests := []struct {
+		name   string
+		claims *auth.Claims
+		// visible lists the entities resolved for the owner, the other user and their orders
+		visible [4]bool
+	}{
+		{name: "owner", claims: claimsFor(owner, models.RoleCustomer), visible: [4]bool{true, false, true, false}},
+		{name: "staff", claims: claimsFor(primitive.NewObjectID(), models.RoleStaff), visible: [4]bool{true, true, true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSubgraphFixture(t, tt.claims)
+			f.users.entities[owner] = models.User{BaseModel: models.BaseModel{ID: owner}, Email: "owner@example.com"}
+			f.users.entities[other] = models.User{BaseModel: models.BaseModel{ID: other}, Email: "other@example.com"}
+			f.orders.entities[ownOrder] = models.Order{BaseModel: models.BaseModel{ID: ownOrder}, UserID: owner.Hex()}
+			f.orders.entities[otherOrder] = models.Order{BaseModel: models.BaseModel{ID: otherOrder}, UserID: other.Hex()}
+
+			entities := f.resolveEntities(t,
+				"User", owner.Hex(),
+				"User", other.Hex(),
+				"Order", ownOrder.Hex(),
+				"Order", otherOrder.Hex(),
+			)
+			for i, visible := range tt.visible {
+				if got := entities[i] != nil; got != visible {
+					t.Errorf("entity %d: got visible %v, want %v", i, got, visible)
+				}
+			}
+			if calls := f.orders.Calls(); calls != 1 {
+				t.Errorf("got %d order lookups, want 1 batched lookup", calls)
+			}
+		})
+	}
+}
+
+func claimsFor(userID primitive.ObjectID, roles ...string) *auth.Claims {
+	claims := &auth.Claims{Roles: roles}
+	claims.Subject = userID.Hex()
+	return claims
+}
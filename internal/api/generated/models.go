@@ -1,34 +1,278 @@
-// Code generated by gqlgen, DO NOT EDIT.
-// This is a mock file for Step 1 setup.
+// Code generated by github.com/99designs/gqlgen, DO NOT EDIT.
 
 package generated
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
 	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// This file will be replaced by actual generated code in Step 2
-// These are placeholder types to make the application compile
+type AddressInput struct {
+	FullName graphql.Omittable[*string] `json:"fullName,omitempty"`
+	Street   string                     `json:"street"`
+	City     string                     `json:"city"`
+	State    graphql.Omittable[*string] `json:"state,omitempty"`
+	ZipCode  string                     `json:"zipCode"`
+	Country  string                     `json:"country"`
+}
+
+type AuthPayload struct {
+	User                  *models.User `json:"user"`
+	Token                 string       `json:"token"`
+	TokenExpiresAt        time.Time    `json:"tokenExpiresAt"`
+	RefreshToken          string       `json:"refreshToken"`
+	RefreshTokenExpiresAt time.Time    `json:"refreshTokenExpiresAt"`
+}
+
+type CardInput struct {
+	Number      string                     `json:"number"`
+	ExpiryMonth int                        `json:"expiryMonth"`
+	ExpiryYear  int                        `json:"expiryYear"`
+	Cvc         string                     `json:"cvc"`
+	HolderName  graphql.Omittable[*string] `json:"holderName,omitempty"`
+}
+
+type CreateCategoryInput struct {
+	Name        string                                 `json:"name"`
+	Description graphql.Omittable[*string]             `json:"description,omitempty"`
+	ParentID    graphql.Omittable[*primitive.ObjectID] `json:"parentId,omitempty"`
+}
+
+type CreateOrderInput struct {
+	ShippingAddress *AddressInput               `json:"shippingAddress"`
+	ExpectedTotal   graphql.Omittable[*float64] `json:"expectedTotal,omitempty"`
+}
+
+type CreateProductInput struct {
+	Name        string                                 `json:"name"`
+	Description graphql.Omittable[*string]             `json:"description,omitempty"`
+	Price       float64                                `json:"price"`
+	Sku         string                                 `json:"sku"`
+	Brand       graphql.Omittable[*string]             `json:"brand,omitempty"`
+	Images      graphql.Omittable[[]ProductImageInput] `json:"images,omitempty"`
+	CategoryID  graphql.Omittable[*primitive.ObjectID] `json:"categoryId,omitempty"`
+	Stock       graphql.Omittable[*int]                `json:"stock,omitempty"`
+}
+
+type Mutation struct {
+}
+
+type MutationResult struct {
+	ID      *primitive.ObjectID `json:"id,omitempty"`
+	Success bool                `json:"success"`
+	Message *string             `json:"message,omitempty"`
+}
+
+type PayOrderInput struct {
+	OrderID           primitive.ObjectID                     `json:"orderId"`
+	Card              graphql.Omittable[*CardInput]          `json:"card,omitempty"`
+	PaymentToken      graphql.Omittable[*string]             `json:"paymentToken,omitempty"`
+	ChallengeResponse graphql.Omittable[*string]             `json:"challengeResponse,omitempty"`
+	PaymentMethodID   graphql.Omittable[*primitive.ObjectID] `json:"paymentMethodId,omitempty"`
+}
+
+type PaymentMethodInput struct {
+	Card      *CardInput               `json:"card"`
+	IsDefault graphql.Omittable[*bool] `json:"isDefault,omitempty"`
+}
+
+type ProductFilterInput struct {
+	CategoryID graphql.Omittable[*primitive.ObjectID] `json:"categoryId,omitempty"`
+	MinPrice   graphql.Omittable[*float64]            `json:"minPrice,omitempty"`
+	MaxPrice   graphql.Omittable[*float64]            `json:"maxPrice,omitempty"`
+	InStock    graphql.Omittable[*bool]               `json:"inStock,omitempty"`
+}
+
+type ProductImageInput struct {
+	URL string                     `json:"url"`
+	Alt graphql.Omittable[*string] `json:"alt,omitempty"`
+}
+
+type ProductReviewInput struct {
+	Rating  int                        `json:"rating"`
+	Title   graphql.Omittable[*string] `json:"title,omitempty"`
+	Content graphql.Omittable[*string] `json:"content,omitempty"`
+}
+
+type ProductSearchFilterInput struct {
+	CategoryID graphql.Omittable[*primitive.ObjectID] `json:"categoryId,omitempty"`
+	Brand      graphql.Omittable[*string]             `json:"brand,omitempty"`
+	MinPrice   graphql.Omittable[*float64]            `json:"minPrice,omitempty"`
+	MaxPrice   graphql.Omittable[*float64]            `json:"maxPrice,omitempty"`
+	InStock    graphql.Omittable[*bool]               `json:"inStock,omitempty"`
+}
+
+type ProductSortInput struct {
+	Field     ProductSortField                  `json:"field"`
+	Direction graphql.Omittable[*SortDirection] `json:"direction,omitempty"`
+}
+
+type Query struct {
+}
+
+type RegisterUserInput struct {
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+}
+
+type ShippingAddressInput struct {
+	FullName  graphql.Omittable[*string] `json:"fullName,omitempty"`
+	Street    string                     `json:"street"`
+	City      string                     `json:"city"`
+	State     graphql.Omittable[*string] `json:"state,omitempty"`
+	ZipCode   string                     `json:"zipCode"`
+	Country   string                     `json:"country"`
+	IsDefault graphql.Omittable[*bool]   `json:"isDefault,omitempty"`
+}
+
+type Subscription struct {
+}
+
+type UpdateCategoryInput struct {
+	Name        graphql.Omittable[*string] `json:"name,omitempty"`
+	Description graphql.Omittable[*string] `json:"description,omitempty"`
+}
+
+type UpdateProductInput struct {
+	Name        graphql.Omittable[*string]             `json:"name,omitempty"`
+	Description graphql.Omittable[*string]             `json:"description,omitempty"`
+	Price       graphql.Omittable[*float64]            `json:"price,omitempty"`
+	Sku         graphql.Omittable[*string]             `json:"sku,omitempty"`
+	Brand       graphql.Omittable[*string]             `json:"brand,omitempty"`
+	Images      graphql.Omittable[[]ProductImageInput] `json:"images,omitempty"`
+	CategoryID  graphql.Omittable[*primitive.ObjectID] `json:"categoryId,omitempty"`
+	Stock       graphql.Omittable[*int]                `json:"stock,omitempty"`
+}
+
+type UpdateUserProfileInput struct {
+	FirstName      graphql.Omittable[*string] `json:"firstName,omitempty"`
+	LastName       graphql.Omittable[*string] `json:"lastName,omitempty"`
+	ProfilePicture graphql.Omittable[*string] `json:"profilePicture,omitempty"`
+}
 
-// Version represents API version information
 type Version struct {
 	Number      string    `json:"number"`
 	BuildDate   time.Time `json:"buildDate"`
 	Environment string    `json:"environment"`
 }
 
-// Models contains the models defined in the GraphQL schema
-type Models struct {
-	Version Version
+type ProductSortField string
+
+const (
+	ProductSortFieldCreatedAt ProductSortField = "CREATED_AT"
+	ProductSortFieldPrice     ProductSortField = "PRICE"
+	ProductSortFieldName      ProductSortField = "NAME"
+)
+
+var AllProductSortField = []ProductSortField{
+	ProductSortFieldCreatedAt,
+	ProductSortFieldPrice,
+	ProductSortFieldName,
 }
 
-// NewModels creates a new instance of Models
-func NewModels() Models {
-	return Models{
-		Version: Version{
-			Number:      "0.1.0",
-			BuildDate:   time.Now(),
-			Environment: "development",
-		},
+func (e ProductSortField) IsValid() bool {
+	switch e {
+	case ProductSortFieldCreatedAt, ProductSortFieldPrice, ProductSortFieldName:
+		return true
 	}
+	return false
+}
+
+func (e ProductSortField) String() string {
+	return string(e)
+}
+
+func (e *ProductSortField) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ProductSortField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ProductSortField", str)
+	}
+	return nil
+}
+
+func (e ProductSortField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ProductSortField) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ProductSortField) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type SortDirection string
+
+const (
+	SortDirectionAsc  SortDirection = "ASC"
+	SortDirectionDesc SortDirection = "DESC"
+)
+
+var AllSortDirection = []SortDirection{
+	SortDirectionAsc,
+	SortDirectionDesc,
+}
+
+func (e SortDirection) IsValid() bool {
+	switch e {
+	case SortDirectionAsc, SortDirectionDesc:
+		return true
+	}
+	return false
+}
+
+func (e SortDirection) String() string {
+	return string(e)
+}
+
+func (e *SortDirection) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SortDirection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SortDirection", str)
+	}
+	return nil
+}
+
+func (e SortDirection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *SortDirection) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e SortDirection) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,108 @@
+// Package gqlerrors provides typed GraphQL errors carrying an extension code
+package gqlerrors
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/payment"
+	"github.com/prototype01/pkg/connection"
+	"github.com/prototype01/pkg/validator"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Code is the value of the "code" error extension
+type Code string
+
+// Error codes returned to clients
+const (
+	// CodeUnauthenticated is returned when an operation requires a signed in user
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	// CodeForbidden is returned when the signed in user lacks the required role
+	CodeForbidden Code = "FORBIDDEN"
+	// CodeBadUserInput is returned when input fails validation
+	CodeBadUserInput Code = "BAD_USER_INPUT"
+	// CodeNotFound is returned when a requested resource does not exist
+	CodeNotFound Code = "NOT_FOUND"
+	// CodeInsufficientStock is returned when fewer units are available than requested
+	CodeInsufficientStock Code = "INSUFFICIENT_STOCK"
+	// CodeInvalidTransition is returned when a resource can't move to the requested state
+	CodeInvalidTransition Code = "INVALID_STATE_TRANSITION"
+	// CodeConflict is returned when a resource was changed by a concurrent request
+	CodeConflict Code = "CONFLICT"
+	// CodePaymentDeclined is returned when the payment gateway refuses a payment
+	CodePaymentDeclined Code = "PAYMENT_DECLINED"
+	// CodePaymentUnavailable is returned when the payment gateway failed or timed out
+	CodePaymentUnavailable Code = "PAYMENT_UNAVAILABLE"
+	// CodeQueryTooDeep is returned when an operation is nested deeper than allowed
+	CodeQueryTooDeep Code = "QUERY_TOO_DEEP"
+	// CodeQueryTooComplex is returned when an operation costs more than allowed
+	CodeQueryTooComplex Code = "QUERY_TOO_COMPLEX"
+	// CodeOperationNotAllowed is returned in allowlist mode for operations that weren't registered
+	CodeOperationNotAllowed Code = "OPERATION_NOT_ALLOWED"
+)
+
+// New creates an error with the given code on the path of the current field
+func New(ctx context.Context, code Code, message string) *gqlerror.Error {
+	return &gqlerror.Error{
+		Message:    message,
+		Path:       graphql.GetPath(ctx),
+		Extensions: map[string]interface{}{"code": code},
+	}
+}
+
+// Unauthenticated returns an UNAUTHENTICATED error
+func Unauthenticated(ctx context.Context) *gqlerror.Error {
+	return New(ctx, CodeUnauthenticated, "authentication required")
+}
+
+// Forbidden returns a FORBIDDEN error
+func Forbidden(ctx context.Context, message string) *gqlerror.Error {
+	return New(ctx, CodeForbidden, message)
+}
+
+// Presenter is the GraphQL error presenter. It adds an extension code to the
+// domain errors returned by resolvers that don't already carry one.
+func Presenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	if _, ok := gqlErr.Extensions["code"]; ok {
+		return gqlErr
+	}
+
+	var validationErrs validator.ValidationErrors
+	var transitionErr *models.TransitionError
+	switch {
+	case errors.As(err, &validationErrs):
+		setExtension(gqlErr, "code", CodeBadUserInput)
+		setExtension(gqlErr, "fields", validationErrs)
+	case errors.Is(err, connection.ErrInvalidCursor):
+		setExtension(gqlErr, "code", CodeBadUserInput)
+	case errors.Is(err, models.ErrNotFound):
+		setExtension(gqlErr, "code", CodeNotFound)
+	case errors.Is(err, models.ErrInsufficientStock):
+		setExtension(gqlErr, "code", CodeInsufficientStock)
+	case errors.As(err, &transitionErr):
+		setExtension(gqlErr, "code", CodeInvalidTransition)
+		setExtension(gqlErr, "from", transitionErr.From)
+		setExtension(gqlErr, "to", transitionErr.To)
+	case errors.Is(err, models.ErrInvalidTransition):
+		setExtension(gqlErr, "code", CodeInvalidTransition)
+	case errors.Is(err, models.ErrConflict):
+		setExtension(gqlErr, "code", CodeConflict)
+	case errors.Is(err, payment.ErrDeclined):
+		setExtension(gqlErr, "code", CodePaymentDeclined)
+	case errors.Is(err, payment.ErrTimeout):
+		setExtension(gqlErr, "code", CodePaymentUnavailable)
+	}
+	return gqlErr
+}
+
+// setExtension sets an extension value, creating the extensions map if needed
+func setExtension(gqlErr *gqlerror.Error, key string, value interface{}) {
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = map[string]interface{}{}
+	}
+	gqlErr.Extensions[key] = value
+}
@@ -2,45 +2,263 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/gorilla/websocket"
+	"github.com/prototype01/internal/api/directives"
 	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/api/loaders"
+	"github.com/prototype01/internal/api/middlewares"
 	"github.com/prototype01/internal/api/resolvers"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/config"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/internal/payment"
+	"github.com/prototype01/internal/repository/mongodb"
+	"github.com/prototype01/pkg/connection"
+	"github.com/prototype01/pkg/logger"
+	"github.com/prototype01/pkg/pubsub"
+	"github.com/vektah/gqlparser/v2/ast"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// NewHandler creates a new GraphQL handler using gqlgen
-func NewHandler(db *mongo.Client) (http.Handler, error) {
-	// Create a new resolver with the DB
-	resolver := &resolvers.Resolver{DB: db}
+// indexTimeout bounds the index creation done when the handler is created
+const indexTimeout = 30 * time.Second
 
-	// Create a config with the resolver
-	config := generated.Config{Resolvers: resolver}
+// NewHandler creates a new GraphQL handler using gqlgen. Background jobs such as
+// the stock reservation sweeper run until ctx is done.
+func NewHandler(ctx context.Context, cfg *config.Config, db *mongo.Client) (http.Handler, error) {
+	// Create the JWT verifier and signer used to authenticate requests and issue tokens
+	verifier, err := auth.NewVerifier(cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := auth.NewSigner(cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create a new handler with the executable schema
-	h := handler.NewDefaultServer(generated.NewExecutableSchema(config))
+	// Create the codec signing pagination cursors
+	cursors, err := newCursorCodec(cfg.Pagination)
+	if err != nil {
+		return nil, err
+	}
 
-	// Add extensions and middleware for Apollo Studio support
-	h.Use(extension.Introspection{})
+	// Create the repositories and make sure their indexes exist
+	database := db.Database(cfg.MongoDB.Database)
+	tokenRepository := mongodb.NewTokenRepository(database)
+	userRepository := mongodb.NewUserRepository(database)
+	productRepository := mongodb.NewProductRepository(database, cursors)
+	categoryRepository := mongodb.NewCategoryRepository(database)
+	inventoryRepository := mongodb.NewInventoryRepository(database, cursors)
+	cartRepository := mongodb.NewCartRepository(database)
+	orderRepository := mongodb.NewOrderRepository(database, cursors)
+	idempotencyRepository := mongodb.NewIdempotencyRepository(database)
+	productSearch := mongodb.NewProductSearch(database)
+	reviewRepository := mongodb.NewReviewRepository(database, cursors)
+	wishlistRepository := mongodb.NewWishlistRepository(database)
+	analyticsRepository := mongodb.NewAnalyticsRepository(database, cfg.Analytics.EventTTL)
+	persistedQueryRepository := mongodb.NewPersistedQueryRepository(database)
+
+	indexCtx, cancel := context.WithTimeout(ctx, indexTimeout)
+	defer cancel()
+	if err := mongodb.EnsureIndexes(indexCtx, tokenRepository, userRepository, productRepository, categoryRepository, inventoryRepository, cartRepository, orderRepository, idempotencyRepository, productSearch, reviewRepository, wishlistRepository, analyticsRepository, persistedQueryRepository); err != nil {
+		return nil, err
+	}
+
+	// Create the domain services
+	authService := services.NewAuthService(tokenRepository, userRepository, signer, cfg.Auth.RefreshTokenTTL)
+	passwordHasher := auth.NewPasswordHasher(auth.PasswordParams{
+		Memory:      cfg.Auth.PasswordMemory,
+		Iterations:  cfg.Auth.PasswordIterations,
+		Parallelism: cfg.Auth.PasswordParallelism,
+	})
+	transactor := mongodb.NewTransactor(db)
+	inventoryService := services.NewInventoryService(transactor, inventoryRepository, productRepository, cfg.Inventory.ReservationTTL)
+
+	// Release stock held by abandoned checkouts
+	go inventoryService.RunSweeper(ctx, cfg.Inventory.SweepInterval)
+
+	// Keep the inventory in step with the order lifecycle
+	orderService := services.NewOrderService(orderRepository)
+	orderService.OnTransition(models.OrderPaid, services.CommitStockOnPayment(inventoryService))
+	orderService.OnTransition(models.OrderCancelled, services.ReleaseStockOnCancel(inventoryService))
+	cartService := services.NewCartService(cartRepository, productRepository, cfg.Cart.GuestTTL, cfg.Cart.UserTTL)
+	checkoutService := services.NewCheckoutService(transactor, orderRepository, cartService, inventoryService, services.CheckoutPricing{
+		TaxRate:               cfg.Checkout.TaxRate,
+		ShippingCost:          cfg.Checkout.ShippingCost,
+		FreeShippingThreshold: cfg.Checkout.FreeShippingThreshold,
+	})
+
+	// Charge and refund orders and release the card holds of cancelled orders
+	paymentProvider, err := newPaymentProvider(cfg.Payment)
+	if err != nil {
+		return nil, err
+	}
+	paymentService := services.NewPaymentService(paymentProvider, orderRepository, orderService, inventoryService, cfg.Payment.Currency)
+	orderService.OnTransition(models.OrderCancelled, services.VoidPaymentOnCancel(paymentService))
 
-	// Add Apollo Tracing support - using built-in tracing features
-	h.Use(extension.AutomaticPersistedQuery{})
+	// Flag reviews of delivered products as verified purchases
+	reviewService := services.NewReviewService(transactor, reviewRepository, productRepository, orderRepository)
+	orderService.OnTransition(models.OrderDelivered, services.MarkVerifiedOnDelivery(reviewService))
+
+	// Count purchases of paid orders and keep the popularity rollups fresh
+	analyticsService := services.NewAnalyticsService(analyticsRepository, productRepository)
+	orderService.OnTransition(models.OrderPaid, services.RecordPurchaseOnPayment(analyticsService))
+	go analyticsService.RunRollups(ctx, cfg.Analytics.RollupInterval)
+
+	// Publish order status and stock changes to subscribers
+	subscriptionService := services.NewSubscriptionService(orderService, productRepository,
+		pubsub.New[*models.Order](pubsub.DefaultBuffer), pubsub.New[models.StockMovement](pubsub.DefaultBuffer))
+	orderService.OnAnyTransition(subscriptionService.PublishOrderStatus())
+	inventoryService.OnStockChange(subscriptionService.PublishStockChange())
+
+	roles := auth.NewRoleHierarchy(cfg.Auth.RoleHierarchy)
+
+	// Create a new resolver with the DB and services
+	resolver := &resolvers.Resolver{
+		DB:                  db,
+		Verifier:            verifier,
+		Roles:               roles,
+		AuthService:         authService,
+		UserService:         services.NewUserService(userRepository, passwordHasher, authService),
+		ProductService:      services.NewProductService(productRepository, categoryRepository, inventoryService, productSearch),
+		CategoryService:     services.NewCategoryService(categoryRepository, productRepository),
+		InventoryService:    inventoryService,
+		CartService:         cartService,
+		OrderService:        orderService,
+		CheckoutService:     checkoutService,
+		PaymentService:      paymentService,
+		SearchService:       services.NewSearchService(productSearch, cursors),
+		ReviewService:       reviewService,
+		WishlistService:     services.NewWishlistService(wishlistRepository, productRepository),
+		ProfileService:      services.NewProfileService(userRepository, paymentProvider),
+		AnalyticsService:    analyticsService,
+		SubscriptionService: subscriptionService,
+	}
+
+	// Create a config with the resolver
+	gqlConfig := generated.Config{
+		Resolvers:  resolver,
+		Complexity: newComplexityRoot(),
+		Directives: generated.DirectiveRoot{
+			Auth:    directives.Auth,
+			HasRole: directives.HasRole(roles),
+		},
+	}
+
+	// Create a new handler with the executable schema
+	h := handler.New(generated.NewExecutableSchema(gqlConfig))
 
-	// Configure WebSocket transport for subscriptions (if needed)
+	// Serve subscriptions over WebSocket, authenticating connections in connection_init
+	h.AddTransport(middlewares.LimitConnections(transport.Websocket{
+		Upgrader:              websocket.Upgrader{CheckOrigin: middlewares.CheckOrigin(cfg.WebSocket.AllowedOrigins)},
+		InitFunc:              middlewares.WebsocketInit(resolver),
+		InitTimeout:           cfg.WebSocket.InitTimeout,
+		KeepAlivePingInterval: cfg.WebSocket.KeepAliveInterval,
+		PingPongInterval:      cfg.WebSocket.KeepAliveInterval,
+	}, cfg.WebSocket.MaxConnections))
 	h.AddTransport(transport.Options{})
 	h.AddTransport(transport.GET{})
 	h.AddTransport(transport.POST{})
 	h.AddTransport(transport.MultipartForm{})
 
+	// Add extension codes to domain errors
+	h.SetErrorPresenter(gqlerrors.Presenter)
+
+	// Cache parsed query documents between requests
+	h.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+
+	// Add extensions and middleware for Apollo Studio support
+	h.Use(extension.Introspection{})
+
+	// Reject operations that are too deep or too costly before they run
+	h.Use(&middlewares.QueryLimits{
+		MaxDepth:      cfg.GraphQL.MaxDepth,
+		MaxComplexity: cfg.GraphQL.MaxComplexity,
+	})
+
+	// Add Apollo persisted query support, keeping the queries in MongoDB
+	persistedQueries := middlewares.NewPersistedQueryCache(
+		services.NewPersistedQueryService(persistedQueryRepository, cfg.GraphQL.AllowlistOnly, cfg.GraphQL.PersistedQueryTTL, cfg.GraphQL.PersistedQueryMaxLength),
+		cfg.GraphQL.PersistedQueryCacheSize,
+	)
+	h.Use(extension.AutomaticPersistedQuery{Cache: persistedQueries})
+
+	// Only run registered operations in allowlist mode
+	if cfg.GraphQL.AllowlistOnly {
+		h.Use(middlewares.PersistedQueryAllowlist{Cache: persistedQueries})
+	}
+
+	// Authenticate operations carrying a bearer token
+	h.AroundOperations(middlewares.AuthMiddleware(resolver))
+
+	// Replay the first response of mutations retried with the same Idempotency-Key
+	idempotencyService := services.NewIdempotencyService(idempotencyRepository, cfg.Idempotency.KeyTTL)
+	h.AroundOperations(middlewares.IdempotencyMiddleware(idempotencyService))
+
+	// Bound the subscriptions a WebSocket connection can hold
+	h.AroundOperations(middlewares.SubscriptionLimit(cfg.WebSocket.MaxSubscriptions))
+
 	// Uncomment when needed:
 	// h.AroundOperations(middleware.OperationMiddleware())
 	// h.AroundResponses(middleware.ResponseMiddleware())
 
-	return h, nil
+	// Batch and cache the entity lookups of nested fields per request and per subscription event
+	loaderRepositories := loaders.Repositories{
+		Users:      userRepository,
+		Products:   productRepository,
+		Categories: categoryRepository,
+		Orders:     orderRepository,
+		Reviews:    reviewRepository,
+		Wishlists:  wishlistRepository,
+		Carts:      cartRepository,
+		Inventory:  inventoryRepository,
+	}
+	h.AroundResponses(loaders.SubscriptionMiddleware(loaderRepositories))
+	withLoaders := loaders.Middleware(loaderRepositories)
+	return withLoaders(h), nil
+}
+
+// newCursorCodec creates the pagination cursor codec. Without a configured secret a
+// random one is used, so cursors don't survive a restart.
+func newCursorCodec(cfg config.PaginationConfig) (*connection.Codec, error) {
+	if cfg.CursorSecret != "" {
+		return connection.NewCodec([]byte(cfg.CursorSecret)), nil
+	}
+
+	logger.Warn("CURSOR_SECRET is not set, pagination cursors will be invalidated on restart")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return connection.NewCodec(secret), nil
+}
+
+// newPaymentProvider creates the configured payment gateway
+func newPaymentProvider(cfg config.PaymentConfig) (payment.Provider, error) {
+	switch cfg.Provider {
+	case "fake":
+		outcome, err := payment.ParseFakeOutcome(cfg.FakeOutcome)
+		if err != nil {
+			return nil, err
+		}
+		logger.Warn("Using the fake payment gateway, no real payment is made")
+		return payment.NewFakeGateway(payment.FakeGatewayOptions{Outcome: outcome, Latency: cfg.FakeLatency}), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", cfg.Provider)
+	}
 }
 
 // NewPlaygroundHandler creates a GraphQL playground handler
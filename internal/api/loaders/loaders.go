@@ -0,0 +1,130 @@
+// Package loaders batches the entity lookups made by GraphQL resolvers. A fresh set
+// of loaders is attached to the context of every request, so each entity is
+// fetched at most once per request and lookups made while resolving a list are
+// merged into one $in query per entity type. Subscriptions get a fresh set for
+// every event they send.
+package loaders
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/dataloader"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Repositories are the batch lookups behind the loaders
+type Repositories struct {
+	Users interface {
+		FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.User, error)
+	}
+	Products interface {
+		FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Product, error)
+	}
+	Categories interface {
+		FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Category, error)
+	}
+	Orders interface {
+		FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Order, error)
+	}
+	Reviews interface {
+		FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Review, error)
+	}
+	Wishlists interface {
+		FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Wishlist, error)
+	}
+	Carts interface {
+		FindByUserIDs(ctx context.Context, userIDs []string) ([]models.Cart, error)
+	}
+	Inventory interface {
+		FindItemsBySKUs(ctx context.Context, skus []string) ([]models.InventoryItem, error)
+	}
+}
+
+// Loaders holds one loader per entity type. Keys without an entity fail with
+// models.ErrNotFound. Loaders don't check access; resolvers loading entities a
+// user may not see must check it themselves.
+type Loaders struct {
+	Users      *dataloader.Loader[primitive.ObjectID, *models.User]
+	Products   *dataloader.Loader[primitive.ObjectID, *models.Product]
+	Categories *dataloader.Loader[primitive.ObjectID, *models.Category]
+	Orders     *dataloader.Loader[primitive.ObjectID, *models.Order]
+	Reviews    *dataloader.Loader[primitive.ObjectID, *models.Review]
+	Wishlists  *dataloader.Loader[primitive.ObjectID, *models.Wishlist]
+	// Carts loads the cart of a signed in user by user ID
+	Carts *dataloader.Loader[string, *models.Cart]
+	// Inventory loads inventory items by SKU
+	Inventory *dataloader.Loader[string, *models.InventoryItem]
+}
+
+// New creates a set of loaders with empty caches
+func New(repos Repositories) *Loaders {
+	return &Loaders{
+		Users:      dataloader.New(fetchBy(repos.Users.FindByIDs, func(u *models.User) primitive.ObjectID { return u.ID })),
+		Products:   dataloader.New(fetchBy(repos.Products.FindByIDs, func(p *models.Product) primitive.ObjectID { return p.ID })),
+		Categories: dataloader.New(fetchBy(repos.Categories.FindByIDs, func(c *models.Category) primitive.ObjectID { return c.ID })),
+		Orders:     dataloader.New(fetchBy(repos.Orders.FindByIDs, func(o *models.Order) primitive.ObjectID { return o.ID })),
+		Reviews:    dataloader.New(fetchBy(repos.Reviews.FindByIDs, func(r *models.Review) primitive.ObjectID { return r.ID })),
+		Wishlists:  dataloader.New(fetchBy(repos.Wishlists.FindByIDs, func(w *models.Wishlist) primitive.ObjectID { return w.ID })),
+		Carts:      dataloader.New(fetchBy(repos.Carts.FindByUserIDs, func(c *models.Cart) string { return c.UserID })),
+		Inventory:  dataloader.New(fetchBy(repos.Inventory.FindItemsBySKUs, func(i *models.InventoryItem) string { return i.SKU })),
+	}
+}
+
+// fetchBy adapts a repository batch lookup returning entities in any order to a
+// dataloader fetch function returning one entity or error per key
+func fetchBy[K comparable, V any](find func(context.Context, []K) ([]V, error), key func(*V) K) dataloader.FetchFunc[K, *V] {
+	return func(ctx context.Context, keys []K) ([]*V, []error) {
+		found, err := find(ctx, keys)
+		if err != nil {
+			return nil, []error{err}
+		}
+		entities := make([]*V, len(found))
+		for i := range found {
+			entities[i] = &found[i]
+		}
+		return dataloader.Align(keys, entities, key, models.ErrNotFound)
+	}
+}
+
+// loadersKey is the context key of the loaders of a request
+type loadersKey struct{}
+
+// WithLoaders returns a copy of ctx carrying loaders
+func WithLoaders(ctx context.Context, l *Loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+// For returns the loaders of the request. It panics when the request didn't go
+// through Middleware, which is a wiring mistake.
+func For(ctx context.Context) *Loaders {
+	l, ok := ctx.Value(loadersKey{}).(*Loaders)
+	if !ok {
+		panic("loaders: the request context has no loaders, is loaders.Middleware installed?")
+	}
+	return l
+}
+
+// Middleware attaches a fresh set of loaders to the context of every request
+func Middleware(repos Repositories) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithLoaders(r.Context(), New(repos))))
+		})
+	}
+}
+
+// SubscriptionMiddleware attaches a fresh set of loaders to every event of a
+// subscription. Without it the loaders of the WebSocket upgrade request would
+// cache entities for the whole life of the connection.
+func SubscriptionMiddleware(repos Repositories) graphql.ResponseMiddleware {
+	return func(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+		if op := graphql.GetOperationContext(ctx); op.Operation != nil && op.Operation.Operation == ast.Subscription {
+			ctx = WithLoaders(ctx, New(repos))
+		}
+		return next(ctx)
+	}
+}
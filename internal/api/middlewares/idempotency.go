@@ -0,0 +1,142 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/logger"
+	"github.com/prototype01/pkg/validator"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// IdempotencyKeyHeader is the request header carrying the idempotency key of a mutation
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength is the maximum length of an idempotency key
+const maxIdempotencyKeyLength = 255
+
+// cartTokenHeader is the request header carrying the guest cart token, which scopes
+// the idempotency keys of guests
+const cartTokenHeader = "X-Cart-Token"
+
+// IdempotencyMiddleware makes mutations sent with an Idempotency-Key header safe to
+// retry. The first response is stored per user and key; retries of the same operation
+// with the same variables replay it, other operations under the key fail with CONFLICT.
+// It must run after AuthMiddleware so keys are scoped to the signed in user. Guest keys
+// are scoped to the guest cart token, guests without one get no idempotency.
+func IdempotencyMiddleware(idempotency *services.IdempotencyService) graphql.OperationMiddleware {
+	return func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		op := graphql.GetOperationContext(ctx)
+		r := auth.GetRequestFromContext(ctx)
+		if r == nil || op.Operation == nil || op.Operation.Operation != ast.Mutation {
+			return next(ctx)
+		}
+		key := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+		if key == "" {
+			return next(ctx)
+		}
+		scope := idempotencyScope(ctx, r.Header.Get(cartTokenHeader))
+		if scope == "" {
+			return next(ctx)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return errorResponse(ctx, validator.ValidationErrors{{
+				Field:   IdempotencyKeyHeader,
+				Message: fmt.Sprintf("the key must be at most %d characters long", maxIdempotencyKeyLength),
+			}})
+		}
+
+		record, stored, err := idempotency.Begin(ctx, scope, key, operationHash(op))
+		if err != nil {
+			return errorResponse(ctx, err)
+		}
+		if record == nil {
+			return replayResponse(ctx, stored)
+		}
+
+		handler := next(ctx)
+		return func(ctx context.Context) *graphql.Response {
+			resp := handler(ctx)
+			if resp == nil {
+				return nil
+			}
+
+			if !replayable(resp) {
+				if err := idempotency.Release(ctx, record); err != nil {
+					logger.Error("Failed to release idempotency key", err)
+				}
+				return resp
+			}
+			encoded, err := json.Marshal(resp)
+			if err == nil {
+				err = idempotency.Complete(ctx, record, encoded)
+			}
+			if err != nil {
+				logger.Error("Failed to store idempotent response", err)
+			}
+			return resp
+		}
+	}
+}
+
+// idempotencyScope returns the namespace of the idempotency keys of a request: the
+// signed in user, or the hash of the guest cart token. Guests without a cart token
+// share nothing that could scope their keys, so their scope is empty.
+func idempotencyScope(ctx context.Context, cartToken string) string {
+	if userID, ok := auth.GetUserIDFromContext(ctx); ok && userID != "" {
+		return userID
+	}
+	if cartToken == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(cartToken))
+	return "cart:" + hex.EncodeToString(sum[:])
+}
+
+// operationHash identifies the query, operation name and variables of an operation
+func operationHash(op *graphql.OperationContext) string {
+	// Maps are encoded with sorted keys, so equal variables hash the same
+	variables, _ := json.Marshal(op.Variables)
+	sum := sha256.Sum256([]byte(op.RawQuery + "\x00" + op.OperationName + "\x00" + string(variables)))
+	return hex.EncodeToString(sum[:])
+}
+
+// replayable reports whether a response is final and can be replayed. Responses with
+// unexpected errors or payment gateway failures are not stored so retries run again.
+func replayable(resp *graphql.Response) bool {
+	for _, err := range resp.Errors {
+		code, ok := err.Extensions["code"]
+		if !ok || fmt.Sprint(code) == string(gqlerrors.CodePaymentUnavailable) {
+			return false
+		}
+	}
+	return true
+}
+
+// replayResponse returns a stored response, flagged with the idempotentReplay extension
+func replayResponse(ctx context.Context, stored []byte) graphql.ResponseHandler {
+	var resp graphql.Response
+	if err := json.Unmarshal(stored, &resp); err != nil {
+		logger.Error("Failed to decode stored idempotent response", err)
+		return errorResponse(ctx, err)
+	}
+	if resp.Extensions == nil {
+		resp.Extensions = map[string]interface{}{}
+	}
+	resp.Extensions["idempotentReplay"] = true
+	return graphql.OneShot(&resp)
+}
+
+// errorResponse returns a response carrying a single presented error
+func errorResponse(ctx context.Context, err error) graphql.ResponseHandler {
+	return graphql.OneShot(&graphql.Response{Errors: gqlerror.List{gqlerrors.Presenter(ctx, err)}})
+}
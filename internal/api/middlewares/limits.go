@@ -0,0 +1,168 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// queryLimitsExtension is the name of the QueryLimits extension and of its stats
+const queryLimitsExtension = "QueryLimits"
+
+// QueryCost is the measured depth and complexity of an operation with the limits it was checked against
+type QueryCost struct {
+	Depth         int `json:"depth"`
+	MaxDepth      int `json:"maxDepth"`
+	Complexity    int `json:"complexity"`
+	MaxComplexity int `json:"maxComplexity"`
+}
+
+// QueryLimits rejects operations nested deeper than MaxDepth or costing more than
+// MaxComplexity before they execute, and reports the cost of every operation in
+// the "cost" response extension. Complexity uses the cost hints of the schema and
+// _entities costs its selection once per representation; introspection fields
+// don't count towards the depth.
+type QueryLimits struct {
+	MaxDepth      int
+	MaxComplexity int
+
+	es graphql.ExecutableSchema
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+	graphql.ResponseInterceptor
+} = &QueryLimits{}
+
+// ExtensionName implements graphql.HandlerExtension
+func (l *QueryLimits) ExtensionName() string {
+	return queryLimitsExtension
+}
+
+// Validate implements graphql.HandlerExtension
+func (l *QueryLimits) Validate(schema graphql.ExecutableSchema) error {
+	if l.MaxDepth <= 0 || l.MaxComplexity <= 0 {
+		return fmt.Errorf("query limits must be positive, got depth %d and complexity %d", l.MaxDepth, l.MaxComplexity)
+	}
+	l.es = schema
+	errcode.RegisterErrorType(string(gqlerrors.CodeQueryTooDeep), errcode.KindProtocol)
+	errcode.RegisterErrorType(string(gqlerrors.CodeQueryTooComplex), errcode.KindProtocol)
+	return nil
+}
+
+// MutateOperationContext measures the operation and rejects it when it is over a limit
+func (l *QueryLimits) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	op := opCtx.Doc.Operations.ForName(opCtx.OperationName)
+	if op == nil {
+		return nil
+	}
+
+	cost := &QueryCost{
+		Depth:         selectionDepth(op.SelectionSet),
+		MaxDepth:      l.MaxDepth,
+		Complexity:    complexity.Calculate(ctx, l.es, op, opCtx.Variables) + l.entitiesCost(ctx, op, opCtx.Variables),
+		MaxComplexity: l.MaxComplexity,
+	}
+	opCtx.Stats.SetExtension(queryLimitsExtension, cost)
+
+	switch {
+	case cost.Depth > cost.MaxDepth:
+		return limitError(gqlerrors.CodeQueryTooDeep, fmt.Sprintf("operation has depth %d, which exceeds the limit of %d", cost.Depth, cost.MaxDepth))
+	case cost.Complexity > cost.MaxComplexity:
+		return limitError(gqlerrors.CodeQueryTooComplex, fmt.Sprintf("operation has complexity %d, which exceeds the limit of %d", cost.Complexity, cost.MaxComplexity))
+	}
+	return nil
+}
+
+// entitiesCost returns the cost the schema hints miss on the _entities fields of an
+// operation. The generated resolver of _entities takes no hint, so its selection is
+// counted once although it is resolved for every representation.
+func (l *QueryLimits) entitiesCost(ctx context.Context, op *ast.OperationDefinition, variables map[string]interface{}) int {
+	cost := 0
+	for _, field := range rootFields(op.SelectionSet) {
+		if field.Name != "_entities" {
+			continue
+		}
+		representations, _ := field.ArgumentMap(variables)["representations"].([]interface{})
+		if len(representations) <= 1 {
+			continue
+		}
+		single := &ast.OperationDefinition{Operation: op.Operation, SelectionSet: ast.SelectionSet{field}}
+		child := complexity.Calculate(ctx, l.es, single, variables) - 1
+		cost += child * (len(representations) - 1)
+	}
+	return cost
+}
+
+// rootFields returns the fields of a selection set, including those selected through fragments
+func rootFields(selections ast.SelectionSet) []*ast.Field {
+	var fields []*ast.Field
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			fields = append(fields, s)
+		case *ast.InlineFragment:
+			fields = append(fields, rootFields(s.SelectionSet)...)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				fields = append(fields, rootFields(s.Definition.SelectionSet)...)
+			}
+		}
+	}
+	return fields
+}
+
+// InterceptResponse adds the cost of the operation to the response extensions
+func (l *QueryLimits) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	if resp == nil || !graphql.HasOperationContext(ctx) {
+		return resp
+	}
+	if cost, ok := graphql.GetOperationContext(ctx).Stats.GetExtension(queryLimitsExtension).(*QueryCost); ok {
+		if resp.Extensions == nil {
+			resp.Extensions = make(map[string]interface{})
+		}
+		resp.Extensions["cost"] = cost
+	}
+	return resp
+}
+
+// limitError is the error of an operation over a limit. Its code is registered as
+// a protocol error so HTTP transports answer with a 422 status.
+func limitError(code gqlerrors.Code, message string) *gqlerror.Error {
+	err := gqlerror.Errorf("%s", message)
+	errcode.Set(err, string(code))
+	return err
+}
+
+// selectionDepth returns the number of nested field levels of a selection set.
+// Fragments don't add a level and introspection fields are ignored.
+func selectionDepth(selections ast.SelectionSet) int {
+	depth := 0
+	for _, selection := range selections {
+		var d int
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			d = 1 + selectionDepth(s.SelectionSet)
+		case *ast.InlineFragment:
+			d = selectionDepth(s.SelectionSet)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				d = selectionDepth(s.Definition.SelectionSet)
+			}
+		}
+		depth = max(depth, d)
+	}
+	return depth
+}
@@ -0,0 +1,114 @@
+package middlewares_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/api/middlewares"
+	"github.com/prototype01/internal/api/resolvers"
+)
+
+// graphqlResponse is the decoded body of a GraphQL response
+type graphqlResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+	Extensions struct {
+		Cost *middlewares.QueryCost `json:"cost"`
+	} `json:"extensions"`
+}
+
+// postQuery runs a query against a schema limited to maxDepth and maxComplexity
+func postQuery(t *testing.T, maxDepth, maxComplexity int, query string) (int, graphqlResponse) {
+	t.Helper()
+	h := handler.New(generated.NewExecutableSchema(generated.Config{Resolvers: &resolvers.Resolver{}}))
+	h.AddTransport(transport.POST{})
+	h.Use(&middlewares.QueryLimits{MaxDepth: maxDepth, MaxComplexity: maxComplexity})
+
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp graphqlResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func TestQueryLimitsReportCost(t *testing.T) {
+	status, resp := postQuery(t, 5, 10, `{ ping version { number } }`)
+	if status != http.StatusOK || len(resp.Errors) > 0 {
+		t.Fatalf("status %d, errors %v, want a successful query", status, resp.Errors)
+	}
+	cost := resp.Extensions.Cost
+	if cost == nil || cost.Depth != 2 || cost.Complexity != 3 || cost.MaxDepth != 5 || cost.MaxComplexity != 10 {
+		t.Fatalf("cost = %+v, want depth 2 and complexity 3 with the limits", cost)
+	}
+}
+
+func TestQueryLimitsRejectOperations(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxDepth      int
+		maxComplexity int
+		query         string
+		code          gqlerrors.Code
+	}{
+		{"too deep", 1, 100, `{ version { number } }`, gqlerrors.CodeQueryTooDeep},
+		{"too deep through fragments", 1, 100, `{ ...V } fragment V on Query { version { number } }`, gqlerrors.CodeQueryTooDeep},
+		{"too complex", 5, 2, `{ ping version { number buildDate } }`, gqlerrors.CodeQueryTooComplex},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := postQuery(t, tt.maxDepth, tt.maxComplexity, tt.query)
+			if status != http.StatusUnprocessableEntity {
+				t.Errorf("status = %d, want 422", status)
+			}
+			if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != string(tt.code) {
+				t.Fatalf("errors = %+v, want a %s error", resp.Errors, tt.code)
+			}
+			if resp.Data != nil {
+				t.Errorf("data = %v, want the operation not to run", resp.Data)
+			}
+			if resp.Extensions.Cost == nil {
+				t.Error("the cost of the rejected operation was not reported")
+			}
+		})
+	}
+}
+
+// entitiesQuery selects product names from count representations, which fail to
+// resolve without running a resolver
+func entitiesQuery(count int) string {
+	representations := strings.TrimSuffix(strings.Repeat(`{__typename: "Unknown"}, `, count), ", ")
+	return `{ ... on Query { _entities(representations: [` + representations + `]) { ... on Product { id name } } } }`
+}
+
+func TestQueryLimitsChargeEveryEntity(t *testing.T) {
+	_, single := postQuery(t, 5, 1000, entitiesQuery(1))
+	_, batch := postQuery(t, 5, 1000, entitiesQuery(10))
+	if single.Extensions.Cost == nil || batch.Extensions.Cost == nil {
+		t.Fatalf("costs = %+v and %+v, want both reported", single.Extensions.Cost, batch.Extensions.Cost)
+	}
+	// Every representation resolves an entity selecting id and name
+	if got, want := batch.Extensions.Cost.Complexity, single.Extensions.Cost.Complexity+9*2; got != want {
+		t.Errorf("complexity of 10 entities = %d, want %d", got, want)
+	}
+
+	status, resp := postQuery(t, 5, 100, entitiesQuery(60))
+	if status != http.StatusUnprocessableEntity || len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != string(gqlerrors.CodeQueryTooComplex) {
+		t.Fatalf("status %d, errors %+v, want a 422 %s error", status, resp.Errors, gqlerrors.CodeQueryTooComplex)
+	}
+}
@@ -3,7 +3,7 @@ package middlewares
 
 import (
 	"context"
-	"net/http"
+	"errors"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql"
@@ -65,53 +65,43 @@ func ResponseMiddleware() graphql.ResponseMiddleware {
 	}
 }
 
+// authenticate verifies an access token and rejects tokens on the revocation list
+func authenticate(ctx context.Context, resolver *resolvers.Resolver, token string) (*auth.Claims, error) {
+	claims, err := resolver.Verifier.VerifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if resolver.AuthService != nil {
+		revoked, err := resolver.AuthService.IsRevoked(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, errors.New("token has been revoked")
+		}
+	}
+	return claims, nil
+}
+
 // AuthMiddleware handles authentication for GraphQL operations
 func AuthMiddleware(resolver *resolvers.Resolver) graphql.OperationMiddleware {
 	return func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
-		// Get HTTP request from context using graphqlRequestContextKey
-		// Since transport.GetHTTP is not available in this version of gqlgen
-		// We'll use our own custom auth package to get the request
-		httpRequest := auth.GetRequestFromContext(ctx)
-		if httpRequest == nil {
-			return next(ctx)
-		}
-
-		// Store the request in context for later token extraction (this is redundant but keeping for clarity)
-		ctx = context.WithValue(ctx, auth.RequestContextKey, httpRequest)
+		// Extract token from the websocket init payload, Authorization header or cookie
+		token := auth.ExtractTokenFromContext(ctx)
 
-		// Extract token from request
-		token := extractToken(httpRequest)
-
-		if token != "" {
-			// Verify the token
-			userID, err := auth.VerifyToken(token)
+		if token != "" && resolver.Verifier != nil {
+			claims, err := authenticate(ctx, resolver, token)
 			if err == nil {
-				// If token is valid, set user in context
-				ctx = context.WithValue(ctx, auth.UserIDKey, userID)
-				logger.Info("Authenticated user: " + userID)
+				// If token is valid, set the claims in context
+				ctx = auth.WithClaims(ctx, claims)
+				logger.Info("Authenticated user: " + claims.UserID())
 			} else {
 				logger.Warn("Invalid authentication token: " + err.Error())
 			}
 		}
 
 		// Continue with the operation regardless of auth status
-		// Individual resolvers can check auth as needed
+		// The @auth and @hasRole directives enforce access per field
 		return next(ctx)
 	}
 }
-
-// extractToken gets the JWT token from the Authorization header
-func extractToken(r *http.Request) string {
-	bearerToken := r.Header.Get("Authorization")
-	if bearerToken == "" {
-		return ""
-	}
-
-	// The header format should be "Bearer <token>"
-	const prefix = "Bearer "
-	if len(bearerToken) > len(prefix) && bearerToken[:len(prefix)] == prefix {
-		return bearerToken[len(prefix):]
-	}
-
-	return ""
-}
@@ -0,0 +1,114 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/logger"
+	"github.com/prototype01/pkg/validator"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// PersistedQueryCache is the query cache of automatic persisted queries. Queries
+// are stored by the PersistedQueryService and the most recently used ones are
+// kept in memory; queries that weren't found aren't cached. Queries found in memory
+// don't extend their stored expiry: once expired they are persisted again by the
+// client.
+type PersistedQueryCache struct {
+	queries *services.PersistedQueryService
+	recent  *lru.LRU[string]
+}
+
+var _ graphql.Cache[string] = &PersistedQueryCache{}
+
+// NewPersistedQueryCache creates a PersistedQueryCache keeping up to size queries in memory
+func NewPersistedQueryCache(queries *services.PersistedQueryService, size int) *PersistedQueryCache {
+	return &PersistedQueryCache{queries: queries, recent: lru.New[string](size)}
+}
+
+// Get implements graphql.Cache, returning the query stored under a hash
+func (c *PersistedQueryCache) Get(ctx context.Context, hash string) (string, bool) {
+	query, err := c.Lookup(ctx, hash)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logger.Error("Failed to find persisted query", err)
+		}
+		return "", false
+	}
+	return query, true
+}
+
+// Add implements graphql.Cache, storing a query sent with its hash. Queries are
+// only stored when the service accepts new queries; rejected ones, e.g. too long,
+// still run but must be sent in full again.
+func (c *PersistedQueryCache) Add(ctx context.Context, hash, query string) {
+	if c.queries.AllowlistOnly() {
+		return
+	}
+	if err := c.queries.Persist(ctx, hash, query); err != nil {
+		var errs validator.ValidationErrors
+		if !errors.As(err, &errs) {
+			logger.Error("Failed to persist query", err)
+		}
+		return
+	}
+	c.recent.Add(ctx, hash, query)
+}
+
+// Lookup returns the query stored under a hash, or models.ErrNotFound
+func (c *PersistedQueryCache) Lookup(ctx context.Context, hash string) (string, error) {
+	if query, ok := c.recent.Get(ctx, hash); ok {
+		return query, nil
+	}
+	persisted, err := c.queries.Find(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	c.recent.Add(ctx, hash, persisted.Query)
+	return persisted.Query, nil
+}
+
+// PersistedQueryAllowlist rejects operations whose query wasn't registered ahead
+// of time, whether the client sent the query or only its hash. It must be used
+// after the AutomaticPersistedQuery extension so hashed queries are resolved first.
+type PersistedQueryAllowlist struct {
+	Cache *PersistedQueryCache
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationParameterMutator
+} = PersistedQueryAllowlist{}
+
+// ExtensionName implements graphql.HandlerExtension
+func (a PersistedQueryAllowlist) ExtensionName() string {
+	return "PersistedQueryAllowlist"
+}
+
+// Validate implements graphql.HandlerExtension
+func (a PersistedQueryAllowlist) Validate(schema graphql.ExecutableSchema) error {
+	if a.Cache == nil || !a.Cache.queries.AllowlistOnly() {
+		return errors.New("the persisted query allowlist needs a cache in allowlist mode")
+	}
+	errcode.RegisterErrorType(string(gqlerrors.CodeOperationNotAllowed), errcode.KindProtocol)
+	return nil
+}
+
+// MutateOperationParameters rejects queries that aren't registered
+func (a PersistedQueryAllowlist) MutateOperationParameters(ctx context.Context, params *graphql.RawParams) *gqlerror.Error {
+	_, err := a.Cache.Lookup(ctx, services.QueryHash(params.Query))
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, models.ErrNotFound) {
+		logger.Error("Failed to find persisted query", err)
+		return gqlerror.Errorf("internal server error")
+	}
+	return limitError(gqlerrors.CodeOperationNotAllowed, "the operation is not registered")
+}
@@ -0,0 +1,156 @@
+package middlewares_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/api/middlewares"
+	"github.com/prototype01/internal/api/resolvers"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+)
+
+// memoryPersistedQueries is an in-memory services.PersistedQueryRepository
+type memoryPersistedQueries map[string]models.PersistedQuery
+
+func (m memoryPersistedQueries) Find(ctx context.Context, hash string) (*models.PersistedQuery, error) {
+	query, ok := m[hash]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &query, nil
+}
+
+func (m memoryPersistedQueries) Create(ctx context.Context, query *models.PersistedQuery) error {
+	if _, ok := m[query.Hash]; !ok {
+		m[query.Hash] = *query
+	}
+	return nil
+}
+
+func (m memoryPersistedQueries) Extend(ctx context.Context, hash string, expiresAt time.Time) error {
+	if query, ok := m[hash]; ok && !query.Registered {
+		query.ExpiresAt = &expiresAt
+		m[hash] = query
+	}
+	return nil
+}
+
+func (m memoryPersistedQueries) Register(ctx context.Context, query *models.PersistedQuery) error {
+	query.Registered = true
+	m[query.Hash] = *query
+	return nil
+}
+
+// newPersistedQueryService creates a PersistedQueryService storing queries of up to 1 KiB in repo
+func newPersistedQueryService(repo memoryPersistedQueries, allowlistOnly bool) *services.PersistedQueryService {
+	return services.NewPersistedQueryService(repo, allowlistOnly, time.Hour, 1024)
+}
+
+// persistedQueryHandler serves the schema with persisted queries stored in repo
+func persistedQueryHandler(repo memoryPersistedQueries, allowlistOnly bool) http.Handler {
+	cache := middlewares.NewPersistedQueryCache(newPersistedQueryService(repo, allowlistOnly), 10)
+	h := handler.New(generated.NewExecutableSchema(generated.Config{Resolvers: &resolvers.Resolver{}}))
+	h.AddTransport(transport.POST{})
+	h.Use(extension.AutomaticPersistedQuery{Cache: cache})
+	if allowlistOnly {
+		h.Use(middlewares.PersistedQueryAllowlist{Cache: cache})
+	}
+	return h
+}
+
+// postPersisted posts a query, a hash or both to h
+func postPersisted(t *testing.T, h http.Handler, query, hash string) (int, graphqlResponse) {
+	t.Helper()
+	params := map[string]interface{}{"query": query}
+	if hash != "" {
+		params["extensions"] = map[string]interface{}{
+			"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hash},
+		}
+	}
+	body, _ := json.Marshal(params)
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp graphqlResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func TestPersistedQueriesAreStored(t *testing.T) {
+	repo := memoryPersistedQueries{}
+	h := persistedQueryHandler(repo, false)
+	query := "query Ping { ping }"
+	hash := services.QueryHash(query)
+
+	if _, resp := postPersisted(t, h, "", hash); len(resp.Errors) != 1 || resp.Errors[0].Message != "PersistedQueryNotFound" {
+		t.Fatalf("unknown hash returned %+v, want PersistedQueryNotFound", resp.Errors)
+	}
+	if _, resp := postPersisted(t, h, query, hash); len(resp.Errors) > 0 {
+		t.Fatalf("query with its hash returned %+v", resp.Errors)
+	}
+	if _, ok := repo[hash]; !ok {
+		t.Fatal("query wasn't stored")
+	}
+
+	// A new cache only finds the query in the repository
+	if _, resp := postPersisted(t, persistedQueryHandler(repo, false), "", hash); len(resp.Errors) > 0 || resp.Data["ping"] == nil {
+		t.Fatalf("stored hash returned %+v", resp)
+	}
+}
+
+func TestAllowlistRejectsUnregisteredQueries(t *testing.T) {
+	repo := memoryPersistedQueries{}
+	registered := "query Ping { ping }"
+	if _, err := newPersistedQueryService(repo, true).Register(context.Background(), "Ping", registered); err != nil {
+		t.Fatal(err)
+	}
+	h := persistedQueryHandler(repo, true)
+
+	for _, query := range []string{"{ ping }", "{ __schema { queryType { name } } }"} {
+		status, resp := postPersisted(t, h, query, "")
+		if status != http.StatusUnprocessableEntity || len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != string(gqlerrors.CodeOperationNotAllowed) {
+			t.Fatalf("%s returned %d %+v, want a 422 OPERATION_NOT_ALLOWED error", query, status, resp.Errors)
+		}
+	}
+	if _, resp := postPersisted(t, h, "{ ping }", services.QueryHash("{ ping }")); len(resp.Errors) != 1 {
+		t.Fatalf("unregistered query with its hash returned %+v, want an error", resp.Errors)
+	}
+	if _, ok := repo[services.QueryHash("{ ping }")]; ok {
+		t.Fatal("unregistered query was stored in allowlist mode")
+	}
+
+	if _, resp := postPersisted(t, h, registered, ""); len(resp.Errors) > 0 {
+		t.Fatalf("registered query returned %+v", resp.Errors)
+	}
+	if _, resp := postPersisted(t, h, "", services.QueryHash(registered)); len(resp.Errors) > 0 || resp.Data["ping"] == nil {
+		t.Fatalf("registered hash returned %+v", resp)
+	}
+}
+
+func TestLongQueriesRunWithoutBeingStored(t *testing.T) {
+	repo := memoryPersistedQueries{}
+	query := "query Ping { ping }" + strings.Repeat(" ", 1024)
+	hash := services.QueryHash(query)
+
+	if _, resp := postPersisted(t, persistedQueryHandler(repo, false), query, hash); len(resp.Errors) > 0 || resp.Data["ping"] == nil {
+		t.Fatalf("long query with its hash returned %+v", resp)
+	}
+	if len(repo) != 0 {
+		t.Fatal("a query over the maximum length was stored")
+	}
+}
@@ -0,0 +1,121 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/prototype01/internal/api/resolvers"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/pkg/logger"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// errInvalidToken is sent to WebSocket clients whose connection_init token is rejected
+var errInvalidToken = errors.New("invalid authentication token")
+
+// subscriptionCounterKey is the context key of the active subscription count of a connection
+type subscriptionCounterKey struct{}
+
+// WebsocketInit authenticates WebSocket connections in connection_init. The token
+// is read from the init payload, falling back to the headers and cookie of the
+// upgrade request. Connections with an invalid or revoked token are refused and
+// authenticated connections are closed when their token expires, so clients
+// reconnect with a fresh token. Anonymous connections are accepted; the @auth
+// directive guards the subscriptions that need a user.
+func WebsocketInit(resolver *resolvers.Resolver) transport.WebsocketInitFunc {
+	return func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		ctx = context.WithValue(ctx, subscriptionCounterKey{}, new(atomic.Int64))
+
+		token := auth.TokenFromInitPayload(payload)
+		if r := auth.GetRequestFromContext(ctx); token == "" && r != nil {
+			token = auth.TokenFromRequest(r)
+		}
+		if token == "" || resolver.Verifier == nil {
+			return ctx, nil, nil
+		}
+
+		claims, err := authenticate(ctx, resolver, token)
+		if err != nil {
+			logger.Warn("Refused WebSocket connection: " + err.Error())
+			return ctx, nil, errInvalidToken
+		}
+		if claims.ExpiresAt != nil {
+			ctx = transport.AppendCloseReason(ctx, "authentication token expired")
+			expiring, cancel := context.WithDeadline(ctx, claims.ExpiresAt.Time)
+			context.AfterFunc(expiring, cancel)
+			ctx = expiring
+		}
+		return auth.WithClaims(ctx, claims), nil, nil
+	}
+}
+
+// SubscriptionLimit rejects subscriptions beyond max active subscriptions per
+// WebSocket connection. It relies on the counter set up by WebsocketInit.
+func SubscriptionLimit(max int) graphql.OperationMiddleware {
+	return func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		op := graphql.GetOperationContext(ctx)
+		active, ok := ctx.Value(subscriptionCounterKey{}).(*atomic.Int64)
+		if !ok || op.Operation == nil || op.Operation.Operation != ast.Subscription {
+			return next(ctx)
+		}
+
+		if active.Add(1) > int64(max) {
+			active.Add(-1)
+			return graphql.OneShot(graphql.ErrorResponse(ctx, "a connection can hold at most %d subscriptions", max))
+		}
+		context.AfterFunc(ctx, func() { active.Add(-1) })
+		return next(ctx)
+	}
+}
+
+// limitedWebsocket is a WebSocket transport serving at most a fixed number of connections
+type limitedWebsocket struct {
+	transport.Websocket
+	slots chan struct{}
+}
+
+// LimitConnections wraps a WebSocket transport so at most max connections are
+// open at once. Upgrades beyond the limit are answered with 503 Service Unavailable.
+func LimitConnections(ws transport.Websocket, max int) graphql.Transport {
+	return limitedWebsocket{Websocket: ws, slots: make(chan struct{}, max)}
+}
+
+// Do implements graphql.Transport
+func (t limitedWebsocket) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecutor) {
+	select {
+	case t.slots <- struct{}{}:
+		defer func() { <-t.slots }()
+		t.Websocket.Do(w, r, exec)
+	default:
+		transport.SendErrorf(w, http.StatusServiceUnavailable, "too many WebSocket connections")
+	}
+}
+
+// CheckOrigin returns the WebSocket origin check. Requests without an Origin
+// header and same-origin requests are accepted, as are the listed origins; "*"
+// accepts any origin.
+func CheckOrigin(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		for _, a := range allowed {
+			if a == "*" || strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+				return true
+			}
+		}
+		logger.Warn(fmt.Sprintf("Refused WebSocket connection from origin %s", origin))
+		return false
+	}
+}
@@ -0,0 +1,201 @@
+package middlewares_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/middlewares"
+	"github.com/prototype01/internal/api/resolvers"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/config"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/pubsub"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryProducts is an in-memory services.SubscriptionProductRepository
+type memoryProducts map[primitive.ObjectID]*models.Product
+
+func (m memoryProducts) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error) {
+	product, ok := m[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return product, nil
+}
+
+// wsMessage is a graphql-transport-ws protocol message
+type wsMessage struct {
+	ID      string                 `json:"id,omitempty"`
+	Type    string                 `json:"type"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+// websocketFixture is a test server serving subscriptions to the stock of a product
+type websocketFixture struct {
+	server  *httptest.Server
+	product *models.Product
+	publish services.StockHook
+	stock   *pubsub.Broker[models.StockMovement]
+	signer  *auth.Signer
+}
+
+func newWebsocketFixture(t *testing.T, maxConnections, maxSubscriptions int) *websocketFixture {
+	t.Helper()
+	authConfig := config.AuthConfig{Algorithm: "HS256", Secret: "test-secret", AccessTokenTTL: time.Hour}
+	verifier, err := auth.NewVerifier(authConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := auth.NewSigner(authConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	product := &models.Product{Name: "Mug", Stock: 5}
+	product.ID = primitive.NewObjectID()
+	stock := pubsub.New[models.StockMovement](1)
+	subscriptions := services.NewSubscriptionService(nil, memoryProducts{product.ID: product}, pubsub.New[*models.Order](1), stock)
+	resolver := &resolvers.Resolver{Verifier: verifier, SubscriptionService: subscriptions}
+
+	h := handler.New(generated.NewExecutableSchema(generated.Config{Resolvers: resolver}))
+	h.AddTransport(middlewares.LimitConnections(transport.Websocket{
+		InitFunc:    middlewares.WebsocketInit(resolver),
+		InitTimeout: time.Second,
+	}, maxConnections))
+	h.AroundOperations(middlewares.AuthMiddleware(resolver))
+	h.AroundOperations(middlewares.SubscriptionLimit(maxSubscriptions))
+
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+	return &websocketFixture{server: server, product: product, publish: subscriptions.PublishStockChange(), stock: stock, signer: signer}
+}
+
+// dial opens a graphql-transport-ws connection and sends connection_init
+func (f *websocketFixture) dial(t *testing.T, payload map[string]interface{}) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(f.server.URL, "http"), nil)
+	if err != nil {
+		return nil, resp, err
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := conn.WriteJSON(wsMessage{Type: "connection_init", Payload: payload}); err != nil {
+		t.Fatal(err)
+	}
+	return conn, resp, nil
+}
+
+// read returns the next message that isn't a ping or pong
+func read(t *testing.T, conn *websocket.Conn) (wsMessage, error) {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return msg, err
+		}
+		if msg.Type != "ping" && msg.Type != "pong" {
+			return msg, nil
+		}
+	}
+}
+
+// subscribe starts a subscription to the stock of the fixture product
+func (f *websocketFixture) subscribe(t *testing.T, conn *websocket.Conn, id string) {
+	t.Helper()
+	if err := conn.WriteJSON(wsMessage{ID: id, Type: "subscribe", Payload: map[string]interface{}{
+		"query":     "subscription Stock($id: ID!) { productStockChanged(productId: $id) { id stock } }",
+		"variables": map[string]interface{}{"id": f.product.ID.Hex()},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebsocketSubscriptionsReceiveStockChanges(t *testing.T) {
+	f := newWebsocketFixture(t, 2, 1)
+	token, err := f.signer.Sign(&auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, _, err := f.dial(t, map[string]interface{}{"Authorization": "Bearer " + token})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	if msg, err := read(t, conn); err != nil || msg.Type != "connection_ack" {
+		t.Fatalf("got %+v, %v; want connection_ack", msg, err)
+	}
+
+	f.subscribe(t, conn, "1")
+	// The subscription is registered once the resolver subscribed to the broker
+	for deadline := time.Now().Add(2 * time.Second); f.stock.Subscribers(f.product.ID.Hex()) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription never reached the broker")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	f.product.Stock = 3
+	if err := f.publish(context.Background(), models.StockMovement{ProductID: f.product.ID}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := read(t, conn)
+	if err != nil || msg.Type != "next" || msg.ID != "1" {
+		t.Fatalf("got %+v, %v; want the next event of subscription 1", msg, err)
+	}
+	data := msg.Payload["data"].(map[string]interface{})["productStockChanged"].(map[string]interface{})
+	if data["stock"] != float64(3) {
+		t.Fatalf("streamed %v, want stock 3", data)
+	}
+
+	// A second subscription is over the per connection limit
+	f.subscribe(t, conn, "2")
+	msg, err = read(t, conn)
+	if err != nil || msg.ID != "2" || msg.Payload["errors"] == nil {
+		t.Fatalf("got %+v, %v; want an error for subscription 2", msg, err)
+	}
+}
+
+func TestWebsocketRefusesInvalidTokens(t *testing.T) {
+	f := newWebsocketFixture(t, 2, 1)
+	conn, _, err := f.dial(t, map[string]interface{}{"Authorization": "Bearer not-a-token"})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	for {
+		msg, err := read(t, conn)
+		if err != nil {
+			break
+		}
+		if msg.Type == "connection_ack" {
+			t.Fatal("connection with an invalid token was acknowledged")
+		}
+	}
+}
+
+func TestWebsocketConnectionLimit(t *testing.T) {
+	f := newWebsocketFixture(t, 1, 1)
+	conn, _, err := f.dial(t, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	if msg, err := read(t, conn); err != nil || msg.Type != "connection_ack" {
+		t.Fatalf("got %+v, %v; want connection_ack", msg, err)
+	}
+
+	_, resp, err := f.dial(t, nil)
+	if !errors.Is(err, websocket.ErrBadHandshake) || resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("second connection: got %v, want a 503 handshake error", err)
+	}
+}
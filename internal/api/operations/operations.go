@@ -0,0 +1,180 @@
+// Package operations loads the named GraphQL operations of a directory of
+// .graphql files so they can be registered as persisted queries
+package operations
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/formatter"
+	"github.com/vektah/gqlparser/v2/parser"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+// Operation is a named operation with the fragments it uses, formatted as the
+// document clients must send for its hash to match
+type Operation struct {
+	Name  string
+	Type  ast.Operation
+	Query string
+}
+
+// LoadDir returns the operations of the .graphql files of a directory sorted by
+// name, validated against the schema. Every operation must be named and names
+// must be unique across files.
+func LoadDir(dir string, schema *ast.Schema) ([]Operation, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.graphql"))
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []Operation
+	seen := map[string]string{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileOperations, err := Parse(filepath.Base(file), string(content), schema)
+		if err != nil {
+			return nil, err
+		}
+		for _, op := range fileOperations {
+			if other, ok := seen[op.Name]; ok {
+				return nil, fmt.Errorf("%s: operation %s is also defined in %s", filepath.Base(file), op.Name, other)
+			}
+			seen[op.Name] = filepath.Base(file)
+			operations = append(operations, op)
+		}
+	}
+
+	sort.Slice(operations, func(i, j int) bool { return operations[i].Name < operations[j].Name })
+	return operations, nil
+}
+
+// Parse returns the operations of a document validated against the schema.
+// Top-level JSON objects, such as the example variables written next to the
+// operations, are ignored.
+func Parse(name, content string, schema *ast.Schema) ([]Operation, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Name: name, Input: stripJSON(content)})
+	if err != nil {
+		return nil, err
+	}
+	if errs := validator.Validate(schema, doc); len(errs) > 0 {
+		return nil, errs
+	}
+
+	operations := make([]Operation, 0, len(doc.Operations))
+	for _, op := range doc.Operations {
+		if op.Name == "" {
+			return nil, fmt.Errorf("%s:%d: operations must be named", name, op.Position.Line)
+		}
+
+		var buf bytes.Buffer
+		formatter.NewFormatter(&buf).FormatQueryDocument(&ast.QueryDocument{
+			Operations: ast.OperationList{op},
+			Fragments:  usedFragments(doc, op.SelectionSet),
+		})
+		operations = append(operations, Operation{Name: op.Name, Type: op.Operation, Query: buf.String()})
+	}
+	return operations, nil
+}
+
+// usedFragments returns the fragments a selection set spreads, directly or
+// through other fragments, sorted by name
+func usedFragments(doc *ast.QueryDocument, selections ast.SelectionSet) ast.FragmentDefinitionList {
+	used := map[string]*ast.FragmentDefinition{}
+	var walk func(ast.SelectionSet)
+	walk = func(selections ast.SelectionSet) {
+		for _, selection := range selections {
+			switch s := selection.(type) {
+			case *ast.Field:
+				walk(s.SelectionSet)
+			case *ast.InlineFragment:
+				walk(s.SelectionSet)
+			case *ast.FragmentSpread:
+				if _, ok := used[s.Name]; ok {
+					continue
+				}
+				if fragment := doc.Fragments.ForName(s.Name); fragment != nil {
+					used[s.Name] = fragment
+					walk(fragment.SelectionSet)
+				}
+			}
+		}
+	}
+	walk(selections)
+
+	fragments := make(ast.FragmentDefinitionList, 0, len(used))
+	for _, fragment := range used {
+		fragments = append(fragments, fragment)
+	}
+	sort.Slice(fragments, func(i, j int) bool { return fragments[i].Name < fragments[j].Name })
+	return fragments
+}
+
+// stripJSON blanks the top-level braced blocks of a document that are valid JSON,
+// keeping line and column numbers. Braces inside strings and comments are skipped;
+// an anonymous query such as "{ ping }" isn't valid JSON and is kept.
+func stripJSON(content string) string {
+	out := []byte(content)
+	depth, start := 0, 0
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; c {
+		case '#':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+		case '"':
+			i = skipString(content, i)
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 && json.Valid([]byte(content[start:i+1])) {
+				for j := start; j <= i; j++ {
+					if out[j] != '\n' {
+						out[j] = ' '
+					}
+				}
+			}
+		}
+	}
+	return string(out)
+}
+
+// skipString returns the index of the closing quote of the string or block
+// string starting at i
+func skipString(content string, i int) int {
+	if len(content) >= i+3 && content[i:i+3] == `"""` {
+		for j := i + 3; j+3 <= len(content); j++ {
+			switch {
+			case content[j] == '\\':
+				j++
+			case content[j:j+3] == `"""`:
+				return j + 2
+			}
+		}
+		return len(content)
+	}
+	for j := i + 1; j < len(content); j++ {
+		switch content[j] {
+		case '\\':
+			j++
+		case '"', '\n':
+			return j
+		}
+	}
+	return len(content)
+}
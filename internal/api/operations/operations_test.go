@@ -0,0 +1,73 @@
+package operations_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/operations"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var schema = generated.NewExecutableSchema(generated.Config{}).Schema()
+
+func TestParseIgnoresExampleVariables(t *testing.T) {
+	ops, err := operations.Parse("example.graphql", `
+# Server status
+query Status {
+  ping
+  ...Version
+}
+
+# Variables for the query, braces in "strings {" are fine
+{
+  "note": "a } brace # and a hash"
+}
+
+fragment Version on Query {
+  version { number }
+}
+
+mutation Logout($refreshToken: String) {
+  logout(refreshToken: $refreshToken)
+}
+`, schema)
+	if err != nil {
+		t.Fatalf("Parse returned %v", err)
+	}
+	if len(ops) != 2 {
+		t.Fatalf("got %d operations, want 2", len(ops))
+	}
+
+	status := ops[0]
+	if status.Name != "Status" || status.Type != ast.Query {
+		t.Fatalf("first operation is %s %s, want query Status", status.Type, status.Name)
+	}
+	if !strings.Contains(status.Query, "fragment Version on Query") || strings.Contains(status.Query, "#") {
+		t.Fatalf("Status document is %q, want the fragment without comments", status.Query)
+	}
+	if strings.Contains(ops[1].Query, "fragment") {
+		t.Fatalf("Logout document is %q, want no fragments", ops[1].Query)
+	}
+}
+
+func TestParseRejectsInvalidOperations(t *testing.T) {
+	for name, doc := range map[string]string{
+		"anonymous":     "{ ping }",
+		"unknown field": "query Q { nope }",
+	} {
+		if _, err := operations.Parse(name+".graphql", doc, schema); err == nil {
+			t.Errorf("%s: Parse succeeded, want an error", name)
+		}
+	}
+}
+
+func TestLoadDirRegistersTheApolloQueries(t *testing.T) {
+	ops, err := operations.LoadDir("../../../apollo/queries", schema)
+	if err != nil {
+		t.Fatalf("LoadDir returned %v", err)
+	}
+	if len(ops) == 0 {
+		t.Fatal("no operations loaded")
+	}
+}
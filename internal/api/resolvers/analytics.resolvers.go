@@ -0,0 +1,16 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+
+	"github.com/prototype01/internal/domain/models"
+)
+
+// PopularProducts is the resolver for the popularProducts field.
+func (r *queryResolver) PopularProducts(ctx context.Context, limit int, period models.TimePeriod) ([]models.PopularProduct, error) {
+	return r.AnalyticsService.PopularProducts(ctx, limit, period)
+}
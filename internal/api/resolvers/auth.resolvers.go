@@ -0,0 +1,43 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/auth"
+)
+
+// RefreshToken is the resolver for the refreshToken field.
+func (r *mutationResolver) RefreshToken(ctx context.Context, refreshToken string) (*generated.AuthPayload, error) {
+	pair, err := r.AuthService.Refresh(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := r.UserService.GetUser(ctx, pair.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return toAuthPayload(user, pair), nil
+}
+
+// Logout is the resolver for the logout field.
+func (r *mutationResolver) Logout(ctx context.Context, refreshToken *string) (bool, error) {
+	claims, _ := auth.GetClaimsFromContext(ctx)
+	if claims == nil && refreshToken == nil {
+		return false, errors.New("an access token or refresh token is required to log out")
+	}
+
+	var token string
+	if refreshToken != nil {
+		token = *refreshToken
+	}
+	if err := r.AuthService.Logout(ctx, claims, token); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,35 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/logger"
+)
+
+// cartTokenHeader is the request header carrying the guest cart token
+const cartTokenHeader = "X-Cart-Token"
+
+// cartOwner identifies the cart of the current request by the signed in user or the guest cart token
+func cartOwner(ctx context.Context) services.CartOwner {
+	var owner services.CartOwner
+	owner.UserID, _ = auth.GetUserIDFromContext(ctx)
+	if r := auth.GetRequestFromContext(ctx); r != nil {
+		owner.Token = r.Header.Get(cartTokenHeader)
+	}
+	return owner
+}
+
+// mergeGuestCart moves the guest cart of the request into the cart of a user who
+// just signed in. Failures are logged, they must not prevent the sign in.
+func (r *Resolver) mergeGuestCart(ctx context.Context, user *models.User) {
+	token := cartOwner(ctx).Token
+	if token == "" {
+		return
+	}
+	if err := r.CartService.MergeGuestCart(ctx, user.ID.Hex(), token); err != nil {
+		logger.Error("Failed to merge guest cart", err)
+	}
+}
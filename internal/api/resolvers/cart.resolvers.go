@@ -0,0 +1,54 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/loaders"
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Product is the resolver for the product field.
+func (r *cartItemResolver) Product(ctx context.Context, obj *models.CartItem) (*models.Product, error) {
+	return nilIfNotFound(loaders.For(ctx).Products.Load(ctx, obj.ProductID))
+}
+
+// AddProductToCart is the resolver for the addProductToCart field.
+func (r *mutationResolver) AddProductToCart(ctx context.Context, productID primitive.ObjectID, quantity int) (*models.Cart, error) {
+	return r.CartService.AddItem(ctx, cartOwner(ctx), productID, quantity)
+}
+
+// UpdateCartItem is the resolver for the updateCartItem field.
+func (r *mutationResolver) UpdateCartItem(ctx context.Context, cartItemID primitive.ObjectID, quantity int) (*models.Cart, error) {
+	return r.CartService.UpdateItem(ctx, cartOwner(ctx), cartItemID, quantity)
+}
+
+// RemoveCartItem is the resolver for the removeCartItem field.
+func (r *mutationResolver) RemoveCartItem(ctx context.Context, cartItemID primitive.ObjectID) (*models.Cart, error) {
+	return r.CartService.RemoveItem(ctx, cartOwner(ctx), cartItemID)
+}
+
+// ClearCart is the resolver for the clearCart field.
+func (r *mutationResolver) ClearCart(ctx context.Context) (*generated.MutationResult, error) {
+	id, err := r.CartService.ClearCart(ctx, cartOwner(ctx))
+	if err != nil {
+		return nil, err
+	}
+	message := "cart cleared"
+	return &generated.MutationResult{ID: &id, Success: true, Message: &message}, nil
+}
+
+// Cart is the resolver for the cart field.
+func (r *queryResolver) Cart(ctx context.Context) (*models.Cart, error) {
+	return r.CartService.GetCart(ctx, cartOwner(ctx))
+}
+
+// CartItem returns generated.CartItemResolver implementation.
+func (r *Resolver) CartItem() generated.CartItemResolver { return &cartItemResolver{r} }
+
+type cartItemResolver struct{ *Resolver }
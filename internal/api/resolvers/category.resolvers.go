@@ -0,0 +1,90 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/loaders"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ParentCategory is the resolver for the parentCategory field.
+func (r *categoryResolver) ParentCategory(ctx context.Context, obj *models.Category) (*models.Category, error) {
+	if obj.ParentID == nil {
+		return nil, nil
+	}
+	return nilIfNotFound(loaders.For(ctx).Categories.Load(ctx, *obj.ParentID))
+}
+
+// ChildCategories is the resolver for the childCategories field.
+func (r *categoryResolver) ChildCategories(ctx context.Context, obj *models.Category) ([]models.Category, error) {
+	return r.CategoryService.GetChildren(ctx, obj.ID)
+}
+
+// CreateCategory is the resolver for the createCategory field.
+func (r *mutationResolver) CreateCategory(ctx context.Context, input generated.CreateCategoryInput) (*models.Category, error) {
+	var description string
+	if d := input.Description.Value(); d != nil {
+		description = *d
+	}
+	return r.CategoryService.CreateCategory(ctx, services.CreateCategoryInput{
+		Name:        input.Name,
+		Description: description,
+		ParentID:    input.ParentID.Value(),
+	})
+}
+
+// UpdateCategory is the resolver for the updateCategory field.
+func (r *mutationResolver) UpdateCategory(ctx context.Context, id primitive.ObjectID, input generated.UpdateCategoryInput) (*models.Category, error) {
+	return r.CategoryService.UpdateCategory(ctx, id, services.UpdateCategoryInput{
+		Name:        input.Name.Value(),
+		Description: input.Description.Value(),
+	})
+}
+
+// MoveCategory is the resolver for the moveCategory field.
+func (r *mutationResolver) MoveCategory(ctx context.Context, id primitive.ObjectID, parentID *primitive.ObjectID) (*models.Category, error) {
+	return r.CategoryService.MoveCategory(ctx, id, parentID)
+}
+
+// DeleteCategory is the resolver for the deleteCategory field.
+func (r *mutationResolver) DeleteCategory(ctx context.Context, id primitive.ObjectID, reassignTo *primitive.ObjectID) (*generated.MutationResult, error) {
+	if err := r.CategoryService.DeleteCategory(ctx, id, reassignTo); err != nil {
+		return nil, err
+	}
+	return deleted(id, "category deleted"), nil
+}
+
+// Category is the resolver for the category field.
+func (r *productResolver) Category(ctx context.Context, obj *models.Product) (*models.Category, error) {
+	if obj.CategoryID == nil {
+		return nil, nil
+	}
+	return nilIfNotFound(loaders.For(ctx).Categories.Load(ctx, *obj.CategoryID))
+}
+
+// Category is the resolver for the category field.
+func (r *queryResolver) Category(ctx context.Context, id primitive.ObjectID) (*models.Category, error) {
+	category, err := r.CategoryService.GetCategory(ctx, id)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+	return category, err
+}
+
+// Categories is the resolver for the categories field.
+func (r *queryResolver) Categories(ctx context.Context, includeEmpty *bool) ([]models.Category, error) {
+	return r.CategoryService.ListCategories(ctx, includeEmpty != nil && *includeEmpty)
+}
+
+// Category returns generated.CategoryResolver implementation.
+func (r *Resolver) Category() generated.CategoryResolver { return &categoryResolver{r} }
+
+type categoryResolver struct{ *Resolver }
@@ -0,0 +1,163 @@
+package resolvers
+
+import (
+	"errors"
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/internal/payment"
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// toAuthPayload converts a signed in user and its token pair to the GraphQL type
+func toAuthPayload(user *models.User, pair *services.TokenPair) *generated.AuthPayload {
+	return &generated.AuthPayload{
+		User:                  user,
+		Token:                 pair.AccessToken,
+		TokenExpiresAt:        pair.AccessExpiresAt,
+		RefreshToken:          pair.RefreshToken,
+		RefreshTokenExpiresAt: pair.RefreshExpiresAt,
+	}
+}
+
+// stringValue returns the value of an optional string argument, empty when omitted
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// boolValue returns the value of an optional boolean argument, false when omitted
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
+
+// nilIfNotFound turns the not found error of a lookup into a nil result, for
+// nullable fields referring to entities that may have been deleted
+func nilIfNotFound[V any](value *V, err error) (*V, error) {
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+	return value, err
+}
+
+// paginationArgs returns the pagination arguments, using the defaults when omitted
+func paginationArgs(pagination *connection.Args) connection.Args {
+	if pagination == nil {
+		return connection.Args{}
+	}
+	return *pagination
+}
+
+// deleted returns a successful MutationResult for a removed resource
+func deleted(id primitive.ObjectID, message string) *generated.MutationResult {
+	return &generated.MutationResult{ID: &id, Success: true, Message: &message}
+}
+
+// toAddress converts a postal address input
+func toAddress(input *generated.AddressInput) models.Address {
+	if input == nil {
+		return models.Address{}
+	}
+	return models.Address{
+		FullName: stringValue(input.FullName.Value()),
+		Street:   input.Street,
+		City:     input.City,
+		State:    stringValue(input.State.Value()),
+		ZipCode:  input.ZipCode,
+		Country:  input.Country,
+	}
+}
+
+// toShippingAddress converts a saved address input
+func toShippingAddress(input generated.ShippingAddressInput) services.ShippingAddressInput {
+	return services.ShippingAddressInput{
+		Address: models.Address{
+			FullName: stringValue(input.FullName.Value()),
+			Street:   input.Street,
+			City:     input.City,
+			State:    stringValue(input.State.Value()),
+			ZipCode:  input.ZipCode,
+			Country:  input.Country,
+		},
+		IsDefault: boolValue(input.IsDefault.Value()),
+	}
+}
+
+// toCard converts card details, nil when omitted
+func toCard(input *generated.CardInput) *payment.Card {
+	if input == nil {
+		return nil
+	}
+	return &payment.Card{
+		Number:      input.Number,
+		ExpiryMonth: input.ExpiryMonth,
+		ExpiryYear:  input.ExpiryYear,
+		CVC:         input.Cvc,
+		HolderName:  stringValue(input.HolderName.Value()),
+	}
+}
+
+// toProductImages converts product image inputs, keeping nil for omitted images
+func toProductImages(inputs []generated.ProductImageInput) []models.ProductImage {
+	if inputs == nil {
+		return nil
+	}
+	images := make([]models.ProductImage, 0, len(inputs))
+	for _, input := range inputs {
+		image := models.ProductImage{URL: input.URL}
+		if alt := input.Alt.Value(); alt != nil {
+			image.Alt = *alt
+		}
+		images = append(images, image)
+	}
+	return images
+}
+
+// toProductFilter converts the product filter input
+func toProductFilter(input *generated.ProductFilterInput) models.ProductFilter {
+	if input == nil {
+		return models.ProductFilter{}
+	}
+	return models.ProductFilter{
+		CategoryID: input.CategoryID.Value(),
+		MinPrice:   input.MinPrice.Value(),
+		MaxPrice:   input.MaxPrice.Value(),
+		InStock:    input.InStock.Value(),
+	}
+}
+
+// toSearchFilter converts the product search filter input
+func toSearchFilter(input *generated.ProductSearchFilterInput) models.SearchFilter {
+	if input == nil {
+		return models.SearchFilter{}
+	}
+	return models.SearchFilter{
+		CategoryID: input.CategoryID.Value(),
+		Brand:      input.Brand.Value(),
+		MinPrice:   input.MinPrice.Value(),
+		MaxPrice:   input.MaxPrice.Value(),
+		InStock:    input.InStock.Value(),
+	}
+}
+
+// productSortFields maps the GraphQL product sort fields to their sort keys
+var productSortFields = map[generated.ProductSortField]string{
+	generated.ProductSortFieldCreatedAt: models.ProductSortCreatedAt,
+	generated.ProductSortFieldPrice:     models.ProductSortPrice,
+	generated.ProductSortFieldName:      models.ProductSortName,
+}
+
+// toProductSort converts the product sort input, returning nil for the default order
+func toProductSort(input *generated.ProductSortInput) *models.ProductSort {
+	if input == nil {
+		return nil
+	}
+	direction := input.Direction.Value()
+	return &models.ProductSort{
+		Field:      productSortFields[input.Field],
+		Descending: direction != nil && *direction == generated.SortDirectionDesc,
+	}
+}
@@ -0,0 +1,57 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/api/loaders"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FindCategoryByID is the resolver for the findCategoryByID field.
+func (r *entityResolver) FindCategoryByID(ctx context.Context, id primitive.ObjectID) (*models.Category, error) {
+	return nilIfNotFound(loaders.For(ctx).Categories.Load(ctx, id))
+}
+
+// FindOrderByID is the resolver for the findOrderByID field.
+func (r *entityResolver) FindOrderByID(ctx context.Context, id primitive.ObjectID) (*models.Order, error) {
+	actor, err := r.orderActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	order, err := nilIfNotFound(loaders.For(ctx).Orders.Load(ctx, id))
+	if err != nil || order == nil || !actor.CanAccess(order) {
+		return nil, err
+	}
+	return order, nil
+}
+
+// FindProductByID is the resolver for the findProductByID field.
+func (r *entityResolver) FindProductByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error) {
+	return nilIfNotFound(loaders.For(ctx).Products.Load(ctx, id))
+}
+
+// FindUserByID is the resolver for the findUserByID field.
+func (r *entityResolver) FindUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	claims, ok := auth.GetClaimsFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	// Accounts are private, so other users resolve to null
+	if claims.UserID() != id.Hex() && !r.Roles.Satisfies(claims.Roles, models.RoleStaff) {
+		return nil, nil
+	}
+	return nilIfNotFound(loaders.For(ctx).Users.Load(ctx, id))
+}
+
+// Entity returns generated.EntityResolver implementation.
+func (r *Resolver) Entity() generated.EntityResolver { return &entityResolver{r} }
+
+type entityResolver struct{ *Resolver }
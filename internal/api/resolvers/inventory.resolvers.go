@@ -0,0 +1,54 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/loaders"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Movements is the resolver for the movements field.
+func (r *inventoryResolver) Movements(ctx context.Context, obj *models.InventoryItem, pagination *connection.Args) (*connection.Connection[models.StockMovement], error) {
+	var args connection.Args
+	if pagination != nil {
+		args = *pagination
+	}
+	return r.InventoryService.ListMovements(ctx, obj.SKU, args)
+}
+
+// UpdateProductInventory is the resolver for the updateProductInventory field.
+func (r *mutationResolver) UpdateProductInventory(ctx context.Context, id primitive.ObjectID, quantity int, reason *string) (*models.Product, error) {
+	var why string
+	if reason != nil {
+		why = *reason
+	}
+	return r.ProductService.UpdateStock(ctx, id, quantity, why)
+}
+
+// Inventory is the resolver for the inventory field.
+func (r *productResolver) Inventory(ctx context.Context, obj *models.Product) (*models.InventoryItem, error) {
+	return nilIfNotFound(loaders.For(ctx).Inventory.Load(ctx, obj.SKU))
+}
+
+// Inventory is the resolver for the inventory field.
+func (r *queryResolver) Inventory(ctx context.Context, sku string) (*models.InventoryItem, error) {
+	item, err := r.InventoryService.GetInventory(ctx, strings.ToUpper(strings.TrimSpace(sku)))
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+	return item, err
+}
+
+// Inventory returns generated.InventoryResolver implementation.
+func (r *Resolver) Inventory() generated.InventoryResolver { return &inventoryResolver{r} }
+
+type inventoryResolver struct{ *Resolver }
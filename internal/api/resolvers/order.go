@@ -0,0 +1,22 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+)
+
+// orderActor returns the signed in user as an order actor
+func (r *Resolver) orderActor(ctx context.Context) (services.OrderActor, error) {
+	claims, ok := auth.GetClaimsFromContext(ctx)
+	if !ok {
+		return services.OrderActor{}, gqlerrors.Unauthenticated(ctx)
+	}
+	return services.OrderActor{
+		UserID: claims.UserID(),
+		Staff:  r.Roles.Satisfies(claims.Roles, models.RoleStaff),
+	}, nil
+}
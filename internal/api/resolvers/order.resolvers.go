@@ -0,0 +1,86 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/api/loaders"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateOrder is the resolver for the createOrder field.
+func (r *mutationResolver) CreateOrder(ctx context.Context, input generated.CreateOrderInput) (*models.Order, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.CheckoutService.PlaceOrder(ctx, userID, services.CheckoutInput{
+		ShippingAddress: toAddress(input.ShippingAddress),
+		ExpectedTotal:   input.ExpectedTotal.Value(),
+	})
+}
+
+// UpdateOrderStatus is the resolver for the updateOrderStatus field.
+func (r *mutationResolver) UpdateOrderStatus(ctx context.Context, id primitive.ObjectID, status models.OrderStatus, reason *string) (*models.Order, error) {
+	actor, err := r.orderActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.OrderService.UpdateStatus(ctx, id, status, stringValue(reason), actor)
+}
+
+// CancelOrder is the resolver for the cancelOrder field.
+func (r *mutationResolver) CancelOrder(ctx context.Context, id primitive.ObjectID, reason *string) (*models.Order, error) {
+	actor, err := r.orderActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.OrderService.CancelOrder(ctx, id, stringValue(reason), actor)
+}
+
+// Product is the resolver for the product field.
+func (r *orderItemResolver) Product(ctx context.Context, obj *models.OrderItem) (*models.Product, error) {
+	return nilIfNotFound(loaders.For(ctx).Products.Load(ctx, obj.ProductID))
+}
+
+// Order is the resolver for the order field.
+func (r *queryResolver) Order(ctx context.Context, id primitive.ObjectID) (*models.Order, error) {
+	actor, err := r.orderActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	order, err := r.OrderService.GetOrder(ctx, id, actor)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+	return order, err
+}
+
+// MyOrders is the resolver for the myOrders field.
+func (r *queryResolver) MyOrders(ctx context.Context, status *models.OrderStatus, pagination *connection.Args) (*connection.Connection[models.Order], error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.OrderService.ListOrders(ctx, models.OrderFilter{UserID: userID, Status: status}, paginationArgs(pagination))
+}
+
+// UserOrders is the resolver for the userOrders field.
+func (r *queryResolver) UserOrders(ctx context.Context, userID primitive.ObjectID, status *models.OrderStatus, pagination *connection.Args) (*connection.Connection[models.Order], error) {
+	return r.OrderService.ListOrders(ctx, models.OrderFilter{UserID: userID.Hex(), Status: status}, paginationArgs(pagination))
+}
+
+// OrderItem returns generated.OrderItemResolver implementation.
+func (r *Resolver) OrderItem() generated.OrderItemResolver { return &orderItemResolver{r} }
+
+type orderItemResolver struct{ *Resolver }
@@ -0,0 +1,48 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/internal/payment"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PayOrder is the resolver for the payOrder field.
+func (r *mutationResolver) PayOrder(ctx context.Context, input generated.PayOrderInput) (*models.Order, error) {
+	actor, err := r.orderActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Saved cards are looked up in the profile of the caller, by ID or by token
+	var savedCard *payment.CardToken
+	if methodID := input.PaymentMethodID.Value(); methodID != nil {
+		if savedCard, err = r.ProfileService.SavedCard(ctx, actor.UserID, *methodID); err != nil {
+			return nil, err
+		}
+	} else if token := input.PaymentToken.Value(); token != nil {
+		if savedCard, err = r.ProfileService.SavedCardByToken(ctx, actor.UserID, *token); err != nil {
+			return nil, err
+		}
+	}
+	return r.PaymentService.PayOrder(ctx, input.OrderID, services.PaymentInput{
+		Card:              toCard(input.Card.Value()),
+		SavedCard:         savedCard,
+		ChallengeResponse: stringValue(input.ChallengeResponse.Value()),
+	}, actor)
+}
+
+// RefundOrder is the resolver for the refundOrder field.
+func (r *mutationResolver) RefundOrder(ctx context.Context, id primitive.ObjectID, reason *string) (*models.Order, error) {
+	actor, err := r.orderActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.PaymentService.RefundOrder(ctx, id, stringValue(reason), actor)
+}
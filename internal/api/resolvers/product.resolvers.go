@@ -0,0 +1,93 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateProduct is the resolver for the createProduct field.
+func (r *mutationResolver) CreateProduct(ctx context.Context, input generated.CreateProductInput) (*models.Product, error) {
+	var description string
+	if d := input.Description.Value(); d != nil {
+		description = *d
+	}
+	var stock int
+	if st := input.Stock.Value(); st != nil {
+		stock = *st
+	}
+	var brand string
+	if b := input.Brand.Value(); b != nil {
+		brand = *b
+	}
+	return r.ProductService.CreateProduct(ctx, services.CreateProductInput{
+		Name:        input.Name,
+		Description: description,
+		Price:       input.Price,
+		SKU:         input.Sku,
+		Brand:       brand,
+		Images:      toProductImages(input.Images.Value()),
+		CategoryID:  input.CategoryID.Value(),
+		Stock:       stock,
+	})
+}
+
+// UpdateProduct is the resolver for the updateProduct field.
+func (r *mutationResolver) UpdateProduct(ctx context.Context, id primitive.ObjectID, input generated.UpdateProductInput) (*models.Product, error) {
+	return r.ProductService.UpdateProduct(ctx, id, services.UpdateProductInput{
+		Name:        input.Name.Value(),
+		Description: input.Description.Value(),
+		Price:       input.Price.Value(),
+		SKU:         input.Sku.Value(),
+		Brand:       input.Brand.Value(),
+		Images:      toProductImages(input.Images.Value()),
+		CategoryID:  input.CategoryID.Value(),
+		Stock:       input.Stock.Value(),
+	})
+}
+
+// DeleteProduct is the resolver for the deleteProduct field.
+func (r *mutationResolver) DeleteProduct(ctx context.Context, id primitive.ObjectID) (*generated.MutationResult, error) {
+	if err := r.ProductService.DeleteProduct(ctx, id); err != nil {
+		return nil, err
+	}
+	return deleted(id, "product deleted"), nil
+}
+
+// Product is the resolver for the product field.
+func (r *queryResolver) Product(ctx context.Context, id primitive.ObjectID) (*models.Product, error) {
+	product, err := r.ProductService.GetProduct(ctx, id)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	userID, _ := auth.GetUserIDFromContext(ctx)
+	r.AnalyticsService.RecordView(ctx, product.ID, userID)
+	return product, nil
+}
+
+// Products is the resolver for the products field.
+func (r *queryResolver) Products(ctx context.Context, filter *generated.ProductFilterInput, sort *generated.ProductSortInput, pagination *connection.Args) (*connection.Connection[models.Product], error) {
+	var args connection.Args
+	if pagination != nil {
+		args = *pagination
+	}
+	return r.ProductService.ListProducts(ctx, toProductFilter(filter), toProductSort(sort), args)
+}
+
+// Product returns generated.ProductResolver implementation.
+func (r *Resolver) Product() generated.ProductResolver { return &productResolver{r} }
+
+type productResolver struct{ *Resolver }
@@ -0,0 +1,89 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UpdateUserProfile is the resolver for the updateUserProfile field.
+func (r *mutationResolver) UpdateUserProfile(ctx context.Context, input generated.UpdateUserProfileInput) (*models.User, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.ProfileService.UpdateProfile(ctx, userID, services.UpdateProfileInput{
+		FirstName:      input.FirstName.Value(),
+		LastName:       input.LastName.Value(),
+		ProfilePicture: input.ProfilePicture.Value(),
+	})
+}
+
+// AddShippingAddress is the resolver for the addShippingAddress field.
+func (r *mutationResolver) AddShippingAddress(ctx context.Context, input generated.ShippingAddressInput) (*models.ShippingAddress, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.ProfileService.AddShippingAddress(ctx, userID, toShippingAddress(input))
+}
+
+// UpdateShippingAddress is the resolver for the updateShippingAddress field.
+func (r *mutationResolver) UpdateShippingAddress(ctx context.Context, id primitive.ObjectID, input generated.ShippingAddressInput) (*models.ShippingAddress, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.ProfileService.UpdateShippingAddress(ctx, userID, id, toShippingAddress(input))
+}
+
+// DeleteShippingAddress is the resolver for the deleteShippingAddress field.
+func (r *mutationResolver) DeleteShippingAddress(ctx context.Context, id primitive.ObjectID) (*generated.MutationResult, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	if err := r.ProfileService.DeleteShippingAddress(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return deleted(id, "shipping address deleted"), nil
+}
+
+// AddPaymentMethod is the resolver for the addPaymentMethod field.
+func (r *mutationResolver) AddPaymentMethod(ctx context.Context, input generated.PaymentMethodInput) (*models.PaymentMethod, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.ProfileService.AddPaymentMethod(ctx, userID, *toCard(input.Card), boolValue(input.IsDefault.Value()))
+}
+
+// SetDefaultPaymentMethod is the resolver for the setDefaultPaymentMethod field.
+func (r *mutationResolver) SetDefaultPaymentMethod(ctx context.Context, id primitive.ObjectID) (*models.PaymentMethod, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.ProfileService.SetDefaultPaymentMethod(ctx, userID, id)
+}
+
+// DeletePaymentMethod is the resolver for the deletePaymentMethod field.
+func (r *mutationResolver) DeletePaymentMethod(ctx context.Context, id primitive.ObjectID) (*generated.MutationResult, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	if err := r.ProfileService.DeletePaymentMethod(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return deleted(id, "payment method deleted"), nil
+}
@@ -1,27 +1,40 @@
 // Package resolvers contains GraphQL resolver implementations
 package resolvers
 
-// No imports needed in this file
+import (
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/services"
+	"go.mongodb.org/mongo-driver/mongo"
+)
 
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
-// This file contains the base resolver setup and dependency injection.
-// The actual Resolver struct is defined in resolvers.go
+// Version information
+const (
+	versionNumber = "0.1.0"
+	buildEnv      = "development" // Will be configurable in Step 2
+)
 
-// ResolverRoot provides root resolver instance for interface implementation
-type ResolverRoot interface {
-	Query() QueryResolver
-	Mutation() MutationResolver
-}
-
-// Query returns a QueryResolver implementation
-func (r *Resolver) Query() QueryResolver {
-	return QueryResolver{r}
-}
-
-// Mutation returns a MutationResolver implementation
-func (r *Resolver) Mutation() MutationResolver {
-	return MutationResolver{r}
+// Resolver is the base GraphQL resolver
+type Resolver struct {
+	DB                  *mongo.Client
+	Verifier            *auth.Verifier
+	Roles               *auth.RoleHierarchy
+	AuthService         *services.AuthService
+	UserService         *services.UserService
+	ProductService      *services.ProductService
+	CategoryService     *services.CategoryService
+	InventoryService    *services.InventoryService
+	CartService         *services.CartService
+	OrderService        *services.OrderService
+	CheckoutService     *services.CheckoutService
+	PaymentService      *services.PaymentService
+	SearchService       *services.SearchService
+	ReviewService       *services.ReviewService
+	WishlistService     *services.WishlistService
+	ProfileService      *services.ProfileService
+	AnalyticsService    *services.AnalyticsService
+	SubscriptionService *services.SubscriptionService
 }
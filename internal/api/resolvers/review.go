@@ -0,0 +1,32 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+)
+
+// reviewActor returns the signed in user as a review actor
+func (r *Resolver) reviewActor(ctx context.Context) (services.ReviewActor, error) {
+	claims, ok := auth.GetClaimsFromContext(ctx)
+	if !ok {
+		return services.ReviewActor{}, gqlerrors.Unauthenticated(ctx)
+	}
+	return services.ReviewActor{
+		UserID: claims.UserID(),
+		Staff:  r.Roles.Satisfies(claims.Roles, models.RoleStaff),
+	}, nil
+}
+
+// toReviewInput converts the review input
+func toReviewInput(input generated.ProductReviewInput) services.ReviewInput {
+	return services.ReviewInput{
+		Rating:  input.Rating,
+		Title:   stringValue(input.Title.Value()),
+		Content: stringValue(input.Content.Value()),
+	}
+}
@@ -0,0 +1,99 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/api/loaders"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateProductReview is the resolver for the createProductReview field.
+func (r *mutationResolver) CreateProductReview(ctx context.Context, productID primitive.ObjectID, input generated.ProductReviewInput) (*models.Review, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.ReviewService.CreateReview(ctx, userID, productID, toReviewInput(input))
+}
+
+// UpdateProductReview is the resolver for the updateProductReview field.
+func (r *mutationResolver) UpdateProductReview(ctx context.Context, reviewID primitive.ObjectID, input generated.ProductReviewInput) (*models.Review, error) {
+	actor, err := r.reviewActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.ReviewService.UpdateReview(ctx, actor, reviewID, toReviewInput(input))
+}
+
+// DeleteProductReview is the resolver for the deleteProductReview field.
+func (r *mutationResolver) DeleteProductReview(ctx context.Context, reviewID primitive.ObjectID) (*generated.MutationResult, error) {
+	actor, err := r.reviewActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ReviewService.DeleteReview(ctx, actor, reviewID); err != nil {
+		return nil, err
+	}
+	return deleted(reviewID, "review deleted"), nil
+}
+
+// ApproveReview is the resolver for the approveReview field.
+func (r *mutationResolver) ApproveReview(ctx context.Context, reviewID primitive.ObjectID) (*models.Review, error) {
+	return r.ReviewService.ApproveReview(ctx, reviewID)
+}
+
+// RejectReview is the resolver for the rejectReview field.
+func (r *mutationResolver) RejectReview(ctx context.Context, reviewID primitive.ObjectID, reason *string) (*models.Review, error) {
+	return r.ReviewService.RejectReview(ctx, reviewID, stringValue(reason))
+}
+
+// Reviews is the resolver for the reviews field.
+func (r *productResolver) Reviews(ctx context.Context, obj *models.Product, pagination *connection.Args) (*connection.Connection[models.Review], error) {
+	return r.ReviewService.ListProductReviews(ctx, obj.ID, paginationArgs(pagination))
+}
+
+// MyReviews is the resolver for the myReviews field.
+func (r *queryResolver) MyReviews(ctx context.Context, pagination *connection.Args) (*connection.Connection[models.Review], error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.ReviewService.ListReviews(ctx, models.ReviewFilter{UserID: userID}, paginationArgs(pagination))
+}
+
+// Reviews is the resolver for the reviews field.
+func (r *queryResolver) Reviews(ctx context.Context, productID *primitive.ObjectID, status *models.ReviewStatus, pagination *connection.Args) (*connection.Connection[models.Review], error) {
+	return r.ReviewService.ListReviews(ctx, models.ReviewFilter{ProductID: productID, Status: status}, paginationArgs(pagination))
+}
+
+// Product is the resolver for the product field.
+func (r *reviewResolver) Product(ctx context.Context, obj *models.Review) (*models.Product, error) {
+	return nilIfNotFound(loaders.For(ctx).Products.Load(ctx, obj.ProductID))
+}
+
+// User is the resolver for the user field.
+func (r *reviewResolver) User(ctx context.Context, obj *models.Review) (*models.ReviewAuthor, error) {
+	id, err := primitive.ObjectIDFromHex(obj.UserID)
+	if err != nil {
+		return nil, nil
+	}
+	user, err := nilIfNotFound(loaders.For(ctx).Users.Load(ctx, id))
+	if user == nil {
+		return nil, err
+	}
+	return &models.ReviewAuthor{ID: obj.UserID, FirstName: user.FirstName}, nil
+}
+
+// Review returns generated.ReviewResolver implementation.
+func (r *Resolver) Review() generated.ReviewResolver { return &reviewResolver{r} }
+
+type reviewResolver struct{ *Resolver }
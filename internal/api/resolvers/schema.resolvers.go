@@ -0,0 +1,63 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/prototype01/internal/api/generated"
+)
+
+// Noop is the resolver for the noop field.
+func (r *mutationResolver) Noop(ctx context.Context) (*bool, error) {
+	return nil, nil
+}
+
+// Ping is the resolver for the ping field.
+func (r *queryResolver) Ping(ctx context.Context) (string, error) {
+	return "GraphQL Server is running! Current time: " + time.Now().Format(time.RFC3339), nil
+}
+
+// Version is the resolver for the version field.
+func (r *queryResolver) Version(ctx context.Context) (*generated.Version, error) {
+	return &generated.Version{}, nil
+}
+
+// Number is the resolver for the number field.
+func (r *versionResolver) Number(ctx context.Context, obj *generated.Version) (string, error) {
+	return versionNumber, nil
+}
+
+// BuildDate is the resolver for the buildDate field.
+func (r *versionResolver) BuildDate(ctx context.Context, obj *generated.Version) (*time.Time, error) {
+	// For now we'll use current time
+	// In production this would be set during the build process
+	now := time.Now()
+	return &now, nil
+}
+
+// Environment is the resolver for the environment field.
+func (r *versionResolver) Environment(ctx context.Context, obj *generated.Version) (string, error) {
+	env := os.Getenv("ENV")
+	if env == "" {
+		env = buildEnv
+	}
+	return env, nil
+}
+
+// Mutation returns generated.MutationResolver implementation.
+func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
+
+// Query returns generated.QueryResolver implementation.
+func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
+
+// Version returns generated.VersionResolver implementation.
+func (r *Resolver) Version() generated.VersionResolver { return &versionResolver{r} }
+
+type mutationResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type versionResolver struct{ *Resolver }
@@ -0,0 +1,22 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/connection"
+)
+
+// SearchProducts is the resolver for the searchProducts field.
+func (r *queryResolver) SearchProducts(ctx context.Context, query string, filter *generated.ProductSearchFilterInput, pagination *connection.Args) (*models.SearchResult, error) {
+	var args connection.Args
+	if pagination != nil {
+		args = *pagination
+	}
+	return r.SearchService.SearchProducts(ctx, query, toSearchFilter(filter), args)
+}
@@ -0,0 +1,32 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OrderStatusChanged is the resolver for the orderStatusChanged field.
+func (r *subscriptionResolver) OrderStatusChanged(ctx context.Context, orderID primitive.ObjectID) (<-chan *models.Order, error) {
+	actor, err := r.orderActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.SubscriptionService.OrderStatusChanged(ctx, orderID, actor)
+}
+
+// ProductStockChanged is the resolver for the productStockChanged field.
+func (r *subscriptionResolver) ProductStockChanged(ctx context.Context, productID primitive.ObjectID) (<-chan *models.Product, error) {
+	return r.SubscriptionService.ProductStockChanged(ctx, productID)
+}
+
+// Subscription returns generated.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
+
+type subscriptionResolver struct{ *Resolver }
@@ -0,0 +1,54 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+)
+
+// RegisterUser is the resolver for the registerUser field.
+func (r *mutationResolver) RegisterUser(ctx context.Context, input generated.RegisterUserInput) (*generated.AuthPayload, error) {
+	user, pair, err := r.UserService.Register(ctx, services.RegisterUserInput{
+		FirstName: input.FirstName,
+		LastName:  input.LastName,
+		Email:     input.Email,
+		Password:  input.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.mergeGuestCart(ctx, user)
+	return toAuthPayload(user, pair), nil
+}
+
+// LoginUser is the resolver for the loginUser field.
+func (r *mutationResolver) LoginUser(ctx context.Context, email string, password string) (*generated.AuthPayload, error) {
+	user, pair, err := r.UserService.Login(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+	r.mergeGuestCart(ctx, user)
+	return toAuthPayload(user, pair), nil
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*models.User, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	user, err := r.ProfileService.GetProfile(ctx, userID)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+	return user, err
+}
@@ -0,0 +1,109 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.73
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prototype01/internal/api/generated"
+	"github.com/prototype01/internal/api/gqlerrors"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateWishlist is the resolver for the createWishlist field.
+func (r *mutationResolver) CreateWishlist(ctx context.Context, name string) (*models.Wishlist, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.WishlistService.CreateWishlist(ctx, userID, name)
+}
+
+// RenameWishlist is the resolver for the renameWishlist field.
+func (r *mutationResolver) RenameWishlist(ctx context.Context, id primitive.ObjectID, name string) (*models.Wishlist, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.WishlistService.RenameWishlist(ctx, userID, id, name)
+}
+
+// DeleteWishlist is the resolver for the deleteWishlist field.
+func (r *mutationResolver) DeleteWishlist(ctx context.Context, id primitive.ObjectID) (*generated.MutationResult, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	if err := r.WishlistService.DeleteWishlist(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return deleted(id, "wishlist deleted"), nil
+}
+
+// AddProductToWishlist is the resolver for the addProductToWishlist field.
+func (r *mutationResolver) AddProductToWishlist(ctx context.Context, productID primitive.ObjectID, wishlistID *primitive.ObjectID) (*models.Wishlist, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.WishlistService.AddProduct(ctx, userID, wishlistID, productID)
+}
+
+// RemoveProductFromWishlist is the resolver for the removeProductFromWishlist field.
+func (r *mutationResolver) RemoveProductFromWishlist(ctx context.Context, productID primitive.ObjectID, wishlistID *primitive.ObjectID) (*models.Wishlist, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.WishlistService.RemoveProduct(ctx, userID, wishlistID, productID)
+}
+
+// ShareWishlist is the resolver for the shareWishlist field.
+func (r *mutationResolver) ShareWishlist(ctx context.Context, id primitive.ObjectID) (*models.Wishlist, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.WishlistService.ShareWishlist(ctx, userID, id)
+}
+
+// UnshareWishlist is the resolver for the unshareWishlist field.
+func (r *mutationResolver) UnshareWishlist(ctx context.Context, id primitive.ObjectID) (*models.Wishlist, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.WishlistService.UnshareWishlist(ctx, userID, id)
+}
+
+// MyWishlist is the resolver for the myWishlist field.
+func (r *queryResolver) MyWishlist(ctx context.Context, id *primitive.ObjectID) (*models.Wishlist, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.WishlistService.GetWishlist(ctx, userID, id)
+}
+
+// MyWishlists is the resolver for the myWishlists field.
+func (r *queryResolver) MyWishlists(ctx context.Context) ([]models.Wishlist, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, gqlerrors.Unauthenticated(ctx)
+	}
+	return r.WishlistService.ListWishlists(ctx, userID)
+}
+
+// SharedWishlist is the resolver for the sharedWishlist field.
+func (r *queryResolver) SharedWishlist(ctx context.Context, token string) (*models.Wishlist, error) {
+	wishlist, err := r.WishlistService.GetSharedWishlist(ctx, token)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+	return wishlist, err
+}
@@ -0,0 +1,31 @@
+// Package scalars contains GraphQL marshalers for custom scalar bindings
+package scalars
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MarshalObjectID marshals a MongoDB ObjectID as its hex string
+func MarshalObjectID(id primitive.ObjectID) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		_, _ = io.WriteString(w, strconv.Quote(id.Hex()))
+	})
+}
+
+// UnmarshalObjectID parses a MongoDB ObjectID from its hex string
+func UnmarshalObjectID(v any) (primitive.ObjectID, error) {
+	s, ok := v.(string)
+	if !ok {
+		return primitive.NilObjectID, fmt.Errorf("%T is not a valid ID", v)
+	}
+	id, err := primitive.ObjectIDFromHex(s)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%q is not a valid ID", s)
+	}
+	return id, nil
+}
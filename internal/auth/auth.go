@@ -3,84 +3,110 @@ package auth
 
 import (
 	"context"
-	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/prototype01/pkg/logger"
 )
 
 // Key type for context values
 type contextKey string
 
-// UserIDKey is the key used to store the user ID in the context
-const UserIDKey contextKey = "userID"
+// ClaimsKey is the key used to store the verified token claims in the context
+const ClaimsKey contextKey = "claims"
 
-// ExtractTokenFromContext extracts the JWT token from the request context
+// AccessTokenCookie is the name of the cookie that may carry the access token of a
+// WebSocket upgrade
+const AccessTokenCookie = "access_token"
+
+// ExtractTokenFromContext extracts the access token for the current operation. It is
+// looked up in the websocket connection_init payload, then in the HTTP request
+// Authorization header and finally, for WebSocket upgrades, in the access token cookie.
 func ExtractTokenFromContext(ctx context.Context) string {
-	// Get the request from the context
-	request := getRequestFromContext(ctx)
+	if token := TokenFromInitPayload(transport.GetInitPayload(ctx)); token != "" {
+		return token
+	}
+
+	request := GetRequestFromContext(ctx)
 	if request == nil {
 		return ""
 	}
+	return TokenFromRequest(request)
+}
 
-	// Extract token from Authorization header
-	authHeader := request.Header.Get("Authorization")
-	if authHeader == "" {
-		return ""
+// TokenFromRequest extracts the access token from the Authorization header, or from
+// the access token cookie of a WebSocket upgrade. Browsers attach cookies to
+// requests any site makes, so plain HTTP requests never authenticate with the
+// cookie; upgrades are protected by the WebSocket origin check instead.
+func TokenFromRequest(r *http.Request) string {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		return parseBearerToken(authHeader)
 	}
 
-	// The header format should be "Bearer <token>"
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-		logger.Warn("Invalid authorization header format")
+	if !isWebSocketUpgrade(r) {
 		return ""
 	}
+	if cookie, err := r.Cookie(AccessTokenCookie); err == nil {
+		return cookie.Value
+	}
 
-	return parts[1]
+	return ""
 }
 
-// VerifyToken verifies the JWT token and returns the user ID if valid
-func VerifyToken(token string) (string, error) {
-	// This is a simplified implementation for prototype purposes
-	// In a real application, you would use a JWT library to verify the token
-
-	if token == "" {
-		return "", errors.New("empty token provided")
+// isWebSocketUpgrade reports whether the request asks to upgrade to a WebSocket
+func isWebSocketUpgrade(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
 	}
-
-	// For the prototype, we'll just check that the token has a minimum length
-	// and return a mock user ID
-	if len(token) < 10 {
-		return "", errors.New("token too short")
+	for _, value := range r.Header.Values("Connection") {
+		for _, option := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(option), "upgrade") {
+				return true
+			}
+		}
 	}
+	return false
+}
+
+// WithClaims returns a copy of the context carrying the verified token claims
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, ClaimsKey, claims)
+}
 
-	// In a real implementation, you would decode and verify the JWT token here
-	// For now, we'll just return a mock user ID
-	return "user-123", nil
+// GetClaimsFromContext retrieves the verified token claims from the context if present
+func GetClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(ClaimsKey).(*Claims)
+	return claims, ok && claims != nil
 }
 
 // GetUserIDFromContext retrieves the user ID from the context if present
 func GetUserIDFromContext(ctx context.Context) (string, bool) {
-	userID, ok := ctx.Value(UserIDKey).(string)
-	return userID, ok
+	claims, ok := GetClaimsFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return claims.UserID(), true
 }
 
-// getRequestFromContext extracts the http.Request from the context
-func getRequestFromContext(ctx context.Context) *http.Request {
-	// The HTTP request is commonly stored in context by HTTP middleware
-	// This might need to be adjusted based on how your GraphQL server is set up
-	if requestData := ctx.Value(graphqlRequestContextKey()); requestData != nil {
-		if request, ok := requestData.(*http.Request); ok {
-			return request
-		}
+// TokenFromInitPayload extracts the access token from a websocket connection_init payload.
+// Clients either send an "Authorization" header value or a bare "authToken"/"token".
+func TokenFromInitPayload(payload transport.InitPayload) string {
+	if authHeader := payload.Authorization(); authHeader != "" {
+		return parseBearerToken(authHeader)
 	}
-	return nil
+	if token := payload.GetString("authToken"); token != "" {
+		return token
+	}
+	return payload.GetString("token")
 }
 
-// graphqlRequestContextKey returns the key used by your GraphQL framework to store the request
-func graphqlRequestContextKey() interface{} {
-	// This may need to be adjusted based on the GraphQL framework you're using
-	// For gqlgen, it's often something like this:
-	return contextKey("request")
+// parseBearerToken returns the token of a "Bearer <token>" header value
+func parseBearerToken(authHeader string) string {
+	parts := strings.Fields(authHeader)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		logger.Warn("Invalid authorization header format")
+		return ""
+	}
+	return parts[1]
 }
@@ -0,0 +1,51 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prototype01/internal/auth"
+)
+
+func TestExtractTokenFromContext(t *testing.T) {
+	if token := auth.ExtractTokenFromContext(context.Background()); token != "" {
+		t.Errorf("expected no token without request, got %q", token)
+	}
+
+	tests := []struct {
+		name      string
+		header    string
+		cookie    string
+		websocket bool
+		want      string
+	}{
+		{"bearer header", "Bearer header-token", "", false, "header-token"},
+		{"lower case scheme", "bearer header-token", "", false, "header-token"},
+		{"header wins over cookie", "Bearer header-token", "cookie-token", true, "header-token"},
+		{"cookie ignored over HTTP", "", "cookie-token", false, ""},
+		{"cookie on websocket upgrade", "", "cookie-token", true, "cookie-token"},
+		{"malformed header", "Token header-token", "", false, ""},
+		{"none", "", "", false, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		if tt.websocket {
+			r = httptest.NewRequest(http.MethodGet, "/graphql", nil)
+			r.Header.Set("Connection", "keep-alive, Upgrade")
+			r.Header.Set("Upgrade", "websocket")
+		}
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		if tt.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: auth.AccessTokenCookie, Value: tt.cookie})
+		}
+
+		ctx := auth.WithRequest(r.Context(), r)
+		if got := auth.ExtractTokenFromContext(ctx); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -8,6 +8,11 @@ import (
 // RequestContextKey is the key used to store the HTTP request in the context
 const RequestContextKey contextKey = "httpRequest"
 
+// WithRequest returns a copy of the context carrying the HTTP request
+func WithRequest(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, RequestContextKey, r)
+}
+
 // GetRequestFromContext retrieves the HTTP request from the context
 func GetRequestFromContext(ctx context.Context) *http.Request {
 	if reqValue := ctx.Value(RequestContextKey); reqValue != nil {
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prototype01/internal/config"
+)
+
+// Claims are the JWT claims carried by access tokens issued for this API
+type Claims struct {
+	Roles    []string `json:"roles,omitempty"`
+	TenantID string   `json:"tenant_id,omitempty"`
+	// SessionID identifies the refresh token family the access token was issued from
+	SessionID string `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the authenticated user ID, stored in the "sub" claim
+func (c *Claims) UserID() string {
+	return c.Subject
+}
+
+// HasRole reports whether the claims contain the given role
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Verifier verifies signed JWT access tokens
+type Verifier struct {
+	method   jwt.SigningMethod
+	key      interface{}
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+// NewVerifier creates a Verifier from the authentication configuration.
+// HMAC algorithms use the shared secret, RSA and ECDSA algorithms use the PEM public key file.
+func NewVerifier(cfg config.AuthConfig) (*Verifier, error) {
+	method := jwt.GetSigningMethod(cfg.Algorithm)
+	if method == nil || method == jwt.SigningMethodNone {
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+
+	key, err := loadVerificationKey(method, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Verifier{
+		method:   method,
+		key:      key,
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		leeway:   cfg.ClockSkew,
+	}, nil
+}
+
+// VerifyToken verifies the JWT token signature and claims and returns the claims if valid
+func (v *Verifier) VerifyToken(token string) (*Claims, error) {
+	if token == "" {
+		return nil, errors.New("empty token provided")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{v.method.Alg()}),
+		jwt.WithLeeway(v.leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		opts = append(opts, jwt.WithAudience(v.audience))
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return v.key, nil
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("invalid token: missing subject")
+	}
+
+	return claims, nil
+}
+
+// Signer signs JWT access tokens
+type Signer struct {
+	method   jwt.SigningMethod
+	key      interface{}
+	issuer   string
+	audience string
+	ttl      time.Duration
+}
+
+// NewSigner creates a Signer from the authentication configuration.
+// HMAC algorithms use the shared secret, RSA and ECDSA algorithms use the PEM private key file.
+func NewSigner(cfg config.AuthConfig) (*Signer, error) {
+	method := jwt.GetSigningMethod(cfg.Algorithm)
+	if method == nil || method == jwt.SigningMethodNone {
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+
+	key, err := loadSigningKey(method, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{
+		method:   method,
+		key:      key,
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		ttl:      cfg.AccessTokenTTL,
+	}, nil
+}
+
+// TTL returns the lifetime of the tokens created by the signer
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+// Sign fills in the registered time, issuer and audience claims and returns the signed token
+func (s *Signer) Sign(claims *Claims, now time.Time) (string, error) {
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(s.ttl))
+	if s.issuer != "" {
+		claims.Issuer = s.issuer
+	}
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+
+	token, err := jwt.NewWithClaims(s.method, claims).SignedString(s.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, nil
+}
+
+// loadSigningKey returns the private key matching the signing method family
+func loadSigningKey(method jwt.SigningMethod, cfg config.AuthConfig) (interface{}, error) {
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("JWT_SECRET is required for %s", method.Alg())
+		}
+		return []byte(cfg.Secret), nil
+	}
+
+	if cfg.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE is required for %s", method.Alg())
+	}
+	pem, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+
+	var key crypto.PrivateKey
+	switch {
+	case strings.HasPrefix(method.Alg(), "RS"), strings.HasPrefix(method.Alg(), "PS"):
+		key, err = jwt.ParseRSAPrivateKeyFromPEM(pem)
+	case strings.HasPrefix(method.Alg(), "ES"):
+		key, err = jwt.ParseECPrivateKeyFromPEM(pem)
+	case method.Alg() == "EdDSA":
+		key, err = jwt.ParseEdPrivateKeyFromPEM(pem)
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", method.Alg())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT private key: %w", err)
+	}
+	return key, nil
+}
+
+// loadVerificationKey returns the key matching the signing method family
+func loadVerificationKey(method jwt.SigningMethod, cfg config.AuthConfig) (interface{}, error) {
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("JWT_SECRET is required for %s", method.Alg())
+		}
+		return []byte(cfg.Secret), nil
+	}
+
+	if cfg.PublicKeyFile == "" {
+		return nil, fmt.Errorf("JWT_PUBLIC_KEY_FILE is required for %s", method.Alg())
+	}
+	pem, err := os.ReadFile(cfg.PublicKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+	}
+
+	var key crypto.PublicKey
+	switch {
+	case strings.HasPrefix(method.Alg(), "RS"), strings.HasPrefix(method.Alg(), "PS"):
+		key, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+	case strings.HasPrefix(method.Alg(), "ES"):
+		key, err = jwt.ParseECPublicKeyFromPEM(pem)
+	case method.Alg() == "EdDSA":
+		key, err = jwt.ParseEdPublicKeyFromPEM(pem)
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", method.Alg())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT public key: %w", err)
+	}
+	return key, nil
+}
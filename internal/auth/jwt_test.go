@@ -0,0 +1,142 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/config"
+)
+
+func signHS256(t *testing.T, secret string, claims auth.Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func validClaims() auth.Claims {
+	now := time.Now()
+	return auth.Claims{
+		Roles:    []string{"customer"},
+		TenantID: "tenant-1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			Issuer:    "prototype01",
+			Audience:  jwt.ClaimStrings{"ecommerce-api"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+}
+
+func TestVerifyTokenHS256(t *testing.T) {
+	cfg := config.AuthConfig{
+		Algorithm: "HS256",
+		Secret:    "test-secret",
+		Issuer:    "prototype01",
+		Audience:  "ecommerce-api",
+		ClockSkew: 30 * time.Second,
+	}
+	verifier, err := auth.NewVerifier(cfg)
+	if err != nil {
+		t.Fatalf("NewVerifier returned error: %v", err)
+	}
+
+	claims, err := verifier.VerifyToken(signHS256(t, cfg.Secret, validClaims()))
+	if err != nil {
+		t.Fatalf("expected valid token, got error: %v", err)
+	}
+	if claims.UserID() != "user-1" || claims.TenantID != "tenant-1" || !claims.HasRole("customer") {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	// Expired within the clock skew is still accepted
+	skewed := validClaims()
+	skewed.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+	if _, err := verifier.VerifyToken(signHS256(t, cfg.Secret, skewed)); err != nil {
+		t.Errorf("expected token within clock skew to be accepted, got: %v", err)
+	}
+
+	tests := map[string]string{
+		"wrong secret": signHS256(t, "other-secret", validClaims()),
+		"expired": signHS256(t, cfg.Secret, func() auth.Claims {
+			c := validClaims()
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			return c
+		}()),
+		"not yet valid": signHS256(t, cfg.Secret, func() auth.Claims {
+			c := validClaims()
+			c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute))
+			return c
+		}()),
+		"wrong issuer": signHS256(t, cfg.Secret, func() auth.Claims {
+			c := validClaims()
+			c.Issuer = "someone-else"
+			return c
+		}()),
+		"wrong audience": signHS256(t, cfg.Secret, func() auth.Claims {
+			c := validClaims()
+			c.Audience = jwt.ClaimStrings{"other-api"}
+			return c
+		}()),
+		"missing expiry": signHS256(t, cfg.Secret, func() auth.Claims {
+			c := validClaims()
+			c.ExpiresAt = nil
+			return c
+		}()),
+		"missing subject": signHS256(t, cfg.Secret, func() auth.Claims {
+			c := validClaims()
+			c.Subject = ""
+			return c
+		}()),
+		"not a jwt": "this-is-not-a-token",
+	}
+	for name, token := range tests {
+		if _, err := verifier.VerifyToken(token); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestVerifyTokenRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write public key: %v", err)
+	}
+
+	verifier, err := auth.NewVerifier(config.AuthConfig{Algorithm: "RS256", PublicKeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("NewVerifier returned error: %v", err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims()).SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if _, err := verifier.VerifyToken(token); err != nil {
+		t.Errorf("expected valid RS256 token, got error: %v", err)
+	}
+
+	// An HS256 token must not be accepted by an RS256 verifier
+	if _, err := verifier.VerifyToken(signHS256(t, "test-secret", validClaims())); err == nil {
+		t.Error("expected HS256 token to be rejected by RS256 verifier")
+	}
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrMalformedHash is returned when a stored password hash can't be decoded
+var ErrMalformedHash = errors.New("malformed password hash")
+
+// Argon2id salt and key sizes in bytes
+const (
+	passwordSaltLength = 16
+	passwordKeyLength  = 32
+)
+
+// PasswordParams are the argon2id cost parameters
+type PasswordParams struct {
+	// Memory is the memory cost in KiB
+	Memory uint32
+	// Iterations is the time cost
+	Iterations uint32
+	// Parallelism is the number of threads
+	Parallelism uint8
+}
+
+// PasswordHasher hashes passwords with argon2id and verifies argon2id and legacy bcrypt hashes
+type PasswordHasher struct {
+	params PasswordParams
+}
+
+// NewPasswordHasher creates a PasswordHasher using the given cost parameters
+func NewPasswordHasher(params PasswordParams) *PasswordHasher {
+	return &PasswordHasher{params: params}
+}
+
+// Hash returns the PHC encoded argon2id hash of the password
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	salt := make([]byte, passwordSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	p := h.params
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, passwordKeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify checks the password against an encoded hash. needsRehash is true when the
+// password matches but the hash was made with another algorithm or other parameters.
+func (h *PasswordHasher) Verify(password, encoded string) (match bool, needsRehash bool, err error) {
+	if strings.HasPrefix(encoded, "$2") {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, ErrMalformedHash
+		}
+		return true, true, nil
+	}
+
+	params, salt, key, err := decodeArgon2idHash(encoded)
+	if err != nil {
+		return false, false, err
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return false, false, nil
+	}
+
+	return true, params != h.params || len(key) != passwordKeyLength, nil
+}
+
+// decodeArgon2idHash parses a "$argon2id$v=19$m=...,t=...,p=...$salt$key" hash
+func decodeArgon2idHash(encoded string) (PasswordParams, []byte, []byte, error) {
+	var params PasswordParams
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,59 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/prototype01/internal/auth"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testPasswordParams = auth.PasswordParams{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+func TestPasswordHasher(t *testing.T) {
+	hasher := auth.NewPasswordHasher(testPasswordParams)
+
+	hash, err := hasher.Hash("Secret123")
+	if err != nil {
+		t.Fatalf("Hash returned error: %v", err)
+	}
+
+	match, needsRehash, err := hasher.Verify("Secret123", hash)
+	if err != nil || !match || needsRehash {
+		t.Errorf("Verify(correct) = %v, %v, %v; want true, false, nil", match, needsRehash, err)
+	}
+
+	match, _, err = hasher.Verify("Wrong123", hash)
+	if err != nil || match {
+		t.Errorf("Verify(wrong) = %v, %v; want false, nil", match, err)
+	}
+
+	// A hasher with stronger parameters still accepts the hash but asks for a rehash
+	stronger := auth.NewPasswordHasher(auth.PasswordParams{Memory: 2048, Iterations: 2, Parallelism: 1})
+	match, needsRehash, err = stronger.Verify("Secret123", hash)
+	if err != nil || !match || !needsRehash {
+		t.Errorf("Verify(old params) = %v, %v, %v; want true, true, nil", match, needsRehash, err)
+	}
+
+	if _, _, err := hasher.Verify("Secret123", "$argon2id$garbage"); err == nil {
+		t.Error("expected error for malformed hash")
+	}
+}
+
+func TestPasswordHasherLegacyBcrypt(t *testing.T) {
+	hasher := auth.NewPasswordHasher(testPasswordParams)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Secret123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to create bcrypt hash: %v", err)
+	}
+
+	match, needsRehash, err := hasher.Verify("Secret123", string(legacy))
+	if err != nil || !match || !needsRehash {
+		t.Errorf("Verify(bcrypt) = %v, %v, %v; want true, true, nil", match, needsRehash, err)
+	}
+
+	match, _, err = hasher.Verify("Wrong123", string(legacy))
+	if err != nil || match {
+		t.Errorf("Verify(bcrypt wrong) = %v, %v; want false, nil", match, err)
+	}
+}
@@ -0,0 +1,34 @@
+package auth
+
+// RoleHierarchy orders roles from least to most privileged. A user holding a role
+// is granted every role ranked below it.
+type RoleHierarchy struct {
+	rank map[string]int
+}
+
+// NewRoleHierarchy creates a RoleHierarchy from roles listed from least to most privileged
+func NewRoleHierarchy(roles []string) *RoleHierarchy {
+	rank := make(map[string]int, len(roles))
+	for i, role := range roles {
+		rank[role] = i
+	}
+	return &RoleHierarchy{rank: rank}
+}
+
+// Satisfies reports whether any of the granted roles meets the required role.
+// Roles outside the hierarchy only match themselves.
+func (h *RoleHierarchy) Satisfies(granted []string, required string) bool {
+	requiredRank, ranked := h.rank[required]
+	for _, role := range granted {
+		if role == required {
+			return true
+		}
+		if !ranked {
+			continue
+		}
+		if rank, ok := h.rank[role]; ok && rank >= requiredRank {
+			return true
+		}
+	}
+	return false
+}
@@ -1,16 +1,32 @@
 package config
 
 import (
+	"fmt"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/joho/godotenv"
 )
 
 // Config holds all application configuration
 type Config struct {
-	Server  ServerConfig
-	MongoDB MongoDBConfig
-	Env     string
+	Server      ServerConfig
+	MongoDB     MongoDBConfig
+	Auth        AuthConfig
+	Pagination  PaginationConfig
+	Inventory   InventoryConfig
+	Cart        CartConfig
+	Checkout    CheckoutConfig
+	Payment     PaymentConfig
+	Idempotency IdempotencyConfig
+	Analytics   AnalyticsConfig
+	GraphQL     GraphQLConfig
+	WebSocket   WebSocketConfig
+	Env         string
 }
 
 // ServerConfig holds server specific configuration
@@ -24,12 +40,174 @@ type MongoDBConfig struct {
 	Database string
 }
 
+// AuthConfig holds JWT authentication configuration
+type AuthConfig struct {
+	// Algorithm is the JWT signing algorithm (HS256, RS256, ES256, ...)
+	Algorithm string
+	// Secret is the shared key used by the HMAC algorithms
+	Secret string
+	// PublicKeyFile is the PEM encoded public key used by the RSA and ECDSA algorithms
+	PublicKeyFile string
+	// PrivateKeyFile is the PEM encoded private key used to sign tokens with the RSA and ECDSA algorithms
+	PrivateKeyFile string
+	// Issuer is the expected "iss" claim, checked when not empty
+	Issuer string
+	// Audience is the expected "aud" claim, checked when not empty
+	Audience string
+	// ClockSkew is the tolerance applied to the exp, nbf and iat claims
+	ClockSkew time.Duration
+	// AccessTokenTTL is the lifetime of issued access tokens
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL is the lifetime of issued refresh tokens
+	RefreshTokenTTL time.Duration
+	// RoleHierarchy lists the roles from least to most privileged
+	RoleHierarchy []string
+	// PasswordMemory is the argon2id memory cost in KiB
+	PasswordMemory uint32
+	// PasswordIterations is the argon2id time cost
+	PasswordIterations uint32
+	// PasswordParallelism is the argon2id thread count
+	PasswordParallelism uint8
+}
+
+// PaginationConfig holds cursor pagination configuration
+type PaginationConfig struct {
+	// CursorSecret signs pagination cursors so clients can't forge them
+	CursorSecret string
+}
+
+// InventoryConfig holds stock reservation configuration
+type InventoryConfig struct {
+	// ReservationTTL is how long reserved stock is held before it is released
+	ReservationTTL time.Duration
+	// SweepInterval is how often expired reservations are released
+	SweepInterval time.Duration
+}
+
+// CartConfig holds shopping cart configuration
+type CartConfig struct {
+	// GuestTTL is how long an unchanged guest cart is kept
+	GuestTTL time.Duration
+	// UserTTL is how long an unchanged user cart is kept
+	UserTTL time.Duration
+}
+
+// CheckoutConfig holds the tax and shipping rules applied to new orders
+type CheckoutConfig struct {
+	// TaxRate is the tax charged on the order subtotal, e.g. 0.2 for 20%
+	TaxRate float64
+	// ShippingCost is the flat shipping cost of an order
+	ShippingCost float64
+	// FreeShippingThreshold is the subtotal from which shipping is free, 0 disables free shipping
+	FreeShippingThreshold float64
+}
+
+// PaymentConfig holds payment gateway configuration
+type PaymentConfig struct {
+	// Provider is the payment gateway; only the "fake" in-process gateway is available.
+	// It defaults to fake in development and must be set in other environments
+	Provider string
+	// Currency is the ISO 4217 code of the charged amounts
+	Currency string
+	// FakeOutcome forces the outcome of fake gateway authorizations (approve, decline,
+	// insufficient_funds, challenge, timeout); empty lets the test card number decide
+	FakeOutcome string
+	// FakeLatency delays every fake gateway call
+	FakeLatency time.Duration
+}
+
+// IdempotencyConfig holds the Idempotency-Key configuration of mutations
+type IdempotencyConfig struct {
+	// KeyTTL is how long the response of a mutation is replayed to retries with the same key
+	KeyTTL time.Duration
+}
+
+// AnalyticsConfig holds product analytics configuration
+type AnalyticsConfig struct {
+	// RollupInterval is how often product events are rolled up into hourly and daily buckets
+	RollupInterval time.Duration
+	// EventTTL is how long raw product events are kept; rollups are kept indefinitely
+	EventTTL time.Duration
+}
+
+// GraphQLConfig holds the limits applied to GraphQL operations
+type GraphQLConfig struct {
+	// MaxDepth is the deepest field nesting an operation may select
+	MaxDepth int
+	// MaxComplexity is the highest cost an operation may have; list fields cost
+	// their selection times the number of requested items
+	MaxComplexity int
+	// PersistedQueryCacheSize is the number of persisted queries kept in memory
+	PersistedQueryCacheSize int
+	// PersistedQueryTTL is how long a query persisted by a client is kept after its last use
+	PersistedQueryTTL time.Duration
+	// PersistedQueryMaxLength is the longest query in bytes clients can persist
+	PersistedQueryMaxLength int
+	// AllowlistOnly rejects every operation whose query wasn't registered ahead of time
+	AllowlistOnly bool
+}
+
+// WebSocketConfig holds the configuration of the WebSocket transport serving subscriptions
+type WebSocketConfig struct {
+	// KeepAliveInterval is how often idle connections are pinged
+	KeepAliveInterval time.Duration
+	// InitTimeout is how long a client has to send connection_init
+	InitTimeout time.Duration
+	// MaxConnections is the number of connections served at once
+	MaxConnections int
+	// MaxSubscriptions is the number of active subscriptions per connection
+	MaxSubscriptions int
+	// AllowedOrigins lists the browser origins allowed to connect besides the
+	// API origin itself; "*" allows any origin
+	AllowedOrigins []string
+}
+
 // Default configuration values
 const (
 	defaultPort          = "8080"
 	defaultMongoURI      = "mongodb://localhost:27017"
 	defaultMongoDatabase = "ecommerce"
 	defaultEnvironment   = "development"
+	defaultJWTAlgorithm  = "HS256"
+	defaultJWTClockSkew  = 30 * time.Second
+	defaultRoleHierarchy = "customer,staff,admin"
+	defaultAccessTTL     = 15 * time.Minute
+	defaultRefreshTTL    = 30 * 24 * time.Hour
+
+	defaultReservationTTL = 15 * time.Minute
+	defaultSweepInterval  = time.Minute
+
+	defaultGuestCartTTL = 7 * 24 * time.Hour
+	defaultUserCartTTL  = 90 * 24 * time.Hour
+
+	defaultPaymentCurrency = "USD"
+
+	defaultIdempotencyKeyTTL = 24 * time.Hour
+
+	defaultAnalyticsRollupInterval = 5 * time.Minute
+	defaultAnalyticsEventTTL       = 30 * 24 * time.Hour
+
+	defaultGraphQLMaxDepth      = 12
+	defaultGraphQLMaxComplexity = 5000
+	defaultPersistedQueryCache  = 1000
+	defaultPersistedQueryTTL    = 7 * 24 * time.Hour
+	defaultPersistedQueryLength = 16 * 1024
+
+	defaultWSKeepAliveInterval = 15 * time.Second
+	defaultWSInitTimeout       = 10 * time.Second
+	defaultWSMaxConnections    = 1000
+	defaultWSMaxSubscriptions  = 20
+
+	defaultPasswordMemory      = 64 * 1024
+	defaultPasswordIterations  = 3
+	defaultPasswordParallelism = 2
+
+	// developmentJWTSecret is only used when ENV=development and no secret is set
+	developmentJWTSecret = "development-only-jwt-secret"
+	// developmentCursorSecret is only used when ENV=development and no secret is set
+	developmentCursorSecret = "development-only-cursor-secret"
+	// developmentPaymentProvider is only used when ENV=development and no provider is set
+	developmentPaymentProvider = "fake"
 )
 
 // Load loads configuration from environment variables and .env file
@@ -37,6 +215,167 @@ func Load() (*Config, error) {
 	// Load .env file if it exists
 	_ = godotenv.Load()
 
+	env := getEnv("ENV", defaultEnvironment)
+
+	clockSkew, err := getEnvDuration("JWT_CLOCK_SKEW", defaultJWTClockSkew)
+	if err != nil {
+		return nil, err
+	}
+
+	accessTTL, err := getEnvDuration("JWT_EXPIRATION", defaultAccessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTTL, err := getEnvDuration("JWT_REFRESH_EXPIRATION", defaultRefreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordMemory, err := getEnvInt("PASSWORD_ARGON2_MEMORY", defaultPasswordMemory)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordIterations, err := getEnvInt("PASSWORD_ARGON2_ITERATIONS", defaultPasswordIterations)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordParallelism, err := getEnvInt("PASSWORD_ARGON2_PARALLELISM", defaultPasswordParallelism)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validatePasswordParams(passwordMemory, passwordIterations, passwordParallelism); err != nil {
+		return nil, err
+	}
+
+	reservationTTL, err := getEnvDuration("STOCK_RESERVATION_TTL", defaultReservationTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	sweepInterval, err := getEnvDuration("STOCK_SWEEP_INTERVAL", defaultSweepInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	guestCartTTL, err := getEnvDuration("CART_GUEST_TTL", defaultGuestCartTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	userCartTTL, err := getEnvDuration("CART_USER_TTL", defaultUserCartTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	taxRate, err := getEnvFloat("TAX_RATE", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	shippingCost, err := getEnvFloat("SHIPPING_COST", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	freeShippingThreshold, err := getEnvFloat("FREE_SHIPPING_THRESHOLD", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fakePaymentLatency, err := getEnvDuration("PAYMENT_FAKE_LATENCY", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	idempotencyKeyTTL, err := getEnvDuration("IDEMPOTENCY_KEY_TTL", defaultIdempotencyKeyTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	analyticsRollupInterval, err := getEnvDuration("ANALYTICS_ROLLUP_INTERVAL", defaultAnalyticsRollupInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	analyticsEventTTL, err := getEnvDuration("ANALYTICS_EVENT_TTL", defaultAnalyticsEventTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	graphqlMaxDepth, err := getEnvInt("GRAPHQL_MAX_DEPTH", defaultGraphQLMaxDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	graphqlMaxComplexity, err := getEnvInt("GRAPHQL_MAX_COMPLEXITY", defaultGraphQLMaxComplexity)
+	if err != nil {
+		return nil, err
+	}
+
+	persistedQueryCacheSize, err := getEnvInt("PERSISTED_QUERY_CACHE_SIZE", defaultPersistedQueryCache)
+	if err != nil {
+		return nil, err
+	}
+
+	persistedQueryTTL, err := getEnvDuration("PERSISTED_QUERY_TTL", defaultPersistedQueryTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	persistedQueryMaxLength, err := getEnvInt("PERSISTED_QUERY_MAX_LENGTH", defaultPersistedQueryLength)
+	if err != nil {
+		return nil, err
+	}
+
+	allowlistOnly, err := getEnvBool("PERSISTED_QUERIES_ALLOWLIST_ONLY", false)
+	if err != nil {
+		return nil, err
+	}
+
+	wsKeepAliveInterval, err := getEnvDuration("WS_KEEPALIVE_INTERVAL", defaultWSKeepAliveInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	wsInitTimeout, err := getEnvDuration("WS_INIT_TIMEOUT", defaultWSInitTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	wsMaxConnections, err := getEnvInt("WS_MAX_CONNECTIONS", defaultWSMaxConnections)
+	if err != nil {
+		return nil, err
+	}
+
+	wsMaxSubscriptions, err := getEnvInt("WS_MAX_SUBSCRIPTIONS", defaultWSMaxSubscriptions)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := getEnv("JWT_SECRET", "")
+	if secret == "" && env == defaultEnvironment {
+		secret = developmentJWTSecret
+	}
+
+	cursorSecret := getEnv("CURSOR_SECRET", "")
+	if cursorSecret == "" && env == defaultEnvironment {
+		cursorSecret = developmentCursorSecret
+	}
+
+	// The fake gateway keeps card tokens in memory and approves test cards, so
+	// anywhere but development it has to be chosen explicitly
+	paymentProvider := getEnv("PAYMENT_PROVIDER", "")
+	if paymentProvider == "" {
+		if env != defaultEnvironment {
+			return nil, fmt.Errorf("PAYMENT_PROVIDER is required when ENV=%s", env)
+		}
+		paymentProvider = developmentPaymentProvider
+	}
+
 	return &Config{
 		Server: ServerConfig{
 			Port: getEnv("PORT", defaultPort),
@@ -45,10 +384,98 @@ func Load() (*Config, error) {
 			URI:      getEnv("MONGODB_URI", getEnv("MDB_MCP_CONNECTION_STRING", defaultMongoURI)),
 			Database: getEnv("MONGODB_DATABASE", defaultMongoDatabase),
 		},
-		Env: getEnv("ENV", defaultEnvironment),
+		Auth: AuthConfig{
+			Algorithm:       jwtAlgorithm(getEnv("JWT_ALGORITHM", defaultJWTAlgorithm)),
+			Secret:          secret,
+			PublicKeyFile:   getEnv("JWT_PUBLIC_KEY_FILE", ""),
+			PrivateKeyFile:  getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			Issuer:          getEnv("JWT_ISSUER", ""),
+			Audience:        getEnv("JWT_AUDIENCE", ""),
+			ClockSkew:       clockSkew,
+			AccessTokenTTL:  accessTTL,
+			RefreshTokenTTL: refreshTTL,
+			RoleHierarchy:   getEnvList("ROLE_HIERARCHY", defaultRoleHierarchy),
+
+			PasswordMemory:      uint32(passwordMemory),
+			PasswordIterations:  uint32(passwordIterations),
+			PasswordParallelism: uint8(passwordParallelism),
+		},
+		Pagination: PaginationConfig{
+			CursorSecret: cursorSecret,
+		},
+		Inventory: InventoryConfig{
+			ReservationTTL: reservationTTL,
+			SweepInterval:  sweepInterval,
+		},
+		Cart: CartConfig{
+			GuestTTL: guestCartTTL,
+			UserTTL:  userCartTTL,
+		},
+		Payment: PaymentConfig{
+			Provider:    paymentProvider,
+			Currency:    strings.ToUpper(getEnv("PAYMENT_CURRENCY", defaultPaymentCurrency)),
+			FakeOutcome: getEnv("PAYMENT_FAKE_OUTCOME", ""),
+			FakeLatency: fakePaymentLatency,
+		},
+		Idempotency: IdempotencyConfig{
+			KeyTTL: idempotencyKeyTTL,
+		},
+		Analytics: AnalyticsConfig{
+			RollupInterval: analyticsRollupInterval,
+			EventTTL:       analyticsEventTTL,
+		},
+		GraphQL: GraphQLConfig{
+			MaxDepth:      graphqlMaxDepth,
+			MaxComplexity: graphqlMaxComplexity,
+
+			PersistedQueryCacheSize: persistedQueryCacheSize,
+			PersistedQueryTTL:       persistedQueryTTL,
+			PersistedQueryMaxLength: persistedQueryMaxLength,
+			AllowlistOnly:           allowlistOnly,
+		},
+		WebSocket: WebSocketConfig{
+			KeepAliveInterval: wsKeepAliveInterval,
+			InitTimeout:       wsInitTimeout,
+			MaxConnections:    wsMaxConnections,
+			MaxSubscriptions:  wsMaxSubscriptions,
+			AllowedOrigins:    getEnvList("WS_ALLOWED_ORIGINS", ""),
+		},
+		Checkout: CheckoutConfig{
+			TaxRate:               taxRate,
+			ShippingCost:          shippingCost,
+			FreeShippingThreshold: freeShippingThreshold,
+		},
+		Env: env,
 	}, nil
 }
 
+// validatePasswordParams checks the argon2id costs fit the parameter types and
+// the limits of argon2, which panics on out of range values
+func validatePasswordParams(memory, iterations, parallelism int) error {
+	if parallelism < 1 || parallelism > math.MaxUint8 {
+		return fmt.Errorf("invalid PASSWORD_ARGON2_PARALLELISM: must be between 1 and %d", math.MaxUint8)
+	}
+	if iterations < 1 || iterations > math.MaxUint32 {
+		return fmt.Errorf("invalid PASSWORD_ARGON2_ITERATIONS: must be between 1 and %d", uint32(math.MaxUint32))
+	}
+	if memory < 8*parallelism || memory > math.MaxUint32 {
+		return fmt.Errorf("invalid PASSWORD_ARGON2_MEMORY: must be between 8 KiB per thread (%d) and %d", 8*parallelism, uint32(math.MaxUint32))
+	}
+	return nil
+}
+
+// jwtAlgorithm returns the registered name of a JWT signing algorithm given in
+// any case, e.g. EdDSA for "eddsa". Unknown names are kept as given so the
+// verifier reports them.
+func jwtAlgorithm(name string) string {
+	for _, alg := range jwt.GetAlgorithms() {
+		if strings.EqualFold(alg, name) {
+			return alg
+		}
+	}
+	return name
+}
+
 // Helper to get environment variable with a default value
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -57,3 +484,66 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// Helper to get a duration environment variable such as "30s" with a default value
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
+
+// Helper to get a positive integer environment variable with a default value
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s: must be a positive integer", key)
+	}
+	return n, nil
+}
+
+// Helper to get a non-negative decimal environment variable with a default value
+func getEnvFloat(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid %s: must be a non-negative number", key)
+	}
+	return f, nil
+}
+
+// Helper to get a boolean environment variable such as "true" with a default value
+func getEnvBool(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: must be true or false", key)
+	}
+	return b, nil
+}
+
+// Helper to get a comma separated list environment variable with a default value
+func getEnvList(key, defaultValue string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
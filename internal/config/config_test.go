@@ -0,0 +1,65 @@
+package config
+
+import "testing"
+
+func TestJWTAlgorithm(t *testing.T) {
+	tests := map[string]string{
+		"HS256":   "HS256",
+		"hs256":   "HS256",
+		"EdDSA":   "EdDSA",
+		"EDDSA":   "EdDSA",
+		"es384":   "ES384",
+		"unknown": "unknown",
+	}
+	for name, want := range tests {
+		if got := jwtAlgorithm(name); got != want {
+			t.Errorf("jwtAlgorithm(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestValidatePasswordParams(t *testing.T) {
+	tests := []struct {
+		name                            string
+		memory, iterations, parallelism int
+		valid                           bool
+	}{
+		{name: "defaults", memory: defaultPasswordMemory, iterations: defaultPasswordIterations, parallelism: defaultPasswordParallelism, valid: true},
+		{name: "minimum", memory: 8, iterations: 1, parallelism: 1, valid: true},
+		{name: "no threads", memory: 64 * 1024, iterations: 3, parallelism: 0},
+		{name: "too many threads", memory: 64 * 1024, iterations: 3, parallelism: 256},
+		{name: "no iterations", memory: 64 * 1024, iterations: 0, parallelism: 2},
+		{name: "too little memory", memory: 15, iterations: 3, parallelism: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePasswordParams(tt.memory, tt.iterations, tt.parallelism)
+			if (err == nil) != tt.valid {
+				t.Errorf("got error %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestLoadPaymentProvider(t *testing.T) {
+	t.Setenv("PAYMENT_PROVIDER", "")
+
+	t.Setenv("ENV", "development")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load in development: %v", err)
+	}
+	if cfg.Payment.Provider != "fake" {
+		t.Errorf("provider = %q, want the fake gateway in development", cfg.Payment.Provider)
+	}
+
+	t.Setenv("ENV", "production")
+	if _, err := Load(); err == nil {
+		t.Error("expected Load to require PAYMENT_PROVIDER in production")
+	}
+
+	t.Setenv("PAYMENT_PROVIDER", "fake")
+	if cfg, err := Load(); err != nil || cfg.Payment.Provider != "fake" {
+		t.Errorf("Load with an explicit provider = %v, %v", cfg, err)
+	}
+}
@@ -0,0 +1,12 @@
+package models
+
+// Address is a postal address
+type Address struct {
+	FullName string `json:"full_name,omitempty" bson:"full_name,omitempty"`
+	Street   string `json:"street" bson:"street"`
+	City     string `json:"city" bson:"city"`
+	State    string `json:"state,omitempty" bson:"state,omitempty"`
+	ZipCode  string `json:"zip_code" bson:"zip_code"`
+	// Country is the ISO 3166-1 alpha-2 country code
+	Country string `json:"country" bson:"country"`
+}
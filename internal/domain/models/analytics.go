@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProductEventType is the kind of a recorded product event
+type ProductEventType string
+
+// Product event types
+const (
+	ProductViewed    ProductEventType = "view"
+	ProductPurchased ProductEventType = "purchase"
+)
+
+// ProductEvent is a raw analytics event. Events carry the hour and day they fall
+// in so they can be rolled up into buckets without date arithmetic.
+type ProductEvent struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	ProductID primitive.ObjectID `json:"product_id" bson:"product_id"`
+	Type      ProductEventType   `json:"type" bson:"type"`
+	// Quantity is 1 for views and the number of units for purchases
+	Quantity   int       `json:"quantity" bson:"quantity"`
+	UserID     string    `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	OccurredAt time.Time `json:"occurred_at" bson:"occurred_at"`
+	Hour       time.Time `json:"hour" bson:"hour"`
+	Day        time.Time `json:"day" bson:"day"`
+}
+
+// NewProductEvent creates an event of a product at the given time, bucketed in UTC
+func NewProductEvent(productID primitive.ObjectID, eventType ProductEventType, quantity int, userID string, at time.Time) ProductEvent {
+	at = at.UTC()
+	return ProductEvent{
+		ID:         primitive.NewObjectID(),
+		ProductID:  productID,
+		Type:       eventType,
+		Quantity:   quantity,
+		UserID:     userID,
+		OccurredAt: at,
+		Hour:       at.Truncate(time.Hour),
+		Day:        time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// StatsGranularity is the size of the buckets product events are rolled up into
+type StatsGranularity string
+
+// Rollup bucket sizes
+const (
+	StatsHourly StatsGranularity = "hourly"
+	StatsDaily  StatsGranularity = "daily"
+)
+
+// TimePeriod is the window popular products are ranked over
+type TimePeriod string
+
+// Ranking windows
+const (
+	PeriodDay     TimePeriod = "day"
+	PeriodWeek    TimePeriod = "week"
+	PeriodMonth   TimePeriod = "month"
+	PeriodAllTime TimePeriod = "all_time"
+)
+
+// PopularProduct is the view and purchase counts of a product over a period
+type PopularProduct struct {
+	ProductID     primitive.ObjectID `json:"product_id" bson:"_id"`
+	ViewCount     int                `json:"view_count" bson:"views"`
+	PurchaseCount int                `json:"purchase_count" bson:"purchases"`
+	Product       *Product           `json:"product,omitempty" bson:"-"`
+}
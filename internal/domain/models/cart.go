@@ -0,0 +1,88 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Cart is a shopping cart owned either by a signed in user or by a guest holding
+// the cart token. Only the SHA-256 hash of a guest token is persisted. Carts are
+// removed by a TTL index once ExpiresAt passes.
+type Cart struct {
+	BaseModel `bson:",inline"`
+	UserID    string     `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	TokenHash string     `json:"-" bson:"token_hash,omitempty"`
+	Items     []CartItem `json:"items" bson:"items"`
+	ExpiresAt time.Time  `json:"expires_at" bson:"expires_at"`
+	// Version is incremented on every save to detect concurrent updates
+	Version int `json:"-" bson:"version"`
+	// CheckoutOrderID is the order placed from the cart, cleared when the cart changes again
+	CheckoutOrderID *primitive.ObjectID `json:"checkout_order_id,omitempty" bson:"checkout_order_id,omitempty"`
+	// Token is the guest cart token, only set when a guest cart was just created
+	Token string `json:"token,omitempty" bson:"-"`
+}
+
+// CartItem is a product in a cart with the price it was last seen at
+type CartItem struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	ProductID primitive.ObjectID `json:"product_id" bson:"product_id"`
+	SKU       string             `json:"sku" bson:"sku"`
+	Name      string             `json:"name" bson:"name"`
+	UnitPrice float64            `json:"unit_price" bson:"unit_price"`
+	// PreviousUnitPrice is set when the catalog price changed since the item was added or updated
+	PreviousUnitPrice *float64  `json:"previous_unit_price,omitempty" bson:"previous_unit_price,omitempty"`
+	Quantity          int       `json:"quantity" bson:"quantity"`
+	AddedAt           time.Time `json:"added_at" bson:"added_at"`
+	// Available reports whether the product still exists with enough stock, refreshed when the cart is loaded
+	Available bool `json:"available" bson:"-"`
+}
+
+// TotalPrice returns the price of the item line
+func (i *CartItem) TotalPrice() float64 {
+	return roundPrice(i.UnitPrice * float64(i.Quantity))
+}
+
+// Subtotal returns the sum of the item line prices
+func (c *Cart) Subtotal() float64 {
+	var subtotal float64
+	for i := range c.Items {
+		subtotal += c.Items[i].TotalPrice()
+	}
+	return roundPrice(subtotal)
+}
+
+// ItemCount returns the number of units in the cart
+func (c *Cart) ItemCount() int {
+	count := 0
+	for _, item := range c.Items {
+		count += item.Quantity
+	}
+	return count
+}
+
+// HasPriceChanges reports whether any item was re-priced since it was added or updated
+func (c *Cart) HasPriceChanges() bool {
+	for _, item := range c.Items {
+		if item.PreviousUnitPrice != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// FindItem returns the item with the given ID
+func (c *Cart) FindItem(id primitive.ObjectID) (*CartItem, bool) {
+	for i := range c.Items {
+		if c.Items[i].ID == id {
+			return &c.Items[i], true
+		}
+	}
+	return nil, false
+}
+
+// roundPrice rounds an amount to cents
+func roundPrice(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
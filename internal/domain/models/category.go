@@ -0,0 +1,35 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Category is a node of the product category tree. The tree is stored with ancestor
+// arrays so subtrees can be queried and moved without recursive lookups.
+type Category struct {
+	BaseModel   `bson:",inline"`
+	Name        string              `json:"name" bson:"name"`
+	Description string              `json:"description" bson:"description"`
+	ParentID    *primitive.ObjectID `json:"parent_id,omitempty" bson:"parent_id"`
+	// Ancestors lists the IDs from the root down to the parent
+	Ancestors []primitive.ObjectID `json:"ancestors" bson:"ancestors"`
+	// ProductCount is the number of products in the category and its descendants
+	ProductCount int `json:"product_count" bson:"product_count"`
+}
+
+// Path returns the IDs from the root down to and including the category
+func (c *Category) Path() []primitive.ObjectID {
+	path := make([]primitive.ObjectID, 0, len(c.Ancestors)+1)
+	path = append(path, c.Ancestors...)
+	return append(path, c.ID)
+}
+
+// IsAncestorOf reports whether the category is an ancestor of other
+func (c *Category) IsAncestorOf(other *Category) bool {
+	for _, id := range other.Ancestors {
+		if id == c.ID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package models
+
+// IsEntity marks the models exposed as Apollo Federation entities, which other
+// subgraphs can reference by id
+func (User) IsEntity() {}
+
+// IsEntity marks products as a federation entity
+func (Product) IsEntity() {}
+
+// IsEntity marks orders as a federation entity
+func (Order) IsEntity() {}
+
+// IsEntity marks categories as a federation entity
+func (Category) IsEntity() {}
@@ -0,0 +1,15 @@
+package models
+
+import "errors"
+
+// Common errors returned by repositories
+var (
+	// ErrNotFound is returned when a requested document does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a document violates a unique constraint
+	ErrDuplicate = errors.New("duplicate")
+	// ErrConflict is returned when a document was changed by a concurrent update
+	ErrConflict = errors.New("conflict")
+	// ErrInsufficientStock is returned when a stock update would leave fewer units than reserved
+	ErrInsufficientStock = errors.New("insufficient stock")
+)
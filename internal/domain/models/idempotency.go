@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// IdempotencyStatus is the state of an idempotency key
+type IdempotencyStatus string
+
+// Idempotency key states
+const (
+	// IdempotencyPending means the first request with the key is still running
+	IdempotencyPending IdempotencyStatus = "pending"
+	// IdempotencyCompleted means the response of the first request is stored
+	IdempotencyCompleted IdempotencyStatus = "completed"
+)
+
+// IdempotencyRecord is the stored outcome of a mutation sent with an Idempotency-Key
+// header. Retries with the same user, key and operation replay the stored response.
+type IdempotencyRecord struct {
+	BaseModel `bson:",inline"`
+	// UserID scopes the key: the signed in user ID, or "cart:" and the hash of a guest cart token
+	UserID string `json:"user_id" bson:"user_id"`
+	Key    string `json:"key" bson:"key"`
+	// OperationHash identifies the query, operation name and variables of the first request
+	OperationHash string            `json:"operation_hash" bson:"operation_hash"`
+	Status        IdempotencyStatus `json:"status" bson:"status"`
+	// Response is the JSON encoded GraphQL response of the first request
+	Response  []byte    `json:"-" bson:"response,omitempty"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InventoryItem tracks the stock of a SKU. Reserved units are held for pending
+// checkouts and can't be sold to anyone else.
+type InventoryItem struct {
+	BaseModel `bson:",inline"`
+	SKU       string             `json:"sku" bson:"sku"`
+	ProductID primitive.ObjectID `json:"product_id" bson:"product_id"`
+	// OnHand is the number of units physically in stock
+	OnHand int `json:"on_hand" bson:"on_hand"`
+	// Reserved is the number of on hand units held by active reservations
+	Reserved int `json:"reserved" bson:"reserved"`
+}
+
+// Available returns the number of units that can still be reserved
+func (i *InventoryItem) Available() int {
+	return i.OnHand - i.Reserved
+}
+
+// ReservationStatus is the state of a stock reservation
+type ReservationStatus string
+
+// Reservation states; only active reservations hold stock
+const (
+	ReservationActive    ReservationStatus = "active"
+	ReservationCommitted ReservationStatus = "committed"
+	ReservationReleased  ReservationStatus = "released"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// Reservation holds units of a SKU until it is committed, released or expires
+type Reservation struct {
+	BaseModel `bson:",inline"`
+	SKU       string             `json:"sku" bson:"sku"`
+	ProductID primitive.ObjectID `json:"product_id" bson:"product_id"`
+	Quantity  int                `json:"quantity" bson:"quantity"`
+	// Reference identifies what the stock is reserved for, such as a cart or an order
+	Reference string            `json:"reference" bson:"reference"`
+	Status    ReservationStatus `json:"status" bson:"status"`
+	ExpiresAt time.Time         `json:"expires_at" bson:"expires_at"`
+}
+
+// StockMovementType is the kind of change recorded by a stock movement
+type StockMovementType string
+
+// Stock movement types
+const (
+	// StockMovementAdjustment changes the on hand quantity, e.g. received goods or a stock count
+	StockMovementAdjustment StockMovementType = "adjustment"
+	StockMovementReserve    StockMovementType = "reserve"
+	StockMovementRelease    StockMovementType = "release"
+	StockMovementExpire     StockMovementType = "expire"
+	// StockMovementCommit removes reserved units from stock once they are sold
+	StockMovementCommit StockMovementType = "commit"
+)
+
+// StockMovement is an entry of the append-only stock ledger
+type StockMovement struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	SKU       string             `json:"sku" bson:"sku"`
+	ProductID primitive.ObjectID `json:"product_id" bson:"product_id"`
+	Type      StockMovementType  `json:"type" bson:"type"`
+	// OnHandDelta and ReservedDelta are the changes applied to the inventory item
+	OnHandDelta   int                 `json:"on_hand_delta" bson:"on_hand_delta"`
+	ReservedDelta int                 `json:"reserved_delta" bson:"reserved_delta"`
+	ReservationID *primitive.ObjectID `json:"reservation_id,omitempty" bson:"reservation_id,omitempty"`
+	Reference     string              `json:"reference,omitempty" bson:"reference,omitempty"`
+	Reason        string              `json:"reason,omitempty" bson:"reason,omitempty"`
+	// ActorID is the user who made the change, empty for system changes
+	ActorID   string    `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// StockMovementConnection is a page of stock movements
+type StockMovementConnection = connection.Connection[StockMovement]
+
+// StockMovementEdge is a stock movement of a page with its cursor
+type StockMovementEdge = connection.Edge[StockMovement]
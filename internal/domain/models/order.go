@@ -0,0 +1,168 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OrderStatus is the lifecycle state of an order
+type OrderStatus string
+
+// Order states
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderPaid      OrderStatus = "paid"
+	OrderFulfilled OrderStatus = "fulfilled"
+	OrderShipped   OrderStatus = "shipped"
+	OrderDelivered OrderStatus = "delivered"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderRefunded  OrderStatus = "refunded"
+)
+
+// orderTransitions lists the states each state can move to. Orders can be
+// cancelled until they ship; refunds apply to delivered orders and to cancelled
+// orders that were paid.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending:   {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderFulfilled, OrderCancelled},
+	OrderFulfilled: {OrderShipped, OrderCancelled},
+	OrderShipped:   {OrderDelivered},
+	OrderDelivered: {OrderRefunded},
+	OrderCancelled: {OrderRefunded},
+}
+
+// ErrInvalidTransition is matched by every TransitionError
+var ErrInvalidTransition = errors.New("invalid order status transition")
+
+// TransitionError is returned when an order can't move from its status to another
+type TransitionError struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+// Error implements error
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("an order can't move from %s to %s", e.From, e.To)
+}
+
+// Is makes errors.Is(err, ErrInvalidTransition) match transition errors
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// Order is a placed order. Items are priced when the order is placed and never
+// re-priced. Status changes go through Transition so only legal moves are recorded.
+type Order struct {
+	BaseModel          `bson:",inline"`
+	UserID             string              `json:"user_id" bson:"user_id"`
+	Items              []OrderItem         `json:"items" bson:"items"`
+	Subtotal           float64             `json:"subtotal" bson:"subtotal"`
+	Tax                float64             `json:"tax" bson:"tax"`
+	ShippingCost       float64             `json:"shipping_cost" bson:"shipping_cost"`
+	Total              float64             `json:"total" bson:"total"`
+	ShippingAddress    Address             `json:"shipping_address" bson:"shipping_address"`
+	Status             OrderStatus         `json:"status" bson:"status"`
+	StatusHistory      []OrderStatusChange `json:"status_history" bson:"status_history"`
+	CancellationReason string              `json:"cancellation_reason,omitempty" bson:"cancellation_reason,omitempty"`
+	PaidAt             *time.Time          `json:"paid_at,omitempty" bson:"paid_at,omitempty"`
+	PaymentInfo        *PaymentInfo        `json:"payment_info,omitempty" bson:"payment,omitempty"`
+	// CheckoutKey identifies the cart version the order was placed from, so a
+	// checkout submitted twice creates a single order
+	CheckoutKey string `json:"-" bson:"checkout_key,omitempty"`
+}
+
+// OrderItem is a product line of an order
+type OrderItem struct {
+	ProductID primitive.ObjectID `json:"product_id" bson:"product_id"`
+	SKU       string             `json:"sku" bson:"sku"`
+	Name      string             `json:"name" bson:"name"`
+	UnitPrice float64            `json:"unit_price" bson:"unit_price"`
+	Quantity  int                `json:"quantity" bson:"quantity"`
+}
+
+// TotalPrice returns the price of the item line
+func (i *OrderItem) TotalPrice() float64 {
+	return roundPrice(i.UnitPrice * float64(i.Quantity))
+}
+
+// ItemsSubtotal returns the sum of the item line prices
+func (o *Order) ItemsSubtotal() float64 {
+	var subtotal float64
+	for i := range o.Items {
+		subtotal += o.Items[i].TotalPrice()
+	}
+	return roundPrice(subtotal)
+}
+
+// ComputeTotals sets the subtotal from the item lines, the tax at taxRate on the
+// subtotal, the shipping cost and the total
+func (o *Order) ComputeTotals(taxRate, shippingCost float64) {
+	o.Subtotal = o.ItemsSubtotal()
+	o.Tax = roundPrice(o.Subtotal * taxRate)
+	o.ShippingCost = roundPrice(shippingCost)
+	o.Total = roundPrice(o.Subtotal + o.Tax + o.ShippingCost)
+}
+
+// OrderStatusChange is an entry of the status history of an order
+type OrderStatusChange struct {
+	// From is nil for the entry recording the order creation
+	From *OrderStatus `json:"from,omitempty" bson:"from,omitempty"`
+	To   OrderStatus  `json:"to" bson:"to"`
+	// ActorID is the user who made the change, empty for system changes
+	ActorID string    `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	Reason  string    `json:"reason,omitempty" bson:"reason,omitempty"`
+	At      time.Time `json:"at" bson:"at"`
+}
+
+// CanTransition reports whether the order can move to the given status
+func (o *Order) CanTransition(to OrderStatus) bool {
+	for _, next := range orderTransitions[o.Status] {
+		if next == to {
+			// Only orders that were paid can be refunded
+			return to != OrderRefunded || o.PaidAt != nil
+		}
+	}
+	return false
+}
+
+// Transition moves the order to another status and records the change. It returns
+// a *TransitionError when the move isn't allowed from the current status.
+func (o *Order) Transition(to OrderStatus, actorID, reason string, at time.Time) (OrderStatusChange, error) {
+	if !o.CanTransition(to) {
+		return OrderStatusChange{}, &TransitionError{From: o.Status, To: to}
+	}
+
+	from := o.Status
+	change := OrderStatusChange{From: &from, To: to, ActorID: actorID, Reason: reason, At: at}
+	o.Status = to
+	o.StatusHistory = append(o.StatusHistory, change)
+	o.UpdatedAt = at
+	switch to {
+	case OrderPaid:
+		o.PaidAt = &at
+	case OrderCancelled:
+		o.CancellationReason = reason
+	}
+	return change, nil
+}
+
+// IsFinal reports whether the order can't change status anymore
+func (o *Order) IsFinal() bool {
+	return len(orderTransitions[o.Status]) == 0
+}
+
+// OrderFilter restricts order listings. Empty fields are ignored.
+type OrderFilter struct {
+	UserID string
+	Status *OrderStatus
+}
+
+// OrderConnection is a page of orders
+type OrderConnection = connection.Connection[Order]
+
+// OrderEdge is an order of a page with its cursor
+type OrderEdge = connection.Edge[Order]
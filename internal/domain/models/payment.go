@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// PaymentStatus is the state of the payment of an order
+type PaymentStatus string
+
+// Payment states
+const (
+	// PaymentProcessing is set while the gateway is being called
+	PaymentProcessing PaymentStatus = "processing"
+	// PaymentRequiresAction means the buyer must complete a 3-D Secure challenge
+	PaymentRequiresAction PaymentStatus = "requires_action"
+	PaymentCaptured       PaymentStatus = "captured"
+	PaymentDeclined       PaymentStatus = "declined"
+	// PaymentFailed means the gateway failed or timed out; the payment can be retried
+	PaymentFailed   PaymentStatus = "failed"
+	PaymentRefunded PaymentStatus = "refunded"
+	// PaymentVoided means the authorization was released without charging the buyer,
+	// e.g. because the order was cancelled
+	PaymentVoided PaymentStatus = "voided"
+)
+
+// CanRetry reports whether a new payment attempt can replace a payment in this status
+func (s PaymentStatus) CanRetry() bool {
+	return s == PaymentRequiresAction || s == PaymentDeclined || s == PaymentFailed
+}
+
+// PaymentInfo is the payment of an order. Cards are only known by the provider token
+// and their last four digits.
+type PaymentInfo struct {
+	Method          string        `json:"method" bson:"method"`
+	Brand           string        `json:"brand,omitempty" bson:"brand,omitempty"`
+	LastFourDigits  string        `json:"last_four_digits" bson:"last_four_digits"`
+	Token           string        `json:"-" bson:"token"`
+	Status          PaymentStatus `json:"status" bson:"status"`
+	Amount          float64       `json:"amount" bson:"amount"`
+	AuthorizationID string        `json:"-" bson:"authorization_id,omitempty"`
+	// ChallengeURL is where the buyer completes a 3-D Secure challenge
+	ChallengeURL  string    `json:"challenge_url,omitempty" bson:"challenge_url,omitempty"`
+	FailureReason string    `json:"failure_reason,omitempty" bson:"failure_reason,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// PaymentMethodCard is the PaymentInfo method of card payments
+const PaymentMethodCard = "card"
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PersistedQuery is a query document stored under the hex SHA-256 hash of its
+// text. Queries are persisted by clients through automatic persisted queries or
+// registered ahead of time; only registered queries run in allowlist mode.
+type PersistedQuery struct {
+	Hash          string `json:"hash" bson:"_id"`
+	Query         string `json:"query" bson:"query"`
+	OperationName string `json:"operation_name,omitempty" bson:"operation_name,omitempty"`
+	Registered    bool   `json:"registered" bson:"registered"`
+	// ExpiresAt is when a query persisted by a client is removed unless it is used
+	// again; registered queries don't expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" bson:"updated_at"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Product is an item of the catalog
+type Product struct {
+	BaseModel   `bson:",inline"`
+	Name        string              `json:"name" bson:"name"`
+	Description string              `json:"description" bson:"description"`
+	Price       float64             `json:"price" bson:"price"`
+	SKU         string              `json:"sku" bson:"sku"`
+	Brand       string              `json:"brand,omitempty" bson:"brand,omitempty"`
+	Images      []ProductImage      `json:"images" bson:"images"`
+	CategoryID  *primitive.ObjectID `json:"category_id,omitempty" bson:"category_id,omitempty"`
+	Stock       int                 `json:"stock" bson:"stock"`
+	// Rating summarizes the approved reviews, maintained by the review service
+	Rating RatingSummary `json:"rating" bson:"rating"`
+	// Score is the search relevance of the product, only set in search results
+	Score *float64 `json:"score,omitempty" bson:"score,omitempty"`
+}
+
+// ProductImage is an image of a product
+type ProductImage struct {
+	URL string `json:"url" bson:"url"`
+	Alt string `json:"alt,omitempty" bson:"alt,omitempty"`
+}
+
+// InStock reports whether the product can be ordered
+func (p *Product) InStock() bool {
+	return p.Stock > 0
+}
+
+// ProductFilter restricts product listings. Nil fields are ignored.
+type ProductFilter struct {
+	CategoryID *primitive.ObjectID
+	MinPrice   *float64
+	MaxPrice   *float64
+	InStock    *bool
+}
+
+// Product sort keys
+const (
+	ProductSortCreatedAt = "created_at"
+	ProductSortPrice     = "price"
+	ProductSortName      = "name"
+)
+
+// ProductSort orders product listings
+type ProductSort struct {
+	Field      string
+	Descending bool
+}
+
+// ProductConnection is a page of products
+type ProductConnection = connection.Connection[Product]
+
+// ProductEdge is a product of a page with its cursor
+type ProductEdge = connection.Edge[Product]
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReviewStatus is the moderation state of a review
+type ReviewStatus string
+
+// Review moderation states
+const (
+	ReviewPending  ReviewStatus = "pending"
+	ReviewApproved ReviewStatus = "approved"
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+// Review rating bounds
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+// Review is the review of a product by a user. A user reviews a product at most
+// once. Reviews are only published and counted in the product rating once approved.
+type Review struct {
+	BaseModel `bson:",inline"`
+	ProductID primitive.ObjectID `json:"product_id" bson:"product_id"`
+	UserID    string             `json:"user_id" bson:"user_id"`
+	Rating    int                `json:"rating" bson:"rating"`
+	Title     string             `json:"title,omitempty" bson:"title,omitempty"`
+	Content   string             `json:"content,omitempty" bson:"content,omitempty"`
+	Status    ReviewStatus       `json:"status" bson:"status"`
+	// ModerationNote explains a rejection to the author
+	ModerationNote string `json:"moderation_note,omitempty" bson:"moderation_note,omitempty"`
+	// VerifiedPurchase is set when the author received the product in a delivered order
+	VerifiedPurchase bool `json:"verified_purchase" bson:"verified_purchase"`
+	// Version is incremented on every save to detect concurrent updates
+	Version int `json:"-" bson:"version"`
+}
+
+// ReviewAuthor is the public profile of the author of a review
+type ReviewAuthor struct {
+	ID        string `json:"id"`
+	FirstName string `json:"first_name"`
+}
+
+// RatingSummary holds the approved review counts of a product. It is updated with
+// each review that is approved or withdrawn rather than recomputed.
+type RatingSummary struct {
+	Count int `json:"count" bson:"count"`
+	Sum   int `json:"sum" bson:"sum"`
+	// Histogram counts the reviews per rating, keyed by the rating
+	Histogram map[string]int `json:"histogram,omitempty" bson:"histogram,omitempty"`
+}
+
+// RatingCount is the number of approved reviews with a given rating
+type RatingCount struct {
+	Rating int `json:"rating"`
+	Count  int `json:"count"`
+}
+
+// AverageRating returns the mean approved rating rounded to two decimals, nil without reviews
+func (p *Product) AverageRating() *float64 {
+	if p.Rating.Count <= 0 {
+		return nil
+	}
+	average := math.Round(float64(p.Rating.Sum)/float64(p.Rating.Count)*100) / 100
+	return &average
+}
+
+// ReviewCount returns the number of approved reviews
+func (p *Product) ReviewCount() int {
+	return max(p.Rating.Count, 0)
+}
+
+// RatingHistogram returns the number of approved reviews per rating, best rating first
+func (p *Product) RatingHistogram() []RatingCount {
+	histogram := make([]RatingCount, 0, MaxRating-MinRating+1)
+	for rating := MaxRating; rating >= MinRating; rating-- {
+		histogram = append(histogram, RatingCount{
+			Rating: rating,
+			Count:  max(p.Rating.Histogram[strconv.Itoa(rating)], 0),
+		})
+	}
+	return histogram
+}
+
+// ReviewFilter restricts review listings. Empty fields are ignored.
+type ReviewFilter struct {
+	ProductID *primitive.ObjectID
+	UserID    string
+	Status    *ReviewStatus
+}
+
+// ReviewConnection is a page of reviews
+type ReviewConnection = connection.Connection[Review]
+
+// ReviewEdge is a review of a page with its cursor
+type ReviewEdge = connection.Edge[Review]
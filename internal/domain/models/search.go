@@ -0,0 +1,55 @@
+package models
+
+import (
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Names of the product search facets
+const (
+	SearchFacetCategory = "category"
+	SearchFacetPrice    = "price"
+	SearchFacetBrand    = "brand"
+	SearchFacetInStock  = "inStock"
+)
+
+// SearchFilter restricts product search results. Nil fields are ignored.
+type SearchFilter struct {
+	CategoryID *primitive.ObjectID
+	Brand      *string
+	MinPrice   *float64
+	MaxPrice   *float64
+	InStock    *bool
+}
+
+// SearchQuery is a product search. An empty text matches every product.
+type SearchQuery struct {
+	Text   string
+	Filter SearchFilter
+	Offset int
+	Limit  int
+}
+
+// SearchResult is a page of products matching a search, best matches first, with
+// the facet counts of every matching product
+type SearchResult struct {
+	Products   []Product     `json:"products"`
+	Facets     []SearchFacet `json:"facets"`
+	TotalCount int           `json:"total_count"`
+	// Fuzzy is set when nothing matched the text exactly and the products come from
+	// the typo tolerant fallback
+	Fuzzy    bool                `json:"fuzzy"`
+	PageInfo connection.PageInfo `json:"page_info"`
+}
+
+// SearchFacet counts the matching products per value of a product attribute
+type SearchFacet struct {
+	Name    string              `json:"name"`
+	Options []SearchFacetOption `json:"options"`
+}
+
+// SearchFacetOption is a value of a facet with its number of matching products
+type SearchFacetOption struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is a stored refresh token. Only the SHA-256 hash of the token is persisted.
+// Tokens rotated from the same login share a FamilyID so the whole family can be revoked at once.
+type RefreshToken struct {
+	BaseModel  `bson:",inline"`
+	UserID     string              `json:"user_id" bson:"user_id"`
+	FamilyID   primitive.ObjectID  `json:"family_id" bson:"family_id"`
+	TokenHash  string              `json:"-" bson:"token_hash"`
+	Roles      []string            `json:"roles,omitempty" bson:"roles,omitempty"`
+	TenantID   string              `json:"tenant_id,omitempty" bson:"tenant_id,omitempty"`
+	ExpiresAt  time.Time           `json:"expires_at" bson:"expires_at"`
+	UsedAt     *time.Time          `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	ReplacedBy *primitive.ObjectID `json:"replaced_by,omitempty" bson:"replaced_by,omitempty"`
+	RevokedAt  *time.Time          `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// IsActive reports whether the refresh token can still be exchanged
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// RevokedToken is an entry in the revocation list. The ID is either an access token
+// "jti" claim or a refresh token family ID ("sid" claim).
+type RevokedToken struct {
+	ID        string    `json:"id" bson:"_id"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at" bson:"revoked_at"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Roles assigned to users
+const (
+	RoleCustomer = "customer"
+	RoleStaff    = "staff"
+	RoleAdmin    = "admin"
+)
+
+// User is a registered customer or back-office user
+type User struct {
+	BaseModel    `bson:",inline"`
+	Email        string   `json:"email" bson:"email"`
+	PasswordHash string   `json:"-" bson:"password_hash"`
+	FirstName    string   `json:"first_name" bson:"first_name"`
+	LastName     string   `json:"last_name" bson:"last_name"`
+	Roles        []string `json:"roles" bson:"roles"`
+	TenantID     string   `json:"tenant_id,omitempty" bson:"tenant_id,omitempty"`
+	// ProfilePicture is the URL of the profile picture
+	ProfilePicture    string            `json:"profile_picture,omitempty" bson:"profile_picture,omitempty"`
+	ShippingAddresses []ShippingAddress `json:"shipping_addresses" bson:"shipping_addresses"`
+	PaymentMethods    []PaymentMethod   `json:"payment_methods" bson:"payment_methods"`
+	// Version is incremented on every profile save to detect concurrent updates
+	Version int `json:"-" bson:"version"`
+}
+
+// ShippingAddress is a saved address of a user. A user with addresses has exactly one default address.
+type ShippingAddress struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	Address   `bson:",inline"`
+	IsDefault bool `json:"is_default" bson:"is_default"`
+}
+
+// PaymentMethod is a saved card of a user, stored as a provider token. A user with
+// payment methods has exactly one default method, which is never an expired card
+// while an unexpired one is saved.
+type PaymentMethod struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id"`
+	Type           string             `json:"type" bson:"type"`
+	Brand          string             `json:"brand" bson:"brand"`
+	LastFourDigits string             `json:"last_four_digits" bson:"last_four_digits"`
+	ExpiryMonth    int                `json:"expiry_month" bson:"expiry_month"`
+	ExpiryYear     int                `json:"expiry_year" bson:"expiry_year"`
+	Token          string             `json:"-" bson:"token"`
+	IsDefault      bool               `json:"is_default" bson:"is_default"`
+	// Expired is set once the card expiry date has passed
+	Expired   bool      `json:"expired" bson:"expired"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// FindShippingAddress returns the saved address with the given ID
+func (u *User) FindShippingAddress(id primitive.ObjectID) (*ShippingAddress, bool) {
+	for i := range u.ShippingAddresses {
+		if u.ShippingAddresses[i].ID == id {
+			return &u.ShippingAddresses[i], true
+		}
+	}
+	return nil, false
+}
+
+// FindPaymentMethod returns the saved payment method with the given ID
+func (u *User) FindPaymentMethod(id primitive.ObjectID) (*PaymentMethod, bool) {
+	for i := range u.PaymentMethods {
+		if u.PaymentMethods[i].ID == id {
+			return &u.PaymentMethods[i], true
+		}
+	}
+	return nil, false
+}
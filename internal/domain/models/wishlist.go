@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Wishlist is a named list of products a user wants to keep an eye on. Every user
+// has a default wishlist. A wishlist can be shared read-only through a link token;
+// only the SHA-256 hash of the token is persisted.
+type Wishlist struct {
+	BaseModel      `bson:",inline"`
+	UserID         string         `json:"user_id" bson:"user_id"`
+	Name           string         `json:"name" bson:"name"`
+	IsDefault      bool           `json:"is_default" bson:"is_default"`
+	Items          []WishlistItem `json:"items" bson:"items"`
+	ShareTokenHash string         `json:"-" bson:"share_token_hash,omitempty"`
+	// Version is incremented on every save to detect concurrent updates
+	Version int `json:"-" bson:"version"`
+	// ShareToken is the share link token, only set when the wishlist was just shared
+	ShareToken string `json:"share_token,omitempty" bson:"-"`
+}
+
+// WishlistItem is a product of a wishlist with the price and availability it had
+// when it was added
+type WishlistItem struct {
+	ProductID        primitive.ObjectID `json:"product_id" bson:"product_id"`
+	PriceWhenAdded   float64            `json:"price_when_added" bson:"price_when_added"`
+	InStockWhenAdded bool               `json:"in_stock_when_added" bson:"in_stock_when_added"`
+	AddedAt          time.Time          `json:"added_at" bson:"added_at"`
+	// Product is the current catalog product, loaded with the wishlist and nil when
+	// the product was removed from the catalog
+	Product *Product `json:"product,omitempty" bson:"-"`
+}
+
+// Shared reports whether the wishlist can be read through a share link
+func (w *Wishlist) Shared() bool {
+	return w.ShareTokenHash != ""
+}
+
+// Products returns the loaded products of the items still in the catalog
+func (w *Wishlist) Products() []Product {
+	products := make([]Product, 0, len(w.Items))
+	for _, item := range w.Items {
+		if item.Product != nil {
+			products = append(products, *item.Product)
+		}
+	}
+	return products
+}
+
+// FindItem returns the item of a product
+func (w *Wishlist) FindItem(productID primitive.ObjectID) (*WishlistItem, bool) {
+	for i := range w.Items {
+		if w.Items[i].ProductID == productID {
+			return &w.Items[i], true
+		}
+	}
+	return nil, false
+}
+
+// PriceDrop returns how much cheaper the product is than when it was added, nil
+// when the price didn't drop
+func (i *WishlistItem) PriceDrop() *float64 {
+	if i.Product == nil || i.Product.Price >= i.PriceWhenAdded {
+		return nil
+	}
+	drop := roundPrice(i.PriceWhenAdded - i.Product.Price)
+	return &drop
+}
+
+// PriceDropped reports whether the product is cheaper than when it was added
+func (i *WishlistItem) PriceDropped() bool {
+	return i.PriceDrop() != nil
+}
+
+// BackInStock reports whether the product was out of stock when it was added and can be ordered now
+func (i *WishlistItem) BackInStock() bool {
+	return !i.InStockWhenAdded && i.Product != nil && i.Product.InStock()
+}
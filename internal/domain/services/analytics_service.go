@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/logger"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxPopularProducts bounds the number of products ranked by PopularProducts
+const maxPopularProducts = 100
+
+// AnalyticsRepository stores product events and their hourly and daily rollups
+type AnalyticsRepository interface {
+	RecordEvents(ctx context.Context, events []models.ProductEvent) error
+	RollupHours(ctx context.Context, from, to time.Time) error
+	RollupDays(ctx context.Context, from, to time.Time) error
+	RollupWatermark(ctx context.Context) (time.Time, error)
+	SetRollupWatermark(ctx context.Context, at time.Time) error
+	TopProducts(ctx context.Context, granularity models.StatsGranularity, since time.Time, limit int) ([]models.PopularProduct, error)
+}
+
+// AnalyticsService records product views and purchases and ranks popular products.
+// Raw events are rolled up into hourly and daily buckets in the background and
+// rankings are only ever computed from the buckets.
+type AnalyticsService struct {
+	analytics AnalyticsRepository
+	products  CartProductRepository
+	now       func() time.Time
+}
+
+// NewAnalyticsService creates a new AnalyticsService
+func NewAnalyticsService(analytics AnalyticsRepository, products CartProductRepository) *AnalyticsService {
+	return &AnalyticsService{
+		analytics: analytics,
+		products:  products,
+		now:       time.Now,
+	}
+}
+
+// RecordView records a view of a product. Failures are logged rather than returned
+// so they never fail the request showing the product.
+func (s *AnalyticsService) RecordView(ctx context.Context, productID primitive.ObjectID, userID string) {
+	event := models.NewProductEvent(productID, models.ProductViewed, 1, userID, s.now())
+	if err := s.analytics.RecordEvents(ctx, []models.ProductEvent{event}); err != nil {
+		logger.Error("Failed to record view of product "+productID.Hex(), err)
+	}
+}
+
+// RecordPurchase records the units of each product bought with an order
+func (s *AnalyticsService) RecordPurchase(ctx context.Context, order *models.Order) error {
+	at := s.now()
+	events := make([]models.ProductEvent, 0, len(order.Items))
+	for _, item := range order.Items {
+		events = append(events, models.NewProductEvent(item.ProductID, models.ProductPurchased, item.Quantity, order.UserID, at))
+	}
+	return s.analytics.RecordEvents(ctx, events)
+}
+
+// RecordPurchaseOnPayment returns a hook recording the purchases of an order once it is paid
+func RecordPurchaseOnPayment(analytics *AnalyticsService) OrderHook {
+	return func(ctx context.Context, order *models.Order, change models.OrderStatusChange) error {
+		return analytics.RecordPurchase(ctx, order)
+	}
+}
+
+// Rollup rolls the events recorded since the last rollup into hourly buckets, then
+// the touched hourly buckets into daily buckets. The current hour is rolled up while
+// still open and recomputed by the next rollup, as is the hour before the watermark
+// so events written while the previous rollup ran are counted.
+func (s *AnalyticsService) Rollup(ctx context.Context) error {
+	watermark, err := s.analytics.RollupWatermark(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := s.now().UTC()
+	currentHour := now.Truncate(time.Hour)
+	from := watermark.UTC().Truncate(time.Hour).Add(-time.Hour)
+	if watermark.IsZero() {
+		from = time.Time{}
+	}
+	to := currentHour.Add(time.Hour)
+
+	if err := s.analytics.RollupHours(ctx, from, to); err != nil {
+		return err
+	}
+	if err := s.analytics.RollupDays(ctx, startOfDay(from), to); err != nil {
+		return err
+	}
+	return s.analytics.SetRollupWatermark(ctx, currentHour)
+}
+
+// RunRollups rolls up product events every interval until the context is cancelled
+func (s *AnalyticsService) RunRollups(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Rollup(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				logger.Error("Failed to roll up product events", err)
+			}
+		}
+	}
+}
+
+// PopularProducts returns up to limit products ranked by purchases, then views, over
+// a period. The last day is ranked from hourly buckets and longer periods from
+// daily buckets. Products deleted since are left out.
+func (s *AnalyticsService) PopularProducts(ctx context.Context, limit int, period models.TimePeriod) ([]models.PopularProduct, error) {
+	if limit < 1 || limit > maxPopularProducts {
+		return nil, validator.ValidationErrors{{Field: "limit", Message: fmt.Sprintf("limit must be between 1 and %d", maxPopularProducts)}}
+	}
+
+	now := s.now().UTC()
+	granularity := models.StatsDaily
+	var since time.Time
+	switch period {
+	case models.PeriodDay:
+		granularity = models.StatsHourly
+		since = now.Truncate(time.Hour).Add(-23 * time.Hour)
+	case models.PeriodWeek:
+		since = startOfDay(now).AddDate(0, 0, -6)
+	case models.PeriodMonth:
+		since = startOfDay(now).AddDate(0, 0, -29)
+	case models.PeriodAllTime:
+	default:
+		return nil, validator.ValidationErrors{{Field: "period", Message: "unknown period"}}
+	}
+
+	// Fetch more of the ranking when deleted products leave the page short
+	for fetch := limit; ; fetch *= 2 {
+		ranked, err := s.analytics.TopProducts(ctx, granularity, since, fetch)
+		if err != nil {
+			return nil, err
+		}
+		popular, err := s.withProducts(ctx, ranked)
+		if err != nil {
+			return nil, err
+		}
+		if len(popular) >= limit || len(ranked) < fetch || fetch >= 4*maxPopularProducts {
+			if len(popular) > limit {
+				popular = popular[:limit]
+			}
+			return popular, nil
+		}
+	}
+}
+
+// withProducts attaches the products to ranked entries, leaving out deleted products
+func (s *AnalyticsService) withProducts(ctx context.Context, ranked []models.PopularProduct) ([]models.PopularProduct, error) {
+	ids := make([]primitive.ObjectID, len(ranked))
+	for i := range ranked {
+		ids[i] = ranked[i].ProductID
+	}
+	found, err := s.products.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	products := make(map[primitive.ObjectID]*models.Product, len(found))
+	for i := range found {
+		products[found[i].ID] = &found[i]
+	}
+
+	popular := make([]models.PopularProduct, 0, len(ranked))
+	for _, entry := range ranked {
+		if product, ok := products[entry.ProductID]; ok {
+			entry.Product = product
+			popular = append(popular, entry)
+		}
+	}
+	return popular, nil
+}
+
+// startOfDay returns midnight UTC of the day of t
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,189 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// statsKey identifies a rollup bucket of a product
+type statsKey struct {
+	productID primitive.ObjectID
+	bucket    time.Time
+}
+
+// memoryAnalyticsRepository is an in-memory services.AnalyticsRepository. Like the
+// MongoDB rollups, it replaces whole buckets when rolling up.
+type memoryAnalyticsRepository struct {
+	events    []models.ProductEvent
+	hourly    map[statsKey]models.PopularProduct
+	daily     map[statsKey]models.PopularProduct
+	watermark time.Time
+}
+
+func newMemoryAnalyticsRepository() *memoryAnalyticsRepository {
+	return &memoryAnalyticsRepository{
+		hourly: map[statsKey]models.PopularProduct{},
+		daily:  map[statsKey]models.PopularProduct{},
+	}
+}
+
+func (r *memoryAnalyticsRepository) RecordEvents(ctx context.Context, events []models.ProductEvent) error {
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func (r *memoryAnalyticsRepository) RollupHours(ctx context.Context, from, to time.Time) error {
+	buckets := map[statsKey]models.PopularProduct{}
+	for _, event := range r.events {
+		if event.Hour.Before(from) || !event.Hour.Before(to) {
+			continue
+		}
+		key := statsKey{event.ProductID, event.Hour}
+		stats := buckets[key]
+		stats.ProductID = event.ProductID
+		if event.Type == models.ProductViewed {
+			stats.ViewCount += event.Quantity
+		} else {
+			stats.PurchaseCount += event.Quantity
+		}
+		buckets[key] = stats
+	}
+	for key, stats := range buckets {
+		r.hourly[key] = stats
+	}
+	return nil
+}
+
+func (r *memoryAnalyticsRepository) RollupDays(ctx context.Context, from, to time.Time) error {
+	buckets := map[statsKey]models.PopularProduct{}
+	for key, hour := range r.hourly {
+		day := time.Date(key.bucket.Year(), key.bucket.Month(), key.bucket.Day(), 0, 0, 0, 0, time.UTC)
+		if day.Before(from) || !day.Before(to) {
+			continue
+		}
+		dayKey := statsKey{key.productID, day}
+		stats := buckets[dayKey]
+		stats.ProductID = key.productID
+		stats.ViewCount += hour.ViewCount
+		stats.PurchaseCount += hour.PurchaseCount
+		buckets[dayKey] = stats
+	}
+	for key, stats := range buckets {
+		r.daily[key] = stats
+	}
+	return nil
+}
+
+func (r *memoryAnalyticsRepository) RollupWatermark(ctx context.Context) (time.Time, error) {
+	return r.watermark, nil
+}
+
+func (r *memoryAnalyticsRepository) SetRollupWatermark(ctx context.Context, at time.Time) error {
+	r.watermark = at
+	return nil
+}
+
+func (r *memoryAnalyticsRepository) TopProducts(ctx context.Context, granularity models.StatsGranularity, since time.Time, limit int) ([]models.PopularProduct, error) {
+	buckets := r.daily
+	if granularity == models.StatsHourly {
+		buckets = r.hourly
+	}
+	totals := map[primitive.ObjectID]*models.PopularProduct{}
+	for key, stats := range buckets {
+		if key.bucket.Before(since) {
+			continue
+		}
+		total, ok := totals[key.productID]
+		if !ok {
+			total = &models.PopularProduct{ProductID: key.productID}
+			totals[key.productID] = total
+		}
+		total.ViewCount += stats.ViewCount
+		total.PurchaseCount += stats.PurchaseCount
+	}
+
+	ranked := []models.PopularProduct{}
+	for _, total := range totals {
+		ranked = append(ranked, *total)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].PurchaseCount != ranked[j].PurchaseCount {
+			return ranked[i].PurchaseCount > ranked[j].PurchaseCount
+		}
+		return ranked[i].ViewCount > ranked[j].ViewCount
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked, nil
+}
+
+func TestPopularProductsRankFromRollups(t *testing.T) {
+	ctx := context.Background()
+	catalog := memoryCatalog{}
+	mug := catalog.add("MUG", 10, 5)
+	hat := catalog.add("HAT", 15, 5)
+	gone := catalog.add("GONE", 5, 5)
+	repo := newMemoryAnalyticsRepository()
+	svc := services.NewAnalyticsService(repo, catalog)
+
+	svc.RecordView(ctx, mug.ID, "")
+	svc.RecordView(ctx, mug.ID, "user-1")
+	svc.RecordView(ctx, hat.ID, "")
+	order := &models.Order{UserID: "user-1", Items: []models.OrderItem{{ProductID: hat.ID, Quantity: 2}, {ProductID: gone.ID, Quantity: 9}}}
+	if err := svc.RecordPurchase(ctx, order); err != nil {
+		t.Fatalf("RecordPurchase: %v", err)
+	}
+	// Purchases from ten days ago only count towards the month and all time
+	old := time.Now().AddDate(0, 0, -10)
+	_ = repo.RecordEvents(ctx, []models.ProductEvent{models.NewProductEvent(mug.ID, models.ProductPurchased, 5, "user-2", old)})
+	delete(catalog, gone.ID)
+
+	popular, err := svc.PopularProducts(ctx, 10, models.PeriodDay)
+	if err != nil {
+		t.Fatalf("PopularProducts: %v", err)
+	}
+	if len(popular) != 0 {
+		t.Fatalf("got %d popular products before the rollup, want none", len(popular))
+	}
+
+	if err := svc.Rollup(ctx); err != nil {
+		t.Fatalf("Rollup: %v", err)
+	}
+	// Rolling up again recomputes the open hour without double counting
+	if err := svc.Rollup(ctx); err != nil {
+		t.Fatalf("Rollup: %v", err)
+	}
+
+	popular, err = svc.PopularProducts(ctx, 10, models.PeriodWeek)
+	if err != nil {
+		t.Fatalf("PopularProducts: %v", err)
+	}
+	if len(popular) != 2 || popular[0].Product.ID != hat.ID || popular[1].Product.ID != mug.ID {
+		t.Fatalf("weekly ranking = %+v, want the hat then the mug", popular)
+	}
+	if popular[0].PurchaseCount != 2 || popular[0].ViewCount != 1 || popular[1].PurchaseCount != 0 || popular[1].ViewCount != 2 {
+		t.Fatalf("weekly counts = %+v, want 2 purchases and 1 view, then 2 views", popular)
+	}
+
+	popular, err = svc.PopularProducts(ctx, 1, models.PeriodMonth)
+	if err != nil {
+		t.Fatalf("PopularProducts: %v", err)
+	}
+	if len(popular) != 1 || popular[0].Product.ID != mug.ID || popular[0].PurchaseCount != 5 {
+		t.Fatalf("monthly ranking = %+v, want the mug with 5 purchases", popular)
+	}
+
+	var errs validator.ValidationErrors
+	if _, err := svc.PopularProducts(ctx, 0, models.PeriodDay); !errors.As(err, &errs) {
+		t.Fatalf("limit 0: got %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned by the AuthService
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, session revoked")
+)
+
+// TokenRepository is the storage used by the AuthService
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	FindRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id, replacedBy primitive.ObjectID, now time.Time) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID primitive.ObjectID, now time.Time) error
+	RevokeToken(ctx context.Context, id string, expiresAt, now time.Time) error
+	IsTokenRevoked(ctx context.Context, ids ...string) (bool, error)
+}
+
+// TokenUserRepository looks up the users tokens are refreshed for
+type TokenUserRepository interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+}
+
+// TokenSubject describes the principal tokens are issued for
+type TokenSubject struct {
+	UserID   string
+	Roles    []string
+	TenantID string
+}
+
+// TokenPair is a short-lived access token together with its refresh token
+type TokenPair struct {
+	UserID           string
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// AuthService issues, rotates and revokes tokens
+type AuthService struct {
+	tokens     TokenRepository
+	users      TokenUserRepository
+	signer     *auth.Signer
+	refreshTTL time.Duration
+}
+
+// NewAuthService creates a new AuthService
+func NewAuthService(tokens TokenRepository, users TokenUserRepository, signer *auth.Signer, refreshTTL time.Duration) *AuthService {
+	return &AuthService{
+		tokens:     tokens,
+		users:      users,
+		signer:     signer,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// IssueTokens starts a new session for the subject and returns its first token pair
+func (s *AuthService) IssueTokens(ctx context.Context, subject TokenSubject) (*TokenPair, error) {
+	return s.issue(ctx, subject, primitive.NewObjectID(), primitive.NewObjectID(), time.Now())
+}
+
+// Refresh exchanges a refresh token for a new token pair of the same session.
+// Presenting a refresh token that was already exchanged revokes the whole session.
+// The new tokens carry the current roles of the user, and the session ends when
+// the user no longer exists.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	now := time.Now()
+
+	stored, err := s.tokens.FindRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if stored.UsedAt != nil {
+		s.revokeSession(ctx, stored.FamilyID, now)
+		return nil, ErrRefreshTokenReused
+	}
+	if !stored.IsActive(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.findUser(ctx, stored.UserID)
+	if errors.Is(err, models.ErrNotFound) {
+		if err := s.revokeFamily(ctx, stored.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Claim the token before issuing its replacement so that concurrent refreshes
+	// with the same token can't both succeed
+	replacementID := primitive.NewObjectID()
+	ok, err := s.tokens.MarkRefreshTokenUsed(ctx, stored.ID, replacementID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.revokeSession(ctx, stored.FamilyID, now)
+		return nil, ErrRefreshTokenReused
+	}
+
+	return s.issue(ctx, tokenSubject(user), stored.FamilyID, replacementID, now)
+}
+
+// Logout revokes the session of the given access token claims and/or refresh token
+func (s *AuthService) Logout(ctx context.Context, claims *auth.Claims, refreshToken string) error {
+	now := time.Now()
+
+	if claims != nil {
+		if claims.ID != "" && claims.ExpiresAt != nil {
+			if err := s.tokens.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time, now); err != nil {
+				return err
+			}
+		}
+		if familyID, err := primitive.ObjectIDFromHex(claims.SessionID); err == nil {
+			if err := s.revokeFamily(ctx, familyID, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	if refreshToken != "" {
+		stored, err := s.tokens.FindRefreshTokenByHash(ctx, hashToken(refreshToken))
+		if errors.Is(err, models.ErrNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		if claims != nil && claims.UserID() != stored.UserID {
+			return ErrInvalidRefreshToken
+		}
+		if err := s.revokeFamily(ctx, stored.FamilyID, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsRevoked reports whether the access token or its session was revoked
+func (s *AuthService) IsRevoked(ctx context.Context, claims *auth.Claims) (bool, error) {
+	ids := make([]string, 0, 2)
+	if claims.ID != "" {
+		ids = append(ids, claims.ID)
+	}
+	if claims.SessionID != "" {
+		ids = append(ids, claims.SessionID)
+	}
+	return s.tokens.IsTokenRevoked(ctx, ids...)
+}
+
+// findUser returns the user a refresh token was issued for
+func (s *AuthService) findUser(ctx context.Context, userID string) (*models.User, error) {
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, models.ErrNotFound
+	}
+	return s.users.FindByID(ctx, id)
+}
+
+// issue signs an access token and stores a new refresh token in the given family
+func (s *AuthService) issue(ctx context.Context, subject TokenSubject, familyID, refreshID primitive.ObjectID, now time.Time) (*TokenPair, error) {
+	refreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	stored := &models.RefreshToken{
+		UserID:    subject.UserID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		Roles:     subject.Roles,
+		TenantID:  subject.TenantID,
+		ExpiresAt: now.Add(s.refreshTTL),
+	}
+	stored.ID = refreshID
+	if err := s.tokens.CreateRefreshToken(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	claims := &auth.Claims{
+		Roles:     subject.Roles,
+		TenantID:  subject.TenantID,
+		SessionID: familyID.Hex(),
+	}
+	claims.Subject = subject.UserID
+	claims.ID = primitive.NewObjectID().Hex()
+
+	accessToken, err := s.signer.Sign(claims, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		UserID:           subject.UserID,
+		AccessToken:      accessToken,
+		AccessExpiresAt:  claims.ExpiresAt.Time,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+// revokeFamily revokes the refresh tokens of a family and the access tokens issued from it
+func (s *AuthService) revokeFamily(ctx context.Context, familyID primitive.ObjectID, now time.Time) error {
+	if err := s.tokens.RevokeRefreshTokenFamily(ctx, familyID, now); err != nil {
+		return err
+	}
+	// Access tokens of the family expire at the latest one access token lifetime from now
+	return s.tokens.RevokeToken(ctx, familyID.Hex(), now.Add(s.signer.TTL()), now)
+}
+
+// revokeSession revokes a family after refresh token reuse, logging instead of failing
+func (s *AuthService) revokeSession(ctx context.Context, familyID primitive.ObjectID, now time.Time) {
+	logger.Warn("Refresh token reuse detected, revoking session " + familyID.Hex())
+	if err := s.revokeFamily(ctx, familyID, now); err != nil {
+		logger.Error("Failed to revoke session after refresh token reuse", err)
+	}
+}
+
+// generateOpaqueToken returns a random URL-safe token
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the SHA-256 hash of an opaque token as stored in the database
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
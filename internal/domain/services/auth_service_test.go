@@ -0,0 +1,210 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/config"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryTokenRepository is an in-memory services.TokenRepository
+type memoryTokenRepository struct {
+	mu      sync.Mutex
+	tokens  map[primitive.ObjectID]*models.RefreshToken
+	revoked map[string]time.Time
+}
+
+func newMemoryTokenRepository() *memoryTokenRepository {
+	return &memoryTokenRepository{
+		tokens:  make(map[primitive.ObjectID]*models.RefreshToken),
+		revoked: make(map[string]time.Time),
+	}
+}
+
+func (r *memoryTokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token.BeforeCreate()
+	copied := *token
+	r.tokens[token.ID] = &copied
+	return nil
+}
+
+func (r *memoryTokenRepository) FindRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+func (r *memoryTokenRepository) MarkRefreshTokenUsed(ctx context.Context, id, replacedBy primitive.ObjectID, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+	token.UsedAt = &now
+	token.ReplacedBy = &replacedBy
+	return true, nil
+}
+
+func (r *memoryTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID primitive.ObjectID, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *memoryTokenRepository) RevokeToken(ctx context.Context, id string, expiresAt, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revoked[id] = expiresAt
+	return nil
+}
+
+func (r *memoryTokenRepository) IsTokenRevoked(ctx context.Context, ids ...string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		if expiresAt, ok := r.revoked[id]; ok && time.Now().Before(expiresAt) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func newTestAuthService(t *testing.T) (*services.AuthService, *auth.Verifier, *memoryUserRepository) {
+	t.Helper()
+	cfg := config.AuthConfig{
+		Algorithm:      "HS256",
+		Secret:         "test-secret",
+		AccessTokenTTL: time.Minute,
+	}
+	signer, err := auth.NewSigner(cfg)
+	if err != nil {
+		t.Fatalf("NewSigner returned error: %v", err)
+	}
+	verifier, err := auth.NewVerifier(cfg)
+	if err != nil {
+		t.Fatalf("NewVerifier returned error: %v", err)
+	}
+	users := newMemoryUserRepository()
+	return services.NewAuthService(newMemoryTokenRepository(), users, signer, time.Hour), verifier, users
+}
+
+func TestAuthServiceRefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	svc, verifier, users := newTestAuthService(t)
+	userID := users.add(models.RoleCustomer)
+
+	first, err := svc.IssueTokens(ctx, services.TokenSubject{UserID: userID, Roles: []string{models.RoleCustomer}})
+	if err != nil {
+		t.Fatalf("IssueTokens returned error: %v", err)
+	}
+	claims, err := verifier.VerifyToken(first.AccessToken)
+	if err != nil {
+		t.Fatalf("issued access token does not verify: %v", err)
+	}
+	if claims.UserID() != userID || claims.SessionID == "" || claims.ID == "" {
+		t.Errorf("unexpected access token claims: %+v", claims)
+	}
+
+	second, err := svc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("expected refresh token to rotate")
+	}
+
+	// Reusing the rotated token revokes the whole family, including the new token
+	if _, err := svc.Refresh(ctx, first.RefreshToken); !errors.Is(err, services.ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+	if _, err := svc.Refresh(ctx, second.RefreshToken); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Errorf("expected revoked family token to be invalid, got %v", err)
+	}
+	if revoked, _ := svc.IsRevoked(ctx, claims); !revoked {
+		t.Error("expected access tokens of the revoked family to be revoked")
+	}
+
+	if _, err := svc.Refresh(ctx, "unknown-token"); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Errorf("expected ErrInvalidRefreshToken for unknown token, got %v", err)
+	}
+}
+
+func TestAuthServiceLogout(t *testing.T) {
+	ctx := context.Background()
+	svc, verifier, users := newTestAuthService(t)
+
+	pair, err := svc.IssueTokens(ctx, services.TokenSubject{UserID: users.add(models.RoleCustomer)})
+	if err != nil {
+		t.Fatalf("IssueTokens returned error: %v", err)
+	}
+	claims, err := verifier.VerifyToken(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("issued access token does not verify: %v", err)
+	}
+
+	if err := svc.Logout(ctx, claims, ""); err != nil {
+		t.Fatalf("Logout returned error: %v", err)
+	}
+	if revoked, _ := svc.IsRevoked(ctx, claims); !revoked {
+		t.Error("expected access token to be revoked after logout")
+	}
+	if _, err := svc.Refresh(ctx, pair.RefreshToken); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Errorf("expected refresh token to be revoked after logout, got %v", err)
+	}
+}
+
+func TestAuthServiceRefreshReloadsUser(t *testing.T) {
+	ctx := context.Background()
+	svc, verifier, users := newTestAuthService(t)
+	userID := users.add(models.RoleStaff)
+
+	pair, err := svc.IssueTokens(ctx, services.TokenSubject{UserID: userID, Roles: []string{models.RoleStaff}})
+	if err != nil {
+		t.Fatalf("IssueTokens returned error: %v", err)
+	}
+
+	// Demoting the user takes effect on the next refresh
+	id, _ := primitive.ObjectIDFromHex(userID)
+	users.users[id].Roles = []string{models.RoleCustomer}
+	pair, err = svc.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	claims, err := verifier.VerifyToken(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("refreshed access token does not verify: %v", err)
+	}
+	if claims.HasRole(models.RoleStaff) || !claims.HasRole(models.RoleCustomer) {
+		t.Errorf("expected the current roles of the user, got %v", claims.Roles)
+	}
+
+	// Deleting the user ends the session
+	delete(users.users, id)
+	if _, err := svc.Refresh(ctx, pair.RefreshToken); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Fatalf("expected ErrInvalidRefreshToken for a deleted user, got %v", err)
+	}
+	if revoked, _ := svc.IsRevoked(ctx, claims); !revoked {
+		t.Error("expected the session of a deleted user to be revoked")
+	}
+}
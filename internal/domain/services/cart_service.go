@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned by the CartService
+var (
+	// ErrCartItemNotFound is returned when a cart has no item with the given ID
+	ErrCartItemNotFound = fmt.Errorf("cart item %w", models.ErrNotFound)
+	// ErrProductUnavailable is returned when a product can't be added to a cart
+	ErrProductUnavailable = fmt.Errorf("%w: product is not available in the requested quantity", models.ErrInsufficientStock)
+)
+
+// maxCartItemQuantity caps the quantity of a single cart line
+const maxCartItemQuantity = 99
+
+// saveRetries is the number of times a cart change is retried after a concurrent update
+const saveRetries = 3
+
+// CartRepository is the storage used by the CartService
+type CartRepository interface {
+	Create(ctx context.Context, cart *models.Cart) error
+	FindByUserID(ctx context.Context, userID string) (*models.Cart, error)
+	FindByTokenHash(ctx context.Context, tokenHash string) (*models.Cart, error)
+	Save(ctx context.Context, cart *models.Cart) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// CartProductRepository is the catalog lookup used to price carts
+type CartProductRepository interface {
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Product, error)
+}
+
+// CartOwner identifies a cart by the signed in user or, for guests, by the cart token
+type CartOwner struct {
+	UserID string
+	Token  string
+}
+
+// IsZero reports whether the owner identifies no cart
+func (o CartOwner) IsZero() bool {
+	return o.UserID == "" && o.Token == ""
+}
+
+// CartService manages shopping carts. Item prices are snapshots of the catalog
+// price, refreshed every time a cart is loaded so changes are flagged to the buyer.
+type CartService struct {
+	carts    CartRepository
+	products CartProductRepository
+	guestTTL time.Duration
+	userTTL  time.Duration
+	now      func() time.Time
+}
+
+// NewCartService creates a new CartService. Guest and user carts are removed after
+// guestTTL and userTTL without changes.
+func NewCartService(carts CartRepository, products CartProductRepository, guestTTL, userTTL time.Duration) *CartService {
+	return &CartService{
+		carts:    carts,
+		products: products,
+		guestTTL: guestTTL,
+		userTTL:  userTTL,
+		now:      time.Now,
+	}
+}
+
+// GetCart returns the re-priced cart of an owner, or nil when the owner has none
+func (s *CartService) GetCart(ctx context.Context, owner CartOwner) (*models.Cart, error) {
+	if owner.IsZero() {
+		return nil, nil
+	}
+
+	var cart *models.Cart
+	err := retryOnConflict(func() error {
+		var err error
+		cart, err = s.find(ctx, owner)
+		if err != nil {
+			return err
+		}
+		changed, err := s.reprice(ctx, cart)
+		if err != nil || !changed {
+			return err
+		}
+		return s.carts.Save(ctx, cart)
+	})
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+	return cart, err
+}
+
+// AddItem adds quantity units of a product to the cart of an owner, creating the
+// cart when needed. Guests without a token get a new cart carrying a fresh token.
+func (s *CartService) AddItem(ctx context.Context, owner CartOwner, productID primitive.ObjectID, quantity int) (*models.Cart, error) {
+	if quantity <= 0 {
+		return nil, validator.ValidationErrors{{Field: "quantity", Message: "quantity must be positive"}}
+	}
+
+	return s.update(ctx, owner, true, func(cart *models.Cart, products map[primitive.ObjectID]*models.Product) error {
+		product, ok := products[productID]
+		if !ok {
+			found, err := s.products.FindByIDs(ctx, []primitive.ObjectID{productID})
+			if err != nil {
+				return err
+			}
+			if len(found) == 0 {
+				return models.ErrNotFound
+			}
+			product = &found[0]
+		}
+
+		for i := range cart.Items {
+			item := &cart.Items[i]
+			if item.ProductID == productID {
+				return setQuantity(item, product, item.Quantity+quantity)
+			}
+		}
+
+		item := models.CartItem{
+			ID:        primitive.NewObjectID(),
+			ProductID: product.ID,
+			AddedAt:   s.now(),
+		}
+		applyProduct(&item, product)
+		if err := setQuantity(&item, product, quantity); err != nil {
+			return err
+		}
+		cart.Items = append(cart.Items, item)
+		return nil
+	})
+}
+
+// UpdateItem sets the quantity of a cart item, removing it when quantity is zero.
+// The new quantity acknowledges any price change of the item.
+func (s *CartService) UpdateItem(ctx context.Context, owner CartOwner, itemID primitive.ObjectID, quantity int) (*models.Cart, error) {
+	if quantity < 0 {
+		return nil, validator.ValidationErrors{{Field: "quantity", Message: "quantity cannot be negative"}}
+	}
+	if quantity == 0 {
+		return s.RemoveItem(ctx, owner, itemID)
+	}
+
+	return s.update(ctx, owner, false, func(cart *models.Cart, products map[primitive.ObjectID]*models.Product) error {
+		item, ok := cart.FindItem(itemID)
+		if !ok {
+			return ErrCartItemNotFound
+		}
+		product, ok := products[item.ProductID]
+		if !ok {
+			return ErrProductUnavailable
+		}
+		item.PreviousUnitPrice = nil
+		return setQuantity(item, product, quantity)
+	})
+}
+
+// RemoveItem removes an item from the cart of an owner
+func (s *CartService) RemoveItem(ctx context.Context, owner CartOwner, itemID primitive.ObjectID) (*models.Cart, error) {
+	return s.update(ctx, owner, false, func(cart *models.Cart, products map[primitive.ObjectID]*models.Product) error {
+		for i := range cart.Items {
+			if cart.Items[i].ID == itemID {
+				cart.Items = append(cart.Items[:i], cart.Items[i+1:]...)
+				return nil
+			}
+		}
+		return ErrCartItemNotFound
+	})
+}
+
+// ClearCart removes every item from the cart of an owner and returns the cart ID
+func (s *CartService) ClearCart(ctx context.Context, owner CartOwner) (primitive.ObjectID, error) {
+	cart, err := s.update(ctx, owner, false, func(cart *models.Cart, products map[primitive.ObjectID]*models.Product) error {
+		cart.Items = []models.CartItem{}
+		return nil
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return cart.ID, nil
+}
+
+// CompleteCheckout empties a cart the given order was placed from and records the
+// order on it. It fails with models.ErrConflict when the cart changed since it was loaded.
+func (s *CartService) CompleteCheckout(ctx context.Context, cart *models.Cart, orderID primitive.ObjectID) error {
+	cart.Items = []models.CartItem{}
+	cart.CheckoutOrderID = &orderID
+	return s.carts.Save(ctx, cart)
+}
+
+// MergeGuestCart moves the items of a guest cart into the cart of a user who just
+// signed in. Quantities of products in both carts are added up. The guest cart is
+// adopted as the user cart when the user has none.
+func (s *CartService) MergeGuestCart(ctx context.Context, userID, token string) error {
+	return retryOnConflict(func() error {
+		guest, err := s.carts.FindByTokenHash(ctx, hashToken(token))
+		if errors.Is(err, models.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		cart, err := s.carts.FindByUserID(ctx, userID)
+		if errors.Is(err, models.ErrNotFound) {
+			guest.UserID = userID
+			guest.TokenHash = ""
+			guest.ExpiresAt = s.now().Add(s.userTTL)
+			return s.carts.Save(ctx, guest)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, guestItem := range guest.Items {
+			merged := false
+			for i := range cart.Items {
+				if item := &cart.Items[i]; item.ProductID == guestItem.ProductID {
+					item.Quantity = min(item.Quantity+guestItem.Quantity, maxCartItemQuantity)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				cart.Items = append(cart.Items, guestItem)
+			}
+		}
+		if len(guest.Items) > 0 {
+			cart.CheckoutOrderID = nil
+		}
+		cart.ExpiresAt = s.now().Add(s.userTTL)
+		if err := s.carts.Save(ctx, cart); err != nil {
+			return err
+		}
+		return s.carts.Delete(ctx, guest.ID)
+	})
+}
+
+// update loads and re-prices the cart of an owner, applies change and saves the
+// cart, retrying when the cart was changed concurrently
+func (s *CartService) update(ctx context.Context, owner CartOwner, create bool, change func(*models.Cart, map[primitive.ObjectID]*models.Product) error) (*models.Cart, error) {
+	if owner.IsZero() && !create {
+		return nil, models.ErrNotFound
+	}
+
+	var cart *models.Cart
+	err := retryOnConflict(func() error {
+		var err error
+		cart, err = s.find(ctx, owner)
+		if errors.Is(err, models.ErrNotFound) && create {
+			cart, err = s.newCart(owner)
+		}
+		if err != nil {
+			return err
+		}
+
+		products, err := s.loadProducts(ctx, cart)
+		if err != nil {
+			return err
+		}
+		repriceItems(cart, products)
+
+		if err := change(cart, products); err != nil {
+			return err
+		}
+		cart.CheckoutOrderID = nil
+		if cart.UserID != "" {
+			cart.ExpiresAt = s.now().Add(s.userTTL)
+		} else {
+			cart.ExpiresAt = s.now().Add(s.guestTTL)
+		}
+
+		if cart.ID.IsZero() {
+			return s.carts.Create(ctx, cart)
+		}
+		return s.carts.Save(ctx, cart)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+// retryOnConflict runs fn again when it fails with a concurrent update
+func retryOnConflict(fn func() error) error {
+	var err error
+	for attempt := 0; attempt < saveRetries; attempt++ {
+		if err = fn(); !errors.Is(err, models.ErrConflict) && !errors.Is(err, models.ErrDuplicate) {
+			return err
+		}
+	}
+	return err
+}
+
+// find returns the cart of an owner, preferring the user cart
+func (s *CartService) find(ctx context.Context, owner CartOwner) (*models.Cart, error) {
+	if owner.UserID != "" {
+		return s.carts.FindByUserID(ctx, owner.UserID)
+	}
+	if owner.Token != "" {
+		return s.carts.FindByTokenHash(ctx, hashToken(owner.Token))
+	}
+	return nil, models.ErrNotFound
+}
+
+// newCart returns an unsaved cart for an owner. Guest carts always get a new token,
+// a token sent by the client is never adopted.
+func (s *CartService) newCart(owner CartOwner) (*models.Cart, error) {
+	cart := &models.Cart{Items: []models.CartItem{}}
+	if owner.UserID != "" {
+		cart.UserID = owner.UserID
+		return cart, nil
+	}
+
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	cart.Token = token
+	cart.TokenHash = hashToken(token)
+	return cart, nil
+}
+
+// reprice refreshes the item prices and availability of a cart and reports whether it changed
+func (s *CartService) reprice(ctx context.Context, cart *models.Cart) (bool, error) {
+	products, err := s.loadProducts(ctx, cart)
+	if err != nil {
+		return false, err
+	}
+	return repriceItems(cart, products), nil
+}
+
+// loadProducts returns the products of the cart items by ID
+func (s *CartService) loadProducts(ctx context.Context, cart *models.Cart) (map[primitive.ObjectID]*models.Product, error) {
+	ids := make([]primitive.ObjectID, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		ids = append(ids, item.ProductID)
+	}
+
+	found, err := s.products.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	products := make(map[primitive.ObjectID]*models.Product, len(found))
+	for i := range found {
+		products[found[i].ID] = &found[i]
+	}
+	return products, nil
+}
+
+// repriceItems applies the current catalog data to the cart items. Items whose
+// product was removed stay in the cart as unavailable. It reports whether any
+// stored field changed.
+func repriceItems(cart *models.Cart, products map[primitive.ObjectID]*models.Product) bool {
+	changed := false
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		product, ok := products[item.ProductID]
+		if !ok {
+			item.Available = false
+			continue
+		}
+
+		if product.Price != item.UnitPrice {
+			if item.PreviousUnitPrice == nil {
+				previous := item.UnitPrice
+				item.PreviousUnitPrice = &previous
+			}
+			changed = true
+		}
+		if product.Name != item.Name || product.SKU != item.SKU {
+			changed = true
+		}
+		applyProduct(item, product)
+		item.Available = product.Stock >= item.Quantity
+	}
+	return changed
+}
+
+// applyProduct copies the catalog data of a product to a cart item
+func applyProduct(item *models.CartItem, product *models.Product) {
+	item.SKU = product.SKU
+	item.Name = product.Name
+	item.UnitPrice = product.Price
+	if item.PreviousUnitPrice != nil && *item.PreviousUnitPrice == product.Price {
+		item.PreviousUnitPrice = nil
+	}
+}
+
+// setQuantity sets the quantity of a cart item after checking the product stock
+func setQuantity(item *models.CartItem, product *models.Product, quantity int) error {
+	if quantity > maxCartItemQuantity {
+		return validator.ValidationErrors{{Field: "quantity", Message: "at most 99 units of a product can be ordered"}}
+	}
+	if product.Stock < quantity {
+		return ErrProductUnavailable
+	}
+	item.Quantity = quantity
+	item.Available = true
+	return nil
+}
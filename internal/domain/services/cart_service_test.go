@@ -0,0 +1,160 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryCartRepository is an in-memory services.CartRepository
+type memoryCartRepository struct {
+	carts map[primitive.ObjectID]models.Cart
+}
+
+func (r *memoryCartRepository) Create(ctx context.Context, cart *models.Cart) error {
+	cart.BeforeCreate()
+	cart.Version = 1
+	r.carts[cart.ID] = copyCart(cart)
+	return nil
+}
+
+func (r *memoryCartRepository) FindByUserID(ctx context.Context, userID string) (*models.Cart, error) {
+	return r.find(func(cart models.Cart) bool { return cart.UserID == userID })
+}
+
+func (r *memoryCartRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.Cart, error) {
+	return r.find(func(cart models.Cart) bool { return cart.TokenHash == tokenHash })
+}
+
+func (r *memoryCartRepository) Save(ctx context.Context, cart *models.Cart) error {
+	if stored, ok := r.carts[cart.ID]; !ok || stored.Version != cart.Version {
+		return models.ErrConflict
+	}
+	cart.Version++
+	r.carts[cart.ID] = copyCart(cart)
+	return nil
+}
+
+func (r *memoryCartRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	delete(r.carts, id)
+	return nil
+}
+
+func (r *memoryCartRepository) find(match func(models.Cart) bool) (*models.Cart, error) {
+	for _, cart := range r.carts {
+		if match(cart) {
+			copied := copyCart(&cart)
+			return &copied, nil
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+func copyCart(cart *models.Cart) models.Cart {
+	copied := *cart
+	copied.Token = ""
+	copied.Items = append([]models.CartItem{}, cart.Items...)
+	return copied
+}
+
+// memoryCatalog is an in-memory services.CartProductRepository
+type memoryCatalog map[primitive.ObjectID]*models.Product
+
+func (c memoryCatalog) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Product, error) {
+	var products []models.Product
+	for _, id := range ids {
+		if product, ok := c[id]; ok {
+			products = append(products, *product)
+		}
+	}
+	return products, nil
+}
+
+func (c memoryCatalog) add(name string, price float64, stock int) *models.Product {
+	product := &models.Product{Name: name, SKU: name, Price: price, Stock: stock}
+	product.ID = primitive.NewObjectID()
+	c[product.ID] = product
+	return product
+}
+
+func TestCartRepricesItems(t *testing.T) {
+	ctx := context.Background()
+	catalog := memoryCatalog{}
+	mug := catalog.add("MUG", 10, 5)
+	svc := services.NewCartService(&memoryCartRepository{carts: map[primitive.ObjectID]models.Cart{}}, catalog, time.Hour, time.Hour)
+
+	cart, err := svc.AddItem(ctx, services.CartOwner{}, mug.ID, 2)
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+	if cart.Token == "" {
+		t.Fatal("guest cart was created without a token")
+	}
+	guest := services.CartOwner{Token: cart.Token}
+
+	if _, err := svc.AddItem(ctx, guest, mug.ID, 4); !errors.Is(err, services.ErrProductUnavailable) {
+		t.Fatalf("AddItem beyond stock: got %v, want ErrProductUnavailable", err)
+	}
+
+	mug.Price = 12.5
+	cart, err = svc.GetCart(ctx, guest)
+	if err != nil {
+		t.Fatalf("GetCart: %v", err)
+	}
+	item := cart.Items[0]
+	if item.UnitPrice != 12.5 || item.PreviousUnitPrice == nil || *item.PreviousUnitPrice != 10 {
+		t.Fatalf("item priced %v (previous %v), want 12.5 (previous 10)", item.UnitPrice, item.PreviousUnitPrice)
+	}
+	if cart.Subtotal() != 25 || !cart.HasPriceChanges() {
+		t.Fatalf("subtotal %v, price changes %v; want 25, true", cart.Subtotal(), cart.HasPriceChanges())
+	}
+
+	cart, err = svc.UpdateItem(ctx, guest, item.ID, 1)
+	if err != nil {
+		t.Fatalf("UpdateItem: %v", err)
+	}
+	if cart.HasPriceChanges() || cart.ItemCount() != 1 {
+		t.Fatalf("after update: price changes %v, item count %d; want false, 1", cart.HasPriceChanges(), cart.ItemCount())
+	}
+}
+
+func TestCartMergesGuestCartOnLogin(t *testing.T) {
+	ctx := context.Background()
+	catalog := memoryCatalog{}
+	mug := catalog.add("MUG", 10, 50)
+	tee := catalog.add("TEE", 20, 50)
+	repo := &memoryCartRepository{carts: map[primitive.ObjectID]models.Cart{}}
+	svc := services.NewCartService(repo, catalog, time.Hour, time.Hour)
+
+	user := services.CartOwner{UserID: "user-1"}
+	if _, err := svc.AddItem(ctx, user, mug.ID, 1); err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+	guestCart, err := svc.AddItem(ctx, services.CartOwner{}, mug.ID, 2)
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+	if _, err := svc.AddItem(ctx, services.CartOwner{Token: guestCart.Token}, tee.ID, 1); err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	if err := svc.MergeGuestCart(ctx, user.UserID, guestCart.Token); err != nil {
+		t.Fatalf("MergeGuestCart: %v", err)
+	}
+
+	cart, err := svc.GetCart(ctx, user)
+	if err != nil {
+		t.Fatalf("GetCart: %v", err)
+	}
+	if len(cart.Items) != 2 || cart.ItemCount() != 4 {
+		t.Fatalf("merged cart has %d lines and %d units, want 2 and 4", len(cart.Items), cart.ItemCount())
+	}
+	if len(repo.carts) != 1 {
+		t.Fatalf("%d carts stored after merge, want 1", len(repo.carts))
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned by the CategoryService
+var (
+	// ErrCategoryCycle is returned when a category would be moved below itself
+	ErrCategoryCycle = errors.New("a category can't be moved below itself or its descendants")
+	// ErrCategoryNotEmpty is returned when deleting a category with products or children without a reassignment target
+	ErrCategoryNotEmpty = errors.New("category has products or subcategories, provide a category to reassign them to")
+)
+
+// CategoryRepository is the storage used by the CategoryService
+type CategoryRepository interface {
+	Create(ctx context.Context, category *models.Category) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Category, error)
+	FindChildren(ctx context.Context, parentID *primitive.ObjectID) ([]models.Category, error)
+	List(ctx context.Context, includeEmpty bool) ([]models.Category, error)
+	Update(ctx context.Context, category *models.Category) error
+	MoveSubtree(ctx context.Context, category *models.Category, parentID *primitive.ObjectID, ancestors []primitive.ObjectID) error
+	IncrementProductCount(ctx context.Context, ids []primitive.ObjectID, delta int) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// CategoryProductRepository is the product storage used to reassign products of deleted categories
+type CategoryProductRepository interface {
+	ReassignCategory(ctx context.Context, from, to primitive.ObjectID) (int, error)
+}
+
+// CreateCategoryInput holds the data needed to create a category
+type CreateCategoryInput struct {
+	Name        string
+	Description string
+	ParentID    *primitive.ObjectID
+}
+
+// UpdateCategoryInput holds the category fields to change. Nil fields are left unchanged.
+type UpdateCategoryInput struct {
+	Name        *string
+	Description *string
+}
+
+// CategoryService manages the category tree
+type CategoryService struct {
+	categories CategoryRepository
+	products   CategoryProductRepository
+}
+
+// NewCategoryService creates a new CategoryService
+func NewCategoryService(categories CategoryRepository, products CategoryProductRepository) *CategoryService {
+	return &CategoryService{
+		categories: categories,
+		products:   products,
+	}
+}
+
+// GetCategory returns the category with the given ID
+func (s *CategoryService) GetCategory(ctx context.Context, id primitive.ObjectID) (*models.Category, error) {
+	return s.categories.FindByID(ctx, id)
+}
+
+// ListCategories returns every category, optionally leaving out categories without products
+func (s *CategoryService) ListCategories(ctx context.Context, includeEmpty bool) ([]models.Category, error) {
+	return s.categories.List(ctx, includeEmpty)
+}
+
+// GetChildren returns the direct children of a category
+func (s *CategoryService) GetChildren(ctx context.Context, id primitive.ObjectID) ([]models.Category, error) {
+	return s.categories.FindChildren(ctx, &id)
+}
+
+// CreateCategory validates the input and creates a category below the optional parent
+func (s *CategoryService) CreateCategory(ctx context.Context, input CreateCategoryInput) (*models.Category, error) {
+	category := &models.Category{
+		Name:        strings.TrimSpace(input.Name),
+		Description: strings.TrimSpace(input.Description),
+		Ancestors:   []primitive.ObjectID{},
+	}
+	if err := validateCategory(category); err != nil {
+		return nil, err
+	}
+
+	if input.ParentID != nil {
+		parent, err := s.categories.FindByID(ctx, *input.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		category.ParentID = &parent.ID
+		category.Ancestors = parent.Path()
+	}
+
+	if err := s.categories.Create(ctx, category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// UpdateCategory applies the changed fields to a category after validating them
+func (s *CategoryService) UpdateCategory(ctx context.Context, id primitive.ObjectID, input UpdateCategoryInput) (*models.Category, error) {
+	category, err := s.categories.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		category.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		category.Description = strings.TrimSpace(*input.Description)
+	}
+	if err := validateCategory(category); err != nil {
+		return nil, err
+	}
+
+	if err := s.categories.Update(ctx, category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// MoveCategory moves a category and its subtree below a new parent, or to the root for a nil parent
+func (s *CategoryService) MoveCategory(ctx context.Context, id primitive.ObjectID, parentID *primitive.ObjectID) (*models.Category, error) {
+	category, err := s.categories.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.moveSubtree(ctx, category, parentID); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// DeleteCategory deletes a category. Categories with products or subcategories are
+// rejected unless reassignTo is given, in which case the products and subcategories
+// are moved to that category first.
+func (s *CategoryService) DeleteCategory(ctx context.Context, id primitive.ObjectID, reassignTo *primitive.ObjectID) error {
+	category, err := s.categories.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	children, err := s.categories.FindChildren(ctx, &category.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(children) > 0 || category.ProductCount > 0 {
+		if reassignTo == nil {
+			return ErrCategoryNotEmpty
+		}
+		target, err := s.categories.FindByID(ctx, *reassignTo)
+		if err != nil {
+			return err
+		}
+		if target.ID == category.ID || category.IsAncestorOf(target) {
+			return ErrCategoryCycle
+		}
+
+		// Move the subcategories first so the remaining count is the category's own products
+		for i := range children {
+			if err := s.moveSubtree(ctx, &children[i], &target.ID); err != nil {
+				return err
+			}
+		}
+
+		moved, err := s.products.ReassignCategory(ctx, category.ID, target.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.categories.IncrementProductCount(ctx, category.Ancestors, -moved); err != nil {
+			return err
+		}
+		if err := s.categories.IncrementProductCount(ctx, target.Path(), moved); err != nil {
+			return err
+		}
+	}
+
+	return s.categories.Delete(ctx, category.ID)
+}
+
+// moveSubtree re-parents a category, rejecting cycles and moving its product count along
+func (s *CategoryService) moveSubtree(ctx context.Context, category *models.Category, parentID *primitive.ObjectID) error {
+	ancestors := []primitive.ObjectID{}
+	if parentID != nil {
+		parent, err := s.categories.FindByID(ctx, *parentID)
+		if err != nil {
+			return err
+		}
+		if parent.ID == category.ID || category.IsAncestorOf(parent) {
+			return ErrCategoryCycle
+		}
+		ancestors = parent.Path()
+	}
+
+	oldAncestors := category.Ancestors
+	if err := s.categories.MoveSubtree(ctx, category, parentID, ancestors); err != nil {
+		return err
+	}
+
+	if err := s.categories.IncrementProductCount(ctx, oldAncestors, -category.ProductCount); err != nil {
+		return err
+	}
+	return s.categories.IncrementProductCount(ctx, ancestors, category.ProductCount)
+}
+
+// validateCategory validates the category fields
+func validateCategory(category *models.Category) error {
+	if err := validator.ValidateNonEmpty("name", category.Name); err != nil {
+		return validator.ValidationErrors{{Field: "name", Message: err.Error()}}
+	}
+	return nil
+}
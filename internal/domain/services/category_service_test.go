@@ -0,0 +1,170 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryCategoryRepository is an in-memory services.CategoryRepository
+type memoryCategoryRepository struct {
+	categories map[primitive.ObjectID]*models.Category
+}
+
+func newMemoryCategoryRepository() *memoryCategoryRepository {
+	return &memoryCategoryRepository{categories: make(map[primitive.ObjectID]*models.Category)}
+}
+
+func (r *memoryCategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	category.BeforeCreate()
+	copied := *category
+	r.categories[category.ID] = &copied
+	return nil
+}
+
+func (r *memoryCategoryRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Category, error) {
+	category, ok := r.categories[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	copied := *category
+	return &copied, nil
+}
+
+func (r *memoryCategoryRepository) FindChildren(ctx context.Context, parentID *primitive.ObjectID) ([]models.Category, error) {
+	var children []models.Category
+	for _, category := range r.categories {
+		if (parentID == nil && category.ParentID == nil) || (parentID != nil && category.ParentID != nil && *category.ParentID == *parentID) {
+			children = append(children, *category)
+		}
+	}
+	return children, nil
+}
+
+func (r *memoryCategoryRepository) List(ctx context.Context, includeEmpty bool) ([]models.Category, error) {
+	var categories []models.Category
+	for _, category := range r.categories {
+		if includeEmpty || category.ProductCount > 0 {
+			categories = append(categories, *category)
+		}
+	}
+	return categories, nil
+}
+
+func (r *memoryCategoryRepository) Update(ctx context.Context, category *models.Category) error {
+	stored := r.categories[category.ID]
+	stored.Name, stored.Description = category.Name, category.Description
+	return nil
+}
+
+func (r *memoryCategoryRepository) MoveSubtree(ctx context.Context, category *models.Category, parentID *primitive.ObjectID, ancestors []primitive.ObjectID) error {
+	for _, other := range r.categories {
+		for i, id := range other.Ancestors {
+			if id == category.ID {
+				other.Ancestors = append(append([]primitive.ObjectID{}, ancestors...), other.Ancestors[i:]...)
+				break
+			}
+		}
+	}
+	stored := r.categories[category.ID]
+	stored.ParentID, stored.Ancestors = parentID, ancestors
+	category.ParentID, category.Ancestors = parentID, ancestors
+	return nil
+}
+
+func (r *memoryCategoryRepository) IncrementProductCount(ctx context.Context, ids []primitive.ObjectID, delta int) error {
+	for _, id := range ids {
+		if category, ok := r.categories[id]; ok {
+			category.ProductCount += delta
+		}
+	}
+	return nil
+}
+
+func (r *memoryCategoryRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	delete(r.categories, id)
+	return nil
+}
+
+// memoryCategoryProducts counts products per category for reassignment
+type memoryCategoryProducts map[primitive.ObjectID]int
+
+func (p memoryCategoryProducts) ReassignCategory(ctx context.Context, from, to primitive.ObjectID) (int, error) {
+	moved := p[from]
+	p[to] += moved
+	delete(p, from)
+	return moved, nil
+}
+
+func TestCategoryServiceMoveRejectsCycles(t *testing.T) {
+	ctx := context.Background()
+	svc := services.NewCategoryService(newMemoryCategoryRepository(), memoryCategoryProducts{})
+
+	root, err := svc.CreateCategory(ctx, services.CreateCategoryInput{Name: "Electronics"})
+	if err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	child, err := svc.CreateCategory(ctx, services.CreateCategoryInput{Name: "Audio", ParentID: &root.ID})
+	if err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+
+	if _, err := svc.MoveCategory(ctx, root.ID, &child.ID); !errors.Is(err, services.ErrCategoryCycle) {
+		t.Fatalf("moving below a descendant: got %v, want ErrCategoryCycle", err)
+	}
+	if _, err := svc.MoveCategory(ctx, root.ID, &root.ID); !errors.Is(err, services.ErrCategoryCycle) {
+		t.Fatalf("moving below itself: got %v, want ErrCategoryCycle", err)
+	}
+}
+
+func TestCategoryServiceMoveAndDeleteKeepCounts(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryCategoryRepository()
+	products := memoryCategoryProducts{}
+	svc := services.NewCategoryService(repo, products)
+
+	electronics, _ := svc.CreateCategory(ctx, services.CreateCategoryInput{Name: "Electronics"})
+	audio, _ := svc.CreateCategory(ctx, services.CreateCategoryInput{Name: "Audio", ParentID: &electronics.ID})
+	headphones, _ := svc.CreateCategory(ctx, services.CreateCategoryInput{Name: "Headphones", ParentID: &audio.ID})
+	home, _ := svc.CreateCategory(ctx, services.CreateCategoryInput{Name: "Home"})
+
+	// Three products in headphones, counted up the tree
+	headphonesPath := []primitive.ObjectID{electronics.ID, audio.ID, headphones.ID}
+	if err := repo.IncrementProductCount(ctx, headphonesPath, 3); err != nil {
+		t.Fatal(err)
+	}
+	products[headphones.ID] = 3
+
+	if _, err := svc.MoveCategory(ctx, audio.ID, &home.ID); err != nil {
+		t.Fatalf("MoveCategory: %v", err)
+	}
+	assertCounts(t, repo, map[primitive.ObjectID]int{electronics.ID: 0, home.ID: 3, audio.ID: 3, headphones.ID: 3})
+	if got := repo.categories[headphones.ID].Ancestors; len(got) != 2 || got[0] != home.ID || got[1] != audio.ID {
+		t.Fatalf("headphones ancestors = %v, want [home audio]", got)
+	}
+
+	if err := svc.DeleteCategory(ctx, audio.ID, nil); !errors.Is(err, services.ErrCategoryNotEmpty) {
+		t.Fatalf("DeleteCategory without reassignment: got %v, want ErrCategoryNotEmpty", err)
+	}
+	if err := svc.DeleteCategory(ctx, audio.ID, &headphones.ID); !errors.Is(err, services.ErrCategoryCycle) {
+		t.Fatalf("DeleteCategory into own subtree: got %v, want ErrCategoryCycle", err)
+	}
+
+	if err := svc.DeleteCategory(ctx, headphones.ID, &electronics.ID); err != nil {
+		t.Fatalf("DeleteCategory: %v", err)
+	}
+	assertCounts(t, repo, map[primitive.ObjectID]int{electronics.ID: 3, home.ID: 0, audio.ID: 0})
+}
+
+func assertCounts(t *testing.T, repo *memoryCategoryRepository, want map[primitive.ObjectID]int) {
+	t.Helper()
+	for id, count := range want {
+		if got := repo.categories[id].ProductCount; got != count {
+			t.Errorf("%s product count = %d, want %d", repo.categories[id].Name, got, count)
+		}
+	}
+}
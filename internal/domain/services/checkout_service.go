@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned by the CheckoutService
+var (
+	// ErrCartEmpty is returned when checking out a cart without items
+	ErrCartEmpty = fmt.Errorf("%w: the cart is empty", models.ErrConflict)
+	// ErrCartChanged is returned when the cart was changed while the order was placed
+	ErrCartChanged = fmt.Errorf("%w: the cart changed during checkout, review it and try again", models.ErrConflict)
+	// ErrTotalChanged is returned when the order total differs from the total the buyer reviewed
+	ErrTotalChanged = fmt.Errorf("%w: prices changed since the cart was reviewed", models.ErrConflict)
+)
+
+// Transactor runs a function in a database transaction. Repositories called with
+// the context passed to fn take part in the transaction.
+type Transactor interface {
+	// WithTransaction runs fn in a transaction, or directly when the database
+	// doesn't support transactions
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	// InTransaction reports whether ctx carries a transaction, i.e. whether
+	// changes made with it are rolled back when fn fails
+	InTransaction(ctx context.Context) bool
+	// AfterCommit runs fn once the transaction ctx carries commits, and never when
+	// it is rolled back. Without a transaction fn runs right away.
+	AfterCommit(ctx context.Context, fn func(ctx context.Context))
+}
+
+// CheckoutOrderRepository is the order storage used by the CheckoutService
+type CheckoutOrderRepository interface {
+	Create(ctx context.Context, order *models.Order) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Order, error)
+	FindByCheckoutKey(ctx context.Context, key string) (*models.Order, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// CheckoutPricing holds the tax and shipping rules applied to new orders
+type CheckoutPricing struct {
+	// TaxRate is the tax charged on the subtotal, e.g. 0.2 for 20%
+	TaxRate float64
+	// ShippingCost is the flat shipping cost of an order
+	ShippingCost float64
+	// FreeShippingThreshold is the subtotal from which shipping is free, 0 disables free shipping
+	FreeShippingThreshold float64
+}
+
+// shippingCost returns the shipping cost of an order with the given subtotal
+func (p CheckoutPricing) shippingCost(subtotal float64) float64 {
+	if p.FreeShippingThreshold > 0 && subtotal >= p.FreeShippingThreshold {
+		return 0
+	}
+	return p.ShippingCost
+}
+
+// CheckoutInput is the data a buyer provides to place an order
+type CheckoutInput struct {
+	ShippingAddress models.Address
+	// ExpectedTotal is the total the buyer reviewed; the checkout fails when it changed
+	ExpectedTotal *float64
+}
+
+// CheckoutService turns carts into orders. Creating the order, reserving its stock
+// and emptying the cart happen in one transaction when the database supports it;
+// otherwise the completed steps are undone when a later one fails.
+type CheckoutService struct {
+	tx        Transactor
+	orders    CheckoutOrderRepository
+	carts     *CartService
+	inventory *InventoryService
+	pricing   CheckoutPricing
+	now       func() time.Time
+}
+
+// NewCheckoutService creates a new CheckoutService
+func NewCheckoutService(tx Transactor, orders CheckoutOrderRepository, carts *CartService, inventory *InventoryService, pricing CheckoutPricing) *CheckoutService {
+	return &CheckoutService{
+		tx:        tx,
+		orders:    orders,
+		carts:     carts,
+		inventory: inventory,
+		pricing:   pricing,
+		now:       time.Now,
+	}
+}
+
+// PlaceOrder places an order for the cart of a signed in user at the current catalog
+// prices. Submitting the same cart twice returns the order placed the first time.
+func (s *CheckoutService) PlaceOrder(ctx context.Context, userID string, input CheckoutInput) (*models.Order, error) {
+	input.ShippingAddress = normalizeAddress(input.ShippingAddress)
+	if err := validateAddress("shippingAddress", input.ShippingAddress); err != nil {
+		return nil, err
+	}
+
+	cart, err := s.carts.GetCart(ctx, CartOwner{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil || len(cart.Items) == 0 {
+		// The cart was emptied by an earlier submission of this checkout
+		if cart != nil && cart.CheckoutOrderID != nil {
+			return s.orders.FindByID(ctx, *cart.CheckoutOrderID)
+		}
+		return nil, ErrCartEmpty
+	}
+
+	order, err := s.newOrder(cart, userID, input.ShippingAddress)
+	if err != nil {
+		return nil, err
+	}
+	if input.ExpectedTotal != nil && math.Abs(*input.ExpectedTotal-order.Total) >= 0.005 {
+		return nil, ErrTotalChanged
+	}
+
+	err = s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		return s.placeOrder(ctx, cart, order)
+	})
+	if errors.Is(err, models.ErrDuplicate) {
+		// A concurrent submission of the same cart placed the order
+		return s.orders.FindByCheckoutKey(ctx, order.CheckoutKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// newOrder builds a pending order from the items of a cart
+func (s *CheckoutService) newOrder(cart *models.Cart, userID string, address models.Address) (*models.Order, error) {
+	now := s.now()
+	order := &models.Order{
+		UserID:          userID,
+		Items:           make([]models.OrderItem, 0, len(cart.Items)),
+		ShippingAddress: address,
+		Status:          models.OrderPending,
+		StatusHistory:   []models.OrderStatusChange{{To: models.OrderPending, ActorID: userID, At: now}},
+		CheckoutKey:     fmt.Sprintf("%s:%d", cart.ID.Hex(), cart.Version),
+	}
+	// The ID is known before the order is stored so stock is reserved under its reference
+	order.ID = primitive.NewObjectID()
+
+	for _, item := range cart.Items {
+		if !item.Available {
+			return nil, fmt.Errorf("%w: %s", ErrProductUnavailable, item.Name)
+		}
+		order.Items = append(order.Items, models.OrderItem{
+			ProductID: item.ProductID,
+			SKU:       item.SKU,
+			Name:      item.Name,
+			UnitPrice: item.UnitPrice,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	order.ComputeTotals(s.pricing.TaxRate, s.pricing.shippingCost(order.ItemsSubtotal()))
+	return order, nil
+}
+
+// placeOrder stores the order, reserves its stock and empties the cart. The order is
+// stored first so a duplicate submission fails before it reserves anything.
+func (s *CheckoutService) placeOrder(ctx context.Context, cart *models.Cart, order *models.Order) error {
+	if err := s.orders.Create(ctx, order); err != nil {
+		return err
+	}
+
+	if _, err := s.inventory.Reserve(ctx, orderStockReference(order.ID), orderStockLines(order)); err != nil {
+		return s.undo(ctx, order, false, err)
+	}
+
+	if err := s.carts.CompleteCheckout(ctx, cart, order.ID); err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			err = ErrCartChanged
+		}
+		return s.undo(ctx, order, true, err)
+	}
+	return nil
+}
+
+// undo removes an order whose checkout failed with cause and releases its stock.
+// Nothing is left to undo when the checkout runs in a transaction.
+func (s *CheckoutService) undo(ctx context.Context, order *models.Order, reserved bool, cause error) error {
+	if s.tx.InTransaction(ctx) {
+		return cause
+	}
+
+	if reserved {
+		if err := s.inventory.Release(ctx, orderStockReference(order.ID), "checkout failed"); err != nil {
+			logger.Error("Failed to release the stock of a failed checkout", err)
+		}
+	}
+	if err := s.orders.Delete(ctx, order.ID); err != nil {
+		logger.Error("Failed to delete the order of a failed checkout", err)
+	}
+	return cause
+}
@@ -0,0 +1,132 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// noTransactions is a services.Transactor for a database without transactions
+type noTransactions struct{}
+
+func (noTransactions) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (noTransactions) InTransaction(ctx context.Context) bool {
+	return false
+}
+
+func (noTransactions) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	fn(ctx)
+}
+
+// checkoutFixture holds a CheckoutService and the fakes behind it
+type checkoutFixture struct {
+	svc       *services.CheckoutService
+	carts     *services.CartService
+	orders    *memoryOrderRepository
+	inventory *memoryInventoryRepository
+	catalog   memoryCatalog
+}
+
+// newCheckoutFixture creates a CheckoutService over a catalog matching the test inventory
+func newCheckoutFixture(t *testing.T) *checkoutFixture {
+	t.Helper()
+	inventory, inventoryRepo, _ := newTestInventory(t, time.Minute)
+	catalog := memoryCatalog{}
+	carts := services.NewCartService(&memoryCartRepository{carts: map[primitive.ObjectID]models.Cart{}}, catalog, time.Hour, time.Hour)
+	orders := newMemoryOrderRepository()
+	pricing := services.CheckoutPricing{TaxRate: 0.1, ShippingCost: 5, FreeShippingThreshold: 100}
+	return &checkoutFixture{
+		svc:       services.NewCheckoutService(noTransactions{}, orders, carts, inventory, pricing),
+		carts:     carts,
+		orders:    orders,
+		inventory: inventoryRepo,
+		catalog:   catalog,
+	}
+}
+
+var testAddress = models.Address{Street: "1 Main St", City: "Springfield", ZipCode: "12345", Country: "US"}
+
+func TestCheckoutPlacesOrderOnce(t *testing.T) {
+	ctx := context.Background()
+	f := newCheckoutFixture(t)
+	mug := f.catalog.add("MUG", 10, 5)
+	tee := f.catalog.add("TEE", 20, 2)
+	owner := services.CartOwner{UserID: "user-1"}
+	for _, line := range []struct {
+		id       primitive.ObjectID
+		quantity int
+	}{{mug.ID, 2}, {tee.ID, 1}} {
+		if _, err := f.carts.AddItem(ctx, owner, line.id, line.quantity); err != nil {
+			t.Fatalf("AddItem: %v", err)
+		}
+	}
+
+	stale := 40.0
+	if _, err := f.svc.PlaceOrder(ctx, owner.UserID, services.CheckoutInput{ShippingAddress: testAddress, ExpectedTotal: &stale}); !errors.Is(err, services.ErrTotalChanged) {
+		t.Fatalf("PlaceOrder with a stale total: got %v, want ErrTotalChanged", err)
+	}
+
+	order, err := f.svc.PlaceOrder(ctx, owner.UserID, services.CheckoutInput{ShippingAddress: testAddress})
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if order.Subtotal != 40 || order.Tax != 4 || order.ShippingCost != 5 || order.Total != 49 {
+		t.Fatalf("order totals %v + %v + %v = %v, want 40 + 4 + 5 = 49", order.Subtotal, order.Tax, order.ShippingCost, order.Total)
+	}
+	if order.Status != models.OrderPending || len(order.StatusHistory) != 1 {
+		t.Fatalf("order is %s with %d history entries, want pending with 1", order.Status, len(order.StatusHistory))
+	}
+	if reserved := f.inventory.items["MUG"].Reserved; reserved != 2 {
+		t.Fatalf("MUG reserved = %d, want 2", reserved)
+	}
+
+	cart, err := f.carts.GetCart(ctx, owner)
+	if err != nil {
+		t.Fatalf("GetCart: %v", err)
+	}
+	if len(cart.Items) != 0 {
+		t.Fatalf("cart has %d items after checkout, want 0", len(cart.Items))
+	}
+
+	// Submitting the checkout again returns the same order
+	again, err := f.svc.PlaceOrder(ctx, owner.UserID, services.CheckoutInput{ShippingAddress: testAddress})
+	if err != nil {
+		t.Fatalf("PlaceOrder again: %v", err)
+	}
+	if again.ID != order.ID || len(f.orders.orders) != 1 {
+		t.Fatalf("second submission returned order %s with %d orders stored, want %s and 1", again.ID.Hex(), len(f.orders.orders), order.ID.Hex())
+	}
+}
+
+func TestCheckoutUndoesOrderWithoutTransaction(t *testing.T) {
+	ctx := context.Background()
+	f := newCheckoutFixture(t)
+	// The catalog shows more TEE units than the inventory holds
+	tee := f.catalog.add("TEE", 20, 5)
+	owner := services.CartOwner{UserID: "user-1"}
+	if _, err := f.carts.AddItem(ctx, owner, tee.ID, 3); err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+
+	if _, err := f.svc.PlaceOrder(ctx, owner.UserID, services.CheckoutInput{ShippingAddress: testAddress}); !errors.Is(err, models.ErrInsufficientStock) {
+		t.Fatalf("PlaceOrder: got %v, want ErrInsufficientStock", err)
+	}
+	if len(f.orders.orders) != 0 {
+		t.Fatalf("%d orders left after a failed checkout, want 0", len(f.orders.orders))
+	}
+	cart, err := f.carts.GetCart(ctx, owner)
+	if err != nil {
+		t.Fatalf("GetCart: %v", err)
+	}
+	if cart.ItemCount() != 3 {
+		t.Fatalf("cart has %d units after a failed checkout, want 3", cart.ItemCount())
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned by the IdempotencyService
+var (
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a different operation or variables
+	ErrIdempotencyKeyReused = fmt.Errorf("%w: the idempotency key was already used for a different request", models.ErrConflict)
+	// ErrIdempotencyKeyInUse is returned while the first request with a key is still running
+	ErrIdempotencyKeyInUse = fmt.Errorf("%w: a request with the same idempotency key is in progress", models.ErrConflict)
+)
+
+// idempotencyLockTimeout is how long a pending key blocks retries before its first
+// request is considered lost, e.g. because the server stopped while running it
+const idempotencyLockTimeout = time.Minute
+
+// IdempotencyRepository is the storage used by the IdempotencyService
+type IdempotencyRepository interface {
+	Create(ctx context.Context, record *models.IdempotencyRecord) error
+	Find(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, id primitive.ObjectID, response []byte) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// IdempotencyService records the responses of requests sent with an idempotency key
+// so that retries replay the first response instead of running the request again
+type IdempotencyService struct {
+	records IdempotencyRepository
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// NewIdempotencyService creates a new IdempotencyService. Keys can be reused for
+// another request once ttl has passed.
+func NewIdempotencyService(records IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{records: records, ttl: ttl, now: time.Now}
+}
+
+// Begin claims a key of a user for the request identified by operationHash. When
+// the request already completed it returns the stored response; otherwise it returns
+// a pending record that the caller completes with the response or releases.
+func (s *IdempotencyService) Begin(ctx context.Context, userID, key, operationHash string) (*models.IdempotencyRecord, []byte, error) {
+	// A second attempt follows the removal of an expired or abandoned record
+	for attempt := 0; attempt < 2; attempt++ {
+		now := s.now()
+		record := &models.IdempotencyRecord{
+			UserID:        userID,
+			Key:           key,
+			OperationHash: operationHash,
+			Status:        models.IdempotencyPending,
+			ExpiresAt:     now.Add(s.ttl),
+		}
+		err := s.records.Create(ctx, record)
+		if err == nil {
+			return record, nil, nil
+		}
+		if !errors.Is(err, models.ErrDuplicate) {
+			return nil, nil, err
+		}
+
+		existing, err := s.records.Find(ctx, userID, key)
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		abandoned := existing.Status == models.IdempotencyPending && now.Sub(existing.CreatedAt) > idempotencyLockTimeout
+		if !existing.ExpiresAt.After(now) || abandoned {
+			if err := s.records.Delete(ctx, existing.ID); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		switch {
+		case existing.OperationHash != operationHash:
+			return nil, nil, ErrIdempotencyKeyReused
+		case existing.Status == models.IdempotencyPending:
+			return nil, nil, ErrIdempotencyKeyInUse
+		default:
+			return nil, existing.Response, nil
+		}
+	}
+	return nil, nil, ErrIdempotencyKeyInUse
+}
+
+// Complete stores the response of the request that claimed a record
+func (s *IdempotencyService) Complete(ctx context.Context, record *models.IdempotencyRecord, response []byte) error {
+	return s.records.Complete(ctx, record.ID, response)
+}
+
+// Release frees the key of a record whose request failed in a way worth retrying
+func (s *IdempotencyService) Release(ctx context.Context, record *models.IdempotencyRecord) error {
+	return s.records.Delete(ctx, record.ID)
+}
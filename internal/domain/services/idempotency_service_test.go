@@ -0,0 +1,108 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryIdempotencyRepository is an in-memory services.IdempotencyRepository
+type memoryIdempotencyRepository struct {
+	records map[string]*models.IdempotencyRecord
+}
+
+func (r *memoryIdempotencyRepository) Create(ctx context.Context, record *models.IdempotencyRecord) error {
+	if _, ok := r.records[record.UserID+"/"+record.Key]; ok {
+		return models.ErrDuplicate
+	}
+	record.BeforeCreate()
+	copied := *record
+	r.records[record.UserID+"/"+record.Key] = &copied
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Find(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error) {
+	record, ok := r.records[userID+"/"+key]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	copied := *record
+	return &copied, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(ctx context.Context, id primitive.ObjectID, response []byte) error {
+	for _, record := range r.records {
+		if record.ID == id {
+			record.Status = models.IdempotencyCompleted
+			record.Response = response
+		}
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	for k, record := range r.records {
+		if record.ID == id {
+			delete(r.records, k)
+		}
+	}
+	return nil
+}
+
+func TestIdempotencyReplaysFirstResponse(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryIdempotencyRepository{records: map[string]*models.IdempotencyRecord{}}
+	svc := services.NewIdempotencyService(repo, time.Hour)
+
+	record, _, err := svc.Begin(ctx, "user-1", "key-1", "hash-a")
+	if err != nil || record == nil {
+		t.Fatalf("Begin: got %v, %v; want a pending record", record, err)
+	}
+
+	if _, _, err := svc.Begin(ctx, "user-1", "key-1", "hash-a"); !errors.Is(err, services.ErrIdempotencyKeyInUse) {
+		t.Fatalf("Begin while pending: got %v, want ErrIdempotencyKeyInUse", err)
+	}
+
+	if err := svc.Complete(ctx, record, []byte(`{"data":{}}`)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	replayed, stored, err := svc.Begin(ctx, "user-1", "key-1", "hash-a")
+	if err != nil || replayed != nil || string(stored) != `{"data":{}}` {
+		t.Fatalf("Begin after completion: got %v, %q, %v; want the stored response", replayed, stored, err)
+	}
+
+	if _, _, err := svc.Begin(ctx, "user-1", "key-1", "hash-b"); !errors.Is(err, services.ErrIdempotencyKeyReused) {
+		t.Fatalf("Begin with another payload: got %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	// Keys are scoped to the user
+	if other, _, err := svc.Begin(ctx, "user-2", "key-1", "hash-b"); err != nil || other == nil {
+		t.Fatalf("Begin by another user: got %v, %v; want a pending record", other, err)
+	}
+}
+
+func TestIdempotencyKeyExpires(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryIdempotencyRepository{records: map[string]*models.IdempotencyRecord{}}
+	svc := services.NewIdempotencyService(repo, time.Hour)
+
+	record, _, err := svc.Begin(ctx, "user-1", "key-1", "hash-a")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if err := svc.Complete(ctx, record, []byte(`{}`)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	// Expire the record without waiting for the TTL index
+	repo.records["user-1/key-1"].ExpiresAt = time.Now().Add(-time.Second)
+	reused, _, err := svc.Begin(ctx, "user-1", "key-1", "hash-b")
+	if err != nil || reused == nil {
+		t.Fatalf("Begin with an expired key: got %v, %v; want a pending record", reused, err)
+	}
+}
@@ -0,0 +1,489 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/connection"
+	"github.com/prototype01/pkg/logger"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sweepBatchSize is the number of expired reservations released per sweeper query
+const sweepBatchSize = 100
+
+// InventoryRepository is the storage used by the InventoryService
+type InventoryRepository interface {
+	CreateItem(ctx context.Context, item *models.InventoryItem) error
+	FindItemBySKU(ctx context.Context, sku string) (*models.InventoryItem, error)
+	SetOnHand(ctx context.Context, sku string, onHand int) (*models.InventoryItem, error)
+	AddOnHand(ctx context.Context, sku string, quantity int) error
+	Reserve(ctx context.Context, sku string, quantity int) error
+	Release(ctx context.Context, sku string, quantity int) error
+	Commit(ctx context.Context, sku string, quantity int) error
+	RenameSKU(ctx context.Context, oldSKU, newSKU string) error
+	DeleteItem(ctx context.Context, sku string) error
+	CreateReservation(ctx context.Context, reservation *models.Reservation) error
+	FindActiveReservations(ctx context.Context, reference string) ([]models.Reservation, error)
+	FindExpiredReservations(ctx context.Context, now time.Time, limit int) ([]models.Reservation, error)
+	ExtendReservations(ctx context.Context, reference string, expiresAt time.Time) error
+	TransitionReservation(ctx context.Context, id primitive.ObjectID, status models.ReservationStatus) (bool, error)
+	RecordMovement(ctx context.Context, movement *models.StockMovement) error
+	ListMovements(ctx context.Context, sku string, args connection.Args) (*models.StockMovementConnection, error)
+}
+
+// ProductStockRepository keeps the available stock shown on products in sync with the inventory
+type ProductStockRepository interface {
+	IncrementStock(ctx context.Context, sku string, delta int) error
+}
+
+// StockHook runs after a stock movement was recorded, once the transaction it was
+// recorded in commits. Errors are logged, the movement is already applied.
+type StockHook func(ctx context.Context, movement models.StockMovement) error
+
+// StockLine is a quantity of a SKU to reserve
+type StockLine struct {
+	SKU      string
+	Quantity int
+}
+
+// InventoryService tracks stock levels. Units are reserved while a checkout is in
+// progress, then either committed when sold or released. Every change is recorded
+// in the stock ledger.
+type InventoryService struct {
+	tx             Transactor
+	inventory      InventoryRepository
+	products       ProductStockRepository
+	reservationTTL time.Duration
+	hooks          []StockHook
+	now            func() time.Time
+}
+
+// NewInventoryService creates a new InventoryService. Reservations expire after reservationTTL.
+func NewInventoryService(tx Transactor, inventory InventoryRepository, products ProductStockRepository, reservationTTL time.Duration) *InventoryService {
+	return &InventoryService{
+		tx:             tx,
+		inventory:      inventory,
+		products:       products,
+		reservationTTL: reservationTTL,
+		now:            time.Now,
+	}
+}
+
+// OnStockChange registers a hook that runs after every recorded stock movement
+func (s *InventoryService) OnStockChange(hook StockHook) {
+	s.hooks = append(s.hooks, hook)
+}
+
+// GetInventory returns the stock levels of a SKU
+func (s *InventoryService) GetInventory(ctx context.Context, sku string) (*models.InventoryItem, error) {
+	return s.inventory.FindItemBySKU(ctx, sku)
+}
+
+// ListMovements returns a page of the stock ledger of a SKU, newest first
+func (s *InventoryService) ListMovements(ctx context.Context, sku string, args connection.Args) (*models.StockMovementConnection, error) {
+	return s.inventory.ListMovements(ctx, sku, args)
+}
+
+// InitializeStock creates the inventory item of a new product with its initial stock
+func (s *InventoryService) InitializeStock(ctx context.Context, product *models.Product, onHand int) error {
+	item := &models.InventoryItem{SKU: product.SKU, ProductID: product.ID, OnHand: onHand}
+	if err := s.inventory.CreateItem(ctx, item); err != nil {
+		return err
+	}
+	if onHand == 0 {
+		return nil
+	}
+	return s.record(ctx, &models.StockMovement{
+		SKU:         item.SKU,
+		ProductID:   item.ProductID,
+		Type:        models.StockMovementAdjustment,
+		OnHandDelta: onHand,
+		Reason:      "initial stock",
+	})
+}
+
+// SetStock sets the on hand quantity of a SKU, e.g. after a stock count or a delivery.
+// It fails with models.ErrInsufficientStock when more units are reserved.
+func (s *InventoryService) SetStock(ctx context.Context, sku string, onHand int, reason string) (*models.InventoryItem, error) {
+	if onHand < 0 {
+		return nil, validator.ValidationErrors{{Field: "quantity", Message: "quantity cannot be negative"}}
+	}
+
+	var item models.InventoryItem
+	err := s.transaction(ctx, func(ctx context.Context) error {
+		before, err := s.inventory.SetOnHand(ctx, sku, onHand)
+		if err != nil {
+			return err
+		}
+
+		delta := onHand - before.OnHand
+		item = *before
+		item.OnHand = onHand
+		if delta == 0 {
+			return nil
+		}
+
+		if err := s.products.IncrementStock(ctx, sku, delta); err != nil {
+			return err
+		}
+		return s.record(ctx, &models.StockMovement{
+			SKU:         sku,
+			ProductID:   item.ProductID,
+			Type:        models.StockMovementAdjustment,
+			OnHandDelta: delta,
+			Reason:      reason,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Reserve holds stock for every line under the given reference. Either every line is
+// reserved or none is; models.ErrInsufficientStock is returned when a SKU runs short.
+func (s *InventoryService) Reserve(ctx context.Context, reference string, lines []StockLine) ([]models.Reservation, error) {
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, validator.ValidationErrors{{Field: "quantity", Message: "quantity must be positive"}}
+		}
+	}
+
+	expiresAt := s.now().Add(s.reservationTTL)
+	var reservations []models.Reservation
+	err := s.transaction(ctx, func(ctx context.Context) error {
+		reservations = make([]models.Reservation, 0, len(lines))
+		for _, line := range lines {
+			reservation, err := s.reserve(ctx, reference, line, expiresAt)
+			if err != nil {
+				s.rollBack(ctx, reservations)
+				return fmt.Errorf("failed to reserve %s: %w", line.SKU, err)
+			}
+			reservations = append(reservations, *reservation)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// Hold makes sure every line of a reference is reserved for another reservation TTL,
+// e.g. before an order is charged. Active reservations are extended and lines whose
+// reservation expired or was released are reserved again; models.ErrInsufficientStock
+// is returned when their stock ran out meanwhile.
+func (s *InventoryService) Hold(ctx context.Context, reference string, lines []StockLine) error {
+	if err := s.inventory.ExtendReservations(ctx, reference, s.now().Add(s.reservationTTL)); err != nil {
+		return err
+	}
+	active, err := s.inventory.FindActiveReservations(ctx, reference)
+	if err != nil {
+		return err
+	}
+
+	held := make(map[string]int, len(active))
+	for _, reservation := range active {
+		held[reservation.SKU] += reservation.Quantity
+	}
+	var missing []StockLine
+	for _, line := range lines {
+		if quantity := line.Quantity - held[line.SKU]; quantity > 0 {
+			missing = append(missing, StockLine{SKU: line.SKU, Quantity: quantity})
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	_, err = s.Reserve(ctx, reference, missing)
+	return err
+}
+
+// Release gives back the stock held by the active reservations of a reference
+func (s *InventoryService) Release(ctx context.Context, reference, reason string) error {
+	return s.finishReference(ctx, reference, models.ReservationReleased, reason)
+}
+
+// Commit removes the stock held by the active reservations of a reference from the
+// on hand quantity once the units are sold
+func (s *InventoryService) Commit(ctx context.Context, reference string) error {
+	return s.finishReference(ctx, reference, models.ReservationCommitted, "")
+}
+
+// Restock puts sold units back in stock, e.g. when a paid order is cancelled
+func (s *InventoryService) Restock(ctx context.Context, reference string, lines []StockLine, reason string) error {
+	return s.transaction(ctx, func(ctx context.Context) error {
+		for _, line := range lines {
+			item, err := s.inventory.FindItemBySKU(ctx, line.SKU)
+			if errors.Is(err, models.ErrNotFound) {
+				// The product was removed from the catalog, there is no stock to return to
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := s.inventory.AddOnHand(ctx, line.SKU, line.Quantity); err != nil {
+				return err
+			}
+			if err := s.products.IncrementStock(ctx, line.SKU, line.Quantity); err != nil {
+				return err
+			}
+			if err := s.record(ctx, &models.StockMovement{
+				SKU:         line.SKU,
+				ProductID:   item.ProductID,
+				Type:        models.StockMovementAdjustment,
+				OnHandDelta: line.Quantity,
+				Reference:   reference,
+				Reason:      reason,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RenameSKU moves the inventory of a product to its new SKU
+func (s *InventoryService) RenameSKU(ctx context.Context, oldSKU, newSKU string) error {
+	return s.inventory.RenameSKU(ctx, oldSKU, newSKU)
+}
+
+// RemoveStock deletes the inventory item of a SKU, keeping its ledger
+func (s *InventoryService) RemoveStock(ctx context.Context, sku string) error {
+	return s.inventory.DeleteItem(ctx, sku)
+}
+
+// ReleaseExpired releases the stock of every expired reservation and returns how many were released
+func (s *InventoryService) ReleaseExpired(ctx context.Context) (int, error) {
+	released := 0
+	for {
+		reservations, err := s.inventory.FindExpiredReservations(ctx, s.now(), sweepBatchSize)
+		if err != nil {
+			return released, err
+		}
+		for i := range reservations {
+			ok, err := s.finish(ctx, &reservations[i], models.ReservationExpired, "reservation expired")
+			if err != nil {
+				return released, err
+			}
+			if ok {
+				released++
+			}
+		}
+		if len(reservations) < sweepBatchSize {
+			return released, nil
+		}
+	}
+}
+
+// RunSweeper releases expired reservations every interval until ctx is done
+func (s *InventoryService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := s.ReleaseExpired(ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				logger.Error("Failed to release expired stock reservations", err)
+			}
+			if released > 0 {
+				logger.Info(fmt.Sprintf("Released %d expired stock reservations", released))
+			}
+		}
+	}
+}
+
+// rollBack gives back the stock of reservations made before a reservation failed.
+// Nothing is left to give back when they were made in a transaction.
+func (s *InventoryService) rollBack(ctx context.Context, reservations []models.Reservation) {
+	if s.tx.InTransaction(ctx) {
+		return
+	}
+	for i := range reservations {
+		if _, err := s.finish(ctx, &reservations[i], models.ReservationReleased, "reservation rolled back"); err != nil {
+			logger.Error("Failed to roll back stock reservation", err)
+		}
+	}
+}
+
+// reserve holds the stock of a single line and records the reservation. Without a
+// transaction, the writes made before a failure are undone.
+func (s *InventoryService) reserve(ctx context.Context, reference string, line StockLine, expiresAt time.Time) (*models.Reservation, error) {
+	item, err := s.inventory.FindItemBySKU(ctx, line.SKU)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.inventory.Reserve(ctx, line.SKU, line.Quantity); err != nil {
+		return nil, err
+	}
+
+	reservation := &models.Reservation{
+		SKU:       line.SKU,
+		ProductID: item.ProductID,
+		Quantity:  line.Quantity,
+		Reference: reference,
+		Status:    models.ReservationActive,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.inventory.CreateReservation(ctx, reservation); err != nil {
+		s.undoReserve(ctx, reservation, false)
+		return nil, err
+	}
+
+	if err := s.products.IncrementStock(ctx, line.SKU, -line.Quantity); err != nil {
+		s.undoReserve(ctx, reservation, true)
+		return nil, err
+	}
+	if err := s.record(ctx, &models.StockMovement{
+		SKU:           line.SKU,
+		ProductID:     item.ProductID,
+		Type:          models.StockMovementReserve,
+		ReservedDelta: line.Quantity,
+		ReservationID: &reservation.ID,
+		Reference:     reference,
+	}); err != nil {
+		// The reservation is complete but unrecorded; releasing it restores every counter
+		if !s.tx.InTransaction(ctx) {
+			if _, releaseErr := s.finish(ctx, reservation, models.ReservationReleased, "reservation rolled back"); releaseErr != nil {
+				logger.Error("Failed to roll back stock reservation", releaseErr)
+			}
+		}
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// undoReserve gives back the reserved units of a reservation whose product stock
+// wasn't updated, ending the reservation when it was saved. Nothing is left to undo
+// in a transaction.
+func (s *InventoryService) undoReserve(ctx context.Context, reservation *models.Reservation, saved bool) {
+	if s.tx.InTransaction(ctx) {
+		return
+	}
+	if saved {
+		if _, err := s.inventory.TransitionReservation(ctx, reservation.ID, models.ReservationReleased); err != nil {
+			logger.Error("Failed to end a rolled back stock reservation", err)
+		}
+	}
+	if err := s.inventory.Release(ctx, reservation.SKU, reservation.Quantity); err != nil {
+		logger.Error("Failed to release stock of a rolled back reservation", err)
+	}
+}
+
+// finishReference moves every active reservation of a reference to status
+func (s *InventoryService) finishReference(ctx context.Context, reference string, status models.ReservationStatus, reason string) error {
+	reservations, err := s.inventory.FindActiveReservations(ctx, reference)
+	if err != nil {
+		return err
+	}
+	for i := range reservations {
+		if _, err := s.finish(ctx, &reservations[i], status, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish ends an active reservation and applies its stock change. It reports false
+// when the reservation was already finished by a concurrent request. The status
+// change and the stock change run in one transaction, so a failed stock update
+// leaves the reservation active for the sweeper or a retry.
+func (s *InventoryService) finish(ctx context.Context, reservation *models.Reservation, status models.ReservationStatus, reason string) (bool, error) {
+	finished := false
+	err := s.transaction(ctx, func(ctx context.Context) error {
+		ok, err := s.inventory.TransitionReservation(ctx, reservation.ID, status)
+		if err != nil || !ok {
+			return err
+		}
+		finished = true
+		return s.applyFinish(ctx, reservation, status, reason)
+	})
+	if err != nil || !finished {
+		return false, err
+	}
+	reservation.Status = status
+	return true, nil
+}
+
+// applyFinish updates the stock counters of a finished reservation and records its movement
+func (s *InventoryService) applyFinish(ctx context.Context, reservation *models.Reservation, status models.ReservationStatus, reason string) error {
+	movement := &models.StockMovement{
+		SKU:           reservation.SKU,
+		ProductID:     reservation.ProductID,
+		ReservedDelta: -reservation.Quantity,
+		ReservationID: &reservation.ID,
+		Reference:     reservation.Reference,
+		Reason:        reason,
+	}
+
+	switch status {
+	case models.ReservationCommitted:
+		if err := s.inventory.Commit(ctx, reservation.SKU, reservation.Quantity); err != nil {
+			return err
+		}
+		movement.Type = models.StockMovementCommit
+		movement.OnHandDelta = -reservation.Quantity
+	default:
+		if err := s.inventory.Release(ctx, reservation.SKU, reservation.Quantity); err != nil {
+			return err
+		}
+		if err := s.products.IncrementStock(ctx, reservation.SKU, reservation.Quantity); err != nil {
+			return err
+		}
+		movement.Type = models.StockMovementRelease
+		if status == models.ReservationExpired {
+			movement.Type = models.StockMovementExpire
+		}
+	}
+
+	return s.record(ctx, movement)
+}
+
+// transaction runs fn in a transaction, joining the one ctx already carries
+func (s *InventoryService) transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx.InTransaction(ctx) {
+		return fn(ctx)
+	}
+	return s.tx.WithTransaction(ctx, fn)
+}
+
+// record appends a movement to the ledger and runs the stock change hooks once the
+// transaction of ctx commits, so rolled back movements are never published
+func (s *InventoryService) record(ctx context.Context, movement *models.StockMovement) error {
+	if err := s.write(ctx, movement); err != nil {
+		return err
+	}
+	recorded := *movement
+	s.tx.AfterCommit(ctx, func(ctx context.Context) {
+		s.notify(ctx, recorded)
+	})
+	return nil
+}
+
+// write appends a movement to the ledger, attributing it to the signed in user
+func (s *InventoryService) write(ctx context.Context, movement *models.StockMovement) error {
+	if actorID, ok := auth.GetUserIDFromContext(ctx); ok {
+		movement.ActorID = actorID
+	}
+	movement.CreatedAt = s.now()
+	return s.inventory.RecordMovement(ctx, movement)
+}
+
+// notify runs the stock change hooks of a recorded movement
+func (s *InventoryService) notify(ctx context.Context, movement models.StockMovement) {
+	for _, hook := range s.hooks {
+		if err := hook(ctx, movement); err != nil {
+			logger.Error("Stock change hook failed for "+movement.SKU, err)
+		}
+	}
+}
@@ -0,0 +1,485 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryInventoryRepository is an in-memory services.InventoryRepository
+type memoryInventoryRepository struct {
+	items        map[string]*models.InventoryItem
+	reservations map[primitive.ObjectID]*models.Reservation
+	movements    []models.StockMovement
+}
+
+func newMemoryInventoryRepository() *memoryInventoryRepository {
+	return &memoryInventoryRepository{
+		items:        make(map[string]*models.InventoryItem),
+		reservations: make(map[primitive.ObjectID]*models.Reservation),
+	}
+}
+
+func (r *memoryInventoryRepository) CreateItem(ctx context.Context, item *models.InventoryItem) error {
+	if _, ok := r.items[item.SKU]; ok {
+		return models.ErrDuplicate
+	}
+	item.BeforeCreate()
+	copied := *item
+	r.items[item.SKU] = &copied
+	return nil
+}
+
+func (r *memoryInventoryRepository) FindItemBySKU(ctx context.Context, sku string) (*models.InventoryItem, error) {
+	item, ok := r.items[sku]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	copied := *item
+	return &copied, nil
+}
+
+func (r *memoryInventoryRepository) SetOnHand(ctx context.Context, sku string, onHand int) (*models.InventoryItem, error) {
+	item, ok := r.items[sku]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	if item.Reserved > onHand {
+		return nil, models.ErrInsufficientStock
+	}
+	before := *item
+	item.OnHand = onHand
+	return &before, nil
+}
+
+func (r *memoryInventoryRepository) AddOnHand(ctx context.Context, sku string, quantity int) error {
+	r.items[sku].OnHand += quantity
+	return nil
+}
+
+func (r *memoryInventoryRepository) Reserve(ctx context.Context, sku string, quantity int) error {
+	item, ok := r.items[sku]
+	if !ok {
+		return models.ErrNotFound
+	}
+	if item.Available() < quantity {
+		return models.ErrInsufficientStock
+	}
+	item.Reserved += quantity
+	return nil
+}
+
+func (r *memoryInventoryRepository) Release(ctx context.Context, sku string, quantity int) error {
+	r.items[sku].Reserved -= quantity
+	return nil
+}
+
+func (r *memoryInventoryRepository) Commit(ctx context.Context, sku string, quantity int) error {
+	r.items[sku].Reserved -= quantity
+	r.items[sku].OnHand -= quantity
+	return nil
+}
+
+func (r *memoryInventoryRepository) RenameSKU(ctx context.Context, oldSKU, newSKU string) error {
+	item, ok := r.items[oldSKU]
+	if !ok {
+		return nil
+	}
+	if _, taken := r.items[newSKU]; taken {
+		return models.ErrDuplicate
+	}
+	delete(r.items, oldSKU)
+	item.SKU = newSKU
+	r.items[newSKU] = item
+	for _, reservation := range r.reservations {
+		if reservation.SKU == oldSKU && reservation.Status == models.ReservationActive {
+			reservation.SKU = newSKU
+		}
+	}
+	return nil
+}
+
+func (r *memoryInventoryRepository) DeleteItem(ctx context.Context, sku string) error {
+	delete(r.items, sku)
+	return nil
+}
+
+func (r *memoryInventoryRepository) CreateReservation(ctx context.Context, reservation *models.Reservation) error {
+	reservation.BeforeCreate()
+	copied := *reservation
+	r.reservations[reservation.ID] = &copied
+	return nil
+}
+
+func (r *memoryInventoryRepository) FindActiveReservations(ctx context.Context, reference string) ([]models.Reservation, error) {
+	var found []models.Reservation
+	for _, reservation := range r.reservations {
+		if reservation.Reference == reference && reservation.Status == models.ReservationActive {
+			found = append(found, *reservation)
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryInventoryRepository) FindExpiredReservations(ctx context.Context, now time.Time, limit int) ([]models.Reservation, error) {
+	var found []models.Reservation
+	for _, reservation := range r.reservations {
+		if reservation.Status == models.ReservationActive && !reservation.ExpiresAt.After(now) && len(found) < limit {
+			found = append(found, *reservation)
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryInventoryRepository) ExtendReservations(ctx context.Context, reference string, expiresAt time.Time) error {
+	for _, reservation := range r.reservations {
+		if reservation.Reference == reference && reservation.Status == models.ReservationActive {
+			reservation.ExpiresAt = expiresAt
+		}
+	}
+	return nil
+}
+
+func (r *memoryInventoryRepository) TransitionReservation(ctx context.Context, id primitive.ObjectID, status models.ReservationStatus) (bool, error) {
+	reservation, ok := r.reservations[id]
+	if !ok || reservation.Status != models.ReservationActive {
+		return false, nil
+	}
+	reservation.Status = status
+	return true, nil
+}
+
+func (r *memoryInventoryRepository) RecordMovement(ctx context.Context, movement *models.StockMovement) error {
+	r.movements = append(r.movements, *movement)
+	return nil
+}
+
+func (r *memoryInventoryRepository) ListMovements(ctx context.Context, sku string, args connection.Args) (*models.StockMovementConnection, error) {
+	return &models.StockMovementConnection{}, nil
+}
+
+// memoryProductStock records the available stock mirrored on products
+type memoryProductStock map[string]int
+
+func (p memoryProductStock) IncrementStock(ctx context.Context, sku string, delta int) error {
+	p[sku] += delta
+	return nil
+}
+
+// newTestInventory creates an InventoryService with two stocked SKUs
+func newTestInventory(t *testing.T, ttl time.Duration) (*services.InventoryService, *memoryInventoryRepository, memoryProductStock) {
+	t.Helper()
+	repo := newMemoryInventoryRepository()
+	stock := memoryProductStock{}
+	svc := services.NewInventoryService(noTransactions{}, repo, stock, ttl)
+	for sku, onHand := range map[string]int{"MUG": 5, "TEE": 2} {
+		product := &models.Product{SKU: sku}
+		product.ID = primitive.NewObjectID()
+		if err := svc.InitializeStock(context.Background(), product, onHand); err != nil {
+			t.Fatalf("InitializeStock: %v", err)
+		}
+		stock[sku] = onHand
+	}
+	return svc, repo, stock
+}
+
+func TestInventoryReserveIsAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, stock := newTestInventory(t, time.Minute)
+
+	_, err := svc.Reserve(ctx, "cart-1", []services.StockLine{{SKU: "MUG", Quantity: 2}, {SKU: "TEE", Quantity: 3}})
+	if !errors.Is(err, models.ErrInsufficientStock) {
+		t.Fatalf("Reserve: got %v, want ErrInsufficientStock", err)
+	}
+	if got := repo.items["MUG"].Reserved; got != 0 {
+		t.Fatalf("MUG reserved after rollback = %d, want 0", got)
+	}
+	if stock["MUG"] != 5 || stock["TEE"] != 2 {
+		t.Fatalf("product stock after rollback = %v, want MUG:5 TEE:2", stock)
+	}
+}
+
+func TestInventoryCommitAndRelease(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, stock := newTestInventory(t, time.Minute)
+
+	if _, err := svc.Reserve(ctx, "order-1", []services.StockLine{{SKU: "MUG", Quantity: 2}}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if _, err := svc.Reserve(ctx, "order-2", []services.StockLine{{SKU: "MUG", Quantity: 3}}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if _, err := svc.Reserve(ctx, "order-3", []services.StockLine{{SKU: "MUG", Quantity: 1}}); !errors.Is(err, models.ErrInsufficientStock) {
+		t.Fatalf("Reserve beyond stock: got %v, want ErrInsufficientStock", err)
+	}
+	if _, err := svc.SetStock(ctx, "MUG", 4, "stock count"); !errors.Is(err, models.ErrInsufficientStock) {
+		t.Fatalf("SetStock below reserved: got %v, want ErrInsufficientStock", err)
+	}
+
+	if err := svc.Commit(ctx, "order-1"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := svc.Release(ctx, "order-2", "cancelled"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	// Finishing twice must not move stock again
+	if err := svc.Release(ctx, "order-2", "cancelled"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	item := repo.items["MUG"]
+	if item.OnHand != 3 || item.Reserved != 0 || stock["MUG"] != 3 {
+		t.Fatalf("MUG = on hand %d, reserved %d, product stock %d; want 3, 0, 3", item.OnHand, item.Reserved, stock["MUG"])
+	}
+}
+
+func TestInventoryReleaseExpired(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, stock := newTestInventory(t, -time.Second)
+
+	if _, err := svc.Reserve(ctx, "cart-1", []services.StockLine{{SKU: "TEE", Quantity: 2}}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	released, err := svc.ReleaseExpired(ctx)
+	if err != nil {
+		t.Fatalf("ReleaseExpired: %v", err)
+	}
+	if released != 1 || repo.items["TEE"].Reserved != 0 || stock["TEE"] != 2 {
+		t.Fatalf("released %d, TEE reserved %d, stock %d; want 1, 0, 2", released, repo.items["TEE"].Reserved, stock["TEE"])
+	}
+	if last := repo.movements[len(repo.movements)-1]; last.Type != models.StockMovementExpire {
+		t.Fatalf("last movement = %s, want expire", last.Type)
+	}
+}
+
+// failingRelease is an inventory repository whose stock releases fail
+type failingRelease struct {
+	*memoryInventoryRepository
+}
+
+func (failingRelease) Release(ctx context.Context, sku string, quantity int) error {
+	return errors.New("connection reset")
+}
+
+// snapshotTransactions is a services.Transactor rolling an in-memory inventory and
+// product stock back when the transaction fails
+type snapshotTransactions struct {
+	repo  *memoryInventoryRepository
+	stock memoryProductStock
+}
+
+// inTransactionKey is the context key of the commit hooks of a running snapshot transaction
+type inTransactionKey struct{}
+
+func (tx snapshotTransactions) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	items := make(map[string]models.InventoryItem, len(tx.repo.items))
+	for sku, item := range tx.repo.items {
+		items[sku] = *item
+	}
+	reservations := make(map[primitive.ObjectID]models.Reservation, len(tx.repo.reservations))
+	for id, reservation := range tx.repo.reservations {
+		reservations[id] = *reservation
+	}
+	movements := len(tx.repo.movements)
+	stock := make(map[string]int, len(tx.stock))
+	for sku, quantity := range tx.stock {
+		stock[sku] = quantity
+	}
+
+	var hooks []func(ctx context.Context)
+	err := fn(context.WithValue(ctx, inTransactionKey{}, &hooks))
+	if err == nil {
+		for _, hook := range hooks {
+			hook(ctx)
+		}
+		return nil
+	}
+
+	tx.repo.items = make(map[string]*models.InventoryItem, len(items))
+	for sku, item := range items {
+		tx.repo.items[sku] = &item
+	}
+	tx.repo.reservations = make(map[primitive.ObjectID]*models.Reservation, len(reservations))
+	for id, reservation := range reservations {
+		tx.repo.reservations[id] = &reservation
+	}
+	tx.repo.movements = tx.repo.movements[:movements]
+	for sku := range tx.stock {
+		delete(tx.stock, sku)
+	}
+	for sku, quantity := range stock {
+		tx.stock[sku] = quantity
+	}
+	return err
+}
+
+func (snapshotTransactions) InTransaction(ctx context.Context) bool {
+	return ctx.Value(inTransactionKey{}) != nil
+}
+
+func (snapshotTransactions) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	hooks, ok := ctx.Value(inTransactionKey{}).(*[]func(ctx context.Context))
+	if !ok {
+		fn(ctx)
+		return
+	}
+	*hooks = append(*hooks, fn)
+}
+
+func TestInventoryFailedReleaseKeepsReservationActive(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryInventoryRepository()
+	stock := memoryProductStock{}
+	working := services.NewInventoryService(snapshotTransactions{repo, stock}, repo, stock, time.Minute)
+	product := &models.Product{SKU: "MUG"}
+	product.ID = primitive.NewObjectID()
+	if err := working.InitializeStock(ctx, product, 5); err != nil {
+		t.Fatalf("InitializeStock: %v", err)
+	}
+	if _, err := working.Reserve(ctx, "order-1", []services.StockLine{{SKU: "MUG", Quantity: 2}}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+
+	failing := services.NewInventoryService(snapshotTransactions{repo, stock}, failingRelease{repo}, stock, time.Minute)
+	if err := failing.Release(ctx, "order-1", "order cancelled"); err == nil {
+		t.Fatal("Release: expected the stock update error")
+	}
+	active, _ := repo.FindActiveReservations(ctx, "order-1")
+	if len(active) != 1 || repo.items["MUG"].Reserved != 2 {
+		t.Fatalf("after a failed release: %d active reservations, %d reserved, want the reservation kept", len(active), repo.items["MUG"].Reserved)
+	}
+
+	// A retry releases the units the failed attempt left reserved
+	if err := working.Release(ctx, "order-1", "order cancelled"); err != nil {
+		t.Fatalf("Release retry: %v", err)
+	}
+	if got := repo.items["MUG"].Reserved; got != 0 {
+		t.Fatalf("MUG reserved after retry = %d, want 0", got)
+	}
+}
+
+// failingProductStock is a product stock whose updates of one SKU fail
+type failingProductStock struct {
+	memoryProductStock
+	sku string
+}
+
+func (p failingProductStock) IncrementStock(ctx context.Context, sku string, delta int) error {
+	if sku == p.sku {
+		return errors.New("connection reset")
+	}
+	return p.memoryProductStock.IncrementStock(ctx, sku, delta)
+}
+
+func TestInventoryFailedStockUpdatesRollBack(t *testing.T) {
+	for _, transactions := range []string{"transaction", "no transaction"} {
+		t.Run(transactions, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newMemoryInventoryRepository()
+			stock := memoryProductStock{}
+			var tx services.Transactor = noTransactions{}
+			if transactions == "transaction" {
+				tx = snapshotTransactions{repo, stock}
+			}
+			working := services.NewInventoryService(tx, repo, stock, time.Minute)
+			for sku, onHand := range map[string]int{"MUG": 5, "TEE": 2} {
+				product := &models.Product{SKU: sku}
+				product.ID = primitive.NewObjectID()
+				if err := working.InitializeStock(ctx, product, onHand); err != nil {
+					t.Fatalf("InitializeStock: %v", err)
+				}
+				stock[sku] = onHand
+			}
+
+			failing := services.NewInventoryService(tx, repo, failingProductStock{stock, "TEE"}, time.Minute)
+			if _, err := failing.Reserve(ctx, "order-1", []services.StockLine{{SKU: "MUG", Quantity: 2}, {SKU: "TEE", Quantity: 1}}); err == nil {
+				t.Fatal("Reserve: expected the product stock error")
+			}
+			active, _ := repo.FindActiveReservations(ctx, "order-1")
+			if len(active) != 0 || repo.items["MUG"].Reserved != 0 || repo.items["TEE"].Reserved != 0 || stock["MUG"] != 5 || stock["TEE"] != 2 {
+				t.Fatalf("after a failed reservation: %d active, reserved MUG %d TEE %d, stock %v; want nothing held", len(active), repo.items["MUG"].Reserved, repo.items["TEE"].Reserved, stock)
+			}
+
+			// The sweeper has nothing to release, so the product stock isn't inflated
+			if released, err := working.ReleaseExpired(ctx); err != nil || released != 0 {
+				t.Fatalf("ReleaseExpired = %d, %v; want nothing released", released, err)
+			}
+			if stock["MUG"] != 5 || stock["TEE"] != 2 {
+				t.Fatalf("product stock = %v, want MUG:5 TEE:2", stock)
+			}
+		})
+	}
+}
+
+func TestInventoryFailedSetStockRollsBack(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryInventoryRepository()
+	stock := memoryProductStock{}
+	tx := snapshotTransactions{repo, stock}
+	working := services.NewInventoryService(tx, repo, stock, time.Minute)
+	product := &models.Product{SKU: "TEE"}
+	product.ID = primitive.NewObjectID()
+	if err := working.InitializeStock(ctx, product, 2); err != nil {
+		t.Fatalf("InitializeStock: %v", err)
+	}
+	stock["TEE"] = 2
+
+	failing := services.NewInventoryService(tx, repo, failingProductStock{stock, "TEE"}, time.Minute)
+	if _, err := failing.SetStock(ctx, "TEE", 7, "delivery"); err == nil {
+		t.Fatal("SetStock: expected the product stock error")
+	}
+	if err := failing.Restock(ctx, "order-1", []services.StockLine{{SKU: "TEE", Quantity: 1}}, "order cancelled"); err == nil {
+		t.Fatal("Restock: expected the product stock error")
+	}
+	if repo.items["TEE"].OnHand != 2 || stock["TEE"] != 2 {
+		t.Fatalf("TEE on hand %d, product stock %d; want both left at 2", repo.items["TEE"].OnHand, stock["TEE"])
+	}
+}
+
+func TestInventoryPublishesMovementsAfterCommit(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryInventoryRepository()
+	stock := memoryProductStock{}
+	tx := snapshotTransactions{repo, stock}
+	svc := services.NewInventoryService(tx, repo, stock, time.Minute)
+	product := &models.Product{SKU: "MUG"}
+	product.ID = primitive.NewObjectID()
+	if err := svc.InitializeStock(ctx, product, 5); err != nil {
+		t.Fatalf("InitializeStock: %v", err)
+	}
+	var published []models.StockMovementType
+	svc.OnStockChange(func(ctx context.Context, movement models.StockMovement) error {
+		published = append(published, movement.Type)
+		return nil
+	})
+
+	// A checkout rolled back after reserving publishes nothing
+	err := tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := svc.Reserve(ctx, "order-1", []services.StockLine{{SKU: "MUG", Quantity: 2}}); err != nil {
+			return err
+		}
+		return errors.New("cart changed")
+	})
+	if err == nil || len(published) != 0 {
+		t.Fatalf("rolled back checkout: got %v with movements %v published, want none", err, published)
+	}
+
+	err = tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := svc.Reserve(ctx, "order-2", []services.StockLine{{SKU: "MUG", Quantity: 2}}); err != nil {
+			return err
+		}
+		if len(published) != 0 {
+			t.Errorf("movements %v published before the commit", published)
+		}
+		return nil
+	})
+	if err != nil || len(published) != 1 || published[0] != models.StockMovementReserve {
+		t.Fatalf("committed checkout: got %v with movements %v published, want the reservation", err, published)
+	}
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/connection"
+	"github.com/prototype01/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned by the OrderService
+var (
+	// ErrCancellationNotAllowed is returned when a customer cancels an order that is already being fulfilled
+	ErrCancellationNotAllowed = fmt.Errorf("%w: the order is already being fulfilled and can't be cancelled anymore", models.ErrInvalidTransition)
+	// ErrStatusManagedByPayments is returned when an order is moved to paid or refunded by hand;
+	// only the PaymentService moves orders to these statuses, along with the money
+	ErrStatusManagedByPayments = fmt.Errorf("%w: orders are paid and refunded through their payment", models.ErrInvalidTransition)
+)
+
+// OrderRepository is the storage used by the OrderService
+type OrderRepository interface {
+	Create(ctx context.Context, order *models.Order) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Order, error)
+	List(ctx context.Context, filter models.OrderFilter, args connection.Args) (*models.OrderConnection, error)
+	SaveTransition(ctx context.Context, order *models.Order, change models.OrderStatusChange) error
+}
+
+// OrderActor is the user reading or changing an order. Staff can access every
+// order, customers only their own.
+type OrderActor struct {
+	UserID string
+	Staff  bool
+}
+
+// CanAccess reports whether the actor may see and change the order
+func (a OrderActor) CanAccess(order *models.Order) bool {
+	return a.Staff || (a.UserID != "" && a.UserID == order.UserID)
+}
+
+// OrderHook runs after an order status change was saved
+type OrderHook func(ctx context.Context, order *models.Order, change models.OrderStatusChange) error
+
+// OrderService manages the order lifecycle. Status changes follow the state machine
+// of models.Order and run the hooks registered for the new status.
+type OrderService struct {
+	orders OrderRepository
+	hooks  map[models.OrderStatus][]OrderHook
+	// anyHooks run on every transition, after the hooks of the new status
+	anyHooks []OrderHook
+	now      func() time.Time
+}
+
+// NewOrderService creates a new OrderService
+func NewOrderService(orders OrderRepository) *OrderService {
+	return &OrderService{
+		orders: orders,
+		hooks:  make(map[models.OrderStatus][]OrderHook),
+		now:    time.Now,
+	}
+}
+
+// OnTransition registers a hook that runs whenever an order moves to the given status
+func (s *OrderService) OnTransition(to models.OrderStatus, hook OrderHook) {
+	s.hooks[to] = append(s.hooks[to], hook)
+}
+
+// OnAnyTransition registers a hook that runs whenever an order changes status
+func (s *OrderService) OnAnyTransition(hook OrderHook) {
+	s.anyHooks = append(s.anyHooks, hook)
+}
+
+// GetOrder returns an order the actor can access. Orders of other users are reported as not found.
+func (s *OrderService) GetOrder(ctx context.Context, id primitive.ObjectID, actor OrderActor) (*models.Order, error) {
+	order, err := s.orders.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanAccess(order) {
+		return nil, models.ErrNotFound
+	}
+	return order, nil
+}
+
+// ListOrders returns a page of the orders matching the filter, newest first
+func (s *OrderService) ListOrders(ctx context.Context, filter models.OrderFilter, args connection.Args) (*models.OrderConnection, error) {
+	return s.orders.List(ctx, filter, args)
+}
+
+// UpdateStatus moves an order to another status. Illegal moves fail with a *models.TransitionError,
+// paid and refunded with ErrStatusManagedByPayments.
+func (s *OrderService) UpdateStatus(ctx context.Context, id primitive.ObjectID, to models.OrderStatus, reason string, actor OrderActor) (*models.Order, error) {
+	if to == models.OrderPaid || to == models.OrderRefunded {
+		return nil, ErrStatusManagedByPayments
+	}
+	order, err := s.GetOrder(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.transition(ctx, order, to, reason, actor); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// CancelOrder cancels an order. Customers can cancel their orders until fulfillment
+// starts, staff until the order ships.
+func (s *OrderService) CancelOrder(ctx context.Context, id primitive.ObjectID, reason string, actor OrderActor) (*models.Order, error) {
+	order, err := s.GetOrder(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.Staff && order.Status != models.OrderPending && order.Status != models.OrderPaid && order.CanTransition(models.OrderCancelled) {
+		return nil, ErrCancellationNotAllowed
+	}
+	if err := s.transition(ctx, order, models.OrderCancelled, reason, actor); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// transition applies and saves a status change, then runs the hooks of the new status
+func (s *OrderService) transition(ctx context.Context, order *models.Order, to models.OrderStatus, reason string, actor OrderActor) error {
+	change, err := order.Transition(to, actor.UserID, reason, s.now())
+	if err != nil {
+		return err
+	}
+	if err := s.orders.SaveTransition(ctx, order, change); err != nil {
+		return err
+	}
+
+	// The change is saved, hook failures are logged rather than reported as a failed transition
+	hooks := append(append([]OrderHook{}, s.hooks[to]...), s.anyHooks...)
+	for _, hook := range hooks {
+		if err := hook(ctx, order, change); err != nil {
+			logger.Error("Order "+order.ID.Hex()+" "+string(to)+" hook failed", err)
+		}
+	}
+	return nil
+}
+
+// orderStockReference is the inventory reservation reference of an order
+func orderStockReference(id primitive.ObjectID) string {
+	return "order:" + id.Hex()
+}
+
+// orderStockLines returns the stock lines of the order items
+func orderStockLines(order *models.Order) []StockLine {
+	lines := make([]StockLine, 0, len(order.Items))
+	for _, item := range order.Items {
+		lines = append(lines, StockLine{SKU: item.SKU, Quantity: item.Quantity})
+	}
+	return lines
+}
+
+// CommitStockOnPayment returns a hook removing the reserved stock of an order from
+// the inventory once it is paid
+func CommitStockOnPayment(inventory *InventoryService) OrderHook {
+	return func(ctx context.Context, order *models.Order, change models.OrderStatusChange) error {
+		return inventory.Commit(ctx, orderStockReference(order.ID))
+	}
+}
+
+// ReleaseStockOnCancel returns a hook giving back the stock of a cancelled order.
+// Reservations of unpaid orders are released; units of paid orders, which were
+// already committed, are put back in stock.
+func ReleaseStockOnCancel(inventory *InventoryService) OrderHook {
+	return func(ctx context.Context, order *models.Order, change models.OrderStatusChange) error {
+		reference := orderStockReference(order.ID)
+		if *change.From == models.OrderPending {
+			return inventory.Release(ctx, reference, "order cancelled")
+		}
+		return inventory.Restock(ctx, reference, orderStockLines(order), "order cancelled")
+	}
+}
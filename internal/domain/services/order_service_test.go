@@ -0,0 +1,196 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/internal/payment"
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryOrderRepository is an in-memory services.OrderRepository
+type memoryOrderRepository struct {
+	orders map[primitive.ObjectID]models.Order
+}
+
+func newMemoryOrderRepository() *memoryOrderRepository {
+	return &memoryOrderRepository{orders: make(map[primitive.ObjectID]models.Order)}
+}
+
+func (r *memoryOrderRepository) Create(ctx context.Context, order *models.Order) error {
+	if order.CheckoutKey != "" {
+		if _, err := r.FindByCheckoutKey(ctx, order.CheckoutKey); err == nil {
+			return models.ErrDuplicate
+		}
+	}
+	order.BeforeCreate()
+	r.orders[order.ID] = *order
+	return nil
+}
+
+func (r *memoryOrderRepository) FindByCheckoutKey(ctx context.Context, key string) (*models.Order, error) {
+	for id, order := range r.orders {
+		if order.CheckoutKey == key {
+			return r.FindByID(ctx, id)
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+func (r *memoryOrderRepository) SavePayment(ctx context.Context, id primitive.ObjectID, payment *models.PaymentInfo, from models.PaymentStatus) error {
+	order, ok := r.orders[id]
+	if !ok {
+		return models.ErrConflict
+	}
+	if (order.PaymentInfo == nil && from != "") || (order.PaymentInfo != nil && order.PaymentInfo.Status != from) {
+		return models.ErrConflict
+	}
+	copied := *payment
+	order.PaymentInfo = &copied
+	r.orders[id] = order
+	return nil
+}
+
+func (r *memoryOrderRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	delete(r.orders, id)
+	return nil
+}
+
+func (r *memoryOrderRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Order, error) {
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	order.StatusHistory = append([]models.OrderStatusChange{}, order.StatusHistory...)
+	if order.PaymentInfo != nil {
+		payment := *order.PaymentInfo
+		order.PaymentInfo = &payment
+	}
+	return &order, nil
+}
+
+func (r *memoryOrderRepository) List(ctx context.Context, filter models.OrderFilter, args connection.Args) (*models.OrderConnection, error) {
+	return &models.OrderConnection{}, nil
+}
+
+func (r *memoryOrderRepository) SaveTransition(ctx context.Context, order *models.Order, change models.OrderStatusChange) error {
+	if r.orders[order.ID].Status != *change.From {
+		return models.ErrConflict
+	}
+	r.orders[order.ID] = *order
+	return nil
+}
+
+func TestOrderStatusTransitions(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryOrderRepository()
+	svc := services.NewOrderService(repo)
+	inventory, _, _ := newTestInventory(t, time.Minute)
+	payments := services.NewPaymentService(payment.NewFakeGateway(payment.FakeGatewayOptions{}), repo, svc, inventory, "USD")
+	staff := services.OrderActor{UserID: "staff-1", Staff: true}
+
+	order := &models.Order{UserID: "user-1", Status: models.OrderPending, Total: 49}
+	if err := repo.Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+
+	var hooked []models.OrderStatus
+	svc.OnTransition(models.OrderPaid, func(ctx context.Context, order *models.Order, change models.OrderStatusChange) error {
+		hooked = append(hooked, change.To)
+		return nil
+	})
+
+	_, err := svc.UpdateStatus(ctx, order.ID, models.OrderShipped, "", staff)
+	var transitionErr *models.TransitionError
+	if !errors.As(err, &transitionErr) || transitionErr.From != models.OrderPending || transitionErr.To != models.OrderShipped {
+		t.Fatalf("pending -> shipped: got %v, want a TransitionError", err)
+	}
+
+	// Orders are only paid by charging them
+	if _, err := svc.UpdateStatus(ctx, order.ID, models.OrderPaid, "paid by phone", staff); !errors.Is(err, services.ErrStatusManagedByPayments) {
+		t.Fatalf("manual pending -> paid: got %v, want ErrStatusManagedByPayments", err)
+	}
+	if status := repo.orders[order.ID].Status; status != models.OrderPending || len(hooked) != 0 {
+		t.Fatalf("order is %s after a manual payment with %d paid hooks run, want pending and none", status, len(hooked))
+	}
+	if _, err := payments.PayOrder(ctx, order.ID, testCard(payment.FakeCardApproved), services.OrderActor{UserID: "user-1"}); err != nil {
+		t.Fatalf("PayOrder: %v", err)
+	}
+	if len(hooked) != 1 {
+		t.Fatalf("paid hook ran %d times, want 1", len(hooked))
+	}
+
+	// Other customers can't see the order, its owner can't cancel it once fulfilled
+	if _, err := svc.CancelOrder(ctx, order.ID, "", services.OrderActor{UserID: "user-2"}); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("cancel by another user: got %v, want ErrNotFound", err)
+	}
+	if _, err := svc.UpdateStatus(ctx, order.ID, models.OrderFulfilled, "", staff); err != nil {
+		t.Fatalf("paid -> fulfilled: %v", err)
+	}
+	if _, err := svc.CancelOrder(ctx, order.ID, "changed my mind", services.OrderActor{UserID: "user-1"}); !errors.Is(err, services.ErrCancellationNotAllowed) {
+		t.Fatalf("cancel by owner after fulfillment: got %v, want ErrCancellationNotAllowed", err)
+	}
+
+	cancelled, err := svc.CancelOrder(ctx, order.ID, "out of stock", staff)
+	if err != nil {
+		t.Fatalf("cancel by staff: %v", err)
+	}
+	if cancelled.CancellationReason != "out of stock" || len(cancelled.StatusHistory) != 3 {
+		t.Fatalf("cancelled order has reason %q and %d history entries, want %q and 3", cancelled.CancellationReason, len(cancelled.StatusHistory), "out of stock")
+	}
+	if last := cancelled.StatusHistory[2]; last.ActorID != "staff-1" || *last.From != models.OrderFulfilled {
+		t.Fatalf("last history entry = %+v, want fulfilled -> cancelled by staff-1", last)
+	}
+
+	// Paid orders are refunded after cancellation by refunding their payment
+	if _, err := svc.UpdateStatus(ctx, order.ID, models.OrderRefunded, "", staff); !errors.Is(err, services.ErrStatusManagedByPayments) {
+		t.Fatalf("manual cancelled -> refunded: got %v, want ErrStatusManagedByPayments", err)
+	}
+	refunded, err := payments.RefundOrder(ctx, order.ID, "", staff)
+	if err != nil {
+		t.Fatalf("cancelled -> refunded: %v", err)
+	}
+	if refunded.Status != models.OrderRefunded || repo.orders[order.ID].PaymentInfo.Status != models.PaymentRefunded {
+		t.Fatalf("order is %s with payment %s, want refunded", refunded.Status, repo.orders[order.ID].PaymentInfo.Status)
+	}
+}
+
+func TestReleaseStockOnCancel(t *testing.T) {
+	ctx := context.Background()
+	inventory, items, stock := newTestInventory(t, 0)
+	repo := newMemoryOrderRepository()
+	svc := services.NewOrderService(repo)
+	svc.OnTransition(models.OrderPaid, services.CommitStockOnPayment(inventory))
+	svc.OnTransition(models.OrderCancelled, services.ReleaseStockOnCancel(inventory))
+	payments := services.NewPaymentService(payment.NewFakeGateway(payment.FakeGatewayOptions{}), repo, svc, inventory, "USD")
+	staff := services.OrderActor{Staff: true}
+
+	order := &models.Order{Status: models.OrderPending, Total: 20, Items: []models.OrderItem{{SKU: "MUG", Quantity: 2}}}
+	order.ID = primitive.NewObjectID()
+	if err := repo.Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inventory.Reserve(ctx, "order:"+order.ID.Hex(), []services.StockLine{{SKU: "MUG", Quantity: 2}}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+
+	if _, err := payments.PayOrder(ctx, order.ID, testCard(payment.FakeCardApproved), staff); err != nil {
+		t.Fatalf("PayOrder: %v", err)
+	}
+	if items.items["MUG"].OnHand != 3 {
+		t.Fatalf("MUG on hand after payment = %d, want 3", items.items["MUG"].OnHand)
+	}
+
+	if _, err := svc.CancelOrder(ctx, order.ID, "", staff); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if items.items["MUG"].OnHand != 5 || items.items["MUG"].Reserved != 0 || stock["MUG"] != 5 {
+		t.Fatalf("MUG after cancellation = on hand %d, reserved %d, stock %d; want 5, 0, 5",
+			items.items["MUG"].OnHand, items.items["MUG"].Reserved, stock["MUG"])
+	}
+}
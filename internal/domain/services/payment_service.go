@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/payment"
+	"github.com/prototype01/pkg/logger"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned by the PaymentService
+var (
+	// ErrPaymentInProgress is returned when an order already has a payment being processed or captured
+	ErrPaymentInProgress = fmt.Errorf("%w: the order already has a payment in progress", models.ErrConflict)
+	// ErrNoChallenge is returned when a challenge response is sent for a payment that isn't challenged
+	ErrNoChallenge = fmt.Errorf("%w: the payment has no pending 3-D Secure challenge", models.ErrConflict)
+)
+
+// systemActor changes orders on behalf of the application, e.g. after a payment
+var systemActor = OrderActor{Staff: true}
+
+// PaymentOrderRepository is the order storage used by the PaymentService
+type PaymentOrderRepository interface {
+	SavePayment(ctx context.Context, id primitive.ObjectID, payment *models.PaymentInfo, from models.PaymentStatus) error
+}
+
+// PaymentInput is the means of payment of an order: card details, which are
+// tokenized and discarded, a saved card of the buyer, or the response to a 3-D
+// Secure challenge
+type PaymentInput struct {
+	Card              *payment.Card
+	SavedCard         *payment.CardToken
+	ChallengeResponse string
+}
+
+// PaymentService charges orders through a payment provider. Orders are authorized
+// and captured in one go and move to paid once captured.
+type PaymentService struct {
+	provider  payment.Provider
+	orders    PaymentOrderRepository
+	lifecycle *OrderService
+	inventory *InventoryService
+	currency  string
+	now       func() time.Time
+}
+
+// NewPaymentService creates a new PaymentService charging amounts in currency
+func NewPaymentService(provider payment.Provider, orders PaymentOrderRepository, lifecycle *OrderService, inventory *InventoryService, currency string) *PaymentService {
+	return &PaymentService{
+		provider:  provider,
+		orders:    orders,
+		lifecycle: lifecycle,
+		inventory: inventory,
+		currency:  currency,
+		now:       time.Now,
+	}
+}
+
+// PayOrder charges the total of a pending order. A challenged payment is returned
+// with the requires_action status and completed by calling PayOrder again with the
+// challenge response. Declines fail with a *payment.DeclineError. The stock of the
+// order is held again before charging, in case its reservation expired while the
+// order was pending; models.ErrInsufficientStock is returned when it ran out.
+func (s *PaymentService) PayOrder(ctx context.Context, orderID primitive.ObjectID, input PaymentInput, actor OrderActor) (*models.Order, error) {
+	order, err := s.lifecycle.GetOrder(ctx, orderID, actor)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderPending {
+		return nil, &models.TransitionError{From: order.Status, To: models.OrderPaid}
+	}
+
+	if err := s.inventory.Hold(ctx, orderStockReference(order.ID), orderStockLines(order)); err != nil {
+		return nil, err
+	}
+
+	var from models.PaymentStatus
+	if order.PaymentInfo != nil {
+		from = order.PaymentInfo.Status
+	}
+
+	var info *models.PaymentInfo
+	req := payment.AuthorizeRequest{Amount: order.Total, Currency: s.currency, Reference: order.ID.Hex()}
+	if input.ChallengeResponse != "" {
+		if from != models.PaymentRequiresAction {
+			return nil, ErrNoChallenge
+		}
+		info = order.PaymentInfo
+		req.AuthorizationID = info.AuthorizationID
+		req.ChallengeResponse = input.ChallengeResponse
+	} else {
+		if from != "" && !from.CanRetry() {
+			return nil, ErrPaymentInProgress
+		}
+		token, err := s.cardToken(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		info = &models.PaymentInfo{
+			Method:         models.PaymentMethodCard,
+			Brand:          token.Brand,
+			LastFourDigits: token.LastFourDigits,
+			Token:          token.Token,
+			Amount:         order.Total,
+		}
+	}
+	req.Token = info.Token
+
+	// Claim the payment so concurrent requests can't charge the order twice
+	info.Status = models.PaymentProcessing
+	info.ChallengeURL = ""
+	info.FailureReason = ""
+	info.UpdatedAt = s.now()
+	if err := s.orders.SavePayment(ctx, order.ID, info, from); err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			return nil, ErrPaymentInProgress
+		}
+		return nil, err
+	}
+	order.PaymentInfo = info
+
+	auth, err := s.provider.Authorize(ctx, req)
+	if err != nil {
+		return nil, s.fail(ctx, order, err)
+	}
+	info.AuthorizationID = auth.ID
+
+	if auth.Status == payment.AuthorizationChallenged {
+		info.ChallengeURL = auth.ChallengeURL
+		if err := s.setStatus(ctx, order, models.PaymentRequiresAction); err != nil {
+			return nil, err
+		}
+		return order, nil
+	}
+
+	if err := s.provider.Capture(ctx, auth.ID, order.Total); err != nil {
+		if voidErr := s.provider.Void(ctx, auth.ID); voidErr != nil {
+			logger.Error("Failed to void the authorization of order "+order.ID.Hex(), voidErr)
+		}
+		return nil, s.fail(ctx, order, err)
+	}
+	if err := s.setStatus(ctx, order, models.PaymentCaptured); err != nil {
+		return nil, err
+	}
+
+	if err := s.lifecycle.transition(ctx, order, models.OrderPaid, "payment captured", systemActor); err != nil {
+		// The order changed while it was paid, e.g. it was cancelled; give the money back
+		if refundErr := s.refund(ctx, order); refundErr != nil {
+			logger.Error("Failed to refund the payment of order "+order.ID.Hex(), refundErr)
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+// RefundOrder refunds the captured payment of a delivered or cancelled order and
+// moves it to refunded. Orders without a captured payment fail with a *models.TransitionError.
+func (s *PaymentService) RefundOrder(ctx context.Context, orderID primitive.ObjectID, reason string, actor OrderActor) (*models.Order, error) {
+	order, err := s.lifecycle.GetOrder(ctx, orderID, actor)
+	if err != nil {
+		return nil, err
+	}
+	if !order.CanTransition(models.OrderRefunded) || order.PaymentInfo == nil || order.PaymentInfo.Status != models.PaymentCaptured {
+		return nil, &models.TransitionError{From: order.Status, To: models.OrderRefunded}
+	}
+	if err := s.refund(ctx, order); err != nil {
+		return nil, err
+	}
+	if err := s.lifecycle.transition(ctx, order, models.OrderRefunded, reason, actor); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// VoidPaymentOnCancel returns a hook releasing the card hold of a cancelled order
+// whose payment was authorized but not captured, e.g. one waiting for its 3-D
+// Secure challenge. Captured payments are refunded with RefundOrder.
+func VoidPaymentOnCancel(payments *PaymentService) OrderHook {
+	return func(ctx context.Context, order *models.Order, change models.OrderStatusChange) error {
+		info := order.PaymentInfo
+		if info == nil || info.AuthorizationID == "" {
+			return nil
+		}
+		if info.Status != models.PaymentRequiresAction && info.Status != models.PaymentProcessing {
+			return nil
+		}
+		if err := payments.provider.Void(ctx, info.AuthorizationID); err != nil {
+			return err
+		}
+		return payments.setStatus(ctx, order, models.PaymentVoided)
+	}
+}
+
+// cardToken returns the provider token of the means of payment
+func (s *PaymentService) cardToken(ctx context.Context, input PaymentInput) (*payment.CardToken, error) {
+	switch {
+	case input.Card != nil:
+		return s.provider.Tokenize(ctx, *input.Card)
+	case input.SavedCard != nil:
+		return input.SavedCard, nil
+	default:
+		return nil, validator.ValidationErrors{{Field: "card", Message: "a card or a payment token is required"}}
+	}
+}
+
+// refund refunds the captured payment of an order in full
+func (s *PaymentService) refund(ctx context.Context, order *models.Order) error {
+	info := order.PaymentInfo
+	if err := s.provider.Refund(ctx, info.AuthorizationID, info.Amount); err != nil {
+		return err
+	}
+	return s.setStatus(ctx, order, models.PaymentRefunded)
+}
+
+// fail records a failed payment attempt and returns cause
+func (s *PaymentService) fail(ctx context.Context, order *models.Order, cause error) error {
+	status := models.PaymentFailed
+	var decline *payment.DeclineError
+	if errors.As(cause, &decline) {
+		status = models.PaymentDeclined
+		order.PaymentInfo.FailureReason = decline.Reason
+	} else {
+		order.PaymentInfo.FailureReason = "the payment could not be processed"
+	}
+	if err := s.setStatus(ctx, order, status); err != nil {
+		logger.Error("Failed to record the failed payment of order "+order.ID.Hex(), err)
+	}
+	return cause
+}
+
+// setStatus moves the payment of an order to another status
+func (s *PaymentService) setStatus(ctx context.Context, order *models.Order, status models.PaymentStatus) error {
+	info := order.PaymentInfo
+	from := info.Status
+	info.Status = status
+	info.UpdatedAt = s.now()
+	return s.orders.SavePayment(ctx, order.ID, info, from)
+}
@@ -0,0 +1,222 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/internal/payment"
+	"github.com/prototype01/pkg/validator"
+)
+
+// newTestPayments creates a PaymentService over the fake gateway and a pending order
+func newTestPayments(t *testing.T, opts payment.FakeGatewayOptions) (*services.PaymentService, *memoryOrderRepository, *models.Order) {
+	t.Helper()
+	repo := newMemoryOrderRepository()
+	order := &models.Order{UserID: "user-1", Status: models.OrderPending, Total: 49}
+	if err := repo.Create(context.Background(), order); err != nil {
+		t.Fatal(err)
+	}
+	inventory, _, _ := newTestInventory(t, time.Minute)
+	svc := services.NewPaymentService(payment.NewFakeGateway(opts), repo, services.NewOrderService(repo), inventory, "USD")
+	return svc, repo, order
+}
+
+// testCard returns a card with the given number expiring next year
+func testCard(number string) services.PaymentInput {
+	return services.PaymentInput{Card: &payment.Card{
+		Number:      number,
+		ExpiryMonth: 12,
+		ExpiryYear:  time.Now().Year() + 1,
+		CVC:         "123",
+	}}
+}
+
+func TestPayOrderCapturesPayment(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, order := newTestPayments(t, payment.FakeGatewayOptions{})
+	owner := services.OrderActor{UserID: "user-1"}
+
+	paid, err := svc.PayOrder(ctx, order.ID, testCard(payment.FakeCardApproved), owner)
+	if err != nil {
+		t.Fatalf("PayOrder: %v", err)
+	}
+	if paid.Status != models.OrderPaid || paid.PaidAt == nil {
+		t.Fatalf("order is %s, want paid", paid.Status)
+	}
+
+	stored := repo.orders[order.ID].PaymentInfo
+	if stored.Status != models.PaymentCaptured || stored.LastFourDigits != "4242" || stored.Brand != "visa" {
+		t.Fatalf("stored payment = %+v, want a captured visa ending in 4242", stored)
+	}
+	if stored.Token == "" || stored.Token == payment.FakeCardApproved {
+		t.Fatalf("stored token = %q, want a gateway token", stored.Token)
+	}
+
+	if _, err := svc.PayOrder(ctx, order.ID, testCard(payment.FakeCardApproved), owner); !errors.Is(err, models.ErrInvalidTransition) {
+		t.Fatalf("paying a paid order: got %v, want ErrInvalidTransition", err)
+	}
+}
+
+func TestPayOrderDeclineThenChallenge(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, order := newTestPayments(t, payment.FakeGatewayOptions{})
+	owner := services.OrderActor{UserID: "user-1"}
+
+	if _, err := svc.PayOrder(ctx, order.ID, testCard(payment.FakeCardInsufficientFunds), owner); !errors.Is(err, payment.ErrDeclined) {
+		t.Fatalf("PayOrder with a declined card: got %v, want ErrDeclined", err)
+	}
+	if stored := repo.orders[order.ID].PaymentInfo; stored.Status != models.PaymentDeclined || stored.FailureReason != "insufficient funds" {
+		t.Fatalf("stored payment = %+v, want declined for insufficient funds", stored)
+	}
+
+	challenged, err := svc.PayOrder(ctx, order.ID, testCard(payment.FakeCardChallenge), owner)
+	if err != nil {
+		t.Fatalf("PayOrder with a 3-D Secure card: %v", err)
+	}
+	if challenged.Status != models.OrderPending || challenged.PaymentInfo.Status != models.PaymentRequiresAction || challenged.PaymentInfo.ChallengeURL == "" {
+		t.Fatalf("challenged payment = %+v, want requires_action with a challenge URL", challenged.PaymentInfo)
+	}
+
+	paid, err := svc.PayOrder(ctx, order.ID, services.PaymentInput{ChallengeResponse: payment.FakeChallengeApproval}, owner)
+	if err != nil {
+		t.Fatalf("PayOrder with the challenge response: %v", err)
+	}
+	if paid.Status != models.OrderPaid || paid.PaymentInfo.Status != models.PaymentCaptured {
+		t.Fatalf("order is %s with payment %s, want paid and captured", paid.Status, paid.PaymentInfo.Status)
+	}
+}
+
+func TestCancelVoidsUncapturedPayment(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryOrderRepository()
+	order := &models.Order{UserID: "user-1", Status: models.OrderPending, Total: 49}
+	if err := repo.Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	inventory, _, _ := newTestInventory(t, time.Minute)
+	gateway := payment.NewFakeGateway(payment.FakeGatewayOptions{})
+	orders := services.NewOrderService(repo)
+	svc := services.NewPaymentService(gateway, repo, orders, inventory, "USD")
+	orders.OnTransition(models.OrderCancelled, services.VoidPaymentOnCancel(svc))
+	owner := services.OrderActor{UserID: "user-1"}
+
+	if _, err := svc.PayOrder(ctx, order.ID, testCard(payment.FakeCardChallenge), owner); err != nil {
+		t.Fatalf("PayOrder with a 3-D Secure card: %v", err)
+	}
+	if _, err := orders.CancelOrder(ctx, order.ID, "changed my mind", owner); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+
+	stored := repo.orders[order.ID].PaymentInfo
+	if stored.Status != models.PaymentVoided {
+		t.Fatalf("stored payment status = %s, want voided", stored.Status)
+	}
+	if err := gateway.Capture(ctx, stored.AuthorizationID, order.Total); !errors.Is(err, payment.ErrInvalidState) {
+		t.Fatalf("capturing the voided authorization: got %v, want ErrInvalidState", err)
+	}
+}
+
+func TestPayOrderTimeoutCanBeRetried(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, order := newTestPayments(t, payment.FakeGatewayOptions{Outcome: payment.FakeTimeout})
+	owner := services.OrderActor{UserID: "user-1"}
+
+	if _, err := svc.PayOrder(ctx, order.ID, testCard(payment.FakeCardApproved), owner); !errors.Is(err, payment.ErrTimeout) {
+		t.Fatalf("PayOrder: got %v, want ErrTimeout", err)
+	}
+	if stored := repo.orders[order.ID].PaymentInfo; stored.Status != models.PaymentFailed {
+		t.Fatalf("stored payment status = %s, want failed", stored.Status)
+	}
+	if _, err := svc.PayOrder(ctx, order.ID, services.PaymentInput{}, owner); !errors.As(err, new(validator.ValidationErrors)) {
+		t.Fatalf("PayOrder without a card: got %v, want validation errors", err)
+	}
+}
+
+// stockFixture is a pending order of 2 MUG whose stock moves with the order lifecycle
+type stockFixture struct {
+	payments  *services.PaymentService
+	orders    *services.OrderService
+	inventory *services.InventoryService
+	repo      *memoryInventoryRepository
+	stock     memoryProductStock
+	order     *models.Order
+	reference string
+}
+
+func newStockFixture(t *testing.T) *stockFixture {
+	t.Helper()
+	ctx := context.Background()
+	inventory, repo, stock := newTestInventory(t, time.Minute)
+	orderRepo := newMemoryOrderRepository()
+	orders := services.NewOrderService(orderRepo)
+	orders.OnTransition(models.OrderPaid, services.CommitStockOnPayment(inventory))
+	orders.OnTransition(models.OrderCancelled, services.ReleaseStockOnCancel(inventory))
+
+	order := &models.Order{UserID: "user-1", Status: models.OrderPending, Total: 20, Items: []models.OrderItem{{SKU: "MUG", Quantity: 2, UnitPrice: 10}}}
+	if err := orderRepo.Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	// Checkout reserves the stock of an order under this reference
+	reference := "order:" + order.ID.Hex()
+	if _, err := inventory.Reserve(ctx, reference, []services.StockLine{{SKU: "MUG", Quantity: 2}}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	payments := services.NewPaymentService(payment.NewFakeGateway(payment.FakeGatewayOptions{}), orderRepo, orders, inventory, "USD")
+	return &stockFixture{payments: payments, orders: orders, inventory: inventory, repo: repo, stock: stock, order: order, reference: reference}
+}
+
+// expire lets the reservations of the order expire and runs the sweeper
+func (f *stockFixture) expire(t *testing.T) {
+	t.Helper()
+	for _, reservation := range f.repo.reservations {
+		reservation.ExpiresAt = time.Now().Add(-time.Second)
+	}
+	if released, err := f.inventory.ReleaseExpired(context.Background()); err != nil || released != 1 {
+		t.Fatalf("ReleaseExpired = %d, %v; want 1 released", released, err)
+	}
+}
+
+func TestPayOrderReservesExpiredStockAgain(t *testing.T) {
+	ctx := context.Background()
+	f := newStockFixture(t)
+	f.expire(t)
+
+	if _, err := f.payments.PayOrder(ctx, f.order.ID, testCard(payment.FakeCardApproved), services.OrderActor{UserID: "user-1"}); err != nil {
+		t.Fatalf("PayOrder: %v", err)
+	}
+	if mug := f.repo.items["MUG"]; mug.OnHand != 3 || mug.Reserved != 0 || f.stock["MUG"] != 3 {
+		t.Fatalf("after payment: MUG on hand %d, reserved %d, stock %d; want 3, 0, 3", mug.OnHand, mug.Reserved, f.stock["MUG"])
+	}
+
+	// Cancelling the paid order puts back exactly the units it sold
+	if _, err := f.orders.CancelOrder(ctx, f.order.ID, "changed my mind", services.OrderActor{UserID: "user-1"}); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if mug := f.repo.items["MUG"]; mug.OnHand != 5 || mug.Reserved != 0 || f.stock["MUG"] != 5 {
+		t.Fatalf("after cancellation: MUG on hand %d, reserved %d, stock %d; want 5, 0, 5", mug.OnHand, mug.Reserved, f.stock["MUG"])
+	}
+}
+
+func TestPayOrderRefusesWhenExpiredStockIsGone(t *testing.T) {
+	ctx := context.Background()
+	f := newStockFixture(t)
+	f.expire(t)
+
+	// Another buyer takes the units the order held
+	if _, err := f.inventory.Reserve(ctx, "order:other", []services.StockLine{{SKU: "MUG", Quantity: 4}}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+
+	_, err := f.payments.PayOrder(ctx, f.order.ID, testCard(payment.FakeCardApproved), services.OrderActor{UserID: "user-1"})
+	if !errors.Is(err, models.ErrInsufficientStock) {
+		t.Fatalf("PayOrder: got %v, want ErrInsufficientStock", err)
+	}
+	order, _ := f.orders.GetOrder(ctx, f.order.ID, services.OrderActor{UserID: "user-1"})
+	if order.Status != models.OrderPending || order.PaymentInfo != nil {
+		t.Fatalf("order = %s with payment %+v, want a pending order that was not charged", order.Status, order.PaymentInfo)
+	}
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/logger"
+	"github.com/prototype01/pkg/validator"
+)
+
+// ErrQueryNotRegistered is returned in allowlist mode for operations that weren't registered ahead of time
+var ErrQueryNotRegistered = errors.New("the operation is not registered")
+
+// PersistedQueryRepository is the storage used by the PersistedQueryService
+type PersistedQueryRepository interface {
+	Find(ctx context.Context, hash string) (*models.PersistedQuery, error)
+	Create(ctx context.Context, query *models.PersistedQuery) error
+	Extend(ctx context.Context, hash string, expiresAt time.Time) error
+	Register(ctx context.Context, query *models.PersistedQuery) error
+}
+
+// PersistedQueryService stores query documents by hash for automatic persisted
+// queries and the operation allowlist. In allowlist mode clients can't persist
+// queries and only registered queries are found. Queries persisted by clients
+// expire when they go unused for the TTL, registered queries are kept.
+type PersistedQueryService struct {
+	queries        PersistedQueryRepository
+	allowlistOnly  bool
+	ttl            time.Duration
+	maxQueryLength int
+	now            func() time.Time
+}
+
+// NewPersistedQueryService creates a new PersistedQueryService. Clients can persist
+// queries of up to maxQueryLength bytes, kept for ttl after their last use.
+func NewPersistedQueryService(queries PersistedQueryRepository, allowlistOnly bool, ttl time.Duration, maxQueryLength int) *PersistedQueryService {
+	return &PersistedQueryService{
+		queries:        queries,
+		allowlistOnly:  allowlistOnly,
+		ttl:            ttl,
+		maxQueryLength: maxQueryLength,
+		now:            time.Now,
+	}
+}
+
+// AllowlistOnly reports whether only registered queries may run
+func (s *PersistedQueryService) AllowlistOnly() bool {
+	return s.allowlistOnly
+}
+
+// QueryHash returns the hex SHA-256 hash identifying a query document
+func QueryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// Find returns the query stored under a hash. In allowlist mode queries that
+// weren't registered are reported as not found. Finding a query persisted by a
+// client pushes back its expiry, at most once per half TTL.
+func (s *PersistedQueryService) Find(ctx context.Context, hash string) (*models.PersistedQuery, error) {
+	query, err := s.queries.Find(ctx, strings.ToLower(hash))
+	if err != nil {
+		return nil, err
+	}
+	if query.Registered {
+		return query, nil
+	}
+	if s.allowlistOnly {
+		return nil, models.ErrNotFound
+	}
+
+	now := s.now()
+	if query.ExpiresAt == nil || query.ExpiresAt.Before(now.Add(s.ttl/2)) {
+		expiresAt := now.Add(s.ttl)
+		if err := s.queries.Extend(ctx, query.Hash, expiresAt); err != nil {
+			// The query is still usable, it is persisted again if it expires
+			logger.Error("Failed to extend persisted query", err)
+		} else {
+			query.ExpiresAt = &expiresAt
+		}
+	}
+	return query, nil
+}
+
+// Persist stores a query sent by a client under its hash, refusing hashes that
+// don't match the query and any new query in allowlist mode
+func (s *PersistedQueryService) Persist(ctx context.Context, hash, query string) error {
+	if s.allowlistOnly {
+		return ErrQueryNotRegistered
+	}
+	if len(query) > s.maxQueryLength {
+		return validator.ValidationErrors{{Field: "query", Message: fmt.Sprintf("persisted queries must be at most %d bytes long", s.maxQueryLength)}}
+	}
+	if QueryHash(query) != strings.ToLower(hash) {
+		return validator.ValidationErrors{{Field: "sha256Hash", Message: "the hash doesn't match the query"}}
+	}
+	expiresAt := s.now().Add(s.ttl)
+	return s.queries.Create(ctx, &models.PersistedQuery{Hash: QueryHash(query), Query: query, ExpiresAt: &expiresAt})
+}
+
+// Register adds a query to the allowlist and returns its hash
+func (s *PersistedQueryService) Register(ctx context.Context, operationName, query string) (string, error) {
+	if strings.TrimSpace(query) == "" {
+		return "", validator.ValidationErrors{{Field: "query", Message: "query is required"}}
+	}
+	persisted := &models.PersistedQuery{Hash: QueryHash(query), Query: query, OperationName: operationName}
+	if err := s.queries.Register(ctx, persisted); err != nil {
+		return "", err
+	}
+	return persisted.Hash, nil
+}
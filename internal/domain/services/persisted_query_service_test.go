@@ -0,0 +1,167 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/validator"
+)
+
+// memoryPersistedQueryRepository is an in-memory services.PersistedQueryRepository
+type memoryPersistedQueryRepository struct {
+	queries map[string]models.PersistedQuery
+	// extended counts the calls to Extend
+	extended int
+}
+
+func (r *memoryPersistedQueryRepository) Find(ctx context.Context, hash string) (*models.PersistedQuery, error) {
+	query, ok := r.queries[hash]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &query, nil
+}
+
+func (r *memoryPersistedQueryRepository) Create(ctx context.Context, query *models.PersistedQuery) error {
+	if _, ok := r.queries[query.Hash]; !ok {
+		r.queries[query.Hash] = *query
+	}
+	return nil
+}
+
+func (r *memoryPersistedQueryRepository) Extend(ctx context.Context, hash string, expiresAt time.Time) error {
+	r.extended++
+	if query, ok := r.queries[hash]; ok && !query.Registered {
+		query.ExpiresAt = &expiresAt
+		r.queries[hash] = query
+	}
+	return nil
+}
+
+func (r *memoryPersistedQueryRepository) Register(ctx context.Context, query *models.PersistedQuery) error {
+	query.Registered = true
+	query.ExpiresAt = nil
+	r.queries[query.Hash] = *query
+	return nil
+}
+
+const pingQuery = "query Ping { ping }"
+
+// testPersistedQueryTTL and testMaxQueryLength configure the PersistedQueryService of the tests
+const (
+	testPersistedQueryTTL = time.Hour
+	testMaxQueryLength    = 1024
+)
+
+func newTestPersistedQueries(allowlistOnly bool) (*services.PersistedQueryService, *memoryPersistedQueryRepository) {
+	repo := &memoryPersistedQueryRepository{queries: map[string]models.PersistedQuery{}}
+	return services.NewPersistedQueryService(repo, allowlistOnly, testPersistedQueryTTL, testMaxQueryLength), repo
+}
+
+func TestPersistedQueriesArePersistedByHash(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestPersistedQueries(false)
+
+	hash := services.QueryHash(pingQuery)
+	if err := svc.Persist(ctx, hash, pingQuery); err != nil {
+		t.Fatalf("Persist returned %v", err)
+	}
+	query, err := svc.Find(ctx, hash)
+	if err != nil {
+		t.Fatalf("Find returned %v", err)
+	}
+	if query.Query != pingQuery || query.Registered {
+		t.Fatalf("found %+v, want the unregistered ping query", query)
+	}
+
+	var validationErrs validator.ValidationErrors
+	if err := svc.Persist(ctx, services.QueryHash("{ ping }"), pingQuery); !errors.As(err, &validationErrs) {
+		t.Fatalf("Persist with a mismatched hash returned %v, want a validation error", err)
+	}
+}
+
+func TestAllowlistOnlyFindsRegisteredQueries(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newTestPersistedQueries(true)
+	persisted := "{ version { number } }"
+	repo.queries[services.QueryHash(persisted)] = models.PersistedQuery{Hash: services.QueryHash(persisted), Query: persisted}
+
+	if err := svc.Persist(ctx, services.QueryHash(pingQuery), pingQuery); !errors.Is(err, services.ErrQueryNotRegistered) {
+		t.Fatalf("Persist returned %v, want ErrQueryNotRegistered", err)
+	}
+	if _, err := svc.Find(ctx, services.QueryHash(persisted)); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("Find of an unregistered query returned %v, want ErrNotFound", err)
+	}
+
+	hash, err := svc.Register(ctx, "Ping", pingQuery)
+	if err != nil {
+		t.Fatalf("Register returned %v", err)
+	}
+	if hash != services.QueryHash(pingQuery) {
+		t.Fatalf("Register returned hash %s, want %s", hash, services.QueryHash(pingQuery))
+	}
+	query, err := svc.Find(ctx, hash)
+	if err != nil {
+		t.Fatalf("Find returned %v", err)
+	}
+	if query.OperationName != "Ping" {
+		t.Fatalf("found operation %q, want Ping", query.OperationName)
+	}
+}
+
+func TestPersistedQueriesExpireUnlessUsed(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newTestPersistedQueries(false)
+	hash := services.QueryHash(pingQuery)
+
+	if err := svc.Persist(ctx, hash, pingQuery); err != nil {
+		t.Fatalf("Persist returned %v", err)
+	}
+	expiresAt := repo.queries[hash].ExpiresAt
+	if expiresAt == nil || time.Until(*expiresAt) < testPersistedQueryTTL-time.Minute {
+		t.Fatalf("persisted query expires at %v, want in %s", expiresAt, testPersistedQueryTTL)
+	}
+
+	// A recently extended query isn't written again
+	if _, err := svc.Find(ctx, hash); err != nil || repo.extended != 0 {
+		t.Fatalf("Find returned %v after %d extensions, want no extension", err, repo.extended)
+	}
+
+	// A query close to its expiry gets a full TTL again
+	soon := time.Now().Add(time.Minute)
+	query := repo.queries[hash]
+	query.ExpiresAt = &soon
+	repo.queries[hash] = query
+	if _, err := svc.Find(ctx, hash); err != nil {
+		t.Fatalf("Find returned %v", err)
+	}
+	if expiresAt := repo.queries[hash].ExpiresAt; repo.extended != 1 || time.Until(*expiresAt) < testPersistedQueryTTL-time.Minute {
+		t.Fatalf("query expires at %v after %d extensions, want one extension to a full TTL", expiresAt, repo.extended)
+	}
+
+	// Registered queries don't expire
+	if _, err := svc.Register(ctx, "Ping", pingQuery); err != nil {
+		t.Fatalf("Register returned %v", err)
+	}
+	if _, err := svc.Find(ctx, hash); err != nil || repo.queries[hash].ExpiresAt != nil || repo.extended != 1 {
+		t.Fatalf("registered query = %+v, %v, want no expiry", repo.queries[hash], err)
+	}
+}
+
+func TestPersistedQueriesRejectLongQueries(t *testing.T) {
+	svc, repo := newTestPersistedQueries(false)
+	query := "{ " + strings.Repeat("ping ", testMaxQueryLength/5+1) + "}"
+
+	var validationErrs validator.ValidationErrors
+	if err := svc.Persist(context.Background(), services.QueryHash(query), query); !errors.As(err, &validationErrs) || validationErrs[0].Field != "query" {
+		t.Fatalf("Persist of a %d bytes query returned %v, want a query validation error", len(query), err)
+	}
+	if len(repo.queries) != 0 {
+		t.Fatal("a query over the maximum length was stored")
+	}
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/connection"
+	"github.com/prototype01/pkg/logger"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrSKUTaken is returned when a product SKU is already used by another product
+var ErrSKUTaken = errors.New("SKU is already used by another product")
+
+// skuPattern restricts SKUs to upper case letters, digits, dashes and underscores
+var skuPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]{1,63}$`)
+
+// ProductRepository is the storage used by the ProductService
+type ProductRepository interface {
+	Create(ctx context.Context, product *models.Product) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error)
+	List(ctx context.Context, filter models.ProductFilter, sort models.ProductSort, args connection.Args) (*models.ProductConnection, error)
+	Update(ctx context.Context, product *models.Product) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// CreateProductInput holds the data needed to create a product
+type CreateProductInput struct {
+	Name        string
+	Description string
+	Price       float64
+	SKU         string
+	Brand       string
+	Images      []models.ProductImage
+	CategoryID  *primitive.ObjectID
+	Stock       int
+}
+
+// UpdateProductInput holds the product fields to change. Nil fields are left unchanged.
+type UpdateProductInput struct {
+	Name        *string
+	Description *string
+	Price       *float64
+	SKU         *string
+	Brand       *string
+	Images      []models.ProductImage
+	CategoryID  *primitive.ObjectID
+	// Stock sets the on hand quantity through the inventory
+	Stock *int
+}
+
+// ProductService manages the product catalog
+type ProductService struct {
+	products   ProductRepository
+	categories CategoryRepository
+	inventory  *InventoryService
+	search     ProductIndexer
+}
+
+// NewProductService creates a new ProductService
+func NewProductService(products ProductRepository, categories CategoryRepository, inventory *InventoryService, search ProductIndexer) *ProductService {
+	return &ProductService{
+		products:   products,
+		categories: categories,
+		inventory:  inventory,
+		search:     search,
+	}
+}
+
+// GetProduct returns the product with the given ID
+func (s *ProductService) GetProduct(ctx context.Context, id primitive.ObjectID) (*models.Product, error) {
+	return s.products.FindByID(ctx, id)
+}
+
+// ListProducts returns a page of the products matching the filter, newest first by default
+func (s *ProductService) ListProducts(ctx context.Context, filter models.ProductFilter, sort *models.ProductSort, args connection.Args) (*models.ProductConnection, error) {
+	if sort == nil {
+		sort = &models.ProductSort{Field: models.ProductSortCreatedAt, Descending: true}
+	}
+	return s.products.List(ctx, filter, *sort, args)
+}
+
+// CreateProduct validates the input and creates a product
+func (s *ProductService) CreateProduct(ctx context.Context, input CreateProductInput) (*models.Product, error) {
+	product := &models.Product{
+		Name:        strings.TrimSpace(input.Name),
+		Description: strings.TrimSpace(input.Description),
+		Price:       input.Price,
+		SKU:         normalizeSKU(input.SKU),
+		Brand:       strings.TrimSpace(input.Brand),
+		Images:      input.Images,
+		CategoryID:  input.CategoryID,
+		Stock:       input.Stock,
+	}
+	if product.Images == nil {
+		product.Images = []models.ProductImage{}
+	}
+
+	if err := validateProduct(product); err != nil {
+		return nil, err
+	}
+
+	category, err := s.requireCategory(ctx, product.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.products.Create(ctx, product); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			return nil, ErrSKUTaken
+		}
+		return nil, err
+	}
+
+	if err := s.inventory.InitializeStock(ctx, product, product.Stock); err != nil {
+		return nil, err
+	}
+	if err := s.countProduct(ctx, category, 1); err != nil {
+		return nil, err
+	}
+	s.index(ctx, product)
+	return product, nil
+}
+
+// UpdateProduct applies the changed fields to a product after validating them
+func (s *ProductService) UpdateProduct(ctx context.Context, id primitive.ObjectID, input UpdateProductInput) (*models.Product, error) {
+	product, err := s.products.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	previousCategoryID := product.CategoryID
+	previousSKU := product.SKU
+
+	if input.Name != nil {
+		product.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		product.Description = strings.TrimSpace(*input.Description)
+	}
+	if input.Price != nil {
+		product.Price = *input.Price
+	}
+	if input.SKU != nil {
+		product.SKU = normalizeSKU(*input.SKU)
+	}
+	if input.Brand != nil {
+		product.Brand = strings.TrimSpace(*input.Brand)
+	}
+	if input.Images != nil {
+		product.Images = input.Images
+	}
+	if input.CategoryID != nil {
+		product.CategoryID = input.CategoryID
+	}
+	if err := validateProduct(product); err != nil {
+		return nil, err
+	}
+
+	var oldCategory, newCategory *models.Category
+	categoryChanged := !sameCategory(previousCategoryID, product.CategoryID)
+	if categoryChanged {
+		if oldCategory, err = s.findCategory(ctx, previousCategoryID); err != nil {
+			return nil, err
+		}
+		if newCategory, err = s.requireCategory(ctx, product.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.products.Update(ctx, product); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			return nil, ErrSKUTaken
+		}
+		return nil, err
+	}
+
+	if product.SKU != previousSKU {
+		if err := s.inventory.RenameSKU(ctx, previousSKU, product.SKU); err != nil {
+			return nil, err
+		}
+	}
+	if input.Stock != nil {
+		item, err := s.inventory.SetStock(ctx, product.SKU, *input.Stock, "product updated")
+		if err != nil {
+			return nil, err
+		}
+		product.Stock = item.Available()
+	}
+
+	if categoryChanged {
+		if err := s.countProduct(ctx, oldCategory, -1); err != nil {
+			return nil, err
+		}
+		if err := s.countProduct(ctx, newCategory, 1); err != nil {
+			return nil, err
+		}
+	}
+	s.index(ctx, product)
+	return product, nil
+}
+
+// UpdateStock sets the on hand quantity of a product and returns the product with its new available stock
+func (s *ProductService) UpdateStock(ctx context.Context, id primitive.ObjectID, quantity int, reason string) (*models.Product, error) {
+	product, err := s.products.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := s.inventory.SetStock(ctx, product.SKU, quantity, reason)
+	if err != nil {
+		return nil, err
+	}
+	product.Stock = item.Available()
+	return product, nil
+}
+
+// DeleteProduct removes a product from the catalog
+func (s *ProductService) DeleteProduct(ctx context.Context, id primitive.ObjectID) error {
+	product, err := s.products.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	category, err := s.findCategory(ctx, product.CategoryID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.products.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := s.inventory.RemoveStock(ctx, product.SKU); err != nil {
+		return err
+	}
+	if err := s.search.RemoveProduct(ctx, id); err != nil {
+		logger.Error("Failed to remove product "+id.Hex()+" from the search index", err)
+	}
+	return s.countProduct(ctx, category, -1)
+}
+
+// index updates the search index after a catalog change. The catalog is the source
+// of truth, so a failure is logged rather than failing the change.
+func (s *ProductService) index(ctx context.Context, product *models.Product) {
+	if err := s.search.IndexProduct(ctx, product); err != nil {
+		logger.Error("Failed to index product "+product.ID.Hex(), err)
+	}
+}
+
+// findCategory loads the category of a product, returning nil for uncategorized
+// products and for categories that no longer exist
+func (s *ProductService) findCategory(ctx context.Context, id *primitive.ObjectID) (*models.Category, error) {
+	if id == nil {
+		return nil, nil
+	}
+	category, err := s.categories.FindByID(ctx, *id)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+	return category, err
+}
+
+// requireCategory loads the category a product is assigned to, rejecting unknown categories
+func (s *ProductService) requireCategory(ctx context.Context, id *primitive.ObjectID) (*models.Category, error) {
+	category, err := s.findCategory(ctx, id)
+	if err == nil && id != nil && category == nil {
+		return nil, validator.ValidationErrors{{Field: "categoryId", Message: "category does not exist"}}
+	}
+	return category, err
+}
+
+// countProduct adjusts the product count of a category and all of its ancestors
+func (s *ProductService) countProduct(ctx context.Context, category *models.Category, delta int) error {
+	if category == nil {
+		return nil
+	}
+	return s.categories.IncrementProductCount(ctx, category.Path(), delta)
+}
+
+// sameCategory reports whether two optional category IDs are equal
+func sameCategory(a, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// validateProduct validates every product field and reports all failures together
+func validateProduct(product *models.Product) error {
+	var errs validator.ValidationErrors
+	if err := validator.ValidateNonEmpty("name", product.Name); err != nil {
+		errs = append(errs, validator.ValidationError{Field: "name", Message: err.Error()})
+	}
+	if product.Price < 0 {
+		errs = append(errs, validator.ValidationError{Field: "price", Message: "price cannot be negative"})
+	}
+	if !skuPattern.MatchString(product.SKU) {
+		errs = append(errs, validator.ValidationError{Field: "sku", Message: "SKU must be 2-64 letters, digits, dashes or underscores"})
+	}
+	if product.Stock < 0 {
+		errs = append(errs, validator.ValidationError{Field: "stock", Message: "stock cannot be negative"})
+	}
+	for _, image := range product.Images {
+		if u, err := url.Parse(image.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, validator.ValidationError{Field: "images", Message: "image URL must be an absolute http(s) URL"})
+			break
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// normalizeSKU trims and upper-cases a SKU
+func normalizeSKU(sku string) string {
+	return strings.ToUpper(strings.TrimSpace(sku))
+}
@@ -0,0 +1,222 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/connection"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryProductRepository is an in-memory services.ProductRepository with unique SKUs
+type memoryProductRepository struct {
+	products map[primitive.ObjectID]*models.Product
+}
+
+func newMemoryProductRepository() *memoryProductRepository {
+	return &memoryProductRepository{products: make(map[primitive.ObjectID]*models.Product)}
+}
+
+func (r *memoryProductRepository) Create(ctx context.Context, product *models.Product) error {
+	if r.skuTaken(product) {
+		return models.ErrDuplicate
+	}
+	product.BeforeCreate()
+	copied := *product
+	r.products[product.ID] = &copied
+	return nil
+}
+
+func (r *memoryProductRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error) {
+	product, ok := r.products[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	copied := *product
+	return &copied, nil
+}
+
+func (r *memoryProductRepository) List(ctx context.Context, filter models.ProductFilter, sort models.ProductSort, args connection.Args) (*models.ProductConnection, error) {
+	return &models.ProductConnection{}, nil
+}
+
+func (r *memoryProductRepository) Update(ctx context.Context, product *models.Product) error {
+	if r.skuTaken(product) {
+		return models.ErrDuplicate
+	}
+	copied := *product
+	r.products[product.ID] = &copied
+	return nil
+}
+
+func (r *memoryProductRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	delete(r.products, id)
+	return nil
+}
+
+// skuTaken reports whether another product uses the SKU of product
+func (r *memoryProductRepository) skuTaken(product *models.Product) bool {
+	for _, other := range r.products {
+		if other.ID != product.ID && other.SKU == product.SKU {
+			return true
+		}
+	}
+	return false
+}
+
+// productFixture is a ProductService over in-memory categories, inventory and search
+type productFixture struct {
+	svc        *services.ProductService
+	categories *memoryCategoryRepository
+	inventory  *memoryInventoryRepository
+	search     *memorySearchEngine
+	// home and kitchen are a top level category and its child
+	home, kitchen *models.Category
+}
+
+func newProductFixture(t *testing.T) *productFixture {
+	t.Helper()
+	ctx := context.Background()
+	f := &productFixture{
+		categories: newMemoryCategoryRepository(),
+		inventory:  newMemoryInventoryRepository(),
+		search:     &memorySearchEngine{},
+	}
+	inventory := services.NewInventoryService(noTransactions{}, f.inventory, memoryProductStock{}, time.Minute)
+	f.svc = services.NewProductService(newMemoryProductRepository(), f.categories, inventory, f.search)
+
+	categories := services.NewCategoryService(f.categories, memoryCategoryProducts{})
+	var err error
+	if f.home, err = categories.CreateCategory(ctx, services.CreateCategoryInput{Name: "Home"}); err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	if f.kitchen, err = categories.CreateCategory(ctx, services.CreateCategoryInput{Name: "Kitchen", ParentID: &f.home.ID}); err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	return f
+}
+
+// productCount returns the stored product count of a category
+func (f *productFixture) productCount(category *models.Category) int {
+	return f.categories.categories[category.ID].ProductCount
+}
+
+func TestProductServiceCreate(t *testing.T) {
+	ctx := context.Background()
+	f := newProductFixture(t)
+
+	product, err := f.svc.CreateProduct(ctx, services.CreateProductInput{
+		Name:       " Mug ",
+		Price:      9.5,
+		SKU:        " mug-01 ",
+		CategoryID: &f.kitchen.ID,
+		Stock:      4,
+	})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	if product.Name != "Mug" || product.SKU != "MUG-01" {
+		t.Errorf("product = %+v, want a trimmed name and an upper case SKU", product)
+	}
+
+	// The category and its ancestors count the product
+	if f.productCount(f.kitchen) != 1 || f.productCount(f.home) != 1 {
+		t.Errorf("product counts = %d kitchen, %d home, want 1 each", f.productCount(f.kitchen), f.productCount(f.home))
+	}
+	item, ok := f.inventory.items["MUG-01"]
+	if !ok || item.OnHand != 4 || item.ProductID != product.ID {
+		t.Errorf("inventory item = %+v, want 4 units of the product", item)
+	}
+	if indexed, ok := f.search.indexed[product.ID]; !ok || indexed.Name != "Mug" {
+		t.Errorf("expected the product to be indexed, got %+v", f.search.indexed)
+	}
+
+	_, err = f.svc.CreateProduct(ctx, services.CreateProductInput{Name: "Other mug", SKU: "MUG-01"})
+	if !errors.Is(err, services.ErrSKUTaken) {
+		t.Errorf("duplicate SKU: got %v, want ErrSKUTaken", err)
+	}
+}
+
+func TestProductServiceCreateRejectsUnknownCategory(t *testing.T) {
+	ctx := context.Background()
+	f := newProductFixture(t)
+	unknown := primitive.NewObjectID()
+
+	_, err := f.svc.CreateProduct(ctx, services.CreateProductInput{Name: "Mug", SKU: "MUG-01", CategoryID: &unknown})
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) || errs[0].Field != "categoryId" {
+		t.Fatalf("got %v, want a categoryId validation error", err)
+	}
+	if len(f.inventory.items) != 0 || len(f.search.indexed) != 0 {
+		t.Error("expected no inventory item and no index entry for a rejected product")
+	}
+}
+
+func TestProductServiceUpdate(t *testing.T) {
+	ctx := context.Background()
+	f := newProductFixture(t)
+	product, err := f.svc.CreateProduct(ctx, services.CreateProductInput{Name: "Mug", SKU: "MUG-01", CategoryID: &f.kitchen.ID, Stock: 4})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	name, sku, stock := "Tall mug", "MUG-02", 7
+	updated, err := f.svc.UpdateProduct(ctx, product.ID, services.UpdateProductInput{
+		Name:       &name,
+		SKU:        &sku,
+		Stock:      &stock,
+		CategoryID: &f.home.ID,
+	})
+	if err != nil {
+		t.Fatalf("UpdateProduct: %v", err)
+	}
+	if updated.Stock != 7 {
+		t.Errorf("stock = %d, want 7", updated.Stock)
+	}
+
+	// The inventory item follows the SKU and gets the new quantity
+	if _, ok := f.inventory.items["MUG-01"]; ok {
+		t.Error("expected the inventory item of the old SKU to be renamed")
+	}
+	if item, ok := f.inventory.items["MUG-02"]; !ok || item.OnHand != 7 {
+		t.Errorf("inventory item = %+v, want 7 units under the new SKU", item)
+	}
+
+	// Moving to the parent category leaves it counted once and the child at zero
+	if f.productCount(f.kitchen) != 0 || f.productCount(f.home) != 1 {
+		t.Errorf("product counts = %d kitchen, %d home, want 0 and 1", f.productCount(f.kitchen), f.productCount(f.home))
+	}
+	if indexed := f.search.indexed[product.ID]; indexed.Name != "Tall mug" || indexed.SKU != "MUG-02" {
+		t.Errorf("indexed product = %+v, want the updated product", indexed)
+	}
+}
+
+func TestProductServiceDelete(t *testing.T) {
+	ctx := context.Background()
+	f := newProductFixture(t)
+	product, err := f.svc.CreateProduct(ctx, services.CreateProductInput{Name: "Mug", SKU: "MUG-01", CategoryID: &f.kitchen.ID, Stock: 4})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	if err := f.svc.DeleteProduct(ctx, product.ID); err != nil {
+		t.Fatalf("DeleteProduct: %v", err)
+	}
+	if _, err := f.svc.GetProduct(ctx, product.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetProduct after delete: got %v, want ErrNotFound", err)
+	}
+	if f.productCount(f.kitchen) != 0 || f.productCount(f.home) != 0 {
+		t.Errorf("product counts = %d kitchen, %d home, want 0 each", f.productCount(f.kitchen), f.productCount(f.home))
+	}
+	if _, ok := f.inventory.items["MUG-01"]; ok {
+		t.Error("expected the inventory item to be removed")
+	}
+	if _, ok := f.search.indexed[product.ID]; ok {
+		t.Error("expected the product to be removed from the search index")
+	}
+}
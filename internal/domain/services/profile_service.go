@@ -0,0 +1,438 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/payment"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errPaymentMethodExpired is returned when an expired card is charged or made the default method
+var errPaymentMethodExpired = validator.ValidationErrors{{Field: "paymentMethodId", Message: "the card has expired"}}
+
+// Profile limits
+const (
+	maxShippingAddresses = 10
+	maxPaymentMethods    = 5
+)
+
+// ProfileRepository is the user storage used by the ProfileService
+type ProfileRepository interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	SaveProfile(ctx context.Context, user *models.User) error
+}
+
+// UpdateProfileInput holds the profile fields to change. Nil fields are left unchanged.
+type UpdateProfileInput struct {
+	FirstName      *string
+	LastName       *string
+	ProfilePicture *string
+}
+
+// ShippingAddressInput is a saved address. IsDefault makes the address the default
+// one; the default address only changes when another address is made the default.
+type ShippingAddressInput struct {
+	Address   models.Address
+	IsDefault bool
+}
+
+// ProfileService manages the profile, saved addresses and saved payment methods of
+// users. Cards are tokenized by the payment provider and only the token is kept.
+type ProfileService struct {
+	users    ProfileRepository
+	provider payment.Provider
+	now      func() time.Time
+}
+
+// NewProfileService creates a new ProfileService
+func NewProfileService(users ProfileRepository, provider payment.Provider) *ProfileService {
+	return &ProfileService{
+		users:    users,
+		provider: provider,
+		now:      time.Now,
+	}
+}
+
+// GetProfile returns a user with their addresses and payment methods. Cards that
+// expired since the last visit are marked expired.
+func (s *ProfileService) GetProfile(ctx context.Context, userID string) (*models.User, error) {
+	var user *models.User
+	err := retryOnConflict(func() error {
+		var err error
+		if user, err = s.find(ctx, userID); err != nil {
+			return err
+		}
+		if !refreshPaymentMethods(user, s.now()) {
+			return nil
+		}
+		return s.users.SaveProfile(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateProfile changes the name and profile picture of a user
+func (s *ProfileService) UpdateProfile(ctx context.Context, userID string, input UpdateProfileInput) (*models.User, error) {
+	return s.update(ctx, userID, func(user *models.User) error {
+		if input.FirstName != nil {
+			user.FirstName = strings.TrimSpace(*input.FirstName)
+		}
+		if input.LastName != nil {
+			user.LastName = strings.TrimSpace(*input.LastName)
+		}
+		if input.ProfilePicture != nil {
+			user.ProfilePicture = strings.TrimSpace(*input.ProfilePicture)
+		}
+		return validateProfile(user)
+	})
+}
+
+// AddShippingAddress saves an address for a user. The first address becomes the default.
+func (s *ProfileService) AddShippingAddress(ctx context.Context, userID string, input ShippingAddressInput) (*models.ShippingAddress, error) {
+	address := models.ShippingAddress{ID: primitive.NewObjectID(), Address: normalizeAddress(input.Address)}
+	if err := validateAddress("", address.Address); err != nil {
+		return nil, err
+	}
+
+	user, err := s.update(ctx, userID, func(user *models.User) error {
+		if len(user.ShippingAddresses) >= maxShippingAddresses {
+			return validator.ValidationErrors{{Field: "input", Message: fmt.Sprintf("at most %d addresses can be saved", maxShippingAddresses)}}
+		}
+		user.ShippingAddresses = append(user.ShippingAddresses, address)
+		if input.IsDefault {
+			setDefaultAddress(user, address.ID)
+		}
+		ensureDefaultAddress(user)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	saved, _ := user.FindShippingAddress(address.ID)
+	return saved, nil
+}
+
+// UpdateShippingAddress replaces a saved address of a user
+func (s *ProfileService) UpdateShippingAddress(ctx context.Context, userID string, id primitive.ObjectID, input ShippingAddressInput) (*models.ShippingAddress, error) {
+	address := normalizeAddress(input.Address)
+	if err := validateAddress("", address); err != nil {
+		return nil, err
+	}
+
+	user, err := s.update(ctx, userID, func(user *models.User) error {
+		saved, ok := user.FindShippingAddress(id)
+		if !ok {
+			return models.ErrNotFound
+		}
+		saved.Address = address
+		if input.IsDefault {
+			setDefaultAddress(user, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	saved, _ := user.FindShippingAddress(id)
+	return saved, nil
+}
+
+// DeleteShippingAddress removes a saved address of a user. When the default address
+// is removed, the oldest remaining address becomes the default.
+func (s *ProfileService) DeleteShippingAddress(ctx context.Context, userID string, id primitive.ObjectID) error {
+	_, err := s.update(ctx, userID, func(user *models.User) error {
+		for i := range user.ShippingAddresses {
+			if user.ShippingAddresses[i].ID == id {
+				user.ShippingAddresses = append(user.ShippingAddresses[:i], user.ShippingAddresses[i+1:]...)
+				ensureDefaultAddress(user)
+				return nil
+			}
+		}
+		return models.ErrNotFound
+	})
+	return err
+}
+
+// AddPaymentMethod tokenizes a card and saves it for a user. The first card becomes the default.
+func (s *ProfileService) AddPaymentMethod(ctx context.Context, userID string, card payment.Card, isDefault bool) (*models.PaymentMethod, error) {
+	if _, err := s.find(ctx, userID); err != nil {
+		return nil, err
+	}
+	token, err := s.provider.Tokenize(ctx, card)
+	if err != nil {
+		return nil, err
+	}
+	method := models.PaymentMethod{
+		ID:             primitive.NewObjectID(),
+		Type:           models.PaymentMethodCard,
+		Brand:          token.Brand,
+		LastFourDigits: token.LastFourDigits,
+		ExpiryMonth:    token.ExpiryMonth,
+		ExpiryYear:     token.ExpiryYear,
+		Token:          token.Token,
+		CreatedAt:      s.now(),
+	}
+
+	user, err := s.update(ctx, userID, func(user *models.User) error {
+		if len(user.PaymentMethods) >= maxPaymentMethods {
+			return validator.ValidationErrors{{Field: "input", Message: fmt.Sprintf("at most %d payment methods can be saved", maxPaymentMethods)}}
+		}
+		user.PaymentMethods = append(user.PaymentMethods, method)
+		if isDefault {
+			setDefaultPaymentMethod(user, method.ID)
+		}
+		refreshPaymentMethods(user, s.now())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	saved, _ := user.FindPaymentMethod(method.ID)
+	return saved, nil
+}
+
+// SetDefaultPaymentMethod makes a saved card of a user the default payment method
+func (s *ProfileService) SetDefaultPaymentMethod(ctx context.Context, userID string, id primitive.ObjectID) (*models.PaymentMethod, error) {
+	user, err := s.update(ctx, userID, func(user *models.User) error {
+		method, ok := user.FindPaymentMethod(id)
+		if !ok {
+			return models.ErrNotFound
+		}
+		if method.Expired {
+			return errPaymentMethodExpired
+		}
+		setDefaultPaymentMethod(user, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	saved, _ := user.FindPaymentMethod(id)
+	return saved, nil
+}
+
+// DeletePaymentMethod removes a saved card of a user
+func (s *ProfileService) DeletePaymentMethod(ctx context.Context, userID string, id primitive.ObjectID) error {
+	_, err := s.update(ctx, userID, func(user *models.User) error {
+		for i := range user.PaymentMethods {
+			if user.PaymentMethods[i].ID == id {
+				user.PaymentMethods = append(user.PaymentMethods[:i], user.PaymentMethods[i+1:]...)
+				refreshPaymentMethods(user, s.now())
+				return nil
+			}
+		}
+		return models.ErrNotFound
+	})
+	return err
+}
+
+// SavedCard returns the provider token and card details of a saved card of a user
+// so it can be charged, refusing expired cards
+func (s *ProfileService) SavedCard(ctx context.Context, userID string, id primitive.ObjectID) (*payment.CardToken, error) {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	method, ok := user.FindPaymentMethod(id)
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return chargeableCard(method)
+}
+
+// SavedCardByToken returns the saved card of a user with the given provider token,
+// so clients can only charge tokens of their own cards. Unknown tokens are reported
+// as not found.
+func (s *ProfileService) SavedCardByToken(ctx context.Context, userID string, token string) (*payment.CardToken, error) {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range user.PaymentMethods {
+		if user.PaymentMethods[i].Token == token {
+			return chargeableCard(&user.PaymentMethods[i])
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+// chargeableCard returns the provider token and card details of a saved card, refusing expired cards
+func chargeableCard(method *models.PaymentMethod) (*payment.CardToken, error) {
+	if method.Expired {
+		return nil, errPaymentMethodExpired
+	}
+	return &payment.CardToken{
+		Token:          method.Token,
+		Brand:          method.Brand,
+		LastFourDigits: method.LastFourDigits,
+		ExpiryMonth:    method.ExpiryMonth,
+		ExpiryYear:     method.ExpiryYear,
+	}, nil
+}
+
+// update loads a user, applies change and saves the profile, retrying when the
+// profile was changed concurrently
+func (s *ProfileService) update(ctx context.Context, userID string, change func(*models.User) error) (*models.User, error) {
+	var user *models.User
+	err := retryOnConflict(func() error {
+		var err error
+		if user, err = s.find(ctx, userID); err != nil {
+			return err
+		}
+		refreshPaymentMethods(user, s.now())
+		if err := change(user); err != nil {
+			return err
+		}
+		return s.users.SaveProfile(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// find returns the user with the given hex ID
+func (s *ProfileService) find(ctx context.Context, userID string) (*models.User, error) {
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, models.ErrNotFound
+	}
+	return s.users.FindByID(ctx, id)
+}
+
+// setDefaultAddress makes the address with the given ID the only default address
+func setDefaultAddress(user *models.User, id primitive.ObjectID) {
+	for i := range user.ShippingAddresses {
+		user.ShippingAddresses[i].IsDefault = user.ShippingAddresses[i].ID == id
+	}
+}
+
+// ensureDefaultAddress makes the oldest address the default when none is
+func ensureDefaultAddress(user *models.User) {
+	for _, address := range user.ShippingAddresses {
+		if address.IsDefault {
+			return
+		}
+	}
+	if len(user.ShippingAddresses) > 0 {
+		user.ShippingAddresses[0].IsDefault = true
+	}
+}
+
+// setDefaultPaymentMethod makes the payment method with the given ID the only default method
+func setDefaultPaymentMethod(user *models.User, id primitive.ObjectID) {
+	for i := range user.PaymentMethods {
+		user.PaymentMethods[i].IsDefault = user.PaymentMethods[i].ID == id
+	}
+}
+
+// refreshPaymentMethods marks the cards that expired before now and keeps exactly
+// one default method, moving the default off an expired card to the newest valid
+// card. It reports whether any method changed.
+func refreshPaymentMethods(user *models.User, now time.Time) bool {
+	changed := false
+	var current, newestValid *models.PaymentMethod
+	for i := range user.PaymentMethods {
+		method := &user.PaymentMethods[i]
+		if !method.Expired && payment.CardExpired(method.ExpiryMonth, method.ExpiryYear, now) {
+			method.Expired = true
+			changed = true
+		}
+		if method.IsDefault && current == nil {
+			current = method
+		}
+		if !method.Expired && (newestValid == nil || !method.CreatedAt.Before(newestValid.CreatedAt)) {
+			newestValid = method
+		}
+	}
+
+	next := current
+	if next == nil || (next.Expired && newestValid != nil) {
+		next = newestValid
+	}
+	if next == nil && len(user.PaymentMethods) > 0 {
+		next = &user.PaymentMethods[0]
+	}
+	for i := range user.PaymentMethods {
+		method := &user.PaymentMethods[i]
+		if isDefault := method == next; method.IsDefault != isDefault {
+			method.IsDefault = isDefault
+			changed = true
+		}
+	}
+	return changed
+}
+
+// validateProfile validates the profile fields of a user
+func validateProfile(user *models.User) error {
+	var errs validator.ValidationErrors
+	if err := validator.ValidateNonEmpty("firstName", user.FirstName); err != nil {
+		errs = append(errs, validator.ValidationError{Field: "firstName", Message: err.Error()})
+	}
+	if err := validator.ValidateNonEmpty("lastName", user.LastName); err != nil {
+		errs = append(errs, validator.ValidationError{Field: "lastName", Message: err.Error()})
+	}
+	if user.ProfilePicture != "" {
+		if u, err := url.Parse(user.ProfilePicture); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, validator.ValidationError{Field: "profilePicture", Message: "profile picture must be an absolute http(s) URL"})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// normalizeAddress trims the fields of an address and upper-cases its country and postal code
+func normalizeAddress(address models.Address) models.Address {
+	return models.Address{
+		FullName: strings.TrimSpace(address.FullName),
+		Street:   strings.TrimSpace(address.Street),
+		City:     strings.TrimSpace(address.City),
+		State:    strings.TrimSpace(address.State),
+		ZipCode:  strings.ToUpper(strings.TrimSpace(address.ZipCode)),
+		Country:  strings.ToUpper(strings.TrimSpace(address.Country)),
+	}
+}
+
+// validateAddress checks that a normalized address is complete and that its postal
+// code matches the format of its country. Field names are prefixed with prefix.
+func validateAddress(prefix string, address models.Address) error {
+	field := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + "." + name
+	}
+
+	var errs validator.ValidationErrors
+	required := []struct{ field, value string }{
+		{field("street"), address.Street},
+		{field("city"), address.City},
+		{field("country"), address.Country},
+	}
+	for _, r := range required {
+		if err := validator.ValidateNonEmpty(r.field, r.value); err != nil {
+			errs = append(errs, validator.ValidationError{Field: r.field, Message: err.Error()})
+		}
+	}
+	if address.Country != "" {
+		if err := validator.ValidateCountryCode(address.Country); err != nil {
+			errs = append(errs, validator.ValidationError{Field: field("country"), Message: err.Error()})
+		} else if err := validator.ValidatePostalCode(address.Country, address.ZipCode); err != nil {
+			errs = append(errs, validator.ValidationError{Field: field("zipCode"), Message: err.Error()})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
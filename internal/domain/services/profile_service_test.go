@@ -0,0 +1,172 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/internal/payment"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryProfileRepository is an in-memory services.ProfileRepository
+type memoryProfileRepository struct {
+	users map[primitive.ObjectID]models.User
+}
+
+func (r *memoryProfileRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	copied := copyUser(&user)
+	return &copied, nil
+}
+
+func (r *memoryProfileRepository) SaveProfile(ctx context.Context, user *models.User) error {
+	if stored, ok := r.users[user.ID]; !ok || stored.Version != user.Version {
+		return models.ErrConflict
+	}
+	user.Version++
+	r.users[user.ID] = copyUser(user)
+	return nil
+}
+
+// copyUser copies a user so stored users don't share slices with callers
+func copyUser(user *models.User) models.User {
+	copied := *user
+	copied.ShippingAddresses = append([]models.ShippingAddress(nil), user.ShippingAddresses...)
+	copied.PaymentMethods = append([]models.PaymentMethod(nil), user.PaymentMethods...)
+	return copied
+}
+
+// newTestProfiles creates a ProfileService over the fake gateway and a stored user
+func newTestProfiles(t *testing.T) (*services.ProfileService, *memoryProfileRepository, string) {
+	t.Helper()
+	user := models.User{FirstName: "Ada", LastName: "Lovelace"}
+	user.BeforeCreate()
+	repo := &memoryProfileRepository{users: map[primitive.ObjectID]models.User{user.ID: user}}
+	return services.NewProfileService(repo, payment.NewFakeGateway(payment.FakeGatewayOptions{})), repo, user.ID.Hex()
+}
+
+func TestShippingAddressesKeepOneDefault(t *testing.T) {
+	ctx := context.Background()
+	svc, _, userID := newTestProfiles(t)
+	input := func(zipCode string, isDefault bool) services.ShippingAddressInput {
+		return services.ShippingAddressInput{
+			Address:   models.Address{Street: "1 Main St", City: "Springfield", ZipCode: zipCode, Country: "us"},
+			IsDefault: isDefault,
+		}
+	}
+
+	first, err := svc.AddShippingAddress(ctx, userID, input("12345", false))
+	if err != nil {
+		t.Fatalf("AddShippingAddress: %v", err)
+	}
+	if !first.IsDefault || first.Country != "US" {
+		t.Fatalf("first address = %+v, want the normalized default address", first)
+	}
+
+	second, err := svc.AddShippingAddress(ctx, userID, input("12345-6789", true))
+	if err != nil {
+		t.Fatalf("AddShippingAddress: %v", err)
+	}
+	user, _ := svc.GetProfile(ctx, userID)
+	if defaults := countDefaultAddresses(user); defaults != 1 || !second.IsDefault {
+		t.Fatalf("%d default addresses, want only the second address", defaults)
+	}
+
+	if err := svc.DeleteShippingAddress(ctx, userID, second.ID); err != nil {
+		t.Fatalf("DeleteShippingAddress: %v", err)
+	}
+	user, _ = svc.GetProfile(ctx, userID)
+	if len(user.ShippingAddresses) != 1 || !user.ShippingAddresses[0].IsDefault {
+		t.Fatalf("addresses = %+v, want the remaining address as default", user.ShippingAddresses)
+	}
+
+	var errs validator.ValidationErrors
+	if _, err := svc.AddShippingAddress(ctx, userID, input("ABCDE", false)); !errors.As(err, &errs) || errs[0].Field != "zipCode" {
+		t.Fatalf("invalid postal code: got %v, want a zipCode validation error", err)
+	}
+}
+
+func TestPaymentMethodsMoveDefaultOffExpiredCards(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, userID := newTestProfiles(t)
+
+	card := payment.Card{Number: payment.FakeCardApproved, ExpiryMonth: 12, ExpiryYear: time.Now().Year() + 1, CVC: "123"}
+	saved, err := svc.AddPaymentMethod(ctx, userID, card, false)
+	if err != nil {
+		t.Fatalf("AddPaymentMethod: %v", err)
+	}
+	if !saved.IsDefault || saved.LastFourDigits != "4242" || saved.Token == "" {
+		t.Fatalf("saved method = %+v, want a tokenized default card", saved)
+	}
+
+	// A stored card that expired since it was saved becomes the default
+	id, _ := primitive.ObjectIDFromHex(userID)
+	user := repo.users[id]
+	expired := models.PaymentMethod{
+		ID:          primitive.NewObjectID(),
+		Type:        models.PaymentMethodCard,
+		ExpiryMonth: 1,
+		ExpiryYear:  time.Now().Year() - 1,
+		Token:       "tok_old",
+		IsDefault:   true,
+		CreatedAt:   time.Now(),
+	}
+	user.PaymentMethods[0].IsDefault = false
+	user.PaymentMethods = append(user.PaymentMethods, expired)
+	repo.users[id] = user
+
+	profile, err := svc.GetProfile(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetProfile: %v", err)
+	}
+	old, _ := profile.FindPaymentMethod(expired.ID)
+	valid, _ := profile.FindPaymentMethod(saved.ID)
+	if !old.Expired || old.IsDefault || !valid.IsDefault {
+		t.Fatalf("methods = %+v, want the expired card marked and the valid card as default", profile.PaymentMethods)
+	}
+	if stored := repo.users[id].PaymentMethods; !stored[1].Expired {
+		t.Fatal("the expired card was not saved as expired")
+	}
+
+	var errs validator.ValidationErrors
+	if _, err := svc.SetDefaultPaymentMethod(ctx, userID, expired.ID); !errors.As(err, &errs) {
+		t.Fatalf("defaulting an expired card: got %v, want a validation error", err)
+	}
+	if _, err := svc.SavedCard(ctx, userID, expired.ID); !errors.As(err, &errs) || errs[0].Field != "paymentMethodId" {
+		t.Fatalf("charging an expired card: got %v, want a paymentMethodId validation error", err)
+	}
+	token, err := svc.SavedCard(ctx, userID, saved.ID)
+	if err != nil || token.Token != saved.Token || token.LastFourDigits != "4242" {
+		t.Fatalf("SavedCard = %+v, %v, want the saved card token", token, err)
+	}
+
+	// Tokens are only accepted for the user's own cards
+	if byToken, err := svc.SavedCardByToken(ctx, userID, saved.Token); err != nil || byToken.LastFourDigits != "4242" {
+		t.Fatalf("SavedCardByToken = %+v, %v, want the saved card", byToken, err)
+	}
+	if _, err := svc.SavedCardByToken(ctx, userID, "tok_someone_else"); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("charging an unknown token: got %v, want ErrNotFound", err)
+	}
+	if _, err := svc.SavedCardByToken(ctx, userID, expired.Token); !errors.As(err, &errs) {
+		t.Fatalf("charging the token of an expired card: got %v, want a validation error", err)
+	}
+}
+
+// countDefaultAddresses returns the number of default addresses of a user
+func countDefaultAddresses(user *models.User) int {
+	count := 0
+	for _, address := range user.ShippingAddresses {
+		if address.IsDefault {
+			count++
+		}
+	}
+	return count
+}
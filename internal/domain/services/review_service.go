@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/connection"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrAlreadyReviewed is returned when a user reviews a product a second time
+var ErrAlreadyReviewed = fmt.Errorf("%w: you already reviewed this product, update your review instead", models.ErrConflict)
+
+// Review text limits
+const (
+	maxReviewTitleLength   = 120
+	maxReviewContentLength = 5000
+)
+
+// ReviewRepository is the storage used by the ReviewService
+type ReviewRepository interface {
+	Create(ctx context.Context, review *models.Review) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Review, error)
+	List(ctx context.Context, filter models.ReviewFilter, args connection.Args) (*models.ReviewConnection, error)
+	Save(ctx context.Context, review *models.Review) error
+	Delete(ctx context.Context, review *models.Review) error
+	MarkVerified(ctx context.Context, userID string, productIDs []primitive.ObjectID) error
+}
+
+// ReviewProductRepository is the product storage used by the ReviewService
+type ReviewProductRepository interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error)
+	AdjustRating(ctx context.Context, id primitive.ObjectID, rating, delta int) error
+}
+
+// ReviewOrderRepository is the order storage used to detect verified purchases
+type ReviewOrderRepository interface {
+	HasDeliveredProduct(ctx context.Context, userID string, productID primitive.ObjectID) (bool, error)
+}
+
+// ReviewInput holds the content of a review
+type ReviewInput struct {
+	Rating  int
+	Title   string
+	Content string
+}
+
+// ReviewActor is the user changing a review. Staff can delete every review,
+// customers only their own.
+type ReviewActor struct {
+	UserID string
+	Staff  bool
+}
+
+// ReviewService manages product reviews and keeps the rating summary of products in
+// step with their approved reviews. New and edited reviews wait for moderation.
+type ReviewService struct {
+	tx       Transactor
+	reviews  ReviewRepository
+	products ReviewProductRepository
+	orders   ReviewOrderRepository
+}
+
+// NewReviewService creates a new ReviewService
+func NewReviewService(tx Transactor, reviews ReviewRepository, products ReviewProductRepository, orders ReviewOrderRepository) *ReviewService {
+	return &ReviewService{
+		tx:       tx,
+		reviews:  reviews,
+		products: products,
+		orders:   orders,
+	}
+}
+
+// GetReview returns the review with the given ID
+func (s *ReviewService) GetReview(ctx context.Context, id primitive.ObjectID) (*models.Review, error) {
+	return s.reviews.FindByID(ctx, id)
+}
+
+// ListProductReviews returns a page of the approved reviews of a product, newest first
+func (s *ReviewService) ListProductReviews(ctx context.Context, productID primitive.ObjectID, args connection.Args) (*models.ReviewConnection, error) {
+	approved := models.ReviewApproved
+	return s.reviews.List(ctx, models.ReviewFilter{ProductID: &productID, Status: &approved}, args)
+}
+
+// ListReviews returns a page of the reviews matching the filter, newest first
+func (s *ReviewService) ListReviews(ctx context.Context, filter models.ReviewFilter, args connection.Args) (*models.ReviewConnection, error) {
+	return s.reviews.List(ctx, filter, args)
+}
+
+// CreateReview adds the review of a user to a product
+func (s *ReviewService) CreateReview(ctx context.Context, userID string, productID primitive.ObjectID, input ReviewInput) (*models.Review, error) {
+	review := &models.Review{ProductID: productID, UserID: userID, Status: models.ReviewPending}
+	applyReviewInput(review, input)
+	if err := validateReview(review); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.products.FindByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	verified, err := s.orders.HasDeliveredProduct(ctx, userID, productID)
+	if err != nil {
+		return nil, err
+	}
+	review.VerifiedPurchase = verified
+
+	if err := s.reviews.Create(ctx, review); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			return nil, ErrAlreadyReviewed
+		}
+		return nil, err
+	}
+	return review, nil
+}
+
+// UpdateReview changes the content of a review. Only its author can edit a review,
+// and the edited review goes back to moderation.
+func (s *ReviewService) UpdateReview(ctx context.Context, actor ReviewActor, id primitive.ObjectID, input ReviewInput) (*models.Review, error) {
+	review, err := s.reviews.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if review.UserID != actor.UserID {
+		return nil, models.ErrNotFound
+	}
+
+	previous := *review
+	applyReviewInput(review, input)
+	if err := validateReview(review); err != nil {
+		return nil, err
+	}
+	review.Status = models.ReviewPending
+	review.ModerationNote = ""
+	if !review.VerifiedPurchase {
+		if review.VerifiedPurchase, err = s.orders.HasDeliveredProduct(ctx, review.UserID, review.ProductID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.save(ctx, &previous, review); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// DeleteReview removes a review. Customers can only delete their own reviews.
+func (s *ReviewService) DeleteReview(ctx context.Context, actor ReviewActor, id primitive.ObjectID) error {
+	review, err := s.reviews.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !actor.Staff && review.UserID != actor.UserID {
+		return models.ErrNotFound
+	}
+
+	return s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.reviews.Delete(ctx, review); err != nil {
+			return err
+		}
+		if review.Status == models.ReviewApproved {
+			return s.adjustRating(ctx, review.ProductID, review.Rating, -1)
+		}
+		return nil
+	})
+}
+
+// ApproveReview publishes a review and counts it in the product rating
+func (s *ReviewService) ApproveReview(ctx context.Context, id primitive.ObjectID) (*models.Review, error) {
+	return s.moderate(ctx, id, models.ReviewApproved, "")
+}
+
+// RejectReview hides a review, explaining why to its author
+func (s *ReviewService) RejectReview(ctx context.Context, id primitive.ObjectID, note string) (*models.Review, error) {
+	return s.moderate(ctx, id, models.ReviewRejected, strings.TrimSpace(note))
+}
+
+// moderate moves a review to a moderation state
+func (s *ReviewService) moderate(ctx context.Context, id primitive.ObjectID, status models.ReviewStatus, note string) (*models.Review, error) {
+	review, err := s.reviews.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if review.Status == status && review.ModerationNote == note {
+		return review, nil
+	}
+
+	previous := *review
+	review.Status = status
+	review.ModerationNote = note
+	if err := s.save(ctx, &previous, review); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// save stores a changed review and moves its rating in or out of the product
+// rating summary when it enters or leaves the approved state. The review is saved
+// only if it is still at the version it was loaded at, so a concurrent change
+// can't count a rating twice.
+func (s *ReviewService) save(ctx context.Context, previous, review *models.Review) error {
+	return s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.reviews.Save(ctx, review); err != nil {
+			return err
+		}
+		if previous.Status == models.ReviewApproved {
+			if err := s.adjustRating(ctx, previous.ProductID, previous.Rating, -1); err != nil {
+				return err
+			}
+		}
+		if review.Status == models.ReviewApproved {
+			return s.adjustRating(ctx, review.ProductID, review.Rating, 1)
+		}
+		return nil
+	})
+}
+
+// adjustRating updates the rating summary of a product. Reviews outlive the products
+// they are about, and a removed product has no summary left to update.
+func (s *ReviewService) adjustRating(ctx context.Context, productID primitive.ObjectID, rating, delta int) error {
+	err := s.products.AdjustRating(ctx, productID, rating, delta)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// MarkVerifiedOnDelivery returns an order hook flagging the reviews of the delivered
+// products as verified purchases
+func MarkVerifiedOnDelivery(reviews *ReviewService) OrderHook {
+	return func(ctx context.Context, order *models.Order, change models.OrderStatusChange) error {
+		productIDs := make([]primitive.ObjectID, 0, len(order.Items))
+		for _, item := range order.Items {
+			productIDs = append(productIDs, item.ProductID)
+		}
+		return reviews.reviews.MarkVerified(ctx, order.UserID, productIDs)
+	}
+}
+
+// applyReviewInput copies the trimmed input to a review
+func applyReviewInput(review *models.Review, input ReviewInput) {
+	review.Rating = input.Rating
+	review.Title = strings.TrimSpace(input.Title)
+	review.Content = strings.TrimSpace(input.Content)
+}
+
+// validateReview validates every review field and reports all failures together
+func validateReview(review *models.Review) error {
+	var errs validator.ValidationErrors
+	if review.Rating < models.MinRating || review.Rating > models.MaxRating {
+		errs = append(errs, validator.ValidationError{Field: "rating", Message: fmt.Sprintf("rating must be between %d and %d", models.MinRating, models.MaxRating)})
+	}
+	if len([]rune(review.Title)) > maxReviewTitleLength {
+		errs = append(errs, validator.ValidationError{Field: "title", Message: fmt.Sprintf("title must be at most %d characters", maxReviewTitleLength)})
+	}
+	if len([]rune(review.Content)) > maxReviewContentLength {
+		errs = append(errs, validator.ValidationError{Field: "content", Message: fmt.Sprintf("content must be at most %d characters", maxReviewContentLength)})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryReviewRepository is an in-memory services.ReviewRepository
+type memoryReviewRepository struct {
+	reviews map[primitive.ObjectID]*models.Review
+}
+
+func (r *memoryReviewRepository) Create(ctx context.Context, review *models.Review) error {
+	for _, existing := range r.reviews {
+		if existing.ProductID == review.ProductID && existing.UserID == review.UserID {
+			return models.ErrDuplicate
+		}
+	}
+	review.BeforeCreate()
+	copied := *review
+	r.reviews[review.ID] = &copied
+	return nil
+}
+
+func (r *memoryReviewRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Review, error) {
+	review, ok := r.reviews[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	copied := *review
+	return &copied, nil
+}
+
+func (r *memoryReviewRepository) List(ctx context.Context, filter models.ReviewFilter, args connection.Args) (*models.ReviewConnection, error) {
+	page := &models.ReviewConnection{}
+	for _, review := range r.reviews {
+		if (filter.ProductID == nil || *filter.ProductID == review.ProductID) &&
+			(filter.UserID == "" || filter.UserID == review.UserID) &&
+			(filter.Status == nil || *filter.Status == review.Status) {
+			page.Edges = append(page.Edges, models.ReviewEdge{Node: *review})
+		}
+	}
+	return page, nil
+}
+
+func (r *memoryReviewRepository) Save(ctx context.Context, review *models.Review) error {
+	stored, ok := r.reviews[review.ID]
+	if !ok || stored.Version != review.Version {
+		return models.ErrConflict
+	}
+	review.Version++
+	copied := *review
+	r.reviews[review.ID] = &copied
+	return nil
+}
+
+func (r *memoryReviewRepository) Delete(ctx context.Context, review *models.Review) error {
+	stored, ok := r.reviews[review.ID]
+	if !ok || stored.Version != review.Version {
+		return models.ErrConflict
+	}
+	delete(r.reviews, review.ID)
+	return nil
+}
+
+func (r *memoryReviewRepository) MarkVerified(ctx context.Context, userID string, productIDs []primitive.ObjectID) error {
+	for _, review := range r.reviews {
+		for _, id := range productIDs {
+			if review.UserID == userID && review.ProductID == id {
+				review.VerifiedPurchase = true
+				review.Version++
+			}
+		}
+	}
+	return nil
+}
+
+// memoryRatedProducts is an in-memory services.ReviewProductRepository
+type memoryRatedProducts map[primitive.ObjectID]*models.Product
+
+func (p memoryRatedProducts) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error) {
+	product, ok := p[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return product, nil
+}
+
+func (p memoryRatedProducts) AdjustRating(ctx context.Context, id primitive.ObjectID, rating, delta int) error {
+	product, ok := p[id]
+	if !ok {
+		return models.ErrNotFound
+	}
+	if product.Rating.Histogram == nil {
+		product.Rating.Histogram = map[string]int{}
+	}
+	product.Rating.Count += delta
+	product.Rating.Sum += rating * delta
+	product.Rating.Histogram[strconv.Itoa(rating)] += delta
+	return nil
+}
+
+// memoryDeliveries records the products delivered to each user
+type memoryDeliveries map[string][]primitive.ObjectID
+
+func (d memoryDeliveries) HasDeliveredProduct(ctx context.Context, userID string, productID primitive.ObjectID) (bool, error) {
+	for _, id := range d[userID] {
+		if id == productID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func newTestReviews() (*services.ReviewService, *memoryReviewRepository, *models.Product, memoryDeliveries) {
+	product := &models.Product{Name: "Phone"}
+	product.ID = primitive.NewObjectID()
+	reviews := &memoryReviewRepository{reviews: map[primitive.ObjectID]*models.Review{}}
+	deliveries := memoryDeliveries{}
+	service := services.NewReviewService(noTransactions{}, reviews, memoryRatedProducts{product.ID: product}, deliveries)
+	return service, reviews, product, deliveries
+}
+
+func TestReviewRatingFollowsModeration(t *testing.T) {
+	reviewService, _, product, deliveries := newTestReviews()
+	ctx := context.Background()
+	deliveries["buyer"] = []primitive.ObjectID{product.ID}
+
+	first, err := reviewService.CreateReview(ctx, "buyer", product.ID, services.ReviewInput{Rating: 5, Title: " Great "})
+	if err != nil {
+		t.Fatalf("CreateReview returned error: %v", err)
+	}
+	if first.Status != models.ReviewPending || !first.VerifiedPurchase || first.Title != "Great" {
+		t.Errorf("expected a trimmed pending verified review, got %+v", first)
+	}
+	second, err := reviewService.CreateReview(ctx, "visitor", product.ID, services.ReviewInput{Rating: 2})
+	if err != nil {
+		t.Fatalf("CreateReview returned error: %v", err)
+	}
+	if second.VerifiedPurchase {
+		t.Error("expected a review without a delivered order not to be verified")
+	}
+	if product.AverageRating() != nil {
+		t.Errorf("expected pending reviews not to be rated, got %v", *product.AverageRating())
+	}
+
+	if _, err := reviewService.ApproveReview(ctx, first.ID); err != nil {
+		t.Fatalf("ApproveReview returned error: %v", err)
+	}
+	if _, err := reviewService.ApproveReview(ctx, second.ID); err != nil {
+		t.Fatalf("ApproveReview returned error: %v", err)
+	}
+	if _, err := reviewService.ApproveReview(ctx, second.ID); err != nil {
+		t.Fatalf("approving twice returned error: %v", err)
+	}
+	if avg := product.AverageRating(); avg == nil || *avg != 3.5 || product.ReviewCount() != 2 {
+		t.Fatalf("expected 2 reviews averaging 3.5, got %d averaging %v", product.ReviewCount(), avg)
+	}
+
+	// Editing an approved review withdraws its rating until it is approved again
+	actor := services.ReviewActor{UserID: "visitor"}
+	if _, err := reviewService.UpdateReview(ctx, actor, second.ID, services.ReviewInput{Rating: 4}); err != nil {
+		t.Fatalf("UpdateReview returned error: %v", err)
+	}
+	if avg := product.AverageRating(); avg == nil || *avg != 5 {
+		t.Errorf("expected only the approved review to count, got %v", avg)
+	}
+	if _, err := reviewService.ApproveReview(ctx, second.ID); err != nil {
+		t.Fatalf("ApproveReview returned error: %v", err)
+	}
+	if _, err := reviewService.RejectReview(ctx, first.ID, "spam"); err != nil {
+		t.Fatalf("RejectReview returned error: %v", err)
+	}
+	histogram := product.RatingHistogram()
+	if histogram[0].Rating != 5 || histogram[0].Count != 0 || histogram[1].Rating != 4 || histogram[1].Count != 1 {
+		t.Errorf("unexpected histogram %+v", histogram)
+	}
+
+	if err := reviewService.DeleteReview(ctx, actor, second.ID); err != nil {
+		t.Fatalf("DeleteReview returned error: %v", err)
+	}
+	if product.AverageRating() != nil || product.ReviewCount() != 0 {
+		t.Errorf("expected no rating after deleting the approved review, got %d reviews", product.ReviewCount())
+	}
+}
+
+func TestReviewOncePerUserAndAuthorOnly(t *testing.T) {
+	reviewService, reviews, product, _ := newTestReviews()
+	ctx := context.Background()
+
+	review, err := reviewService.CreateReview(ctx, "author", product.ID, services.ReviewInput{Rating: 4})
+	if err != nil {
+		t.Fatalf("CreateReview returned error: %v", err)
+	}
+	if _, err := reviewService.CreateReview(ctx, "author", product.ID, services.ReviewInput{Rating: 1}); !errors.Is(err, services.ErrAlreadyReviewed) {
+		t.Errorf("expected a second review to be rejected, got %v", err)
+	}
+	if _, err := reviewService.CreateReview(ctx, "other", product.ID, services.ReviewInput{Rating: 6}); err == nil {
+		t.Error("expected a rating above 5 to be rejected")
+	}
+
+	other := services.ReviewActor{UserID: "other"}
+	if _, err := reviewService.UpdateReview(ctx, other, review.ID, services.ReviewInput{Rating: 1}); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("expected another user's edit to be rejected, got %v", err)
+	}
+	if err := reviewService.DeleteReview(ctx, other, review.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("expected another user's delete to be rejected, got %v", err)
+	}
+	if err := reviewService.DeleteReview(ctx, services.ReviewActor{UserID: "moderator", Staff: true}, review.ID); err != nil {
+		t.Errorf("expected staff to delete any review, got %v", err)
+	}
+	if len(reviews.reviews) != 0 {
+		t.Errorf("expected the review to be deleted, %d left", len(reviews.reviews))
+	}
+}
+
+func TestMarkVerifiedOnDelivery(t *testing.T) {
+	reviewService, reviews, product, _ := newTestReviews()
+	ctx := context.Background()
+
+	review, err := reviewService.CreateReview(ctx, "buyer", product.ID, services.ReviewInput{Rating: 3})
+	if err != nil {
+		t.Fatalf("CreateReview returned error: %v", err)
+	}
+	order := &models.Order{UserID: "buyer", Items: []models.OrderItem{{ProductID: product.ID}}}
+	hook := services.MarkVerifiedOnDelivery(reviewService)
+	if err := hook(ctx, order, models.OrderStatusChange{To: models.OrderDelivered}); err != nil {
+		t.Fatalf("hook returned error: %v", err)
+	}
+	if !reviews.reviews[review.ID].VerifiedPurchase {
+		t.Error("expected the review to be verified once the order is delivered")
+	}
+}
+
+func TestReviewsOfRemovedProducts(t *testing.T) {
+	ctx := context.Background()
+	product := &models.Product{Name: "Phone"}
+	product.ID = primitive.NewObjectID()
+	products := memoryRatedProducts{product.ID: product}
+	reviews := &memoryReviewRepository{reviews: map[primitive.ObjectID]*models.Review{}}
+	reviewService := services.NewReviewService(noTransactions{}, reviews, products, memoryDeliveries{})
+
+	var approved []*models.Review
+	for _, userID := range []string{"first", "second", "third"} {
+		review, err := reviewService.CreateReview(ctx, userID, product.ID, services.ReviewInput{Rating: 4})
+		if err != nil {
+			t.Fatalf("CreateReview: %v", err)
+		}
+		if review, err = reviewService.ApproveReview(ctx, review.ID); err != nil {
+			t.Fatalf("ApproveReview: %v", err)
+		}
+		approved = append(approved, review)
+	}
+	delete(products, product.ID)
+
+	// Approved reviews of the removed product can still be moderated, edited and deleted
+	if _, err := reviewService.RejectReview(ctx, approved[0].ID, "spam"); err != nil {
+		t.Errorf("RejectReview: %v", err)
+	}
+	if _, err := reviewService.UpdateReview(ctx, services.ReviewActor{UserID: "second"}, approved[1].ID, services.ReviewInput{Rating: 2}); err != nil {
+		t.Errorf("UpdateReview: %v", err)
+	}
+	if err := reviewService.DeleteReview(ctx, services.ReviewActor{Staff: true}, approved[2].ID); err != nil {
+		t.Errorf("DeleteReview: %v", err)
+	}
+	if _, err := reviewService.GetReview(ctx, approved[2].ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetReview after delete: got %v, want ErrNotFound", err)
+	}
+}
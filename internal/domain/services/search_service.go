@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/connection"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxSearchLength is the longest search text accepted
+const maxSearchLength = 200
+
+// searchCursorSort is the sort key of search result cursors, which hold positions
+const searchCursorSort = "search"
+
+// ProductIndexer keeps a search index in sync with the product catalog
+type ProductIndexer interface {
+	IndexProduct(ctx context.Context, product *models.Product) error
+	RemoveProduct(ctx context.Context, id primitive.ObjectID) error
+}
+
+// SearchEngine ranks products against a search text and counts the facets of the matches
+type SearchEngine interface {
+	ProductIndexer
+	Search(ctx context.Context, query models.SearchQuery) (*models.SearchResult, error)
+}
+
+// SearchService searches the product catalog
+type SearchService struct {
+	engine  SearchEngine
+	cursors *connection.Codec
+}
+
+// NewSearchService creates a new SearchService
+func NewSearchService(engine SearchEngine, cursors *connection.Codec) *SearchService {
+	return &SearchService{engine: engine, cursors: cursors}
+}
+
+// SearchProducts returns a page of the products matching the text and filter, best
+// matches first. Results are ranked rather than sorted on a field, so pages can only
+// be requested forward.
+func (s *SearchService) SearchProducts(ctx context.Context, text string, filter models.SearchFilter, args connection.Args) (*models.SearchResult, error) {
+	text = strings.TrimSpace(text)
+
+	var errs validator.ValidationErrors
+	if len(text) > maxSearchLength {
+		errs = append(errs, validator.ValidationError{Field: "query", Message: "search text is too long"})
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		errs = append(errs, validator.ValidationError{Field: "filter", Message: "minimum price cannot exceed maximum price"})
+	}
+	if err := args.Validate(); err != nil {
+		errs = append(errs, validator.ValidationError{Field: "pagination", Message: err.Error()})
+	} else if args.Backward() {
+		errs = append(errs, validator.ValidationError{Field: "pagination", Message: "search results can only be paged forward"})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	offset := 0
+	if args.After != nil {
+		cursor, err := s.cursors.Decode(*args.After, searchCursorSort)
+		if err != nil {
+			return nil, err
+		}
+		position, ok := cursor.Value.AsInt64OK()
+		if !ok || position < 0 {
+			return nil, connection.ErrInvalidCursor
+		}
+		offset = int(position)
+	}
+
+	result, err := s.engine.Search(ctx, models.SearchQuery{
+		Text:   text,
+		Filter: filter,
+		Offset: offset,
+		Limit:  args.Limit(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.PageInfo = connection.PageInfo{
+		HasPreviousPage: offset > 0,
+		HasNextPage:     offset+len(result.Products) < result.TotalCount,
+	}
+	if len(result.Products) > 0 {
+		start, err := s.positionCursor(offset + 1)
+		if err != nil {
+			return nil, err
+		}
+		end, err := s.positionCursor(offset + len(result.Products))
+		if err != nil {
+			return nil, err
+		}
+		result.PageInfo.StartCursor = &start
+		result.PageInfo.EndCursor = &end
+	}
+	return result, nil
+}
+
+// positionCursor returns the cursor of the result at the given 1-based position;
+// paging after it starts at the next result
+func (s *SearchService) positionCursor(position int) (string, error) {
+	kind, data, err := bson.MarshalValue(int64(position))
+	if err != nil {
+		return "", err
+	}
+	return s.cursors.Encode(connection.Cursor{
+		Sort:  searchCursorSort,
+		Value: bson.RawValue{Type: kind, Value: data},
+	})
+}
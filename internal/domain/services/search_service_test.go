@@ -0,0 +1,97 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/connection"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memorySearchEngine is a services.SearchEngine returning a fixed ranking. It
+// records the indexed products by ID.
+type memorySearchEngine struct {
+	ranked  []models.Product
+	query   models.SearchQuery
+	indexed map[primitive.ObjectID]models.Product
+}
+
+func (e *memorySearchEngine) IndexProduct(ctx context.Context, product *models.Product) error {
+	if e.indexed == nil {
+		e.indexed = make(map[primitive.ObjectID]models.Product)
+	}
+	e.indexed[product.ID] = *product
+	return nil
+}
+
+func (e *memorySearchEngine) RemoveProduct(ctx context.Context, id primitive.ObjectID) error {
+	delete(e.indexed, id)
+	return nil
+}
+
+func (e *memorySearchEngine) Search(ctx context.Context, query models.SearchQuery) (*models.SearchResult, error) {
+	e.query = query
+	result := &models.SearchResult{Products: []models.Product{}, TotalCount: len(e.ranked)}
+	for i := query.Offset; i < len(e.ranked) && i < query.Offset+query.Limit; i++ {
+		result.Products = append(result.Products, e.ranked[i])
+	}
+	return result, nil
+}
+
+func TestSearchPagesForwardThroughRankedResults(t *testing.T) {
+	engine := &memorySearchEngine{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		engine.ranked = append(engine.ranked, models.Product{Name: name})
+	}
+	search := services.NewSearchService(engine, connection.NewCodec([]byte("secret")))
+	ctx := context.Background()
+	first := 2
+
+	var names []string
+	args := connection.Args{First: &first}
+	for page := 0; ; page++ {
+		result, err := search.SearchProducts(ctx, "  phone ", models.SearchFilter{}, args)
+		if err != nil {
+			t.Fatalf("SearchProducts returned error: %v", err)
+		}
+		if engine.query.Text != "phone" {
+			t.Errorf("expected the search text to be trimmed, got %q", engine.query.Text)
+		}
+		if result.PageInfo.HasPreviousPage != (page > 0) {
+			t.Errorf("page %d: got hasPreviousPage %v", page, result.PageInfo.HasPreviousPage)
+		}
+		for _, product := range result.Products {
+			names = append(names, product.Name)
+		}
+		if !result.PageInfo.HasNextPage {
+			break
+		}
+		args.After = result.PageInfo.EndCursor
+	}
+	if got := len(names); got != 5 || names[0] != "a" || names[4] != "e" {
+		t.Errorf("expected every result once in rank order, got %v", names)
+	}
+}
+
+func TestSearchRejectsInvalidQueries(t *testing.T) {
+	search := services.NewSearchService(&memorySearchEngine{}, connection.NewCodec([]byte("secret")))
+	ctx := context.Background()
+	last := 5
+	minPrice, maxPrice := 50.0, 10.0
+	forged := "not-a-cursor"
+
+	var validationErrs validator.ValidationErrors
+	if _, err := search.SearchProducts(ctx, "phone", models.SearchFilter{}, connection.Args{Last: &last}); !errors.As(err, &validationErrs) {
+		t.Errorf("expected backward paging to be rejected, got %v", err)
+	}
+	if _, err := search.SearchProducts(ctx, "phone", models.SearchFilter{MinPrice: &minPrice, MaxPrice: &maxPrice}, connection.Args{}); !errors.As(err, &validationErrs) {
+		t.Errorf("expected an empty price range to be rejected, got %v", err)
+	}
+	if _, err := search.SearchProducts(ctx, "phone", models.SearchFilter{}, connection.Args{After: &forged}); !errors.Is(err, connection.ErrInvalidCursor) {
+		t.Errorf("expected a forged cursor to be rejected, got %v", err)
+	}
+}
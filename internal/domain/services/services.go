@@ -1 +1,2 @@
+// Package services contains the domain business logic of the application
 package services
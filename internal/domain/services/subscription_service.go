@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EventBus publishes events on topics and streams them to subscribers, such as
+// the in-process pubsub.Broker. A subscription ends when its context is done.
+type EventBus[T any] interface {
+	Publish(ctx context.Context, topic string, event T) error
+	Subscribe(ctx context.Context, topic string) (<-chan T, error)
+}
+
+// SubscriptionProductRepository is the product storage used by the SubscriptionService
+type SubscriptionProductRepository interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error)
+}
+
+// SubscriptionService streams order status changes and product stock changes.
+// Order events carry the order as it was saved; stock events are published per
+// product and streamed as the product with its current stock.
+type SubscriptionService struct {
+	orders      *OrderService
+	products    SubscriptionProductRepository
+	orderEvents EventBus[*models.Order]
+	stockEvents EventBus[models.StockMovement]
+}
+
+// NewSubscriptionService creates a new SubscriptionService
+func NewSubscriptionService(orders *OrderService, products SubscriptionProductRepository, orderEvents EventBus[*models.Order], stockEvents EventBus[models.StockMovement]) *SubscriptionService {
+	return &SubscriptionService{
+		orders:      orders,
+		products:    products,
+		orderEvents: orderEvents,
+		stockEvents: stockEvents,
+	}
+}
+
+// PublishOrderStatus is an OrderHook publishing the order after every status change
+func (s *SubscriptionService) PublishOrderStatus() OrderHook {
+	return func(ctx context.Context, order *models.Order, change models.OrderStatusChange) error {
+		published := *order
+		published.StatusHistory = append([]models.OrderStatusChange(nil), order.StatusHistory...)
+		return s.orderEvents.Publish(ctx, order.ID.Hex(), &published)
+	}
+}
+
+// PublishStockChange is a StockHook publishing every stock movement of a product
+func (s *SubscriptionService) PublishStockChange() StockHook {
+	return func(ctx context.Context, movement models.StockMovement) error {
+		return s.stockEvents.Publish(ctx, movement.ProductID.Hex(), movement)
+	}
+}
+
+// OrderStatusChanged streams an order every time its status changes until ctx is
+// done. Orders the actor can't access are reported as not found.
+func (s *SubscriptionService) OrderStatusChanged(ctx context.Context, orderID primitive.ObjectID, actor OrderActor) (<-chan *models.Order, error) {
+	if _, err := s.orders.GetOrder(ctx, orderID, actor); err != nil {
+		return nil, err
+	}
+	return s.orderEvents.Subscribe(ctx, orderID.Hex())
+}
+
+// ProductStockChanged streams a product every time its stock changes until ctx is
+// done. Products removed while subscribed end the stream.
+func (s *SubscriptionService) ProductStockChanged(ctx context.Context, productID primitive.ObjectID) (<-chan *models.Product, error) {
+	if _, err := s.products.FindByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	movements, err := s.stockEvents.Subscribe(ctx, productID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	products := make(chan *models.Product, 1)
+	go func() {
+		defer close(products)
+		for range movements {
+			// Movements arriving together are answered with a single read of the product
+			for drained := false; !drained; {
+				select {
+				case _, ok := <-movements:
+					drained = !ok
+				default:
+					drained = true
+				}
+			}
+
+			product, err := s.products.FindByID(ctx, productID)
+			if errors.Is(err, models.ErrNotFound) {
+				return
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Error("Failed to load product "+productID.Hex()+" for a stock change", err)
+				}
+				continue
+			}
+
+			select {
+			case products <- product:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return products, nil
+}
@@ -0,0 +1,101 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/pubsub"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// receive returns the next value of ch, failing the test when none arrives
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed, want a value")
+		}
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("no value received")
+	}
+	var zero T
+	return zero
+}
+
+func newTestSubscriptions(orders *services.OrderService, products services.SubscriptionProductRepository) *services.SubscriptionService {
+	return services.NewSubscriptionService(orders, products,
+		pubsub.New[*models.Order](pubsub.DefaultBuffer), pubsub.New[models.StockMovement](pubsub.DefaultBuffer))
+}
+
+func TestOrderStatusChangesAreStreamedToTheOwner(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := newMemoryOrderRepository()
+	orders := services.NewOrderService(repo)
+	svc := newTestSubscriptions(orders, memoryRatedProducts{})
+	orders.OnAnyTransition(svc.PublishOrderStatus())
+
+	order := &models.Order{UserID: "user-1", Status: models.OrderPaid}
+	if err := repo.Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.OrderStatusChanged(ctx, order.ID, services.OrderActor{UserID: "user-2"}); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("subscription by another user: got %v, want ErrNotFound", err)
+	}
+	updates, err := svc.OrderStatusChanged(ctx, order.ID, services.OrderActor{UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("OrderStatusChanged: %v", err)
+	}
+
+	staff := services.OrderActor{UserID: "staff-1", Staff: true}
+	for _, status := range []models.OrderStatus{models.OrderFulfilled, models.OrderCancelled} {
+		if _, err := orders.UpdateStatus(ctx, order.ID, status, "", staff); err != nil {
+			t.Fatalf("UpdateStatus %s: %v", status, err)
+		}
+		if got := receive(t, updates); got.Status != status {
+			t.Fatalf("streamed status %s, want %s", got.Status, status)
+		}
+	}
+}
+
+func TestStockChangesStreamTheProduct(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	product := &models.Product{SKU: "MUG"}
+	product.ID = primitive.NewObjectID()
+	products := memoryRatedProducts{product.ID: product}
+	inventory := services.NewInventoryService(noTransactions{}, newMemoryInventoryRepository(), memoryProductStock{}, time.Minute)
+	svc := newTestSubscriptions(services.NewOrderService(newMemoryOrderRepository()), products)
+	inventory.OnStockChange(svc.PublishStockChange())
+
+	if err := inventory.InitializeStock(ctx, product, 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ProductStockChanged(ctx, primitive.NewObjectID()); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("subscription to an unknown product: got %v, want ErrNotFound", err)
+	}
+	updates, err := svc.ProductStockChanged(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("ProductStockChanged: %v", err)
+	}
+
+	product.Stock = 3
+	if _, err := inventory.Reserve(ctx, "cart-1", []services.StockLine{{SKU: "MUG", Quantity: 2}}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if got := receive(t, updates); got.ID != product.ID || got.Stock != 3 {
+		t.Fatalf("streamed product %s with stock %d, want %s with 3", got.ID.Hex(), got.Stock, product.ID.Hex())
+	}
+
+	cancel()
+	if _, ok := <-updates; ok {
+		t.Fatal("stream still open after the subscription ended")
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/logger"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned by the UserService
+var (
+	// ErrInvalidCredentials is returned when the email or password is wrong
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrEmailTaken is returned when registering an email that already has an account
+	ErrEmailTaken = errors.New("email is already registered")
+)
+
+// UserRepository is the storage used by the UserService
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdatePasswordHash(ctx context.Context, id primitive.ObjectID, hash string) error
+}
+
+// RegisterUserInput holds the data needed to register a user
+type RegisterUserInput struct {
+	FirstName string
+	LastName  string
+	Email     string
+	Password  string
+}
+
+// UserService handles user registration and login
+type UserService struct {
+	users  UserRepository
+	hasher *auth.PasswordHasher
+	auth   *AuthService
+}
+
+// NewUserService creates a new UserService
+func NewUserService(users UserRepository, hasher *auth.PasswordHasher, authService *AuthService) *UserService {
+	return &UserService{
+		users:  users,
+		hasher: hasher,
+		auth:   authService,
+	}
+}
+
+// Register validates the input, creates a customer account and signs the user in
+func (s *UserService) Register(ctx context.Context, input RegisterUserInput) (*models.User, *TokenPair, error) {
+	input.Email = normalizeEmail(input.Email)
+	input.FirstName = strings.TrimSpace(input.FirstName)
+	input.LastName = strings.TrimSpace(input.LastName)
+
+	if err := validateRegisterUserInput(input); err != nil {
+		return nil, nil, err
+	}
+
+	hash, err := s.hasher.Hash(input.Password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user := &models.User{
+		Email:        input.Email,
+		PasswordHash: hash,
+		FirstName:    input.FirstName,
+		LastName:     input.LastName,
+		Roles:        []string{models.RoleCustomer},
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			return nil, nil, ErrEmailTaken
+		}
+		return nil, nil, err
+	}
+
+	pair, err := s.auth.IssueTokens(ctx, tokenSubject(user))
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, pair, nil
+}
+
+// Login checks the credentials and signs the user in. Password hashes made with
+// outdated parameters are transparently upgraded.
+func (s *UserService) Login(ctx context.Context, email, password string) (*models.User, *TokenPair, error) {
+	user, err := s.users.FindByEmail(ctx, normalizeEmail(email))
+	if errors.Is(err, models.ErrNotFound) {
+		// Hash anyway so unknown emails take as long as wrong passwords
+		_, _ = s.hasher.Hash(password)
+		return nil, nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	match, needsRehash, err := s.hasher.Verify(password, user.PasswordHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !match {
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	if needsRehash {
+		s.rehashPassword(ctx, user, password)
+	}
+
+	pair, err := s.auth.IssueTokens(ctx, tokenSubject(user))
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, pair, nil
+}
+
+// GetUser returns the user with the given ID
+func (s *UserService) GetUser(ctx context.Context, id string) (*models.User, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, models.ErrNotFound
+	}
+	return s.users.FindByID(ctx, objectID)
+}
+
+// rehashPassword stores a hash made with the current parameters, logging instead of failing the login
+func (s *UserService) rehashPassword(ctx context.Context, user *models.User, password string) {
+	hash, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.users.UpdatePasswordHash(ctx, user.ID, hash)
+	}
+	if err != nil {
+		logger.Error("Failed to rehash password for user "+user.ID.Hex(), err)
+		return
+	}
+	user.PasswordHash = hash
+}
+
+// validateRegisterUserInput validates every field and reports all failures together
+func validateRegisterUserInput(input RegisterUserInput) error {
+	var errs validator.ValidationErrors
+	if err := validator.ValidateNonEmpty("firstName", input.FirstName); err != nil {
+		errs = append(errs, validator.ValidationError{Field: "firstName", Message: err.Error()})
+	}
+	if err := validator.ValidateNonEmpty("lastName", input.LastName); err != nil {
+		errs = append(errs, validator.ValidationError{Field: "lastName", Message: err.Error()})
+	}
+	if err := validator.ValidateEmail(input.Email); err != nil {
+		errs = append(errs, validator.ValidationError{Field: "email", Message: err.Error()})
+	}
+	if err := validator.ValidatePassword(input.Password); err != nil {
+		errs = append(errs, validator.ValidationError{Field: "password", Message: err.Error()})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// tokenSubject returns the token subject for a user
+func tokenSubject(user *models.User) TokenSubject {
+	return TokenSubject{
+		UserID:   user.ID.Hex(),
+		Roles:    user.Roles,
+		TenantID: user.TenantID,
+	}
+}
+
+// normalizeEmail lower-cases and trims an email so lookups are case-insensitive
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,223 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prototype01/internal/auth"
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memoryUserRepository is an in-memory services.UserRepository
+type memoryUserRepository struct {
+	mu    sync.Mutex
+	users map[primitive.ObjectID]*models.User
+}
+
+func newMemoryUserRepository() *memoryUserRepository {
+	return &memoryUserRepository{users: make(map[primitive.ObjectID]*models.User)}
+}
+
+// add stores a user with the given roles and returns its ID
+func (r *memoryUserRepository) add(roles ...string) string {
+	user := &models.User{Email: primitive.NewObjectID().Hex() + "@example.com", Roles: roles}
+	if err := r.Create(context.Background(), user); err != nil {
+		panic(err)
+	}
+	return user.ID.Hex()
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, stored := range r.users {
+		if stored.Email == user.Email {
+			return models.ErrDuplicate
+		}
+	}
+	user.BeforeCreate()
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *memoryUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+func (r *memoryUserRepository) UpdatePasswordHash(ctx context.Context, id primitive.ObjectID, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return models.ErrNotFound
+	}
+	user.PasswordHash = hash
+	return nil
+}
+
+// testPasswordParams keeps password hashing cheap in tests
+var testPasswordParams = auth.PasswordParams{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+// newTestUsers creates a UserService hashing with params
+func newTestUsers(t *testing.T, params auth.PasswordParams) (*services.UserService, *memoryUserRepository, *auth.Verifier) {
+	t.Helper()
+	authService, verifier, users := newTestAuthService(t)
+	return services.NewUserService(users, auth.NewPasswordHasher(params), authService), users, verifier
+}
+
+func TestUserServiceRegister(t *testing.T) {
+	ctx := context.Background()
+	svc, users, verifier := newTestUsers(t, testPasswordParams)
+
+	user, pair, err := svc.Register(ctx, services.RegisterUserInput{
+		FirstName: " Ada ",
+		LastName:  "Lovelace",
+		Email:     " Ada@Example.com ",
+		Password:  "Secret123",
+	})
+	if err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	if user.Email != "ada@example.com" || user.FirstName != "Ada" {
+		t.Errorf("expected a normalized user, got %+v", user)
+	}
+	if len(user.Roles) != 1 || user.Roles[0] != models.RoleCustomer {
+		t.Errorf("expected a customer, got roles %v", user.Roles)
+	}
+	stored, err := users.FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("registered user not stored: %v", err)
+	}
+	if !strings.HasPrefix(stored.PasswordHash, "$argon2id$") {
+		t.Errorf("expected an argon2id password hash, got %q", stored.PasswordHash)
+	}
+	claims, err := verifier.VerifyToken(pair.AccessToken)
+	if err != nil || claims.UserID() != user.ID.Hex() {
+		t.Errorf("expected an access token for the new user, got %+v, %v", claims, err)
+	}
+
+	// Emails are unique regardless of case
+	_, _, err = svc.Register(ctx, services.RegisterUserInput{
+		FirstName: "Ada",
+		LastName:  "Byron",
+		Email:     "ADA@example.com",
+		Password:  "Secret123",
+	})
+	if !errors.Is(err, services.ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken, got %v", err)
+	}
+}
+
+func TestUserServiceRegisterValidation(t *testing.T) {
+	svc, users, _ := newTestUsers(t, testPasswordParams)
+
+	_, _, err := svc.Register(context.Background(), services.RegisterUserInput{Email: "not-an-email", Password: "short"})
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	fields := map[string]bool{}
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	for _, field := range []string{"firstName", "lastName", "email", "password"} {
+		if !fields[field] {
+			t.Errorf("expected a validation error for %s, got %v", field, errs)
+		}
+	}
+	if len(users.users) != 0 {
+		t.Error("expected no user to be created")
+	}
+}
+
+func TestUserServiceLogin(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestUsers(t, testPasswordParams)
+	registered, _, err := svc.Register(ctx, services.RegisterUserInput{
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Email:     "ada@example.com",
+		Password:  "Secret123",
+	})
+	if err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+
+	user, pair, err := svc.Login(ctx, "ADA@example.com", "Secret123")
+	if err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	if user.ID != registered.ID || pair.AccessToken == "" {
+		t.Errorf("expected a session for the registered user, got %+v", user)
+	}
+
+	if _, _, err := svc.Login(ctx, "ada@example.com", "Wrong123"); !errors.Is(err, services.ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials for a wrong password, got %v", err)
+	}
+	if _, _, err := svc.Login(ctx, "nobody@example.com", "Secret123"); !errors.Is(err, services.ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials for an unknown email, got %v", err)
+	}
+}
+
+func TestUserServiceLoginUpgradesHashes(t *testing.T) {
+	ctx := context.Background()
+	svc, users, _ := newTestUsers(t, testPasswordParams)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Secret123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weak, err := auth.NewPasswordHasher(auth.PasswordParams{Memory: 512, Iterations: 1, Parallelism: 1}).Hash("Secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, hash := range map[string]string{"bcrypt": string(legacy), "outdated argon2id": weak} {
+		t.Run(name, func(t *testing.T) {
+			user := &models.User{Email: strings.ReplaceAll(name, " ", "-") + "@example.com", PasswordHash: hash}
+			if err := users.Create(ctx, user); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, _, err := svc.Login(ctx, user.Email, "Secret123"); err != nil {
+				t.Fatalf("Login returned error: %v", err)
+			}
+			stored, _ := users.FindByID(ctx, user.ID)
+			if stored.PasswordHash == hash || !strings.Contains(stored.PasswordHash, "$m=1024,t=1,p=1$") {
+				t.Errorf("expected the hash to be upgraded to the current parameters, got %q", stored.PasswordHash)
+			}
+
+			// The upgraded hash keeps working
+			if _, _, err := svc.Login(ctx, user.Email, "Secret123"); err != nil {
+				t.Errorf("Login with the upgraded hash returned error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned by the WishlistService
+var (
+	// ErrWishlistNameTaken is returned when a user already has a wishlist with the name
+	ErrWishlistNameTaken = fmt.Errorf("%w: you already have a wishlist with this name", models.ErrConflict)
+	// ErrDefaultWishlist is returned when deleting the default wishlist
+	ErrDefaultWishlist = fmt.Errorf("%w: the default wishlist can't be deleted", models.ErrConflict)
+)
+
+// Wishlist limits
+const (
+	maxWishlists          = 20
+	maxWishlistItems      = 100
+	maxWishlistNameLength = 50
+)
+
+// defaultWishlistName is the name of the wishlist every user gets
+const defaultWishlistName = "Wishlist"
+
+// WishlistRepository is the storage used by the WishlistService
+type WishlistRepository interface {
+	Create(ctx context.Context, wishlist *models.Wishlist) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Wishlist, error)
+	FindDefault(ctx context.Context, userID string) (*models.Wishlist, error)
+	FindByShareTokenHash(ctx context.Context, hash string) (*models.Wishlist, error)
+	ListByUser(ctx context.Context, userID string) ([]models.Wishlist, error)
+	Save(ctx context.Context, wishlist *models.Wishlist) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// WishlistService manages the wishlists of users. The products of wishlists are
+// loaded with a single catalog lookup per call, however many lists and items are
+// returned.
+type WishlistService struct {
+	wishlists WishlistRepository
+	products  CartProductRepository
+	now       func() time.Time
+}
+
+// NewWishlistService creates a new WishlistService
+func NewWishlistService(wishlists WishlistRepository, products CartProductRepository) *WishlistService {
+	return &WishlistService{
+		wishlists: wishlists,
+		products:  products,
+		now:       time.Now,
+	}
+}
+
+// GetWishlist returns a wishlist of a user, or the default wishlist when id is nil.
+// The default wishlist is created the first time it is needed.
+func (s *WishlistService) GetWishlist(ctx context.Context, userID string, id *primitive.ObjectID) (*models.Wishlist, error) {
+	wishlist, err := s.find(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadProducts(ctx, wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+// ListWishlists returns every wishlist of a user, the default wishlist first
+func (s *WishlistService) ListWishlists(ctx context.Context, userID string) ([]models.Wishlist, error) {
+	if _, err := s.findDefault(ctx, userID); err != nil {
+		return nil, err
+	}
+	wishlists, err := s.wishlists.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make([]*models.Wishlist, len(wishlists))
+	for i := range wishlists {
+		loaded[i] = &wishlists[i]
+	}
+	if err := s.loadProducts(ctx, loaded...); err != nil {
+		return nil, err
+	}
+	return wishlists, nil
+}
+
+// GetSharedWishlist returns the wishlist shared with a link token
+func (s *WishlistService) GetSharedWishlist(ctx context.Context, token string) (*models.Wishlist, error) {
+	if token == "" {
+		return nil, models.ErrNotFound
+	}
+	wishlist, err := s.wishlists.FindByShareTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadProducts(ctx, wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+// CreateWishlist adds a named wishlist for a user
+func (s *WishlistService) CreateWishlist(ctx context.Context, userID, name string) (*models.Wishlist, error) {
+	name, err := validateWishlistName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The default wishlist takes its name before any other list can
+	if _, err := s.findDefault(ctx, userID); err != nil {
+		return nil, err
+	}
+	existing, err := s.wishlists.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxWishlists {
+		return nil, validator.ValidationErrors{{Field: "name", Message: fmt.Sprintf("at most %d wishlists can be created", maxWishlists)}}
+	}
+
+	wishlist := &models.Wishlist{UserID: userID, Name: name, Items: []models.WishlistItem{}}
+	if err := s.wishlists.Create(ctx, wishlist); err != nil {
+		if errors.Is(err, models.ErrDuplicate) {
+			return nil, ErrWishlistNameTaken
+		}
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+// RenameWishlist changes the name of a wishlist of a user
+func (s *WishlistService) RenameWishlist(ctx context.Context, userID string, id primitive.ObjectID, name string) (*models.Wishlist, error) {
+	name, err := validateWishlistName(name)
+	if err != nil {
+		return nil, err
+	}
+	wishlist, err := s.update(ctx, userID, &id, func(wishlist *models.Wishlist) error {
+		wishlist.Name = name
+		return nil
+	})
+	if errors.Is(err, models.ErrDuplicate) {
+		return nil, ErrWishlistNameTaken
+	}
+	return wishlist, err
+}
+
+// DeleteWishlist removes a wishlist of a user other than the default wishlist
+func (s *WishlistService) DeleteWishlist(ctx context.Context, userID string, id primitive.ObjectID) error {
+	wishlist, err := s.find(ctx, userID, &id)
+	if err != nil {
+		return err
+	}
+	if wishlist.IsDefault {
+		return ErrDefaultWishlist
+	}
+	return s.wishlists.Delete(ctx, id)
+}
+
+// AddProduct adds a product to a wishlist of a user, or to the default wishlist
+// when wishlistID is nil, recording its current price and availability. Adding a
+// product twice keeps the first snapshot.
+func (s *WishlistService) AddProduct(ctx context.Context, userID string, wishlistID *primitive.ObjectID, productID primitive.ObjectID) (*models.Wishlist, error) {
+	return s.update(ctx, userID, wishlistID, func(wishlist *models.Wishlist) error {
+		if _, ok := wishlist.FindItem(productID); ok {
+			return nil
+		}
+		if len(wishlist.Items) >= maxWishlistItems {
+			return validator.ValidationErrors{{Field: "productId", Message: fmt.Sprintf("a wishlist holds at most %d products", maxWishlistItems)}}
+		}
+
+		found, err := s.products.FindByIDs(ctx, []primitive.ObjectID{productID})
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return models.ErrNotFound
+		}
+		wishlist.Items = append(wishlist.Items, models.WishlistItem{
+			ProductID:        productID,
+			PriceWhenAdded:   found[0].Price,
+			InStockWhenAdded: found[0].InStock(),
+			AddedAt:          s.now(),
+		})
+		return nil
+	})
+}
+
+// RemoveProduct removes a product from a wishlist of a user, or from the default
+// wishlist when wishlistID is nil
+func (s *WishlistService) RemoveProduct(ctx context.Context, userID string, wishlistID *primitive.ObjectID, productID primitive.ObjectID) (*models.Wishlist, error) {
+	return s.update(ctx, userID, wishlistID, func(wishlist *models.Wishlist) error {
+		for i := range wishlist.Items {
+			if wishlist.Items[i].ProductID == productID {
+				wishlist.Items = append(wishlist.Items[:i], wishlist.Items[i+1:]...)
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// ShareWishlist creates a new read-only share link token for a wishlist of a user.
+// Links made before stop working.
+func (s *WishlistService) ShareWishlist(ctx context.Context, userID string, id primitive.ObjectID) (*models.Wishlist, error) {
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	wishlist, err := s.update(ctx, userID, &id, func(wishlist *models.Wishlist) error {
+		wishlist.ShareTokenHash = hashToken(token)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	wishlist.ShareToken = token
+	return wishlist, nil
+}
+
+// UnshareWishlist revokes the share link of a wishlist of a user
+func (s *WishlistService) UnshareWishlist(ctx context.Context, userID string, id primitive.ObjectID) (*models.Wishlist, error) {
+	return s.update(ctx, userID, &id, func(wishlist *models.Wishlist) error {
+		wishlist.ShareTokenHash = ""
+		return nil
+	})
+}
+
+// update loads a wishlist of a user, applies change and saves it, retrying when
+// the wishlist was changed concurrently
+func (s *WishlistService) update(ctx context.Context, userID string, id *primitive.ObjectID, change func(*models.Wishlist) error) (*models.Wishlist, error) {
+	var wishlist *models.Wishlist
+	err := retryOnConflict(func() error {
+		var err error
+		if wishlist, err = s.find(ctx, userID, id); err != nil {
+			return err
+		}
+		if err := change(wishlist); err != nil {
+			return err
+		}
+		return s.wishlists.Save(ctx, wishlist)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadProducts(ctx, wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+// find returns a wishlist of a user, or the default wishlist when id is nil.
+// Wishlists of other users are reported as not found.
+func (s *WishlistService) find(ctx context.Context, userID string, id *primitive.ObjectID) (*models.Wishlist, error) {
+	if id == nil {
+		return s.findDefault(ctx, userID)
+	}
+	wishlist, err := s.wishlists.FindByID(ctx, *id)
+	if err != nil {
+		return nil, err
+	}
+	if wishlist.UserID != userID {
+		return nil, models.ErrNotFound
+	}
+	return wishlist, nil
+}
+
+// findDefault returns the default wishlist of a user, creating it when missing
+func (s *WishlistService) findDefault(ctx context.Context, userID string) (*models.Wishlist, error) {
+	var wishlist *models.Wishlist
+	err := retryOnConflict(func() error {
+		var err error
+		wishlist, err = s.wishlists.FindDefault(ctx, userID)
+		if !errors.Is(err, models.ErrNotFound) {
+			return err
+		}
+		wishlist = &models.Wishlist{UserID: userID, Name: defaultWishlistName, IsDefault: true, Items: []models.WishlistItem{}}
+		return s.wishlists.Create(ctx, wishlist)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+// loadProducts attaches the current catalog products to the items of the wishlists
+// with one lookup
+func (s *WishlistService) loadProducts(ctx context.Context, wishlists ...*models.Wishlist) error {
+	var ids []primitive.ObjectID
+	for _, wishlist := range wishlists {
+		for _, item := range wishlist.Items {
+			ids = append(ids, item.ProductID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	found, err := s.products.FindByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	products := make(map[primitive.ObjectID]*models.Product, len(found))
+	for i := range found {
+		products[found[i].ID] = &found[i]
+	}
+	for _, wishlist := range wishlists {
+		for i := range wishlist.Items {
+			wishlist.Items[i].Product = products[wishlist.Items[i].ProductID]
+		}
+	}
+	return nil
+}
+
+// validateWishlistName trims a wishlist name and checks its length
+func validateWishlistName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxWishlistNameLength {
+		return "", validator.ValidationErrors{{Field: "name", Message: fmt.Sprintf("name must be 1-%d characters", maxWishlistNameLength)}}
+	}
+	return name, nil
+}
@@ -0,0 +1,196 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/internal/domain/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryWishlistRepository is an in-memory services.WishlistRepository
+type memoryWishlistRepository struct {
+	wishlists map[primitive.ObjectID]models.Wishlist
+}
+
+func (r *memoryWishlistRepository) Create(ctx context.Context, wishlist *models.Wishlist) error {
+	for _, existing := range r.wishlists {
+		if existing.UserID == wishlist.UserID && (existing.Name == wishlist.Name || existing.IsDefault && wishlist.IsDefault) {
+			return models.ErrDuplicate
+		}
+	}
+	wishlist.BeforeCreate()
+	r.wishlists[wishlist.ID] = copyWishlist(wishlist)
+	return nil
+}
+
+func (r *memoryWishlistRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Wishlist, error) {
+	return r.find(func(wishlist models.Wishlist) bool { return wishlist.ID == id })
+}
+
+func (r *memoryWishlistRepository) FindDefault(ctx context.Context, userID string) (*models.Wishlist, error) {
+	return r.find(func(wishlist models.Wishlist) bool { return wishlist.UserID == userID && wishlist.IsDefault })
+}
+
+func (r *memoryWishlistRepository) FindByShareTokenHash(ctx context.Context, hash string) (*models.Wishlist, error) {
+	return r.find(func(wishlist models.Wishlist) bool { return wishlist.ShareTokenHash == hash })
+}
+
+func (r *memoryWishlistRepository) ListByUser(ctx context.Context, userID string) ([]models.Wishlist, error) {
+	wishlists := []models.Wishlist{}
+	for _, wishlist := range r.wishlists {
+		if wishlist.UserID == userID {
+			wishlists = append(wishlists, copyWishlist(&wishlist))
+		}
+	}
+	sort.Slice(wishlists, func(i, j int) bool { return wishlists[i].IsDefault && !wishlists[j].IsDefault })
+	return wishlists, nil
+}
+
+func (r *memoryWishlistRepository) Save(ctx context.Context, wishlist *models.Wishlist) error {
+	if stored, ok := r.wishlists[wishlist.ID]; !ok || stored.Version != wishlist.Version {
+		return models.ErrConflict
+	}
+	for _, existing := range r.wishlists {
+		if existing.ID != wishlist.ID && existing.UserID == wishlist.UserID && existing.Name == wishlist.Name {
+			return models.ErrDuplicate
+		}
+	}
+	wishlist.Version++
+	r.wishlists[wishlist.ID] = copyWishlist(wishlist)
+	return nil
+}
+
+func (r *memoryWishlistRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	delete(r.wishlists, id)
+	return nil
+}
+
+func (r *memoryWishlistRepository) find(match func(models.Wishlist) bool) (*models.Wishlist, error) {
+	for _, wishlist := range r.wishlists {
+		if match(wishlist) {
+			copied := copyWishlist(&wishlist)
+			return &copied, nil
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+func copyWishlist(wishlist *models.Wishlist) models.Wishlist {
+	copied := *wishlist
+	copied.ShareToken = ""
+	copied.Items = make([]models.WishlistItem, len(wishlist.Items))
+	for i, item := range wishlist.Items {
+		item.Product = nil
+		copied.Items[i] = item
+	}
+	return copied
+}
+
+func TestWishlistReportsPriceDropsAndRestocks(t *testing.T) {
+	ctx := context.Background()
+	catalog := memoryCatalog{}
+	lamp := catalog.add("LAMP", 40, 0)
+	desk := catalog.add("DESK", 200, 3)
+	svc := services.NewWishlistService(&memoryWishlistRepository{wishlists: map[primitive.ObjectID]models.Wishlist{}}, catalog)
+
+	if _, err := svc.AddProduct(ctx, "u1", nil, lamp.ID); err != nil {
+		t.Fatalf("AddProduct returned error: %v", err)
+	}
+	if _, err := svc.AddProduct(ctx, "u1", nil, desk.ID); err != nil {
+		t.Fatalf("AddProduct returned error: %v", err)
+	}
+	if _, err := svc.AddProduct(ctx, "u1", nil, desk.ID); err != nil {
+		t.Fatalf("adding a product twice returned error: %v", err)
+	}
+
+	lamp.Stock = 2
+	desk.Price = 150
+	wishlist, err := svc.GetWishlist(ctx, "u1", nil)
+	if err != nil {
+		t.Fatalf("GetWishlist returned error: %v", err)
+	}
+	if !wishlist.IsDefault || len(wishlist.Items) != 2 {
+		t.Fatalf("expected the default wishlist with 2 items, got %+v", wishlist)
+	}
+	lampItem, _ := wishlist.FindItem(lamp.ID)
+	deskItem, _ := wishlist.FindItem(desk.ID)
+	if !lampItem.BackInStock() || lampItem.PriceDropped() {
+		t.Errorf("expected the lamp to be back in stock at the same price, got %+v", lampItem)
+	}
+	if deskItem.BackInStock() || deskItem.PriceWhenAdded != 200 {
+		t.Errorf("expected the desk to keep its price when added, got %+v", deskItem)
+	}
+	if drop := deskItem.PriceDrop(); drop == nil || *drop != 50 {
+		t.Errorf("expected a price drop of 50, got %v", drop)
+	}
+
+	delete(catalog, lamp.ID)
+	wishlist, err = svc.GetWishlist(ctx, "u1", nil)
+	if err != nil {
+		t.Fatalf("GetWishlist returned error: %v", err)
+	}
+	if products := wishlist.Products(); len(products) != 1 || products[0].ID != desk.ID {
+		t.Errorf("expected removed products to be skipped, got %v", products)
+	}
+}
+
+func TestWishlistSharingAndOwnership(t *testing.T) {
+	ctx := context.Background()
+	catalog := memoryCatalog{}
+	mug := catalog.add("MUG", 10, 5)
+	svc := services.NewWishlistService(&memoryWishlistRepository{wishlists: map[primitive.ObjectID]models.Wishlist{}}, catalog)
+
+	gifts, err := svc.CreateWishlist(ctx, "u1", " Gifts ")
+	if err != nil {
+		t.Fatalf("CreateWishlist returned error: %v", err)
+	}
+	if _, err := svc.CreateWishlist(ctx, "u1", "Gifts"); !errors.Is(err, services.ErrWishlistNameTaken) {
+		t.Errorf("expected a duplicate name to be rejected, got %v", err)
+	}
+	if _, err := svc.AddProduct(ctx, "u1", &gifts.ID, mug.ID); err != nil {
+		t.Fatalf("AddProduct returned error: %v", err)
+	}
+	if _, err := svc.AddProduct(ctx, "u2", &gifts.ID, mug.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("expected another user's wishlist to be hidden, got %v", err)
+	}
+
+	shared, err := svc.ShareWishlist(ctx, "u1", gifts.ID)
+	if err != nil {
+		t.Fatalf("ShareWishlist returned error: %v", err)
+	}
+	if shared.ShareToken == "" || !shared.Shared() {
+		t.Fatal("expected a share token")
+	}
+	viewed, err := svc.GetSharedWishlist(ctx, shared.ShareToken)
+	if err != nil {
+		t.Fatalf("GetSharedWishlist returned error: %v", err)
+	}
+	if viewed.ID != gifts.ID || len(viewed.Products()) != 1 {
+		t.Errorf("expected the shared wishlist with its product, got %+v", viewed)
+	}
+
+	if _, err := svc.ShareWishlist(ctx, "u1", gifts.ID); err != nil {
+		t.Fatalf("ShareWishlist returned error: %v", err)
+	}
+	if _, err := svc.GetSharedWishlist(ctx, shared.ShareToken); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("expected the old share link to stop working, got %v", err)
+	}
+
+	wishlists, err := svc.ListWishlists(ctx, "u1")
+	if err != nil {
+		t.Fatalf("ListWishlists returned error: %v", err)
+	}
+	if len(wishlists) != 2 || !wishlists[0].IsDefault {
+		t.Fatalf("expected the default wishlist first, got %+v", wishlists)
+	}
+	if err := svc.DeleteWishlist(ctx, "u1", wishlists[0].ID); !errors.Is(err, services.ErrDefaultWishlist) {
+		t.Errorf("expected the default wishlist to be kept, got %v", err)
+	}
+	if err := svc.DeleteWishlist(ctx, "u1", gifts.ID); err != nil {
+		t.Errorf("DeleteWishlist returned error: %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/prototype01/internal/auth"
+)
+
+// RequestContextMiddleware stores the HTTP request in the request context so the
+// GraphQL operation middleware can read the Authorization header and cookies
+func RequestContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(auth.WithRequest(r.Context(), r)))
+	})
+}
@@ -0,0 +1,78 @@
+package payment
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prototype01/pkg/validator"
+)
+
+// Validate checks the card number checksum, the expiry date and the CVC
+func (c Card) Validate(now time.Time) error {
+	var errs validator.ValidationErrors
+	if !luhnValid(c.normalizedNumber()) {
+		errs = append(errs, validator.ValidationError{Field: "card.number", Message: "invalid card number"})
+	}
+	if c.ExpiryMonth < 1 || c.ExpiryMonth > 12 {
+		errs = append(errs, validator.ValidationError{Field: "card.expiryMonth", Message: "expiry month must be between 1 and 12"})
+	} else if CardExpired(c.ExpiryMonth, c.ExpiryYear, now) {
+		errs = append(errs, validator.ValidationError{Field: "card.expiryYear", Message: "the card has expired"})
+	}
+	if n := len(c.CVC); n < 3 || n > 4 || strings.Trim(c.CVC, "0123456789") != "" {
+		errs = append(errs, validator.ValidationError{Field: "card.cvc", Message: "CVC must have 3 or 4 digits"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// CardExpired reports whether a card expiring at the end of the given month has expired at now
+func CardExpired(month, year int, now time.Time) bool {
+	expiry := time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+	return !now.Before(expiry)
+}
+
+// normalizedNumber returns the card number without spaces and dashes
+func (c Card) normalizedNumber() string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(c.Number)
+}
+
+// brand returns the card network guessed from the number prefix
+func (c Card) brand() string {
+	number := c.normalizedNumber()
+	switch {
+	case strings.HasPrefix(number, "4"):
+		return "visa"
+	case len(number) >= 2 && number[0] == '5' && number[1] >= '1' && number[1] <= '5',
+		strings.HasPrefix(number, "2"):
+		return "mastercard"
+	case strings.HasPrefix(number, "34"), strings.HasPrefix(number, "37"):
+		return "amex"
+	default:
+		return "unknown"
+	}
+}
+
+// luhnValid reports whether a card number has 12 to 19 digits and a valid Luhn checksum
+func luhnValid(number string) bool {
+	if len(number) < 12 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,90 @@
+package payment_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/payment"
+	"github.com/prototype01/pkg/validator"
+)
+
+// validCard returns a card that passes validation at now
+func validCard(number string) payment.Card {
+	return payment.Card{Number: number, ExpiryMonth: 12, ExpiryYear: time.Now().Year() + 1, CVC: "123"}
+}
+
+func TestCardValidate(t *testing.T) {
+	now := time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		card  payment.Card
+		field string
+	}{
+		{name: "valid", card: payment.Card{Number: "4242 4242-4242 4242", ExpiryMonth: 3, ExpiryYear: 2026, CVC: "123"}},
+		{name: "four digit CVC", card: payment.Card{Number: "378282246310005", ExpiryMonth: 1, ExpiryYear: 2030, CVC: "1234"}},
+		{name: "bad checksum", card: payment.Card{Number: "4242424242424241", ExpiryMonth: 1, ExpiryYear: 2030, CVC: "123"}, field: "card.number"},
+		{name: "too short", card: payment.Card{Number: "42424242424", ExpiryMonth: 1, ExpiryYear: 2030, CVC: "123"}, field: "card.number"},
+		{name: "not digits", card: payment.Card{Number: "4242abcd42424242", ExpiryMonth: 1, ExpiryYear: 2030, CVC: "123"}, field: "card.number"},
+		{name: "bad month", card: payment.Card{Number: "4242424242424242", ExpiryMonth: 13, ExpiryYear: 2030, CVC: "123"}, field: "card.expiryMonth"},
+		{name: "expired", card: payment.Card{Number: "4242424242424242", ExpiryMonth: 2, ExpiryYear: 2026, CVC: "123"}, field: "card.expiryYear"},
+		{name: "short CVC", card: payment.Card{Number: "4242424242424242", ExpiryMonth: 1, ExpiryYear: 2030, CVC: "12"}, field: "card.cvc"},
+		{name: "CVC letters", card: payment.Card{Number: "4242424242424242", ExpiryMonth: 1, ExpiryYear: 2030, CVC: "12a"}, field: "card.cvc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.card.Validate(now)
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			var errs validator.ValidationErrors
+			if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != tt.field {
+				t.Fatalf("got %v, want a single %s error", err, tt.field)
+			}
+		})
+	}
+}
+
+func TestCardExpired(t *testing.T) {
+	tests := []struct {
+		now     time.Time
+		expired bool
+	}{
+		{now: time.Date(2026, time.March, 31, 23, 59, 0, 0, time.UTC), expired: false},
+		{now: time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC), expired: true},
+		{now: time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC), expired: false},
+	}
+	for _, tt := range tests {
+		if got := payment.CardExpired(3, 2026, tt.now); got != tt.expired {
+			t.Errorf("CardExpired(3, 2026) at %s = %v, want %v", tt.now, got, tt.expired)
+		}
+	}
+	// December rolls over into the next year
+	if payment.CardExpired(12, 2026, time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Error("expected a card expiring in December to be valid until the end of the year")
+	}
+}
+
+func TestCardBrand(t *testing.T) {
+	tests := map[string]string{
+		"4242424242424242": "visa",
+		"5555555555554444": "mastercard",
+		"2223003122003222": "mastercard",
+		"378282246310005":  "amex",
+		"6011111111111117": "unknown",
+	}
+	gateway := payment.NewFakeGateway(payment.FakeGatewayOptions{})
+	for number, want := range tests {
+		token, err := gateway.Tokenize(context.Background(), validCard(number))
+		if err != nil {
+			t.Fatalf("Tokenize(%s): %v", number, err)
+		}
+		if token.Brand != want || token.LastFourDigits != number[len(number)-4:] {
+			t.Errorf("Tokenize(%s) = %s ending in %s, want %s", number, token.Brand, token.LastFourDigits, want)
+		}
+	}
+}
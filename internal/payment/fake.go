@@ -0,0 +1,283 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Test card numbers recognized by the FakeGateway. Other valid numbers are approved.
+const (
+	FakeCardApproved          = "4242424242424242"
+	FakeCardDeclined          = "4000000000000002"
+	FakeCardInsufficientFunds = "4000000000009995"
+	FakeCardChallenge         = "4000000000003220"
+	FakeCardTimeout           = "4000000000000119"
+)
+
+// FakeChallengeApproval is the challenge response passing a FakeGateway 3-D Secure
+// challenge; any other response fails it
+const FakeChallengeApproval = "approve"
+
+// FakeOutcome is the simulated outcome of a FakeGateway authorization
+type FakeOutcome string
+
+// Simulated outcomes
+const (
+	FakeApprove           FakeOutcome = "approve"
+	FakeDecline           FakeOutcome = "decline"
+	FakeInsufficientFunds FakeOutcome = "insufficient_funds"
+	FakeChallenge         FakeOutcome = "challenge"
+	FakeTimeout           FakeOutcome = "timeout"
+)
+
+// ParseFakeOutcome parses a simulated outcome, empty meaning the outcome depends on the card
+func ParseFakeOutcome(s string) (FakeOutcome, error) {
+	switch outcome := FakeOutcome(s); outcome {
+	case "", FakeApprove, FakeDecline, FakeInsufficientFunds, FakeChallenge, FakeTimeout:
+		return outcome, nil
+	default:
+		return "", fmt.Errorf("unknown fake payment outcome %q", s)
+	}
+}
+
+// FakeGatewayOptions configures the simulated behavior of a FakeGateway
+type FakeGatewayOptions struct {
+	// Outcome forces the outcome of every authorization whatever the card, when set
+	Outcome FakeOutcome
+	// Latency delays every call; calls fail with ErrTimeout when the context ends first
+	Latency time.Duration
+}
+
+// fakeAuthorizationState is the state of a FakeGateway authorization
+type fakeAuthorizationState int
+
+const (
+	fakeChallenged fakeAuthorizationState = iota
+	fakeApproved
+	fakeCaptured
+	fakeVoided
+)
+
+// fakeAuthorization is an authorization held by a FakeGateway
+type fakeAuthorization struct {
+	state    fakeAuthorizationState
+	amount   float64
+	captured float64
+	refunded float64
+}
+
+// FakeGateway is a deterministic in-process Provider for development and tests. The
+// outcome of an authorization depends on the test card number the token was created
+// from, or on FakeGatewayOptions.Outcome. Card numbers are not kept, tokens only map
+// to the simulated outcome.
+type FakeGateway struct {
+	opts FakeGatewayOptions
+	now  func() time.Time
+
+	mu             sync.Mutex
+	sequence       int
+	tokens         map[string]FakeOutcome
+	authorizations map[string]*fakeAuthorization
+}
+
+// NewFakeGateway creates a new FakeGateway
+func NewFakeGateway(opts FakeGatewayOptions) *FakeGateway {
+	return &FakeGateway{
+		opts:           opts,
+		now:            time.Now,
+		tokens:         make(map[string]FakeOutcome),
+		authorizations: make(map[string]*fakeAuthorization),
+	}
+}
+
+// Tokenize validates a card and returns a token for it
+func (g *FakeGateway) Tokenize(ctx context.Context, card Card) (*CardToken, error) {
+	if err := g.wait(ctx); err != nil {
+		return nil, err
+	}
+	if err := card.Validate(g.now()); err != nil {
+		return nil, err
+	}
+
+	number := card.normalizedNumber()
+	outcome := FakeApprove
+	switch number {
+	case FakeCardDeclined:
+		outcome = FakeDecline
+	case FakeCardInsufficientFunds:
+		outcome = FakeInsufficientFunds
+	case FakeCardChallenge:
+		outcome = FakeChallenge
+	case FakeCardTimeout:
+		outcome = FakeTimeout
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	token := g.nextID("tok")
+	g.tokens[token] = outcome
+	return &CardToken{
+		Token:          token,
+		Brand:          card.brand(),
+		LastFourDigits: number[len(number)-4:],
+		ExpiryMonth:    card.ExpiryMonth,
+		ExpiryYear:     card.ExpiryYear,
+	}, nil
+}
+
+// Authorize holds an amount on a tokenized card or completes a challenged authorization
+func (g *FakeGateway) Authorize(ctx context.Context, req AuthorizeRequest) (*Authorization, error) {
+	if err := g.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if req.AuthorizationID != "" {
+		return g.completeChallenge(req)
+	}
+
+	outcome, ok := g.tokens[req.Token]
+	if !ok {
+		return nil, &DeclineError{Reason: "unknown card token"}
+	}
+	if g.opts.Outcome != "" {
+		outcome = g.opts.Outcome
+	}
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("invalid amount %v", req.Amount)
+	}
+
+	switch outcome {
+	case FakeDecline:
+		return nil, &DeclineError{Reason: "the card was declined"}
+	case FakeInsufficientFunds:
+		return nil, &DeclineError{Reason: "insufficient funds"}
+	case FakeTimeout:
+		return nil, ErrTimeout
+	}
+
+	id := g.nextID("auth")
+	auth := &fakeAuthorization{state: fakeApproved, amount: req.Amount}
+	result := &Authorization{ID: id, Status: AuthorizationApproved, Amount: req.Amount}
+	if outcome == FakeChallenge {
+		auth.state = fakeChallenged
+		result.Status = AuthorizationChallenged
+		result.ChallengeURL = "https://fake-gateway.invalid/3ds/" + id
+	}
+	g.authorizations[id] = auth
+	return result, nil
+}
+
+// Capture charges an approved authorization
+func (g *FakeGateway) Capture(ctx context.Context, authorizationID string, amount float64) error {
+	if err := g.wait(ctx); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	auth, err := g.authorization(authorizationID, fakeApproved)
+	if err != nil {
+		return err
+	}
+	if amount <= 0 || exceeds(amount, auth.amount) {
+		return fmt.Errorf("%w: capture amount %v exceeds the authorized %v", ErrInvalidState, amount, auth.amount)
+	}
+	auth.state = fakeCaptured
+	auth.captured = amount
+	return nil
+}
+
+// Void releases an approved authorization
+func (g *FakeGateway) Void(ctx context.Context, authorizationID string) error {
+	if err := g.wait(ctx); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	auth, ok := g.authorizations[authorizationID]
+	if !ok {
+		return ErrAuthorizationNotFound
+	}
+	if auth.state != fakeApproved && auth.state != fakeChallenged {
+		return ErrInvalidState
+	}
+	auth.state = fakeVoided
+	return nil
+}
+
+// Refund returns part or all of the captured amount of an authorization
+func (g *FakeGateway) Refund(ctx context.Context, authorizationID string, amount float64) error {
+	if err := g.wait(ctx); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	auth, err := g.authorization(authorizationID, fakeCaptured)
+	if err != nil {
+		return err
+	}
+	if amount <= 0 || exceeds(auth.refunded+amount, auth.captured) {
+		return fmt.Errorf("%w: refund amount %v exceeds the refundable %v", ErrInvalidState, amount, auth.captured-auth.refunded)
+	}
+	auth.refunded += amount
+	return nil
+}
+
+// completeChallenge approves or declines a challenged authorization
+func (g *FakeGateway) completeChallenge(req AuthorizeRequest) (*Authorization, error) {
+	auth, err := g.authorization(req.AuthorizationID, fakeChallenged)
+	if err != nil {
+		return nil, err
+	}
+	if req.ChallengeResponse != FakeChallengeApproval {
+		auth.state = fakeVoided
+		return nil, &DeclineError{Reason: "3-D Secure authentication failed"}
+	}
+	auth.state = fakeApproved
+	return &Authorization{ID: req.AuthorizationID, Status: AuthorizationApproved, Amount: auth.amount}, nil
+}
+
+// authorization returns an authorization in the given state. The caller holds g.mu.
+func (g *FakeGateway) authorization(id string, state fakeAuthorizationState) (*fakeAuthorization, error) {
+	auth, ok := g.authorizations[id]
+	if !ok {
+		return nil, ErrAuthorizationNotFound
+	}
+	if auth.state != state {
+		return nil, ErrInvalidState
+	}
+	return auth, nil
+}
+
+// nextID returns a new sequential ID with the given prefix. The caller holds g.mu.
+func (g *FakeGateway) nextID(prefix string) string {
+	g.sequence++
+	return fmt.Sprintf("%s_fake_%06d", prefix, g.sequence)
+}
+
+// wait simulates the configured latency, failing with ErrTimeout when ctx ends first
+func (g *FakeGateway) wait(ctx context.Context) error {
+	if g.opts.Latency <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(g.opts.Latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ErrTimeout
+	}
+}
+
+// exceeds reports whether amount is more than limit, ignoring sub-cent rounding
+func exceeds(amount, limit float64) bool {
+	return math.Round(amount*100) > math.Round(limit*100)
+}
@@ -0,0 +1,195 @@
+package payment_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prototype01/internal/payment"
+)
+
+// authorize tokenizes a test card and authorizes amount on it
+func authorize(t *testing.T, gateway *payment.FakeGateway, number string, amount float64) (*payment.Authorization, error) {
+	t.Helper()
+	token, err := gateway.Tokenize(context.Background(), validCard(number))
+	if err != nil {
+		t.Fatalf("Tokenize: %v", err)
+	}
+	return gateway.Authorize(context.Background(), payment.AuthorizeRequest{Token: token.Token, Amount: amount, Currency: "USD"})
+}
+
+func TestFakeGatewayCards(t *testing.T) {
+	tests := []struct {
+		number string
+		status payment.AuthorizationStatus
+		err    error
+	}{
+		{number: payment.FakeCardApproved, status: payment.AuthorizationApproved},
+		{number: "5555555555554444", status: payment.AuthorizationApproved},
+		{number: payment.FakeCardDeclined, err: payment.ErrDeclined},
+		{number: payment.FakeCardInsufficientFunds, err: payment.ErrDeclined},
+		{number: payment.FakeCardChallenge, status: payment.AuthorizationChallenged},
+		{number: payment.FakeCardTimeout, err: payment.ErrTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			auth, err := authorize(t, payment.NewFakeGateway(payment.FakeGatewayOptions{}), tt.number, 20)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authorize: %v", err)
+			}
+			if auth.Status != tt.status || auth.Amount != 20 {
+				t.Errorf("authorization = %+v, want %s for 20", auth, tt.status)
+			}
+		})
+	}
+
+	var decline *payment.DeclineError
+	_, err := authorize(t, payment.NewFakeGateway(payment.FakeGatewayOptions{}), payment.FakeCardInsufficientFunds, 20)
+	if !errors.As(err, &decline) || decline.Reason != "insufficient funds" {
+		t.Errorf("got %v, want an insufficient funds decline", err)
+	}
+}
+
+func TestFakeGatewayChallenge(t *testing.T) {
+	ctx := context.Background()
+	gateway := payment.NewFakeGateway(payment.FakeGatewayOptions{})
+	auth, err := authorize(t, gateway, payment.FakeCardChallenge, 20)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if !strings.HasSuffix(auth.ChallengeURL, auth.ID) {
+		t.Errorf("challenge URL = %q, want it to identify %s", auth.ChallengeURL, auth.ID)
+	}
+	if err := gateway.Capture(ctx, auth.ID, 20); !errors.Is(err, payment.ErrInvalidState) {
+		t.Errorf("Capture before the challenge: got %v, want ErrInvalidState", err)
+	}
+
+	completed, err := gateway.Authorize(ctx, payment.AuthorizeRequest{AuthorizationID: auth.ID, ChallengeResponse: payment.FakeChallengeApproval})
+	if err != nil || completed.Status != payment.AuthorizationApproved {
+		t.Fatalf("completing the challenge = %+v, %v", completed, err)
+	}
+	if err := gateway.Capture(ctx, auth.ID, 20); err != nil {
+		t.Errorf("Capture: %v", err)
+	}
+
+	// A failed challenge declines and voids the authorization
+	auth, _ = authorize(t, gateway, payment.FakeCardChallenge, 20)
+	_, err = gateway.Authorize(ctx, payment.AuthorizeRequest{AuthorizationID: auth.ID, ChallengeResponse: "wrong"})
+	if !errors.Is(err, payment.ErrDeclined) {
+		t.Errorf("failed challenge: got %v, want ErrDeclined", err)
+	}
+	if err := gateway.Void(ctx, auth.ID); !errors.Is(err, payment.ErrInvalidState) {
+		t.Errorf("Void after a failed challenge: got %v, want ErrInvalidState", err)
+	}
+}
+
+func TestFakeGatewayOutcome(t *testing.T) {
+	tests := []struct {
+		outcome payment.FakeOutcome
+		number  string
+		status  payment.AuthorizationStatus
+		err     error
+	}{
+		{outcome: payment.FakeApprove, number: payment.FakeCardDeclined, status: payment.AuthorizationApproved},
+		{outcome: payment.FakeDecline, number: payment.FakeCardApproved, err: payment.ErrDeclined},
+		{outcome: payment.FakeInsufficientFunds, number: payment.FakeCardApproved, err: payment.ErrDeclined},
+		{outcome: payment.FakeChallenge, number: payment.FakeCardApproved, status: payment.AuthorizationChallenged},
+		{outcome: payment.FakeTimeout, number: payment.FakeCardApproved, err: payment.ErrTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.outcome), func(t *testing.T) {
+			gateway := payment.NewFakeGateway(payment.FakeGatewayOptions{Outcome: tt.outcome})
+			auth, err := authorize(t, gateway, tt.number, 20)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil || auth.Status != tt.status {
+				t.Fatalf("authorization = %+v, %v, want %s", auth, err, tt.status)
+			}
+		})
+	}
+}
+
+func TestParseFakeOutcome(t *testing.T) {
+	for _, s := range []string{"", "approve", "decline", "insufficient_funds", "challenge", "timeout"} {
+		if outcome, err := payment.ParseFakeOutcome(s); err != nil || string(outcome) != s {
+			t.Errorf("ParseFakeOutcome(%q) = %q, %v", s, outcome, err)
+		}
+	}
+	if _, err := payment.ParseFakeOutcome("explode"); err == nil {
+		t.Error("expected an unknown outcome to be rejected")
+	}
+}
+
+func TestFakeGatewayLatency(t *testing.T) {
+	gateway := payment.NewFakeGateway(payment.FakeGatewayOptions{Latency: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := gateway.Tokenize(ctx, validCard(payment.FakeCardApproved)); !errors.Is(err, payment.ErrTimeout) {
+		t.Errorf("Tokenize: got %v, want ErrTimeout", err)
+	}
+	if err := gateway.Capture(ctx, "auth_fake_000001", 20); !errors.Is(err, payment.ErrTimeout) {
+		t.Errorf("Capture: got %v, want ErrTimeout", err)
+	}
+
+	// Calls answering within the deadline succeed
+	gateway = payment.NewFakeGateway(payment.FakeGatewayOptions{Latency: time.Millisecond})
+	if _, err := gateway.Tokenize(context.Background(), validCard(payment.FakeCardApproved)); err != nil {
+		t.Errorf("Tokenize: %v", err)
+	}
+}
+
+func TestFakeGatewayCaptureAndRefundLimits(t *testing.T) {
+	ctx := context.Background()
+	gateway := payment.NewFakeGateway(payment.FakeGatewayOptions{})
+	auth, err := authorize(t, gateway, payment.FakeCardApproved, 20)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	if err := gateway.Capture(ctx, auth.ID, 20.01); !errors.Is(err, payment.ErrInvalidState) {
+		t.Errorf("Capture over the authorized amount: got %v, want ErrInvalidState", err)
+	}
+	if err := gateway.Capture(ctx, auth.ID, 0); !errors.Is(err, payment.ErrInvalidState) {
+		t.Errorf("Capture of nothing: got %v, want ErrInvalidState", err)
+	}
+	// Sub-cent rounding errors don't exceed the limit
+	if err := gateway.Capture(ctx, auth.ID, 15+0.000001); err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+	if err := gateway.Capture(ctx, auth.ID, 15); !errors.Is(err, payment.ErrInvalidState) {
+		t.Errorf("second Capture: got %v, want ErrInvalidState", err)
+	}
+
+	if err := gateway.Refund(ctx, auth.ID, 10); err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+	if err := gateway.Refund(ctx, auth.ID, 5.01); !errors.Is(err, payment.ErrInvalidState) {
+		t.Errorf("Refund over the captured amount: got %v, want ErrInvalidState", err)
+	}
+	if err := gateway.Refund(ctx, auth.ID, 5); err != nil {
+		t.Errorf("Refund of the rest: %v", err)
+	}
+	if err := gateway.Void(ctx, auth.ID); !errors.Is(err, payment.ErrInvalidState) {
+		t.Errorf("Void after capture: got %v, want ErrInvalidState", err)
+	}
+
+	if err := gateway.Capture(ctx, "auth_unknown", 1); !errors.Is(err, payment.ErrAuthorizationNotFound) {
+		t.Errorf("Capture of an unknown authorization: got %v, want ErrAuthorizationNotFound", err)
+	}
+	if err := gateway.Refund(ctx, "auth_unknown", 1); !errors.Is(err, payment.ErrAuthorizationNotFound) {
+		t.Errorf("Refund of an unknown authorization: got %v, want ErrAuthorizationNotFound", err)
+	}
+}
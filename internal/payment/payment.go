@@ -0,0 +1,104 @@
+// Package payment abstracts the payment gateways used to charge cards
+package payment
+
+import (
+	"context"
+	"errors"
+)
+
+// Errors returned by providers
+var (
+	// ErrDeclined is matched by every *DeclineError
+	ErrDeclined = errors.New("payment declined")
+	// ErrTimeout is returned when the gateway didn't answer in time. The outcome of
+	// the operation is unknown to the caller.
+	ErrTimeout = errors.New("payment gateway timed out")
+	// ErrAuthorizationNotFound is returned for an unknown authorization ID
+	ErrAuthorizationNotFound = errors.New("payment authorization not found")
+	// ErrInvalidState is returned when an authorization can't be captured, voided or refunded in its state
+	ErrInvalidState = errors.New("payment authorization is not in a state allowing this operation")
+)
+
+// DeclineError is returned when the issuer or the gateway refuses a payment
+type DeclineError struct {
+	// Reason is a message that can be shown to the buyer
+	Reason string
+}
+
+// Error implements error
+func (e *DeclineError) Error() string {
+	return "payment declined: " + e.Reason
+}
+
+// Is makes errors.Is(err, ErrDeclined) match decline errors
+func (e *DeclineError) Is(target error) bool {
+	return target == ErrDeclined
+}
+
+// Card is a payment card as entered by the buyer. It is only passed to Tokenize and
+// must never be stored or logged.
+type Card struct {
+	Number      string
+	ExpiryMonth int
+	ExpiryYear  int
+	CVC         string
+	HolderName  string
+}
+
+// CardToken is the provider token standing for a card, safe to store
+type CardToken struct {
+	Token          string
+	Brand          string
+	LastFourDigits string
+	ExpiryMonth    int
+	ExpiryYear     int
+}
+
+// AuthorizationStatus is the outcome of an authorization request
+type AuthorizationStatus string
+
+// Authorization outcomes
+const (
+	// AuthorizationApproved means the amount is held and can be captured
+	AuthorizationApproved AuthorizationStatus = "approved"
+	// AuthorizationChallenged means the buyer must complete a 3-D Secure challenge
+	// before the authorization can be completed
+	AuthorizationChallenged AuthorizationStatus = "challenged"
+)
+
+// AuthorizeRequest asks to hold an amount on a tokenized card. A challenged
+// authorization is completed by sending its ID with the challenge response.
+type AuthorizeRequest struct {
+	Token    string
+	Amount   float64
+	Currency string
+	// Reference identifies the payment on the merchant side, e.g. the order ID
+	Reference string
+
+	// AuthorizationID and ChallengeResponse complete a challenged authorization
+	AuthorizationID   string
+	ChallengeResponse string
+}
+
+// Authorization is an amount held on a card
+type Authorization struct {
+	ID     string
+	Status AuthorizationStatus
+	Amount float64
+	// ChallengeURL is where the buyer completes a 3-D Secure challenge
+	ChallengeURL string
+}
+
+// Provider is a payment gateway. Amounts are in the major unit of the currency.
+type Provider interface {
+	// Tokenize exchanges card details for a token that can be stored and charged later
+	Tokenize(ctx context.Context, card Card) (*CardToken, error)
+	// Authorize holds an amount on a tokenized card, failing with a *DeclineError when refused
+	Authorize(ctx context.Context, req AuthorizeRequest) (*Authorization, error)
+	// Capture charges an approved authorization for up to the authorized amount
+	Capture(ctx context.Context, authorizationID string, amount float64) error
+	// Void releases an approved authorization that wasn't captured
+	Void(ctx context.Context, authorizationID string) error
+	// Refund returns up to the captured amount of an authorization to the card
+	Refund(ctx context.Context, authorizationID string, amount float64) error
+}
@@ -0,0 +1,204 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Names of the analytics collections
+const (
+	productEventsCollection      = "product_events"
+	productStatsHourlyCollection = "product_stats_hourly"
+	productStatsDailyCollection  = "product_stats_daily"
+	analyticsStateCollection     = "analytics_state"
+)
+
+// rollupStateID is the ID of the document holding the rollup watermark
+const rollupStateID = "rollup"
+
+// AnalyticsRepository stores raw product events and their hourly and daily rollups.
+// Rollups are recomputed from their source with $merge, replacing whole buckets,
+// so running a rollup twice over the same window gives the same counts.
+type AnalyticsRepository struct {
+	events *mongo.Collection
+	hourly *mongo.Collection
+	daily  *mongo.Collection
+	state  *mongo.Collection
+	// eventTTL is how long raw events are kept after they occurred
+	eventTTL time.Duration
+}
+
+// NewAnalyticsRepository creates a new AnalyticsRepository keeping raw events for eventTTL
+func NewAnalyticsRepository(db *mongo.Database, eventTTL time.Duration) *AnalyticsRepository {
+	return &AnalyticsRepository{
+		events:   db.Collection(productEventsCollection),
+		hourly:   db.Collection(productStatsHourlyCollection),
+		daily:    db.Collection(productStatsDailyCollection),
+		state:    db.Collection(analyticsStateCollection),
+		eventTTL: eventTTL,
+	}
+}
+
+// EnsureIndexes creates the event hour index, the raw event expiry index and the
+// bucket indexes used by rollups and rankings
+func (r *AnalyticsRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.events.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hour", Value: 1}}},
+		{Keys: bson.D{{Key: "occurred_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(r.eventTTL.Seconds()))},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create product event indexes: %w", err)
+	}
+
+	_, err = r.hourly.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "bucket", Value: 1}}},
+		{Keys: bson.D{{Key: "day", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create hourly product stats indexes: %w", err)
+	}
+
+	_, err = r.daily.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "bucket", Value: 1}}})
+	if err != nil {
+		return fmt.Errorf("failed to create daily product stats indexes: %w", err)
+	}
+	return nil
+}
+
+// RecordEvents inserts raw product events
+func (r *AnalyticsRepository) RecordEvents(ctx context.Context, events []models.ProductEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(events))
+	for i := range events {
+		docs[i] = events[i]
+	}
+	if _, err := r.events.InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("failed to record product events: %w", err)
+	}
+	return nil
+}
+
+// RollupHours recomputes the hourly buckets of the hours in [from, to) from the raw events
+func (r *AnalyticsRepository) RollupHours(ctx context.Context, from, to time.Time) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"hour": bson.M{"$gte": from, "$lt": to}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       bson.M{"product_id": "$product_id", "bucket": "$hour"},
+			"day":       bson.M{"$first": "$day"},
+			"views":     bson.M{"$sum": eventQuantity(models.ProductViewed)},
+			"purchases": bson.M{"$sum": eventQuantity(models.ProductPurchased)},
+		}}},
+		{{Key: "$project", Value: bucketProjection}},
+		{{Key: "$merge", Value: bson.M{"into": productStatsHourlyCollection, "whenMatched": "replace", "whenNotMatched": "insert"}}},
+	}
+	cursor, err := r.events.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to roll up hourly product stats: %w", err)
+	}
+	return cursor.Close(ctx)
+}
+
+// RollupDays recomputes the daily buckets of the days in [from, to) from the hourly buckets
+func (r *AnalyticsRepository) RollupDays(ctx context.Context, from, to time.Time) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"day": bson.M{"$gte": from, "$lt": to}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       bson.M{"product_id": "$product_id", "bucket": "$day"},
+			"day":       bson.M{"$first": "$day"},
+			"views":     bson.M{"$sum": "$views"},
+			"purchases": bson.M{"$sum": "$purchases"},
+		}}},
+		{{Key: "$project", Value: bucketProjection}},
+		{{Key: "$merge", Value: bson.M{"into": productStatsDailyCollection, "whenMatched": "replace", "whenNotMatched": "insert"}}},
+	}
+	cursor, err := r.hourly.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to roll up daily product stats: %w", err)
+	}
+	return cursor.Close(ctx)
+}
+
+// RollupWatermark returns the time up to which events were rolled up, zero before the first rollup
+func (r *AnalyticsRepository) RollupWatermark(ctx context.Context) (time.Time, error) {
+	var state struct {
+		RolledUpTo time.Time `bson:"rolled_up_to"`
+	}
+	err := r.state.FindOne(ctx, bson.M{"_id": rollupStateID}).Decode(&state)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to find rollup watermark: %w", err)
+	}
+	return state.RolledUpTo, nil
+}
+
+// SetRollupWatermark records the time up to which events were rolled up
+func (r *AnalyticsRepository) SetRollupWatermark(ctx context.Context, at time.Time) error {
+	_, err := r.state.UpdateOne(ctx,
+		bson.M{"_id": rollupStateID},
+		bson.M{"$set": bson.M{"rolled_up_to": at}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set rollup watermark: %w", err)
+	}
+	return nil
+}
+
+// TopProducts returns up to limit products with the most purchases, then views, in
+// the buckets starting at or after since. A zero since ranks over every bucket.
+func (r *AnalyticsRepository) TopProducts(ctx context.Context, granularity models.StatsGranularity, since time.Time, limit int) ([]models.PopularProduct, error) {
+	buckets := r.daily
+	if granularity == models.StatsHourly {
+		buckets = r.hourly
+	}
+
+	match := bson.M{}
+	if !since.IsZero() {
+		match["bucket"] = bson.M{"$gte": since}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$product_id",
+			"views":     bson.M{"$sum": "$views"},
+			"purchases": bson.M{"$sum": "$purchases"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "purchases", Value: -1}, {Key: "views", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+	cursor, err := buckets.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank products: %w", err)
+	}
+
+	popular := []models.PopularProduct{}
+	if err := cursor.All(ctx, &popular); err != nil {
+		return nil, fmt.Errorf("failed to decode product stats: %w", err)
+	}
+	return popular, nil
+}
+
+// bucketProjection shapes grouped counts into a bucket document keyed by product and bucket start
+var bucketProjection = bson.M{
+	"product_id": "$_id.product_id",
+	"bucket":     "$_id.bucket",
+	"day":        1,
+	"views":      1,
+	"purchases":  1,
+}
+
+// eventQuantity sums the quantity of the events of a type
+func eventQuantity(eventType models.ProductEventType) bson.M {
+	return bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$type", eventType}}, "$quantity", 0}}
+}
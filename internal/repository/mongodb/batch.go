@@ -0,0 +1,27 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// findIn returns the documents whose field is one of values with a single $in
+// query, in no particular order. what names the documents in errors.
+func findIn[T any, K any](ctx context.Context, coll *mongo.Collection, field string, values []K, what string) ([]T, error) {
+	docs := []T{}
+	if len(values) == 0 {
+		return docs, nil
+	}
+
+	cursor, err := coll.Find(ctx, bson.M{field: bson.M{"$in": values}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s: %w", what, err)
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", what, err)
+	}
+	return docs, nil
+}
@@ -0,0 +1,118 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// cartsCollection is the name of the carts collection
+const cartsCollection = "carts"
+
+// CartRepository stores shopping carts
+type CartRepository struct {
+	carts *mongo.Collection
+}
+
+// NewCartRepository creates a new CartRepository
+func NewCartRepository(db *mongo.Database) *CartRepository {
+	return &CartRepository{carts: db.Collection(cartsCollection)}
+}
+
+// EnsureIndexes creates the owner indexes and the TTL index removing abandoned carts
+func (r *CartRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.carts.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"user_id": bson.M{"$exists": true}}),
+		},
+		{
+			Keys: bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"token_hash": bson.M{"$exists": true}}),
+		},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create cart indexes: %w", err)
+	}
+	return nil
+}
+
+// Create inserts a new cart, returning models.ErrDuplicate when the owner already has one
+func (r *CartRepository) Create(ctx context.Context, cart *models.Cart) error {
+	cart.BeforeCreate()
+	cart.Version = 1
+	if _, err := r.carts.InsertOne(ctx, cart); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrDuplicate
+		}
+		return fmt.Errorf("failed to insert cart: %w", err)
+	}
+	return nil
+}
+
+// FindByUserID returns the cart of a signed in user
+func (r *CartRepository) FindByUserID(ctx context.Context, userID string) (*models.Cart, error) {
+	return r.findOne(ctx, bson.M{"user_id": userID})
+}
+
+// FindByUserIDs returns the carts of the given users, in no particular order
+func (r *CartRepository) FindByUserIDs(ctx context.Context, userIDs []string) ([]models.Cart, error) {
+	return findIn[models.Cart](ctx, r.carts, "user_id", userIDs, "carts")
+}
+
+// FindByTokenHash returns the guest cart with the given token hash
+func (r *CartRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.Cart, error) {
+	return r.findOne(ctx, bson.M{"token_hash": tokenHash})
+}
+
+// Save replaces a cart if it wasn't changed since it was loaded, returning
+// models.ErrConflict otherwise
+func (r *CartRepository) Save(ctx context.Context, cart *models.Cart) error {
+	cart.BeforeUpdate()
+	version := cart.Version
+	cart.Version++
+
+	res, err := r.carts.ReplaceOne(ctx, bson.M{"_id": cart.ID, "version": version}, cart)
+	if err != nil {
+		cart.Version = version
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrDuplicate
+		}
+		return fmt.Errorf("failed to save cart: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		cart.Version = version
+		return models.ErrConflict
+	}
+	return nil
+}
+
+// Delete removes a cart
+func (r *CartRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if _, err := r.carts.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return fmt.Errorf("failed to delete cart: %w", err)
+	}
+	return nil
+}
+
+// findOne returns the cart matching the filter
+func (r *CartRepository) findOne(ctx context.Context, filter bson.M) (*models.Cart, error) {
+	var cart models.Cart
+	err := r.carts.FindOne(ctx, filter).Decode(&cart)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cart: %w", err)
+	}
+	return &cart, nil
+}
@@ -0,0 +1,182 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// categoriesCollection is the name of the categories collection
+const categoriesCollection = "categories"
+
+// CategoryRepository stores the category tree
+type CategoryRepository struct {
+	categories *mongo.Collection
+}
+
+// NewCategoryRepository creates a new CategoryRepository
+func NewCategoryRepository(db *mongo.Database) *CategoryRepository {
+	return &CategoryRepository{categories: db.Collection(categoriesCollection)}
+}
+
+// EnsureIndexes creates the parent and ancestor indexes
+func (r *CategoryRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.categories.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "parent_id", Value: 1}, {Key: "name", Value: 1}}},
+		{Keys: bson.D{{Key: "ancestors", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create category indexes: %w", err)
+	}
+	return nil
+}
+
+// Create inserts a new category
+func (r *CategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	category.BeforeCreate()
+	if category.Ancestors == nil {
+		category.Ancestors = []primitive.ObjectID{}
+	}
+	if _, err := r.categories.InsertOne(ctx, category); err != nil {
+		return fmt.Errorf("failed to insert category: %w", err)
+	}
+	return nil
+}
+
+// FindByID returns the category with the given ID
+func (r *CategoryRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Category, error) {
+	var category models.Category
+	err := r.categories.FindOne(ctx, bson.M{"_id": id}).Decode(&category)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find category: %w", err)
+	}
+	return &category, nil
+}
+
+// FindByIDs returns the categories with the given IDs, in no particular order
+func (r *CategoryRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Category, error) {
+	return findIn[models.Category](ctx, r.categories, "_id", ids, "categories")
+}
+
+// FindChildren returns the direct children of a category, or the root categories for a nil parent
+func (r *CategoryRepository) FindChildren(ctx context.Context, parentID *primitive.ObjectID) ([]models.Category, error) {
+	filter := bson.M{"parent_id": nil}
+	if parentID != nil {
+		filter = bson.M{"parent_id": *parentID}
+	}
+	return r.find(ctx, filter)
+}
+
+// List returns every category, optionally leaving out categories without products
+func (r *CategoryRepository) List(ctx context.Context, includeEmpty bool) ([]models.Category, error) {
+	filter := bson.M{}
+	if !includeEmpty {
+		filter["product_count"] = bson.M{"$gt": 0}
+	}
+	return r.find(ctx, filter)
+}
+
+// Update saves the name and description of a category
+func (r *CategoryRepository) Update(ctx context.Context, category *models.Category) error {
+	category.BeforeUpdate()
+	res, err := r.categories.UpdateOne(ctx,
+		bson.M{"_id": category.ID},
+		bson.M{"$set": bson.M{
+			"name":        category.Name,
+			"description": category.Description,
+			"updated_at":  category.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update category: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// MoveSubtree re-parents a category and rewrites the ancestor arrays of its descendants
+func (r *CategoryRepository) MoveSubtree(ctx context.Context, category *models.Category, parentID *primitive.ObjectID, ancestors []primitive.ObjectID) error {
+	category.BeforeUpdate()
+	_, err := r.categories.UpdateOne(ctx,
+		bson.M{"_id": category.ID},
+		bson.M{"$set": bson.M{"parent_id": parentID, "ancestors": ancestors, "updated_at": category.UpdatedAt}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to move category: %w", err)
+	}
+
+	// Descendants keep the part of their path starting at the moved category
+	_, err = r.categories.UpdateMany(ctx,
+		bson.M{"ancestors": category.ID},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"ancestors": bson.M{"$concatArrays": bson.A{
+				ancestors,
+				bson.M{"$slice": bson.A{
+					"$ancestors",
+					bson.M{"$indexOfArray": bson.A{"$ancestors", category.ID}},
+					bson.M{"$size": "$ancestors"},
+				}},
+			}},
+			"updated_at": category.UpdatedAt,
+		}}}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to move category descendants: %w", err)
+	}
+
+	category.ParentID = parentID
+	category.Ancestors = ancestors
+	return nil
+}
+
+// IncrementProductCount adds delta to the product count of the given categories
+func (r *CategoryRepository) IncrementProductCount(ctx context.Context, ids []primitive.ObjectID, delta int) error {
+	if len(ids) == 0 || delta == 0 {
+		return nil
+	}
+	_, err := r.categories.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		bson.M{"$inc": bson.M{"product_count": delta}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update category product counts: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a category
+func (r *CategoryRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.categories.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// find returns the categories matching the filter ordered by name
+func (r *CategoryRepository) find(ctx context.Context, filter bson.M) ([]models.Category, error) {
+	cursor, err := r.categories.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find categories: %w", err)
+	}
+
+	categories := []models.Category{}
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, fmt.Errorf("failed to decode categories: %w", err)
+	}
+	return categories, nil
+}
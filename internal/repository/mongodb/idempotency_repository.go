@@ -0,0 +1,85 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// idempotencyKeysCollection is the name of the idempotency key collection
+const idempotencyKeysCollection = "idempotency_keys"
+
+// IdempotencyRepository stores the responses of mutations sent with an idempotency key
+type IdempotencyRepository struct {
+	records *mongo.Collection
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository
+func NewIdempotencyRepository(db *mongo.Database) *IdempotencyRepository {
+	return &IdempotencyRepository{records: db.Collection(idempotencyKeysCollection)}
+}
+
+// EnsureIndexes creates the unique key index and the expiry index
+func (r *IdempotencyRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.records.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create idempotency key indexes: %w", err)
+	}
+	return nil
+}
+
+// Create inserts a record, returning models.ErrDuplicate when the user already used the key
+func (r *IdempotencyRepository) Create(ctx context.Context, record *models.IdempotencyRecord) error {
+	record.BeforeCreate()
+	if _, err := r.records.InsertOne(ctx, record); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrDuplicate
+		}
+		return fmt.Errorf("failed to insert idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Find returns the record of a key used by a user. It may have expired without being
+// removed by the TTL index yet.
+func (r *IdempotencyRepository) Find(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	err := r.records.FindOne(ctx, bson.M{"user_id": userID, "key": key}).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find idempotency key: %w", err)
+	}
+	return &record, nil
+}
+
+// Complete stores the response of a pending record
+func (r *IdempotencyRepository) Complete(ctx context.Context, id primitive.ObjectID, response []byte) error {
+	_, err := r.records.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.IdempotencyPending},
+		bson.M{"$set": bson.M{"status": models.IdempotencyCompleted, "response": response, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a record, e.g. when its request failed and may be retried
+func (r *IdempotencyRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if _, err := r.records.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+	return nil
+}
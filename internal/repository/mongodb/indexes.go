@@ -0,0 +1,20 @@
+package mongodb
+
+import (
+	"context"
+)
+
+// Indexer is implemented by repositories that need indexes on their collections
+type Indexer interface {
+	EnsureIndexes(ctx context.Context) error
+}
+
+// EnsureIndexes creates the indexes of every given repository
+func EnsureIndexes(ctx context.Context, indexers ...Indexer) error {
+	for _, indexer := range indexers {
+		if err := indexer.EnsureIndexes(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Names of the inventory collections
+const (
+	inventoryCollection      = "inventory"
+	reservationsCollection   = "stock_reservations"
+	stockMovementsCollection = "stock_movements"
+)
+
+// InventoryRepository stores stock levels, reservations and the stock ledger. Stock
+// levels are only changed with conditional updates so concurrent requests can't
+// reserve or remove more units than are available.
+type InventoryRepository struct {
+	items        *mongo.Collection
+	reservations *mongo.Collection
+	movements    *mongo.Collection
+	cursors      *connection.Codec
+}
+
+// NewInventoryRepository creates a new InventoryRepository
+func NewInventoryRepository(db *mongo.Database, cursors *connection.Codec) *InventoryRepository {
+	return &InventoryRepository{
+		items:        db.Collection(inventoryCollection),
+		reservations: db.Collection(reservationsCollection),
+		movements:    db.Collection(stockMovementsCollection),
+		cursors:      cursors,
+	}
+}
+
+// EnsureIndexes creates the unique SKU index, the reservation lookup indexes and the ledger index
+func (r *InventoryRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.items.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "sku", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "product_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create inventory indexes: %w", err)
+	}
+
+	_, err = r.reservations.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}}},
+		{Keys: bson.D{{Key: "reference", Value: 1}, {Key: "status", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create reservation indexes: %w", err)
+	}
+
+	_, err = r.movements.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "sku", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create stock movement indexes: %w", err)
+	}
+	return nil
+}
+
+// CreateItem inserts the inventory item of a SKU, returning models.ErrDuplicate when it exists
+func (r *InventoryRepository) CreateItem(ctx context.Context, item *models.InventoryItem) error {
+	item.BeforeCreate()
+	if _, err := r.items.InsertOne(ctx, item); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrDuplicate
+		}
+		return fmt.Errorf("failed to insert inventory item: %w", err)
+	}
+	return nil
+}
+
+// FindItemBySKU returns the inventory item of a SKU
+func (r *InventoryRepository) FindItemBySKU(ctx context.Context, sku string) (*models.InventoryItem, error) {
+	var item models.InventoryItem
+	err := r.items.FindOne(ctx, bson.M{"sku": sku}).Decode(&item)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find inventory item: %w", err)
+	}
+	return &item, nil
+}
+
+// FindItemsBySKUs returns the inventory items of the given SKUs, in no particular order
+func (r *InventoryRepository) FindItemsBySKUs(ctx context.Context, skus []string) ([]models.InventoryItem, error) {
+	return findIn[models.InventoryItem](ctx, r.items, "sku", skus, "inventory items")
+}
+
+// SetOnHand sets the on hand quantity of a SKU and returns the item as it was before.
+// It returns models.ErrInsufficientStock when more units are reserved than the new quantity.
+func (r *InventoryRepository) SetOnHand(ctx context.Context, sku string, onHand int) (*models.InventoryItem, error) {
+	var before models.InventoryItem
+	err := r.items.FindOneAndUpdate(ctx,
+		bson.M{"sku": sku, "reserved": bson.M{"$lte": onHand}},
+		bson.M{"$set": bson.M{"on_hand": onHand, "updated_at": time.Now()}},
+	).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, r.stockConflict(ctx, sku)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set on hand quantity: %w", err)
+	}
+	return &before, nil
+}
+
+// AddOnHand adds quantity units to the on hand quantity of a SKU, e.g. when sold units are returned
+func (r *InventoryRepository) AddOnHand(ctx context.Context, sku string, quantity int) error {
+	res, err := r.items.UpdateOne(ctx,
+		bson.M{"sku": sku},
+		bson.M{
+			"$inc": bson.M{"on_hand": quantity},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add stock: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// Reserve holds quantity units of a SKU, returning models.ErrInsufficientStock when
+// fewer units are available
+func (r *InventoryRepository) Reserve(ctx context.Context, sku string, quantity int) error {
+	res, err := r.items.UpdateOne(ctx,
+		bson.M{
+			"sku":   sku,
+			"$expr": bson.M{"$gte": bson.A{bson.M{"$subtract": bson.A{"$on_hand", "$reserved"}}, quantity}},
+		},
+		bson.M{
+			"$inc": bson.M{"reserved": quantity},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reserve stock: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return r.stockConflict(ctx, sku)
+	}
+	return nil
+}
+
+// Release returns quantity reserved units of a SKU to the available stock
+func (r *InventoryRepository) Release(ctx context.Context, sku string, quantity int) error {
+	return r.consume(ctx, sku, quantity, 0)
+}
+
+// Commit removes quantity reserved units of a SKU from stock once they are sold
+func (r *InventoryRepository) Commit(ctx context.Context, sku string, quantity int) error {
+	return r.consume(ctx, sku, quantity, quantity)
+}
+
+// consume decrements the reserved quantity and the on hand quantity of a SKU
+func (r *InventoryRepository) consume(ctx context.Context, sku string, reserved, onHand int) error {
+	res, err := r.items.UpdateOne(ctx,
+		bson.M{"sku": sku, "reserved": bson.M{"$gte": reserved}, "on_hand": bson.M{"$gte": onHand}},
+		bson.M{
+			"$inc": bson.M{"reserved": -reserved, "on_hand": -onHand},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update reserved stock: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return r.stockConflict(ctx, sku)
+	}
+	return nil
+}
+
+// RenameSKU moves the inventory item and the active reservations of a SKU to a new SKU
+func (r *InventoryRepository) RenameSKU(ctx context.Context, oldSKU, newSKU string) error {
+	if _, err := r.items.UpdateOne(ctx,
+		bson.M{"sku": oldSKU},
+		bson.M{"$set": bson.M{"sku": newSKU, "updated_at": time.Now()}},
+	); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrDuplicate
+		}
+		return fmt.Errorf("failed to rename inventory item: %w", err)
+	}
+
+	if _, err := r.reservations.UpdateMany(ctx,
+		bson.M{"sku": oldSKU, "status": models.ReservationActive},
+		bson.M{"$set": bson.M{"sku": newSKU}},
+	); err != nil {
+		return fmt.Errorf("failed to rename reservations: %w", err)
+	}
+	return nil
+}
+
+// DeleteItem removes the inventory item of a SKU, keeping its ledger
+func (r *InventoryRepository) DeleteItem(ctx context.Context, sku string) error {
+	if _, err := r.items.DeleteOne(ctx, bson.M{"sku": sku}); err != nil {
+		return fmt.Errorf("failed to delete inventory item: %w", err)
+	}
+	return nil
+}
+
+// CreateReservation inserts a new reservation
+func (r *InventoryRepository) CreateReservation(ctx context.Context, reservation *models.Reservation) error {
+	reservation.BeforeCreate()
+	if _, err := r.reservations.InsertOne(ctx, reservation); err != nil {
+		return fmt.Errorf("failed to insert reservation: %w", err)
+	}
+	return nil
+}
+
+// FindActiveReservations returns the active reservations made for a reference
+func (r *InventoryRepository) FindActiveReservations(ctx context.Context, reference string) ([]models.Reservation, error) {
+	return r.findReservations(ctx, bson.M{"reference": reference, "status": models.ReservationActive}, 0)
+}
+
+// FindExpiredReservations returns up to limit active reservations that expired before now
+func (r *InventoryRepository) FindExpiredReservations(ctx context.Context, now time.Time, limit int) ([]models.Reservation, error) {
+	return r.findReservations(ctx, bson.M{"status": models.ReservationActive, "expires_at": bson.M{"$lte": now}}, limit)
+}
+
+// ExtendReservations moves the expiry of the active reservations of a reference to expiresAt
+func (r *InventoryRepository) ExtendReservations(ctx context.Context, reference string, expiresAt time.Time) error {
+	if _, err := r.reservations.UpdateMany(ctx,
+		bson.M{"reference": reference, "status": models.ReservationActive},
+		bson.M{"$set": bson.M{"expires_at": expiresAt, "updated_at": time.Now()}},
+	); err != nil {
+		return fmt.Errorf("failed to extend reservations: %w", err)
+	}
+	return nil
+}
+
+// TransitionReservation moves an active reservation to another status. It reports
+// false when the reservation is no longer active, so each reservation is released,
+// expired or committed exactly once.
+func (r *InventoryRepository) TransitionReservation(ctx context.Context, id primitive.ObjectID, status models.ReservationStatus) (bool, error) {
+	res, err := r.reservations.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.ReservationActive},
+		bson.M{"$set": bson.M{"status": status, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update reservation: %w", err)
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// RecordMovement appends an entry to the stock ledger
+func (r *InventoryRepository) RecordMovement(ctx context.Context, movement *models.StockMovement) error {
+	if movement.ID.IsZero() {
+		movement.ID = primitive.NewObjectID()
+	}
+	if movement.CreatedAt.IsZero() {
+		movement.CreatedAt = time.Now()
+	}
+	if _, err := r.movements.InsertOne(ctx, movement); err != nil {
+		return fmt.Errorf("failed to record stock movement: %w", err)
+	}
+	return nil
+}
+
+// ListMovements returns a page of the stock ledger of a SKU, newest first
+func (r *InventoryRepository) ListMovements(ctx context.Context, sku string, args connection.Args) (*models.StockMovementConnection, error) {
+	return findPage[models.StockMovement](ctx, r.movements, r.cursors, PageQuery{
+		Filter:     bson.M{"sku": sku},
+		SortField:  "created_at",
+		Descending: true,
+		Args:       args,
+	})
+}
+
+// findReservations returns the reservations matching the filter, oldest first
+func (r *InventoryRepository) findReservations(ctx context.Context, filter bson.M, limit int) ([]models.Reservation, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}, {Key: "_id", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := r.reservations.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reservations: %w", err)
+	}
+
+	reservations := []models.Reservation{}
+	if err := cursor.All(ctx, &reservations); err != nil {
+		return nil, fmt.Errorf("failed to decode reservations: %w", err)
+	}
+	return reservations, nil
+}
+
+// stockConflict explains why a conditional stock update matched nothing
+func (r *InventoryRepository) stockConflict(ctx context.Context, sku string) error {
+	count, err := r.items.CountDocuments(ctx, bson.M{"sku": sku})
+	if err != nil {
+		return fmt.Errorf("failed to find inventory item: %w", err)
+	}
+	if count == 0 {
+		return models.ErrNotFound
+	}
+	return models.ErrInsufficientStock
+}
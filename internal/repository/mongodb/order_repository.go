@@ -0,0 +1,175 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ordersCollection is the name of the orders collection
+const ordersCollection = "orders"
+
+// OrderRepository stores orders
+type OrderRepository struct {
+	orders  *mongo.Collection
+	cursors *connection.Codec
+}
+
+// NewOrderRepository creates a new OrderRepository
+func NewOrderRepository(db *mongo.Database, cursors *connection.Codec) *OrderRepository {
+	return &OrderRepository{
+		orders:  db.Collection(ordersCollection),
+		cursors: cursors,
+	}
+}
+
+// EnsureIndexes creates the listing indexes
+func (r *OrderRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.orders.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "checkout_key", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"checkout_key": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create order indexes: %w", err)
+	}
+	return nil
+}
+
+// Create inserts a new order, returning models.ErrDuplicate when an order was
+// already placed with the same checkout key
+func (r *OrderRepository) Create(ctx context.Context, order *models.Order) error {
+	order.BeforeCreate()
+	if _, err := r.orders.InsertOne(ctx, order); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrDuplicate
+		}
+		return fmt.Errorf("failed to insert order: %w", err)
+	}
+	return nil
+}
+
+// FindByCheckoutKey returns the order placed with the given checkout key
+func (r *OrderRepository) FindByCheckoutKey(ctx context.Context, key string) (*models.Order, error) {
+	var order models.Order
+	err := r.orders.FindOne(ctx, bson.M{"checkout_key": key}).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find order: %w", err)
+	}
+	return &order, nil
+}
+
+// SavePayment stores the payment of an order if the stored payment is still in the
+// from status, an empty from meaning the order has no payment yet. It returns
+// models.ErrConflict when the payment was changed concurrently.
+func (r *OrderRepository) SavePayment(ctx context.Context, id primitive.ObjectID, payment *models.PaymentInfo, from models.PaymentStatus) error {
+	filter := bson.M{"_id": id, "payment.status": from}
+	if from == "" {
+		filter = bson.M{"_id": id, "payment": bson.M{"$exists": false}}
+	}
+	res, err := r.orders.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"payment": payment, "updated_at": payment.UpdatedAt}})
+	if err != nil {
+		return fmt.Errorf("failed to save payment: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return models.ErrConflict
+	}
+	return nil
+}
+
+// Delete removes an order. It is only used to undo a checkout that failed without a transaction.
+func (r *OrderRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if _, err := r.orders.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return fmt.Errorf("failed to delete order: %w", err)
+	}
+	return nil
+}
+
+// FindByID returns the order with the given ID
+func (r *OrderRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Order, error) {
+	var order models.Order
+	err := r.orders.FindOne(ctx, bson.M{"_id": id}).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find order: %w", err)
+	}
+	return &order, nil
+}
+
+// FindByIDs returns the orders with the given IDs, in no particular order
+func (r *OrderRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Order, error) {
+	return findIn[models.Order](ctx, r.orders, "_id", ids, "orders")
+}
+
+// List returns a page of the orders matching the filter, newest first
+func (r *OrderRepository) List(ctx context.Context, filter models.OrderFilter, args connection.Args) (*models.OrderConnection, error) {
+	query := bson.M{}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Status != nil {
+		query["status"] = *filter.Status
+	}
+	return findPage[models.Order](ctx, r.orders, r.cursors, PageQuery{
+		Filter:     query,
+		SortField:  "created_at",
+		Descending: true,
+		Args:       args,
+	})
+}
+
+// HasDeliveredProduct reports whether an order of the user containing the product
+// was delivered, including delivered orders that were refunded afterwards
+func (r *OrderRepository) HasDeliveredProduct(ctx context.Context, userID string, productID primitive.ObjectID) (bool, error) {
+	count, err := r.orders.CountDocuments(ctx, bson.M{
+		"user_id":           userID,
+		"items.product_id":  productID,
+		"status_history.to": models.OrderDelivered,
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to find delivered orders: %w", err)
+	}
+	return count > 0, nil
+}
+
+// SaveTransition persists a status change applied with Order.Transition. The update
+// only matches while the stored order still has the previous status, so concurrent
+// transitions can't both succeed; the loser gets models.ErrConflict.
+func (r *OrderRepository) SaveTransition(ctx context.Context, order *models.Order, change models.OrderStatusChange) error {
+	set := bson.M{"status": order.Status, "updated_at": order.UpdatedAt}
+	if order.PaidAt != nil {
+		set["paid_at"] = order.PaidAt
+	}
+	if order.CancellationReason != "" {
+		set["cancellation_reason"] = order.CancellationReason
+	}
+
+	res, err := r.orders.UpdateOne(ctx,
+		bson.M{"_id": order.ID, "status": *change.From},
+		bson.M{"$set": set, "$push": bson.M{"status_history": change}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return models.ErrConflict
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PageQuery describes a paginated listing of a collection
+type PageQuery struct {
+	// Filter selects the documents of the connection
+	Filter bson.M
+	// SortField is the sort key; _id is always used as the tie breaker
+	SortField string
+	// Descending sorts from the largest to the smallest key
+	Descending bool
+	// Args are the Relay pagination arguments
+	Args connection.Args
+}
+
+// findPage runs a keyset paginated query over (sort key, _id) and returns a connection.
+// Keyset pagination keeps pages stable while documents are inserted, and the _id
+// tie breaker gives a total order on non-unique sort keys.
+func findPage[T any](ctx context.Context, coll *mongo.Collection, codec *connection.Codec, q PageQuery) (*connection.Connection[T], error) {
+	if err := q.Args.Validate(); err != nil {
+		return nil, err
+	}
+
+	backward := q.Args.Backward()
+	limit := q.Args.Limit()
+
+	// Pages requested with last/before are read in reverse order and flipped afterwards
+	descending := q.Descending != backward
+	direction := 1
+	if descending {
+		direction = -1
+	}
+
+	filter := q.Filter
+	if filter == nil {
+		filter = bson.M{}
+	}
+	pageFilter := filter
+
+	var position *string
+	if backward {
+		position = q.Args.Before
+	} else {
+		position = q.Args.After
+	}
+	if position != nil {
+		cursor, err := codec.Decode(*position, q.SortField)
+		if err != nil {
+			return nil, err
+		}
+		pageFilter = bson.M{"$and": bson.A{filter, keysetFilter(q.SortField, cursor, descending)}}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: q.SortField, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(limit) + 1)
+
+	result, err := coll.Find(ctx, pageFilter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query page: %w", err)
+	}
+	defer result.Close(ctx)
+
+	edges := []connection.Edge[T]{}
+	for result.Next(ctx) && len(edges) <= limit {
+		var node T
+		if err := result.Decode(&node); err != nil {
+			return nil, fmt.Errorf("failed to decode page: %w", err)
+		}
+		cursor, err := encodeCursor(codec, q.SortField, result.Current)
+		if err != nil {
+			return nil, err
+		}
+		edges = append(edges, connection.Edge[T]{Node: node, Cursor: cursor})
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read page: %w", err)
+	}
+
+	hasMore := len(edges) > limit
+	if hasMore {
+		edges = edges[:limit]
+	}
+
+	page := &connection.Connection[T]{
+		Edges: edges,
+		Count: func(ctx context.Context) (int64, error) {
+			return coll.CountDocuments(ctx, filter)
+		},
+	}
+
+	if backward {
+		for i, j := 0, len(edges)-1; i < j; i, j = i+1, j-1 {
+			edges[i], edges[j] = edges[j], edges[i]
+		}
+		page.PageInfo.HasPreviousPage = hasMore
+		page.PageInfo.HasNextPage = position != nil
+	} else {
+		page.PageInfo.HasNextPage = hasMore
+		page.PageInfo.HasPreviousPage = position != nil
+	}
+
+	if len(edges) > 0 {
+		page.PageInfo.StartCursor = &edges[0].Cursor
+		page.PageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+	return page, nil
+}
+
+// keysetFilter selects the documents after the cursor in the given sort direction
+func keysetFilter(sortField string, cursor *connection.Cursor, descending bool) bson.M {
+	op := "$gt"
+	if descending {
+		op = "$lt"
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{sortField: bson.M{op: cursor.Value}},
+		bson.M{sortField: cursor.Value, "_id": bson.M{op: cursor.ID}},
+	}}
+}
+
+// encodeCursor builds the cursor of a raw document from its sort key and _id
+func encodeCursor(codec *connection.Codec, sortField string, doc bson.Raw) (string, error) {
+	id, ok := doc.Lookup("_id").ObjectIDOK()
+	if !ok {
+		return "", fmt.Errorf("document has no ObjectID")
+	}
+	return codec.Encode(connection.Cursor{
+		Sort:  sortField,
+		Value: doc.Lookup(strings.Split(sortField, ".")...),
+		ID:    id,
+	})
+}
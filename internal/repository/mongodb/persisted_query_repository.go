@@ -0,0 +1,114 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// persistedQueriesCollection is the name of the persisted query collection
+const persistedQueriesCollection = "persisted_queries"
+
+// PersistedQueryRepository stores query documents by the hash of their text
+type PersistedQueryRepository struct {
+	queries *mongo.Collection
+}
+
+// NewPersistedQueryRepository creates a new PersistedQueryRepository
+func NewPersistedQueryRepository(db *mongo.Database) *PersistedQueryRepository {
+	return &PersistedQueryRepository{queries: db.Collection(persistedQueriesCollection)}
+}
+
+// EnsureIndexes creates the index listing registered queries and the expiry index
+// removing unused queries persisted by clients
+func (r *PersistedQueryRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.queries.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "registered", Value: 1}, {Key: "operation_name", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create persisted query indexes: %w", err)
+	}
+	return nil
+}
+
+// Find returns the query stored under a hash
+func (r *PersistedQueryRepository) Find(ctx context.Context, hash string) (*models.PersistedQuery, error) {
+	var query models.PersistedQuery
+	err := r.queries.FindOne(ctx, bson.M{"_id": hash}).Decode(&query)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find persisted query: %w", err)
+	}
+	return &query, nil
+}
+
+// Create stores a query persisted by a client. An existing unregistered query with
+// the same hash gets the new expiry, a registered one is left untouched.
+func (r *PersistedQueryRepository) Create(ctx context.Context, query *models.PersistedQuery) error {
+	now := time.Now()
+	query.CreatedAt, query.UpdatedAt = now, now
+	// A registered query doesn't match and the upsert fails on its _id
+	_, err := r.queries.UpdateOne(ctx,
+		bson.M{"_id": query.Hash, "registered": bson.M{"$ne": true}},
+		bson.M{
+			"$set": bson.M{"expires_at": query.ExpiresAt, "updated_at": now},
+			"$setOnInsert": bson.M{
+				"query":      query.Query,
+				"registered": false,
+				"created_at": now,
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to persist query: %w", err)
+	}
+	return nil
+}
+
+// Extend pushes back the expiry of a query persisted by a client
+func (r *PersistedQueryRepository) Extend(ctx context.Context, hash string, expiresAt time.Time) error {
+	_, err := r.queries.UpdateOne(ctx,
+		bson.M{"_id": hash, "registered": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"expires_at": expiresAt, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to extend persisted query: %w", err)
+	}
+	return nil
+}
+
+// Register stores a query as registered, creating it or marking an existing one
+// so that it no longer expires
+func (r *PersistedQueryRepository) Register(ctx context.Context, query *models.PersistedQuery) error {
+	now := time.Now()
+	query.Registered = true
+	query.UpdatedAt = now
+	_, err := r.queries.UpdateOne(ctx,
+		bson.M{"_id": query.Hash},
+		bson.M{
+			"$set": bson.M{
+				"query":          query.Query,
+				"operation_name": query.OperationName,
+				"registered":     true,
+				"updated_at":     now,
+			},
+			"$unset":       bson.M{"expires_at": ""},
+			"$setOnInsert": bson.M{"created_at": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register query: %w", err)
+	}
+	return nil
+}
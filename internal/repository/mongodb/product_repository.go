@@ -0,0 +1,203 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// productsCollection is the name of the products collection
+const productsCollection = "products"
+
+// ProductRepository stores the product catalog
+type ProductRepository struct {
+	products *mongo.Collection
+	cursors  *connection.Codec
+}
+
+// NewProductRepository creates a new ProductRepository
+func NewProductRepository(db *mongo.Database, cursors *connection.Codec) *ProductRepository {
+	return &ProductRepository{
+		products: db.Collection(productsCollection),
+		cursors:  cursors,
+	}
+}
+
+// EnsureIndexes creates the unique SKU index and the listing indexes
+func (r *ProductRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.products.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "sku", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "category_id", Value: 1}, {Key: "price", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "price", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create product indexes: %w", err)
+	}
+	return nil
+}
+
+// Create inserts a new product, returning models.ErrDuplicate when the SKU is taken
+func (r *ProductRepository) Create(ctx context.Context, product *models.Product) error {
+	product.BeforeCreate()
+	if _, err := r.products.InsertOne(ctx, product); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrDuplicate
+		}
+		return fmt.Errorf("failed to insert product: %w", err)
+	}
+	return nil
+}
+
+// FindByID returns the product with the given ID
+func (r *ProductRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error) {
+	var product models.Product
+	err := r.products.FindOne(ctx, bson.M{"_id": id}).Decode(&product)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find product: %w", err)
+	}
+	return &product, nil
+}
+
+// FindByIDs returns the products with the given IDs in no particular order, skipping unknown IDs
+func (r *ProductRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Product, error) {
+	return findIn[models.Product](ctx, r.products, "_id", ids, "products")
+}
+
+// List returns a page of the products matching the filter
+func (r *ProductRepository) List(ctx context.Context, filter models.ProductFilter, sort models.ProductSort, args connection.Args) (*models.ProductConnection, error) {
+	return findPage[models.Product](ctx, r.products, r.cursors, PageQuery{
+		Filter:     productFilterQuery(filter),
+		SortField:  sort.Field,
+		Descending: sort.Descending,
+		Args:       args,
+	})
+}
+
+// Update saves the catalog fields of a product, returning models.ErrDuplicate when
+// the new SKU is taken. The stock is left alone, it is only changed through IncrementStock.
+func (r *ProductRepository) Update(ctx context.Context, product *models.Product) error {
+	product.BeforeUpdate()
+	res, err := r.products.UpdateOne(ctx,
+		bson.M{"_id": product.ID},
+		bson.M{"$set": bson.M{
+			"name":        product.Name,
+			"description": product.Description,
+			"price":       product.Price,
+			"sku":         product.SKU,
+			"brand":       product.Brand,
+			"images":      product.Images,
+			"category_id": product.CategoryID,
+			"updated_at":  product.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrDuplicate
+		}
+		return fmt.Errorf("failed to update product: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a product
+func (r *ProductRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.products.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete product: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// IncrementStock adds delta to the available stock of the product with the given SKU
+func (r *ProductRepository) IncrementStock(ctx context.Context, sku string, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+	if _, err := r.products.UpdateOne(ctx,
+		bson.M{"sku": sku},
+		bson.M{"$inc": bson.M{"stock": delta}},
+	); err != nil {
+		return fmt.Errorf("failed to update product stock: %w", err)
+	}
+	return nil
+}
+
+// AdjustRating adds delta reviews with the given rating to the rating summary of a product
+func (r *ProductRepository) AdjustRating(ctx context.Context, id primitive.ObjectID, rating, delta int) error {
+	res, err := r.products.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$inc": bson.M{
+			"rating.count": delta,
+			"rating.sum":   rating * delta,
+			"rating.histogram." + strconv.Itoa(rating): delta,
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update product rating: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// ReassignCategory moves every product of a category to another category and
+// returns the number of moved products
+func (r *ProductRepository) ReassignCategory(ctx context.Context, from, to primitive.ObjectID) (int, error) {
+	res, err := r.products.UpdateMany(ctx,
+		bson.M{"category_id": from},
+		bson.M{"$set": bson.M{"category_id": to, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reassign products: %w", err)
+	}
+	return int(res.ModifiedCount), nil
+}
+
+// productFilterQuery builds the MongoDB query for a product filter
+func productFilterQuery(filter models.ProductFilter) bson.M {
+	query := bson.M{}
+	if filter.CategoryID != nil {
+		query["category_id"] = *filter.CategoryID
+	}
+
+	price := bson.M{}
+	if filter.MinPrice != nil {
+		price["$gte"] = *filter.MinPrice
+	}
+	if filter.MaxPrice != nil {
+		price["$lte"] = *filter.MaxPrice
+	}
+	if len(price) > 0 {
+		query["price"] = price
+	}
+
+	if filter.InStock != nil {
+		if *filter.InStock {
+			query["stock"] = bson.M{"$gt": 0}
+		} else {
+			query["stock"] = bson.M{"$lte": 0}
+		}
+	}
+	return query
+}
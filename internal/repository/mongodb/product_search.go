@@ -0,0 +1,273 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/textsearch"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// productTextIndex is the name of the weighted text index of the products
+const productTextIndex = "product_text_search"
+
+// minFuzzySimilarity is the share of the query trigrams a product must contain to
+// be returned by the typo tolerant fallback
+const minFuzzySimilarity = 0.5
+
+// maxBrandOptions caps the number of values of the brand facet
+const maxBrandOptions = 20
+
+// priceBuckets are the lower bounds of the price facet buckets; prices from the
+// last bound up fall in an open ended bucket
+var priceBuckets = []float64{0, 25, 50, 100, 250, 500}
+
+// ProductSearch searches the product collection with a weighted text index. When no
+// product matches the words exactly, products sharing enough trigrams of their
+// name and brand with the query are returned instead, which tolerates typos.
+type ProductSearch struct {
+	products *mongo.Collection
+}
+
+// NewProductSearch creates a new ProductSearch
+func NewProductSearch(db *mongo.Database) *ProductSearch {
+	return &ProductSearch{products: db.Collection(productsCollection)}
+}
+
+// EnsureIndexes creates the text and trigram indexes and computes the trigrams of
+// products stored before search was available
+func (s *ProductSearch) EnsureIndexes(ctx context.Context) error {
+	_, err := s.products.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "brand", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().SetName(productTextIndex).
+				SetWeights(bson.D{{Key: "name", Value: 10}, {Key: "brand", Value: 5}, {Key: "description", Value: 1}}),
+		},
+		{Keys: bson.D{{Key: "search_grams", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create product search indexes: %w", err)
+	}
+
+	cursor, err := s.products.Find(ctx, bson.M{"search_grams": bson.M{"$exists": false}},
+		options.Find().SetProjection(bson.M{"name": 1, "brand": 1}))
+	if err != nil {
+		return fmt.Errorf("failed to find unindexed products: %w", err)
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var product models.Product
+		if err := cursor.Decode(&product); err != nil {
+			return fmt.Errorf("failed to decode product: %w", err)
+		}
+		if err := s.IndexProduct(ctx, &product); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// IndexProduct stores the trigrams of the name and brand of a product
+func (s *ProductSearch) IndexProduct(ctx context.Context, product *models.Product) error {
+	_, err := s.products.UpdateOne(ctx,
+		bson.M{"_id": product.ID},
+		bson.M{"$set": bson.M{"search_grams": productGrams(product)}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to index product: %w", err)
+	}
+	return nil
+}
+
+// RemoveProduct does nothing, the search data is removed with the product
+func (s *ProductSearch) RemoveProduct(ctx context.Context, id primitive.ObjectID) error {
+	return nil
+}
+
+// Search returns a page of the products matching a query with the facet counts of
+// every match. Text matches are ranked by their text score, fuzzy matches by the
+// share of query trigrams they contain.
+func (s *ProductSearch) Search(ctx context.Context, query models.SearchQuery) (*models.SearchResult, error) {
+	filter := productFilterQuery(models.ProductFilter{
+		CategoryID: query.Filter.CategoryID,
+		MinPrice:   query.Filter.MinPrice,
+		MaxPrice:   query.Filter.MaxPrice,
+		InStock:    query.Filter.InStock,
+	})
+	if query.Filter.Brand != nil {
+		filter["brand"] = *query.Filter.Brand
+	}
+
+	if query.Text == "" {
+		return s.run(ctx, query, bson.A{bson.M{"$match": filter}}, bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	}
+
+	byScore := bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}
+	textFilter := bson.M{"$text": bson.M{"$search": query.Text}}
+	for k, v := range filter {
+		textFilter[k] = v
+	}
+	result, err := s.run(ctx, query, bson.A{
+		bson.M{"$match": textFilter},
+		bson.M{"$addFields": bson.M{"score": bson.M{"$meta": "textScore"}}},
+	}, byScore)
+	if err != nil || result.TotalCount > 0 {
+		return result, err
+	}
+
+	grams := textsearch.Trigrams(query.Text)
+	if len(grams) == 0 {
+		return result, nil
+	}
+	fuzzyFilter := bson.M{"search_grams": bson.M{"$in": grams}}
+	for k, v := range filter {
+		fuzzyFilter[k] = v
+	}
+	result, err = s.run(ctx, query, bson.A{
+		bson.M{"$match": fuzzyFilter},
+		bson.M{"$addFields": bson.M{"score": bson.M{"$divide": bson.A{
+			bson.M{"$size": bson.M{"$setIntersection": bson.A{"$search_grams", grams}}},
+			len(grams),
+		}}}},
+		bson.M{"$match": bson.M{"score": bson.M{"$gte": minFuzzySimilarity}}},
+	}, byScore)
+	if err != nil {
+		return nil, err
+	}
+	result.Fuzzy = true
+	return result, nil
+}
+
+// facetBucket is a value of a facet with its count
+type facetBucket struct {
+	ID    interface{} `bson:"_id"`
+	Count int         `bson:"count"`
+}
+
+// searchFacets is the output of the $facet stage of a search
+type searchFacets struct {
+	Results []models.Product `bson:"results"`
+	Total   []struct {
+		Count int `bson:"count"`
+	} `bson:"total"`
+	Category []facetBucket `bson:"category"`
+	Price    []facetBucket `bson:"price"`
+	Brand    []facetBucket `bson:"brand"`
+	InStock  []facetBucket `bson:"in_stock"`
+}
+
+// run appends the page and facet stages to the matching stages and decodes the result
+func (s *ProductSearch) run(ctx context.Context, query models.SearchQuery, match bson.A, sort bson.D) (*models.SearchResult, error) {
+	count := bson.M{"$sum": 1}
+	byCount := bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}
+	pipeline := append(match, bson.M{"$facet": bson.M{
+		"results": bson.A{
+			bson.M{"$sort": sort},
+			bson.M{"$skip": query.Offset},
+			bson.M{"$limit": query.Limit},
+			bson.M{"$project": bson.M{"search_grams": 0}},
+		},
+		"total": bson.A{bson.M{"$count": "count"}},
+		"category": bson.A{
+			bson.M{"$match": bson.M{"category_id": bson.M{"$ne": nil}}},
+			bson.M{"$group": bson.M{"_id": "$category_id", "count": count}},
+			byCount,
+		},
+		"price": bson.A{
+			bson.M{"$bucket": bson.M{
+				"groupBy":    "$price",
+				"boundaries": priceBuckets,
+				"default":    priceBuckets[len(priceBuckets)-1],
+				"output":     bson.M{"count": count},
+			}},
+		},
+		"brand": bson.A{
+			bson.M{"$match": bson.M{"brand": bson.M{"$nin": bson.A{nil, ""}}}},
+			bson.M{"$group": bson.M{"_id": "$brand", "count": count}},
+			byCount,
+			bson.M{"$limit": maxBrandOptions},
+		},
+		"in_stock": bson.A{
+			bson.M{"$group": bson.M{"_id": bson.M{"$gt": bson.A{"$stock", 0}}, "count": count}},
+			bson.M{"$sort": bson.M{"_id": -1}},
+		},
+	}})
+
+	cursor, err := s.products.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+	var out []searchFacets
+	if err := cursor.All(ctx, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode search results: %w", err)
+	}
+
+	result := &models.SearchResult{Products: []models.Product{}, Facets: []models.SearchFacet{}}
+	if len(out) == 0 {
+		return result, nil
+	}
+	facets := out[0]
+	result.Products = append(result.Products, facets.Results...)
+	if len(facets.Total) > 0 {
+		result.TotalCount = facets.Total[0].Count
+	}
+	result.Facets = []models.SearchFacet{
+		toSearchFacet(models.SearchFacetCategory, facets.Category),
+		toSearchFacet(models.SearchFacetPrice, facets.Price),
+		toSearchFacet(models.SearchFacetBrand, facets.Brand),
+		toSearchFacet(models.SearchFacetInStock, facets.InStock),
+	}
+	return result, nil
+}
+
+// toSearchFacet converts facet buckets, labelling price buckets with their range
+func toSearchFacet(name string, buckets []facetBucket) models.SearchFacet {
+	facet := models.SearchFacet{Name: name, Options: make([]models.SearchFacetOption, 0, len(buckets))}
+	for _, bucket := range buckets {
+		var value string
+		switch v := bucket.ID.(type) {
+		case primitive.ObjectID:
+			value = v.Hex()
+		case bool:
+			value = strconv.FormatBool(v)
+		case string:
+			value = v
+		default:
+			value = fmt.Sprint(v)
+		}
+		if name == models.SearchFacetPrice {
+			value = priceBucketLabel(bucket.ID)
+		}
+		facet.Options = append(facet.Options, models.SearchFacetOption{Value: value, Count: bucket.Count})
+	}
+	return facet
+}
+
+// priceBucketLabel returns the "min-max" label of the price bucket with the given lower bound
+func priceBucketLabel(id interface{}) string {
+	var lower float64
+	switch v := id.(type) {
+	case float64:
+		lower = v
+	case int32:
+		lower = float64(v)
+	case int64:
+		lower = float64(v)
+	}
+	for i, bound := range priceBuckets {
+		if bound == lower && i+1 < len(priceBuckets) {
+			return fmt.Sprintf("%g-%g", bound, priceBuckets[i+1])
+		}
+	}
+	return fmt.Sprintf("%g+", lower)
+}
+
+// productGrams returns the trigrams indexed for typo tolerant search
+func productGrams(product *models.Product) []string {
+	return textsearch.Trigrams(product.Name + " " + product.Brand)
+}
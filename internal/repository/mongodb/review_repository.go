@@ -0,0 +1,142 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// reviewsCollection is the name of the reviews collection
+const reviewsCollection = "reviews"
+
+// ReviewRepository stores product reviews
+type ReviewRepository struct {
+	reviews *mongo.Collection
+	cursors *connection.Codec
+}
+
+// NewReviewRepository creates a new ReviewRepository
+func NewReviewRepository(db *mongo.Database, cursors *connection.Codec) *ReviewRepository {
+	return &ReviewRepository{
+		reviews: db.Collection(reviewsCollection),
+		cursors: cursors,
+	}
+}
+
+// EnsureIndexes creates the one review per user and product index and the listing indexes
+func (r *ReviewRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.reviews.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create review indexes: %w", err)
+	}
+	return nil
+}
+
+// Create inserts a new review, returning models.ErrDuplicate when the user already reviewed the product
+func (r *ReviewRepository) Create(ctx context.Context, review *models.Review) error {
+	review.BeforeCreate()
+	if _, err := r.reviews.InsertOne(ctx, review); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrDuplicate
+		}
+		return fmt.Errorf("failed to insert review: %w", err)
+	}
+	return nil
+}
+
+// FindByID returns the review with the given ID
+func (r *ReviewRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Review, error) {
+	var review models.Review
+	err := r.reviews.FindOne(ctx, bson.M{"_id": id}).Decode(&review)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find review: %w", err)
+	}
+	return &review, nil
+}
+
+// FindByIDs returns the reviews with the given IDs, in no particular order
+func (r *ReviewRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Review, error) {
+	return findIn[models.Review](ctx, r.reviews, "_id", ids, "reviews")
+}
+
+// List returns a page of the reviews matching the filter, newest first
+func (r *ReviewRepository) List(ctx context.Context, filter models.ReviewFilter, args connection.Args) (*models.ReviewConnection, error) {
+	query := bson.M{}
+	if filter.ProductID != nil {
+		query["product_id"] = *filter.ProductID
+	}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Status != nil {
+		query["status"] = *filter.Status
+	}
+	return findPage[models.Review](ctx, r.reviews, r.cursors, PageQuery{
+		Filter:     query,
+		SortField:  "created_at",
+		Descending: true,
+		Args:       args,
+	})
+}
+
+// Save replaces a review if it wasn't changed since it was loaded, returning
+// models.ErrConflict otherwise
+func (r *ReviewRepository) Save(ctx context.Context, review *models.Review) error {
+	review.BeforeUpdate()
+	version := review.Version
+	review.Version++
+
+	res, err := r.reviews.ReplaceOne(ctx, bson.M{"_id": review.ID, "version": version}, review)
+	if err != nil {
+		review.Version = version
+		return fmt.Errorf("failed to save review: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		review.Version = version
+		return models.ErrConflict
+	}
+	return nil
+}
+
+// Delete removes a review if it wasn't changed since it was loaded, returning
+// models.ErrConflict otherwise
+func (r *ReviewRepository) Delete(ctx context.Context, review *models.Review) error {
+	res, err := r.reviews.DeleteOne(ctx, bson.M{"_id": review.ID, "version": review.Version})
+	if err != nil {
+		return fmt.Errorf("failed to delete review: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return models.ErrConflict
+	}
+	return nil
+}
+
+// MarkVerified flags the reviews of a user for any of the products as verified purchases
+func (r *ReviewRepository) MarkVerified(ctx context.Context, userID string, productIDs []primitive.ObjectID) error {
+	if _, err := r.reviews.UpdateMany(ctx,
+		bson.M{"user_id": userID, "product_id": bson.M{"$in": productIDs}, "verified_purchase": false},
+		bson.M{
+			"$set": bson.M{"verified_purchase": true, "updated_at": time.Now()},
+			"$inc": bson.M{"version": 1},
+		},
+	); err != nil {
+		return fmt.Errorf("failed to mark verified reviews: %w", err)
+	}
+	return nil
+}
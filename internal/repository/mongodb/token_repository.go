@@ -0,0 +1,130 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection names used by the token repository
+const (
+	refreshTokensCollection = "refresh_tokens"
+	revokedTokensCollection = "revoked_tokens"
+)
+
+// TokenRepository stores refresh tokens and the token revocation list
+type TokenRepository struct {
+	refreshTokens *mongo.Collection
+	revokedTokens *mongo.Collection
+}
+
+// NewTokenRepository creates a new TokenRepository
+func NewTokenRepository(db *mongo.Database) *TokenRepository {
+	return &TokenRepository{
+		refreshTokens: db.Collection(refreshTokensCollection),
+		revokedTokens: db.Collection(revokedTokensCollection),
+	}
+}
+
+// EnsureIndexes creates the token lookup and expiry indexes
+func (r *TokenRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.refreshTokens.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token indexes: %w", err)
+	}
+
+	_, err = r.revokedTokens.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create revoked token indexes: %w", err)
+	}
+	return nil
+}
+
+// CreateRefreshToken inserts a new refresh token
+func (r *TokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	token.BeforeCreate()
+	if _, err := r.refreshTokens.InsertOne(ctx, token); err != nil {
+		return fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+	return nil
+}
+
+// FindRefreshTokenByHash returns the refresh token with the given hash
+func (r *TokenRepository) FindRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.refreshTokens.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find refresh token: %w", err)
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed atomically marks an active refresh token as exchanged for its replacement.
+// It returns false when the token was already used or revoked, which indicates token reuse.
+func (r *TokenRepository) MarkRefreshTokenUsed(ctx context.Context, id, replacedBy primitive.ObjectID, now time.Time) (bool, error) {
+	res, err := r.refreshTokens.UpdateOne(ctx,
+		bson.M{"_id": id, "used_at": nil, "revoked_at": nil},
+		bson.M{"$set": bson.M{"used_at": now, "replaced_by": replacedBy, "updated_at": now}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token of the family
+func (r *TokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID primitive.ObjectID, now time.Time) error {
+	_, err := r.refreshTokens.UpdateMany(ctx,
+		bson.M{"family_id": familyID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return nil
+}
+
+// RevokeToken adds a token or family ID to the revocation list until it expires
+func (r *TokenRepository) RevokeToken(ctx context.Context, id string, expiresAt, now time.Time) error {
+	_, err := r.revokedTokens.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"expires_at": expiresAt, "revoked_at": now}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// IsTokenRevoked reports whether any of the given token or family IDs is on the revocation list
+func (r *TokenRepository) IsTokenRevoked(ctx context.Context, ids ...string) (bool, error) {
+	if len(ids) == 0 {
+		return false, nil
+	}
+	count, err := r.revokedTokens.CountDocuments(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "expires_at": bson.M{"$gt": time.Now()}},
+		options.Count().SetLimit(1),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return count > 0, nil
+}
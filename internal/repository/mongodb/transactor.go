@@ -0,0 +1,144 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/prototype01/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// illegalOperationCode is the server error code returned when a transaction is
+// started on a deployment that doesn't support them
+const illegalOperationCode = 20
+
+// commitHooks are the functions to run once a transaction commits
+type commitHooks struct {
+	mu  sync.Mutex
+	fns []func(ctx context.Context)
+}
+
+// commitHooksKey is the context key of the commit hooks of a running transaction
+type commitHooksKey struct{}
+
+// Transactor runs functions in multi-document transactions. Transactions require a
+// replica set or a sharded cluster; on a standalone server functions run without one.
+type Transactor struct {
+	client *mongo.Client
+
+	mu        sync.Mutex
+	checked   bool
+	supported bool
+}
+
+// NewTransactor creates a new Transactor using sessions of the client
+func NewTransactor(client *mongo.Client) *Transactor {
+	return &Transactor{client: client}
+}
+
+// WithTransaction runs fn in a transaction, retrying it on transient errors. It runs
+// fn without a transaction when the deployment doesn't support transactions.
+func (t *Transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !t.transactionsSupported(ctx) {
+		return fn(ctx)
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	hooks := &commitHooks{}
+	_, err = session.WithTransaction(context.WithValue(ctx, commitHooksKey{}, hooks), func(sc mongo.SessionContext) (interface{}, error) {
+		// A retried transaction starts over, the hooks of the aborted attempt are dropped
+		hooks.reset()
+		return nil, fn(sc)
+	})
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == illegalOperationCode {
+		// The deployment changed or was misdetected; nothing was committed
+		logger.Warn("MongoDB rejected a transaction, running without transactions")
+		t.setSupported(false)
+		return fn(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	hooks.run(ctx)
+	return nil
+}
+
+// AfterCommit runs fn once the transaction started by WithTransaction commits, with
+// the context WithTransaction was called with. Without a transaction fn runs right away.
+func (t *Transactor) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	hooks, ok := ctx.Value(commitHooksKey{}).(*commitHooks)
+	if !ok || !t.InTransaction(ctx) {
+		fn(ctx)
+		return
+	}
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.fns = append(hooks.fns, fn)
+}
+
+// InTransaction reports whether ctx carries a session with a running transaction
+func (t *Transactor) InTransaction(ctx context.Context) bool {
+	return mongo.SessionFromContext(ctx) != nil
+}
+
+// reset drops the queued hooks
+func (h *commitHooks) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fns = nil
+}
+
+// run runs the queued hooks in order
+func (h *commitHooks) run(ctx context.Context) {
+	h.mu.Lock()
+	fns := h.fns
+	h.fns = nil
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn(ctx)
+	}
+}
+
+// transactionsSupported reports whether the deployment is a replica set or a sharded
+// cluster. The answer is cached once the server could be asked.
+func (t *Transactor) transactionsSupported(ctx context.Context) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.checked {
+		return t.supported
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := t.client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		logger.Error("Failed to detect MongoDB transaction support", err)
+		return false
+	}
+
+	t.checked = true
+	t.supported = hello.SetName != "" || hello.Msg == "isdbgrid"
+	if !t.supported {
+		logger.Warn("MongoDB is a standalone server, multi-document transactions are disabled")
+	}
+	return t.supported
+}
+
+// setSupported overrides the detected transaction support
+func (t *Transactor) setSupported(supported bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.checked = true
+	t.supported = supported
+}
@@ -0,0 +1,120 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// usersCollection is the name of the users collection
+const usersCollection = "users"
+
+// UserRepository stores users
+type UserRepository struct {
+	users *mongo.Collection
+}
+
+// NewUserRepository creates a new UserRepository
+func NewUserRepository(db *mongo.Database) *UserRepository {
+	return &UserRepository{users: db.Collection(usersCollection)}
+}
+
+// EnsureIndexes creates the unique email index
+func (r *UserRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.users.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create user indexes: %w", err)
+	}
+	return nil
+}
+
+// Create inserts a new user, returning models.ErrDuplicate when the email is taken
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	user.BeforeCreate()
+	if _, err := r.users.InsertOne(ctx, user); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrDuplicate
+		}
+		return fmt.Errorf("failed to insert user: %w", err)
+	}
+	return nil
+}
+
+// FindByID returns the user with the given ID
+func (r *UserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+// FindByIDs returns the users with the given IDs, in no particular order
+func (r *UserRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.User, error) {
+	return findIn[models.User](ctx, r.users, "_id", ids, "users")
+}
+
+// FindByEmail returns the user with the given normalized email
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"email": email})
+}
+
+// UpdatePasswordHash replaces the stored password hash of a user
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, id primitive.ObjectID, hash string) error {
+	_, err := r.users.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"password_hash": hash, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+	return nil
+}
+
+// SaveProfile stores the profile, addresses and payment methods of a user if they
+// weren't changed since the user was loaded, returning models.ErrConflict otherwise
+func (r *UserRepository) SaveProfile(ctx context.Context, user *models.User) error {
+	user.BeforeUpdate()
+	filter := bson.M{"_id": user.ID, "version": user.Version}
+	if user.Version == 0 {
+		// Users registered before profiles were versioned have no version yet
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	res, err := r.users.UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"first_name":         user.FirstName,
+		"last_name":          user.LastName,
+		"profile_picture":    user.ProfilePicture,
+		"shipping_addresses": user.ShippingAddresses,
+		"payment_methods":    user.PaymentMethods,
+		"version":            user.Version + 1,
+		"updated_at":         user.UpdatedAt,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to save user profile: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return models.ErrConflict
+	}
+	user.Version++
+	return nil
+}
+
+// findOne returns the single user matching the filter
+func (r *UserRepository) findOne(ctx context.Context, filter bson.M) (*models.User, error) {
+	var user models.User
+	err := r.users.FindOne(ctx, filter).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	return &user, nil
+}
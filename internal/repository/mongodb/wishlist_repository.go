@@ -0,0 +1,139 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/prototype01/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// wishlistsCollection is the name of the wishlists collection
+const wishlistsCollection = "wishlists"
+
+// WishlistRepository stores wishlists
+type WishlistRepository struct {
+	wishlists *mongo.Collection
+}
+
+// NewWishlistRepository creates a new WishlistRepository
+func NewWishlistRepository(db *mongo.Database) *WishlistRepository {
+	return &WishlistRepository{wishlists: db.Collection(wishlistsCollection)}
+}
+
+// EnsureIndexes creates the unique name, default wishlist and share token indexes
+func (r *WishlistRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.wishlists.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"is_default": true}),
+		},
+		{
+			Keys: bson.D{{Key: "share_token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"share_token_hash": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create wishlist indexes: %w", err)
+	}
+	return nil
+}
+
+// Create inserts a new wishlist, returning models.ErrDuplicate when the user has a
+// wishlist with the same name or already has a default wishlist
+func (r *WishlistRepository) Create(ctx context.Context, wishlist *models.Wishlist) error {
+	wishlist.BeforeCreate()
+	if _, err := r.wishlists.InsertOne(ctx, wishlist); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrDuplicate
+		}
+		return fmt.Errorf("failed to insert wishlist: %w", err)
+	}
+	return nil
+}
+
+// FindByID returns the wishlist with the given ID
+func (r *WishlistRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Wishlist, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+// FindByIDs returns the wishlists with the given IDs, in no particular order
+func (r *WishlistRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Wishlist, error) {
+	return findIn[models.Wishlist](ctx, r.wishlists, "_id", ids, "wishlists")
+}
+
+// FindDefault returns the default wishlist of a user
+func (r *WishlistRepository) FindDefault(ctx context.Context, userID string) (*models.Wishlist, error) {
+	return r.findOne(ctx, bson.M{"user_id": userID, "is_default": true})
+}
+
+// FindByShareTokenHash returns the wishlist shared with the token of the given hash
+func (r *WishlistRepository) FindByShareTokenHash(ctx context.Context, hash string) (*models.Wishlist, error) {
+	return r.findOne(ctx, bson.M{"share_token_hash": hash})
+}
+
+// ListByUser returns the wishlists of a user, the default wishlist first and then
+// oldest first
+func (r *WishlistRepository) ListByUser(ctx context.Context, userID string) ([]models.Wishlist, error) {
+	cursor, err := r.wishlists.Find(ctx, bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "is_default", Value: -1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find wishlists: %w", err)
+	}
+
+	wishlists := []models.Wishlist{}
+	if err := cursor.All(ctx, &wishlists); err != nil {
+		return nil, fmt.Errorf("failed to decode wishlists: %w", err)
+	}
+	return wishlists, nil
+}
+
+// Save replaces a wishlist if it wasn't changed since it was loaded, returning
+// models.ErrConflict otherwise and models.ErrDuplicate when the new name is taken
+func (r *WishlistRepository) Save(ctx context.Context, wishlist *models.Wishlist) error {
+	wishlist.BeforeUpdate()
+	version := wishlist.Version
+	wishlist.Version++
+
+	res, err := r.wishlists.ReplaceOne(ctx, bson.M{"_id": wishlist.ID, "version": version}, wishlist)
+	if err != nil {
+		wishlist.Version = version
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrDuplicate
+		}
+		return fmt.Errorf("failed to save wishlist: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		wishlist.Version = version
+		return models.ErrConflict
+	}
+	return nil
+}
+
+// Delete removes a wishlist
+func (r *WishlistRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if _, err := r.wishlists.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return fmt.Errorf("failed to delete wishlist: %w", err)
+	}
+	return nil
+}
+
+// findOne returns the wishlist matching the filter
+func (r *WishlistRepository) findOne(ctx context.Context, filter bson.M) (*models.Wishlist, error) {
+	var wishlist models.Wishlist
+	err := r.wishlists.FindOne(ctx, filter).Decode(&wishlist)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find wishlist: %w", err)
+	}
+	return &wishlist, nil
+}
@@ -0,0 +1,104 @@
+// Package connection implements Relay style cursor connections
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Page size limits
+const (
+	// DefaultPageSize is used when neither first nor last is given
+	DefaultPageSize = 20
+	// MaxPageSize is the largest page a client can request
+	MaxPageSize = 100
+)
+
+// Args are the Relay pagination arguments
+type Args struct {
+	First  *int    `json:"first,omitempty"`
+	After  *string `json:"after,omitempty"`
+	Last   *int    `json:"last,omitempty"`
+	Before *string `json:"before,omitempty"`
+}
+
+// Backward reports whether the page is requested with last/before
+func (a Args) Backward() bool {
+	return a.Last != nil || (a.First == nil && a.Before != nil)
+}
+
+// Limit returns the requested page size
+func (a Args) Limit() int {
+	switch {
+	case a.First != nil:
+		return *a.First
+	case a.Last != nil:
+		return *a.Last
+	default:
+		return DefaultPageSize
+	}
+}
+
+// Validate checks that the arguments describe a single page in one direction
+func (a Args) Validate() error {
+	if a.First != nil && a.Last != nil {
+		return errors.New("first and last can't be used together")
+	}
+	if a.First != nil && a.Before != nil {
+		return errors.New("first can't be used with before, use last")
+	}
+	if a.Last != nil && a.After != nil {
+		return errors.New("last can't be used with after, use first")
+	}
+	if limit := a.Limit(); limit < 0 || limit > MaxPageSize {
+		return fmt.Errorf("page size must be between 0 and %d", MaxPageSize)
+	}
+	return nil
+}
+
+// PageInfo describes the position of a page in the full result
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
+// Edge is a node together with its cursor
+type Edge[T any] struct {
+	Node   T      `json:"node"`
+	Cursor string `json:"cursor"`
+}
+
+// CountFunc counts every node matching the connection, ignoring pagination
+type CountFunc func(ctx context.Context) (int64, error)
+
+// Connection is a page of nodes
+type Connection[T any] struct {
+	Edges    []Edge[T] `json:"edges"`
+	PageInfo PageInfo  `json:"pageInfo"`
+	Count    CountFunc `json:"-"`
+}
+
+// TotalCount counts every node matching the connection. The count only runs when
+// the field is requested.
+func (c *Connection[T]) TotalCount(ctx context.Context) (int, error) {
+	if c.Count == nil {
+		return len(c.Edges), nil
+	}
+	n, err := c.Count(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// Nodes returns the nodes of the page in order
+func (c *Connection[T]) Nodes() []T {
+	nodes := make([]T, 0, len(c.Edges))
+	for _, edge := range c.Edges {
+		nodes = append(nodes, edge.Node)
+	}
+	return nodes
+}
@@ -0,0 +1,90 @@
+package connection_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prototype01/pkg/connection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func rawValue(t *testing.T, v interface{}) bson.RawValue {
+	t.Helper()
+	doc, err := bson.Marshal(bson.M{"v": v})
+	if err != nil {
+		t.Fatalf("failed to marshal value: %v", err)
+	}
+	return bson.Raw(doc).Lookup("v")
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	codec := connection.NewCodec([]byte("secret"))
+	createdAt := time.Date(2025, 5, 18, 10, 0, 0, 0, time.UTC)
+	id := primitive.NewObjectID()
+
+	encoded, err := codec.Encode(connection.Cursor{Sort: "created_at", Value: rawValue(t, createdAt), ID: id})
+	if err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+
+	cursor, err := codec.Decode(encoded, "created_at")
+	if err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	if cursor.ID != id {
+		t.Errorf("got ID %s, want %s", cursor.ID.Hex(), id.Hex())
+	}
+	if got := cursor.Value.Time(); !got.Equal(createdAt) {
+		t.Errorf("got sort value %v, want %v", got, createdAt)
+	}
+}
+
+func TestCodecRejectsTamperedCursors(t *testing.T) {
+	codec := connection.NewCodec([]byte("secret"))
+	encoded, err := codec.Encode(connection.Cursor{Sort: "price", Value: rawValue(t, 9.99), ID: primitive.NewObjectID()})
+	if err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+
+	if _, err := codec.Decode(encoded, "name"); err != connection.ErrInvalidCursor {
+		t.Errorf("expected cursor for another sort key to be rejected, got %v", err)
+	}
+	if _, err := connection.NewCodec([]byte("other")).Decode(encoded, "price"); err != connection.ErrInvalidCursor {
+		t.Errorf("expected cursor signed with another secret to be rejected, got %v", err)
+	}
+
+	tampered := []byte(encoded)
+	tampered[5] ^= 1
+	if _, err := codec.Decode(string(tampered), "price"); err != connection.ErrInvalidCursor {
+		t.Errorf("expected tampered cursor to be rejected, got %v", err)
+	}
+	if _, err := codec.Decode("not-a-cursor", "price"); err != connection.ErrInvalidCursor {
+		t.Errorf("expected garbage cursor to be rejected, got %v", err)
+	}
+}
+
+func TestArgsValidate(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+	strPtr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name  string
+		args  connection.Args
+		valid bool
+	}{
+		{"defaults", connection.Args{}, true},
+		{"first after", connection.Args{First: intPtr(10), After: strPtr("c")}, true},
+		{"last before", connection.Args{Last: intPtr(10), Before: strPtr("c")}, true},
+		{"first and last", connection.Args{First: intPtr(1), Last: intPtr(1)}, false},
+		{"first before", connection.Args{First: intPtr(1), Before: strPtr("c")}, false},
+		{"last after", connection.Args{Last: intPtr(1), After: strPtr("c")}, false},
+		{"negative", connection.Args{First: intPtr(-1)}, false},
+		{"too large", connection.Args{First: intPtr(connection.MaxPageSize + 1)}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.args.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v, want valid=%v", tt.name, err, tt.valid)
+		}
+	}
+}
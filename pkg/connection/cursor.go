@@ -0,0 +1,78 @@
+package connection
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidCursor is returned for cursors that were altered or made for another sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// macLength is the number of HMAC bytes appended to a cursor
+const macLength = 16
+
+// Cursor is the decoded position of a node: its sort key value and ID
+type Cursor struct {
+	Sort  string
+	Value bson.RawValue
+	ID    primitive.ObjectID
+}
+
+// cursorPayload is the BSON document signed into a cursor. BSON keeps the type of
+// the sort value, so dates and numbers compare correctly when the cursor is used.
+type cursorPayload struct {
+	Sort  string             `bson:"s"`
+	Value bson.RawValue      `bson:"v"`
+	ID    primitive.ObjectID `bson:"id"`
+}
+
+// Codec encodes opaque cursors signed with an HMAC so clients can't forge positions
+type Codec struct {
+	key []byte
+}
+
+// NewCodec creates a Codec signing cursors with the given secret
+func NewCodec(secret []byte) *Codec {
+	return &Codec{key: secret}
+}
+
+// Encode returns the opaque cursor for a node
+func (c *Codec) Encode(cursor Cursor) (string, error) {
+	payload, err := bson.Marshal(cursorPayload(cursor))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(append(payload, c.sign(payload)...)), nil
+}
+
+// Decode verifies and decodes a cursor made for the given sort key
+func (c *Codec) Decode(cursor, sort string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(data) <= macLength {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, mac := data[:len(data)-macLength], data[len(data)-macLength:]
+	if !hmac.Equal(mac, c.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var decoded cursorPayload
+	if err := bson.Unmarshal(payload, &decoded); err != nil || decoded.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+	return (*Cursor)(&decoded), nil
+}
+
+// sign returns the truncated HMAC-SHA256 of a payload
+func (c *Codec) sign(payload []byte) []byte {
+	h := hmac.New(sha256.New, c.key)
+	h.Write(payload)
+	return h.Sum(nil)[:macLength]
+}
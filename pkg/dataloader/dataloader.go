@@ -0,0 +1,243 @@
+// Package dataloader batches lookups by key made during a short window into a
+// single fetch and caches their results, so resolvers of nested fields don't each
+// issue their own query
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Batching defaults
+const (
+	// DefaultWait is how long a batch collects keys before it is fetched
+	DefaultWait = time.Millisecond
+	// DefaultMaxBatch is the largest number of keys fetched at once
+	DefaultMaxBatch = 100
+)
+
+// ErrBatchMismatch is returned for every key of a batch when the fetch function
+// doesn't return one result per key
+var ErrBatchMismatch = errors.New("dataloader: fetch returned a result count different from the key count")
+
+// FetchFunc loads the values of keys. It returns one value per key in the order of
+// the keys, and either no errors, one error applying to every key, or one error
+// per key.
+type FetchFunc[K comparable, V any] func(ctx context.Context, keys []K) ([]V, []error)
+
+// Loader batches and caches the lookups of values by key. A Loader is meant to live
+// for a single request; it never evicts cached results.
+type Loader[K comparable, V any] struct {
+	fetch    FetchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu    sync.Mutex
+	cache map[K]*result[V]
+	batch *batch[K, V]
+}
+
+// Option configures a Loader
+type Option func(*options)
+
+type options struct {
+	wait     time.Duration
+	maxBatch int
+}
+
+// WithWait sets how long a batch collects keys before it is fetched
+func WithWait(wait time.Duration) Option {
+	return func(o *options) { o.wait = wait }
+}
+
+// WithMaxBatch sets the largest number of keys fetched at once
+func WithMaxBatch(n int) Option {
+	return func(o *options) { o.maxBatch = n }
+}
+
+// result is the value or error of a key, available once done is closed
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// batch is a set of keys waiting to be fetched together
+type batch[K comparable, V any] struct {
+	ctx     context.Context
+	keys    []K
+	results []*result[V]
+}
+
+// New creates a Loader fetching values with fetch
+func New[K comparable, V any](fetch FetchFunc[K, V], opts ...Option) *Loader[K, V] {
+	o := options{wait: DefaultWait, maxBatch: DefaultMaxBatch}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Loader[K, V]{
+		fetch:    fetch,
+		wait:     o.wait,
+		maxBatch: o.maxBatch,
+		cache:    make(map[K]*result[V]),
+	}
+}
+
+// Load returns the value of a key, waiting for the batch it was added to
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	return l.await(ctx, l.enqueue(ctx, key))
+}
+
+// LoadMany returns the values of keys in the order of the keys, with the error of
+// each key at the same index. Errors is nil when every key loaded.
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys []K) ([]V, []error) {
+	pending := make([]*result[V], len(keys))
+	for i, key := range keys {
+		pending[i] = l.enqueue(ctx, key)
+	}
+
+	values := make([]V, len(keys))
+	var errs []error
+	for i, r := range pending {
+		value, err := l.await(ctx, r)
+		if err != nil {
+			if errs == nil {
+				errs = make([]error, len(keys))
+			}
+			errs[i] = err
+		}
+		values[i] = value
+	}
+	return values, errs
+}
+
+// Prime caches the value of a key unless it was already requested, e.g. with a
+// value just written by a mutation
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.cache[key]; !ok {
+		r := &result[V]{done: make(chan struct{}), value: value}
+		close(r.done)
+		l.cache[key] = r
+	}
+}
+
+// Clear removes the cached result of a key so the next Load fetches it again
+func (l *Loader[K, V]) Clear(key K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.cache, key)
+}
+
+// enqueue returns the cached result of a key, adding the key to the current batch
+// when it wasn't requested yet
+func (l *Loader[K, V]) enqueue(ctx context.Context, key K) *result[V] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r, ok := l.cache[key]; ok {
+		return r
+	}
+	r := &result[V]{done: make(chan struct{})}
+	l.cache[key] = r
+
+	if l.batch == nil {
+		b := &batch[K, V]{ctx: context.WithoutCancel(ctx)}
+		l.batch = b
+		time.AfterFunc(l.wait, func() { l.dispatch(b) })
+	}
+	l.batch.keys = append(l.batch.keys, key)
+	l.batch.results = append(l.batch.results, r)
+
+	// Full batches are fetched right away
+	if len(l.batch.keys) >= l.maxBatch {
+		b := l.batch
+		l.batch = nil
+		go l.run(b)
+	}
+	return r
+}
+
+// await waits for a result, giving up when the context is done
+func (l *Loader[K, V]) await(ctx context.Context, r *result[V]) (V, error) {
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// dispatch fetches a batch whose wait elapsed, unless it was fetched once full
+func (l *Loader[K, V]) dispatch(b *batch[K, V]) {
+	l.mu.Lock()
+	if l.batch != b {
+		l.mu.Unlock()
+		return
+	}
+	l.batch = nil
+	l.mu.Unlock()
+	l.run(b)
+}
+
+// run fetches the keys of a batch and completes their results
+func (l *Loader[K, V]) run(b *batch[K, V]) {
+	values, errs := l.safeFetch(b.ctx, b.keys)
+	for i, r := range b.results {
+		switch {
+		case len(errs) == 1:
+			r.err = errs[0]
+		case len(errs) > 1 && len(errs) != len(b.keys):
+			r.err = ErrBatchMismatch
+		case len(errs) > 1:
+			r.err = errs[i]
+		}
+		if r.err == nil {
+			if len(values) == len(b.keys) {
+				r.value = values[i]
+			} else {
+				r.err = ErrBatchMismatch
+			}
+		}
+		close(r.done)
+	}
+}
+
+// safeFetch calls the fetch function, turning a panic into an error for the batch
+func (l *Loader[K, V]) safeFetch(ctx context.Context, keys []K) (values []V, errs []error) {
+	defer func() {
+		if p := recover(); p != nil {
+			values, errs = nil, []error{fmt.Errorf("dataloader: fetch panicked: %v", p)}
+		}
+	}()
+	return l.fetch(ctx, keys)
+}
+
+// Align orders fetched values by key for a FetchFunc. Values may come in any order
+// and keys without a value get the missing error.
+func Align[K comparable, V any](keys []K, values []V, key func(V) K, missing error) ([]V, []error) {
+	byKey := make(map[K]V, len(values))
+	for _, value := range values {
+		byKey[key(value)] = value
+	}
+
+	aligned := make([]V, len(keys))
+	var errs []error
+	for i, k := range keys {
+		value, ok := byKey[k]
+		if !ok {
+			if errs == nil {
+				errs = make([]error, len(keys))
+			}
+			errs[i] = missing
+			continue
+		}
+		aligned[i] = value
+	}
+	return aligned, errs
+}
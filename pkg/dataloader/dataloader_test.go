@@ -0,0 +1,115 @@
+package dataloader_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prototype01/pkg/dataloader"
+)
+
+var errMissing = errors.New("missing")
+
+// recordingFetch returns the square of each even key and records every batch
+type recordingFetch struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (f *recordingFetch) fetch(ctx context.Context, keys []int) ([]int, []error) {
+	f.mu.Lock()
+	f.batches = append(f.batches, append([]int(nil), keys...))
+	f.mu.Unlock()
+
+	var found []int
+	for _, key := range keys {
+		if key%2 == 0 {
+			found = append(found, key)
+		}
+	}
+	values, errs := dataloader.Align(keys, found, func(v int) int { return v }, errMissing)
+	for i := range values {
+		values[i] *= values[i]
+	}
+	return values, errs
+}
+
+func TestLoadBatchesAndCaches(t *testing.T) {
+	ctx := context.Background()
+	f := &recordingFetch{}
+	loader := dataloader.New(f.fetch, dataloader.WithWait(5*time.Millisecond))
+
+	var wg sync.WaitGroup
+	got := make([]int, 4)
+	for i, key := range []int{4, 2, 4, 6} {
+		wg.Add(1)
+		go func(i, key int) {
+			defer wg.Done()
+			got[i], _ = loader.Load(ctx, key)
+		}(i, key)
+	}
+	wg.Wait()
+
+	if want := []int{16, 4, 16, 36}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if len(f.batches) != 1 || len(f.batches[0]) != 3 {
+		t.Fatalf("batches = %v, want one batch of the 3 distinct keys", f.batches)
+	}
+
+	if v, err := loader.Load(ctx, 2); err != nil || v != 4 {
+		t.Fatalf("cached Load = %d, %v, want 4", v, err)
+	}
+	if len(f.batches) != 1 {
+		t.Fatalf("a cached key was fetched again: %v", f.batches)
+	}
+}
+
+func TestLoadManyKeepsOrderAndPerKeyErrors(t *testing.T) {
+	f := &recordingFetch{}
+	loader := dataloader.New(f.fetch)
+
+	values, errs := loader.LoadMany(context.Background(), []int{6, 3, 2})
+	if want := []int{36, 0, 4}; !reflect.DeepEqual(values, want) {
+		t.Fatalf("values = %v, want %v", values, want)
+	}
+	if len(errs) != 3 || errs[0] != nil || !errors.Is(errs[1], errMissing) || errs[2] != nil {
+		t.Fatalf("errs = %v, want only the odd key missing", errs)
+	}
+}
+
+func TestLoadSplitsFullBatches(t *testing.T) {
+	f := &recordingFetch{}
+	loader := dataloader.New(f.fetch, dataloader.WithMaxBatch(2))
+
+	if _, errs := loader.LoadMany(context.Background(), []int{2, 4, 6, 8, 10}); errs != nil {
+		t.Fatalf("LoadMany: %v", errs)
+	}
+	if len(f.batches) != 3 {
+		t.Fatalf("batches = %v, want 3 batches of at most 2 keys", f.batches)
+	}
+}
+
+func TestLoadReportsBatchErrors(t *testing.T) {
+	ctx := context.Background()
+	failure := errors.New("database down")
+	failing := dataloader.New(func(ctx context.Context, keys []int) ([]int, []error) {
+		return nil, []error{failure}
+	})
+	if _, errs := failing.LoadMany(ctx, []int{1, 2}); len(errs) != 2 || !errors.Is(errs[0], failure) || !errors.Is(errs[1], failure) {
+		t.Fatalf("errs = %v, want the batch error for every key", errs)
+	}
+
+	short := dataloader.New(func(ctx context.Context, keys []int) ([]int, []error) {
+		return []int{1}, nil
+	})
+	if _, err := short.Load(ctx, 1); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, errs := short.LoadMany(ctx, []int{2, 3}); len(errs) != 2 || !errors.Is(errs[0], dataloader.ErrBatchMismatch) {
+		t.Fatalf("errs = %v, want ErrBatchMismatch", errs)
+	}
+}
@@ -0,0 +1,73 @@
+// Package pubsub delivers events published on a topic to the subscribers of that
+// topic within the process
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultBuffer is the number of undelivered events a subscriber can fall behind by
+const DefaultBuffer = 16
+
+// Broker fans events out to the current subscribers of their topic. Delivery is
+// best effort: events published while nobody subscribes are lost, and a
+// subscriber whose buffer is full misses events rather than blocking publishers.
+type Broker[T any] struct {
+	buffer int
+
+	mu     sync.RWMutex
+	topics map[string]map[chan T]struct{}
+}
+
+// New creates a Broker buffering up to buffer events per subscriber
+func New[T any](buffer int) *Broker[T] {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return &Broker[T]{buffer: buffer, topics: map[string]map[chan T]struct{}{}}
+}
+
+// Publish delivers an event to the subscribers of a topic without waiting for them
+func (b *Broker[T]) Publish(ctx context.Context, topic string, event T) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.topics[topic] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a channel receiving the events published on a topic until
+// ctx is done, when the channel is closed
+func (b *Broker[T]) Subscribe(ctx context.Context, topic string) (<-chan T, error) {
+	ch := make(chan T, b.buffer)
+
+	b.mu.Lock()
+	if b.topics[topic] == nil {
+		b.topics[topic] = map[chan T]struct{}{}
+	}
+	b.topics[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	context.AfterFunc(ctx, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.topics[topic], ch)
+		if len(b.topics[topic]) == 0 {
+			delete(b.topics, topic)
+		}
+		close(ch)
+	})
+	return ch, nil
+}
+
+// Subscribers returns the number of subscribers of a topic
+func (b *Broker[T]) Subscribers(topic string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.topics[topic])
+}
@@ -0,0 +1,73 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prototype01/pkg/pubsub"
+)
+
+func TestPublishReachesTopicSubscribers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := pubsub.New[int](4)
+
+	first, _ := b.Subscribe(ctx, "a")
+	second, _ := b.Subscribe(ctx, "a")
+	other, _ := b.Subscribe(ctx, "b")
+
+	_ = b.Publish(ctx, "a", 1)
+	for _, ch := range []<-chan int{first, second} {
+		select {
+		case got := <-ch:
+			if got != 1 {
+				t.Fatalf("received %d, want 1", got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("event not delivered")
+		}
+	}
+	select {
+	case got := <-other:
+		t.Fatalf("subscriber of another topic received %d", got)
+	default:
+	}
+}
+
+func TestSlowSubscribersMissEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := pubsub.New[int](2)
+	ch, _ := b.Subscribe(ctx, "a")
+
+	for i := 0; i < 5; i++ {
+		_ = b.Publish(ctx, "a", i)
+	}
+	if got := len(ch); got != 2 {
+		t.Fatalf("buffered %d events, want 2", got)
+	}
+	if got := <-ch; got != 0 {
+		t.Fatalf("received %d first, want 0", got)
+	}
+}
+
+func TestSubscriptionEndsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := pubsub.New[int](1)
+	ch, _ := b.Subscribe(ctx, "a")
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("received an event, want a closed channel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after the context was cancelled")
+	}
+	if n := b.Subscribers("a"); n != 0 {
+		t.Fatalf("%d subscribers left, want 0", n)
+	}
+	_ = b.Publish(context.Background(), "a", 1)
+}
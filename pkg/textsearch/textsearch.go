@@ -0,0 +1,46 @@
+// Package textsearch provides text helpers for typo tolerant search
+package textsearch
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Words splits text into lower case words without accents or punctuation
+func Words(text string) []string {
+	var sb strings.Builder
+	for _, r := range norm.NFD.String(text) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Drop the combining marks left by the decomposition, e.g. accents
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(unicode.ToLower(r))
+		default:
+			sb.WriteRune(' ')
+		}
+	}
+	return strings.Fields(sb.String())
+}
+
+// Trigrams returns the sorted distinct trigrams of the words of text. Words are
+// padded with a space on both sides so word boundaries weigh in the comparison,
+// e.g. "ipone" shares " ip", "one" and "ne " with "iphone".
+func Trigrams(text string) []string {
+	seen := map[string]bool{}
+	for _, word := range Words(text) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			seen[string(runes[i:i+3])] = true
+		}
+	}
+
+	grams := make([]string, 0, len(seen))
+	for gram := range seen {
+		grams = append(grams, gram)
+	}
+	sort.Strings(grams)
+	return grams
+}
@@ -0,0 +1,27 @@
+package textsearch_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prototype01/pkg/textsearch"
+)
+
+func TestWords(t *testing.T) {
+	got := textsearch.Words("  Crème Brûlée, 2-pack! ")
+	want := []string{"creme", "brulee", "2", "pack"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTrigrams(t *testing.T) {
+	got := textsearch.Trigrams("Ab ab")
+	want := []string{" ab", "ab "}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if grams := textsearch.Trigrams(" !? "); len(grams) != 0 {
+		t.Errorf("expected no trigrams without words, got %q", grams)
+	}
+}
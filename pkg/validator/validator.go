@@ -76,3 +76,82 @@ func ValidateNonEmpty(field, value string) error {
 	}
 	return nil
 }
+
+// countryCodePattern matches ISO 3166-1 alpha-2 country codes
+var countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// postalCodePatterns are the postal code formats of the countries with known rules
+var postalCodePatterns = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FI": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"IE": regexp.MustCompile(`^[A-Z]\d[\dW] ?[A-Z\d]{4}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"MX": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"NO": regexp.MustCompile(`^\d{4}$`),
+	"NZ": regexp.MustCompile(`^\d{4}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// optionalPostalCodeCountries are the countries without postal codes, or where
+// addresses commonly have none, e.g. Hong Kong, the United Arab Emirates and Ireland
+var optionalPostalCodeCountries = map[string]bool{
+	"AE": true, "AG": true, "AO": true, "AW": true, "BF": true, "BI": true, "BJ": true,
+	"BO": true, "BS": true, "BW": true, "BZ": true, "CD": true, "CF": true, "CG": true,
+	"CI": true, "CK": true, "CM": true, "DJ": true, "DM": true, "ER": true, "FJ": true,
+	"GA": true, "GD": true, "GH": true, "GM": true, "GQ": true, "GY": true, "HK": true,
+	"IE": true, "KI": true, "KM": true, "KN": true, "KP": true, "LC": true, "ML": true,
+	"MO": true, "MR": true, "MW": true, "NR": true, "NU": true, "QA": true, "RW": true,
+	"SB": true, "SC": true, "SL": true, "SR": true, "ST": true, "SY": true, "TD": true,
+	"TF": true, "TG": true, "TK": true, "TL": true, "TO": true, "TT": true, "TV": true,
+	"UG": true, "VU": true, "YE": true, "ZW": true,
+}
+
+// genericPostalCodePattern is applied to the postal codes of other countries
+var genericPostalCodePattern = regexp.MustCompile(`^[A-Z\d][A-Z\d -]{1,8}[A-Z\d]$`)
+
+// ValidateCountryCode validates an upper case ISO 3166-1 alpha-2 country code
+func ValidateCountryCode(country string) error {
+	if !countryCodePattern.MatchString(country) {
+		return fmt.Errorf("country must be an ISO 3166-1 alpha-2 code")
+	}
+	return nil
+}
+
+// ValidatePostalCode validates a postal code against the format of a country, given
+// as an ISO 3166-1 alpha-2 code. Letters are compared case-insensitively. Countries
+// without known rules accept 3 to 10 letters, digits, spaces and dashes. The postal
+// code may be empty in countries without postal codes.
+func ValidatePostalCode(country, code string) error {
+	country = strings.ToUpper(country)
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		if optionalPostalCodeCountries[country] {
+			return nil
+		}
+		return fmt.Errorf("postal code cannot be empty")
+	}
+	pattern, ok := postalCodePatterns[country]
+	if !ok {
+		pattern = genericPostalCodePattern
+	}
+	if !pattern.MatchString(code) {
+		return fmt.Errorf("invalid postal code for country %s", country)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package validator_test
+
+import (
+	"testing"
+
+	"github.com/prototype01/pkg/validator"
+)
+
+func TestValidatePostalCode(t *testing.T) {
+	tests := []struct {
+		country string
+		code    string
+		valid   bool
+	}{
+		{"US", "94105", true},
+		{"US", "94105-1234", true},
+		{"US", "9410", false},
+		{"CA", "k1a 0b1", true},
+		{"CA", "K1A0B1", true},
+		{"CA", "12345", false},
+		{"GB", "SW1A 1AA", true},
+		{"GB", "M1 1AE", true},
+		{"GB", "12345", false},
+		{"NL", "1012 AB", true},
+		{"DE", "1011", false},
+		{"JP", "100-0001", true},
+		{"PL", "00-950", true},
+		{"PL", "00950", false},
+		{"ZA", "0001", true},
+		{"ZA", "!!", false},
+		{"US", " ", false},
+		{"ZA", "", false},
+		{"HK", "", true},
+		{"ae", " ", true},
+		{"IE", "", true},
+		{"IE", "D02 X285", true},
+		{"IE", "12345", false},
+	}
+	for _, tt := range tests {
+		err := validator.ValidatePostalCode(tt.country, tt.code)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidatePostalCode(%q, %q) = %v, want valid=%v", tt.country, tt.code, err, tt.valid)
+		}
+	}
+}
+
+func TestValidateCountryCode(t *testing.T) {
+	for _, code := range []string{"US", "FR"} {
+		if err := validator.ValidateCountryCode(code); err != nil {
+			t.Errorf("expected %q to be valid, got %v", code, err)
+		}
+	}
+	for _, code := range []string{"", "us", "USA", "U1"} {
+		if err := validator.ValidateCountryCode(code); err == nil {
+			t.Errorf("expected %q to be rejected", code)
+		}
+	}
+}